@@ -0,0 +1,28 @@
+// Command rotate-config-key re-encrypts the secrets in ~/.noteai/config.json
+// with a new random key. Stop the server before running it.
+package main
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/your-org/note-server/internal/config"
+)
+
+func main() {
+	manager := config.NewConfigManager()
+
+	newKey, envKey, err := manager.RotateKey()
+	if err != nil {
+		log.Fatalf("Failed to rotate config key: %v", err)
+	}
+
+	if envKey {
+		// The key came from the environment, which can't be updated from here
+		fmt.Printf("Secrets re-encrypted. Set %s to the new key before restarting the server:\n\n", config.KeyEnv)
+		fmt.Printf("%s=%s\n", config.KeyEnv, config.EncodeKey(newKey))
+		return
+	}
+
+	log.Println("Secrets re-encrypted and key file replaced")
+}
@@ -2,25 +2,48 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
 	"path/filepath"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/rs/zerolog"
+	"github.com/your-org/note-server/internal/auth"
 	"github.com/your-org/note-server/internal/config"
 	"github.com/your-org/note-server/internal/database"
+	"github.com/your-org/note-server/internal/events"
+	"github.com/your-org/note-server/internal/health"
+	"github.com/your-org/note-server/internal/jobs"
+	"github.com/your-org/note-server/internal/logging"
 	apphttp "github.com/your-org/note-server/internal/http"
+	"github.com/your-org/note-server/internal/ratelimit"
+	"github.com/your-org/note-server/internal/server"
 	"github.com/your-org/note-server/internal/service"
+	"github.com/your-org/note-server/internal/tracing"
 	"github.com/your-org/note-server/internal/ws"
 )
 
 func main() {
-	// Parse env config
-	cfg, err := config.Load()
+	args := os.Args[1:]
+
+	// "note-server config print [flags]" shows the effective configuration
+	if len(args) >= 2 && args[0] == "config" && args[1] == "print" {
+		printConfig(args[2:])
+		return
+	}
+
+	// Layer defaults, the config file, env vars and flags
+	cfg, err := config.Load(args)
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
 	if err != nil {
 		log.Fatalf("Failed to load config: %v", err)
 	}
@@ -30,59 +53,150 @@ func main() {
 		log.Fatalf("Invalid config: %v", err)
 	}
 
-	// Setup zerolog
-	setupLogger(cfg)
-	logger := zerolog.New(os.Stdout).With().Timestamp().Logger()
+	// One logger for the whole server; requests, sessions and jobs log with
+	// children of it carried in their contexts
+	logger := logging.New(os.Stdout, cfg.LogLevel, cfg.DevMode)
+	logging.SetDefault(logger)
 
-	// Initialize database
-	// Use ~/.noteai/notes.db to match frontend expectations
-	homeDir, err := os.UserHomeDir()
+	// Trace requests through the services, ffmpeg and the database, exporting
+	// spans to a collector if one is configured
+	exporter, err := tracing.NewExporter(context.Background(), cfg.TracingExporter, cfg.TracingEndpoint)
 	if err != nil {
-		log.Fatalf("Failed to get home directory: %v", err)
+		logger.Fatal().Err(err).Msg("Failed to set up tracing")
 	}
-	dbPath := filepath.Join(homeDir, ".noteai", "notes.db")
+	shutdownTracing := tracing.Setup(tracing.Options{
+		ServiceName: "note-server",
+		SampleRatio: cfg.TracingSampleRatio,
+		Exporter:    exporter,
+	})
+
+	// Initialize database
+	// Defaults to ~/.noteai/notes.db to match frontend expectations
+	dbPath := cfg.DBPath
 	
-	// Ensure .noteai directory exists
+	// Ensure the database directory exists
 	if err := os.MkdirAll(filepath.Dir(dbPath), 0755); err != nil {
-		log.Fatalf("Failed to create database directory: %v", err)
+		logger.Fatal().Err(err).Msg("Failed to create database directory")
 	}
 	
 	if err := database.InitDB(dbPath); err != nil {
-		log.Fatalf("Failed to initialize database: %v", err)
+		logger.Fatal().Err(err).Msg("Failed to initialize database")
 	}
 	logger.Info().Str("database_path", dbPath).Msg("Database initialized")
 
 	// Initialize services
 	transcribeService := service.NewTranscribeService()
-	transcribeHub := ws.NewTranscribeHub(transcribeService)
+	eventBus := events.NewBus()
+	jobManager := jobs.NewManager(eventBus)
+	// Usage of the transcription and summary providers is metered per client
+	meter := ratelimit.NewMeter(database.NewUsageStore(), map[string]ratelimit.Quota{
+		ratelimit.TranscriptionMinutes: {Daily: cfg.QuotaTranscriptionMinutesDaily, Monthly: cfg.QuotaTranscriptionMinutesMonthly},
+		ratelimit.SummaryTokens:        {Daily: cfg.QuotaSummaryTokensDaily, Monthly: cfg.QuotaSummaryTokensMonthly},
+	})
+	var limiter *ratelimit.Limiter
+	if cfg.RateLimitRPS > 0 {
+		limiter = ratelimit.New(cfg.RateLimitRPS, cfg.RateLimitBurst)
+	}
+	authenticator := auth.New(auth.Options{
+		Required:     cfg.AuthRequired,
+		Tokens:       cfg.AuthTokens,
+		TicketSecret: []byte(cfg.AuthTicketSecret),
+		TicketTTL:    cfg.AuthTicketTTL,
+		Sessions:     database.NewSessionStore(),
+		SessionTTL:   cfg.AuthSessionTTL,
+		APITokens:    database.NewTokenStore(),
+	})
+	// Uploads, and live sessions clients ask to record, are kept with other media
+	recordingsDir := filepath.Join(cfg.MediaDir, "recordings")
+	transcribeHub := ws.NewTranscribeHubWithOptions(transcribeService, ws.HubOptions{
+		RecordingsDir:   recordingsDir,
+		MaxConnections:  cfg.WSMaxConnections,
+		ReadBufferSize:  cfg.WSReadBufferSize,
+		WriteBufferSize: cfg.WSWriteBufferSize,
+		MaxMessageSize:  cfg.WSMaxMessageSize,
+		ReadTimeout:     cfg.WSReadTimeout,
+		WriteTimeout:    cfg.WSWriteTimeout,
+		AllowedOrigins:  cfg.WSAllowedOrigins,
+		Authenticator:   authenticator,
+		Events:          eventBus,
+		Meter:           meter,
+	})
 
 	// Start the WebSocket hub
 	go transcribeHub.Run()
 
+	// Pick up edits to the JSON config made by the web app or CLI: rebuild the
+	// transcription providers and tell event clients which settings changed
+	configManager := config.GetManager()
+	configManager.SetDefaults(cfg.ProviderDefaults())
+	configureTranscription(logger, transcribeService, configManager.Effective())
+	configManager.Subscribe(func(previous, current config.AppConfig) {
+		configureTranscription(logger, transcribeService, configManager.Effective())
+
+		var fields []string
+		for _, change := range config.Diff(previous, current) {
+			fields = append(fields, change.Field)
+		}
+		eventBus.Publish(events.TopicConfigChanged, events.ConfigChanged{Fields: fields})
+	})
+	watchCtx, stopWatching := context.WithCancel(context.Background())
+	defer stopWatching()
+	if cfg.ConfigWatchInterval > 0 {
+		go configManager.Watch(logger.WithContext(watchCtx), cfg.ConfigWatchInterval)
+	}
+
 	// Initialize chi router with WebSocket hub
-	router := apphttp.NewRouter(transcribeHub)
+	// HTTP transcription shares the hub's service, so both use the configured providers
+	handlers := apphttp.NewHandlersWithServices(transcribeService, service.NewSummarizeService())
+	handlers.SetAuthenticator(authenticator)
+	handlers.SetEvents(eventBus, jobManager)
+	handlers.SetValidateAPI(cfg.DevMode)
+	handlers.SetRecordingsDir(recordingsDir)
+	// Validate has already checked the proxies parse
+	trustedProxies, _ := cfg.TrustedProxyPrefixes()
+	handlers.SetTrustedProxies(trustedProxies)
+	handlers.SetLimits(limiter, meter)
+	handlers.SetHealthChecker(health.NewChecker(cfg.HealthCheckTimeout,
+		health.Database(),
+		health.MediaDir(cfg.MediaDir),
+		health.Binary("ffmpeg"),
+		health.Binary("ffprobe"),
+		health.Providers(&http.Client{Transport: tracing.Transport(nil)}, health.ProviderEndpoints, func() []string {
+			effective := configManager.Effective()
+			providers := []string{effective.TranscriptionProvider}
+			if effective.SummaryProvider != effective.TranscriptionProvider {
+				providers = append(providers, effective.SummaryProvider)
+			}
+			return providers
+		}),
+		health.Hub(transcribeHub),
+	))
+	router := apphttp.NewRouterWithHandlers(transcribeHub, handlers)
 
-	// Create HTTP server
-	addr := "0.0.0.0:" + cfg.Port
-	srv := &http.Server{
-		Addr:    addr,
-		Handler: router,
+	// Create HTTP server on Host:Port, with TLS and a Unix socket if configured
+	srv, err := server.New(cfg, router)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("Failed to create server")
+	}
+	if err := srv.Listen(); err != nil {
+		logger.Fatal().Err(err).Msg("Failed to listen")
 	}
 
 	logger.Info().
-		Str("address", addr).
-		Str("media_dir", cfg.MediaTmpDir).
+		Strs("addresses", srv.Addresses()).
+		Str("media_dir", cfg.MediaDir).
 		Bool("dev_mode", cfg.DevMode).
+		Str("tracing_exporter", cfg.TracingExporter).
 		Msg("Starting note server")
 
 	// Start server in a goroutine
 	go func() {
-		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			logger.Fatal().Err(err).Msg("Server failed to start")
+		if err := srv.Serve(); err != nil {
+			logger.Fatal().Err(err).Msg("Server failed")
 		}
 	}()
 
-	logger.Info().Msgf("Server listening on %s", addr)
+	logger.Info().Msgf("Server listening on %s", strings.Join(srv.Addresses(), ", "))
 
 	// Wait for interrupt signal to gracefully shutdown the server
 	quit := make(chan os.Signal, 1)
@@ -99,30 +213,68 @@ func main() {
 	transcribeHub.Shutdown()
 
 	// Shutdown HTTP server
-	if err := srv.Shutdown(shutdownCtx); err != nil {
+	err = srv.Shutdown(shutdownCtx)
+
+	// Cancel background jobs once no new ones can be submitted
+	jobManager.Shutdown()
+
+	// Send the last spans, including those of the jobs just cancelled
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		logger.Error().Err(err).Msg("Failed to flush traces")
+	}
+
+	if err != nil {
 		logger.Error().Err(err).Msg("Server forced to shutdown")
 	} else {
 		logger.Info().Msg("Server exited gracefully")
 	}
 }
 
-func setupLogger(cfg *config.Config) {
-	// Set log level based on config
-	switch cfg.LogLevel {
-	case "debug":
-		zerolog.SetGlobalLevel(zerolog.DebugLevel)
-	case "info":
-		zerolog.SetGlobalLevel(zerolog.InfoLevel)
-	case "warn":
-		zerolog.SetGlobalLevel(zerolog.WarnLevel)
-	case "error":
-		zerolog.SetGlobalLevel(zerolog.ErrorLevel)
-	default:
-		zerolog.SetGlobalLevel(zerolog.InfoLevel)
-	}
-
-	// Pretty print logs in development mode
-	if cfg.DevMode {
-		log.SetOutput(zerolog.ConsoleWriter{Out: os.Stderr})
+// configureTranscription registers a transcriber for every provider the
+// configuration has credentials for, and makes the configured provider the
+// default. Without one the placeholder transcriber is used.
+func configureTranscription(logger zerolog.Logger, transcribeService *service.TranscribeService, cfg config.AppConfig) {
+	providers := map[string]service.Transcriber{}
+	if cfg.OpenAIKey != "" {
+		model := ""
+		if cfg.TranscriptionProvider == "openai" {
+			model = cfg.TranscriptionModel
+		}
+		providers["openai"] = service.NewOpenAITranscriber(cfg.OpenAIKey, model)
+	}
+
+	var transcriber service.Transcriber = &service.PlaceholderTranscriber{}
+	if provider, ok := providers[cfg.TranscriptionProvider]; ok {
+		transcriber = provider
+	} else if cfg.TranscriptionProvider != "" {
+		logger.Warn().Str("provider", cfg.TranscriptionProvider).Msg("Transcription provider isn't available, using the placeholder transcriber")
+	}
+	transcribeService.SetProviders(transcriber, providers)
+
+	names := make([]string, 0, len(providers))
+	for name := range providers {
+		names = append(names, name)
+	}
+	logger.Info().Strs("providers", names).Str("default", cfg.TranscriptionProvider).Msg("Transcription providers configured")
+}
+
+// printConfig prints each setting's effective value and its source
+func printConfig(args []string) {
+	cfg, err := config.Load(args)
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+	if cfg.File != "" {
+		fmt.Printf("Config file: %s\n\n", cfg.File)
+	}
+	if err := cfg.Print(os.Stdout); err != nil {
+		log.Fatalf("Failed to print config: %v", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		fmt.Printf("\nInvalid config: %v\n", err)
+		os.Exit(1)
 	}
 }
@@ -0,0 +1,362 @@
+package audio
+
+import (
+	"encoding/binary"
+	"math"
+	"time"
+)
+
+// Speech event types emitted by the Segmenter
+const (
+	SpeechStart = "speech_start"
+	SpeechEnd   = "speech_end"
+)
+
+// FrameClassifier decides whether a single frame of PCM16 mono audio contains speech.
+// EnergyVAD is the built-in implementation; a WebRTC-style classifier can be plugged
+// in through VADOptions.Classifier.
+type FrameClassifier interface {
+	IsSpeech(frame []byte) bool
+}
+
+// VADOptions configures voice activity detection and segmentation
+type VADOptions struct {
+	SampleRate int
+
+	// Length of the frames audio is classified in
+	FrameDuration time.Duration
+
+	// Speech must last this long before a segment starts
+	MinSpeech time.Duration
+
+	// Silence must last this long before a segment ends
+	MinSilence time.Duration
+
+	// Audio kept before the start and after the end of each segment
+	Padding time.Duration
+
+	// Segments longer than this are cut so transcription can keep up
+	MaxSegment time.Duration
+
+	// Frame classifier; defaults to an EnergyVAD for SampleRate
+	Classifier FrameClassifier
+}
+
+// DefaultVADOptions returns options tuned for conversational speech at 16kHz
+func DefaultVADOptions() VADOptions {
+	return VADOptions{
+		SampleRate:    16000,
+		FrameDuration: 20 * time.Millisecond,
+		MinSpeech:     60 * time.Millisecond,
+		MinSilence:    400 * time.Millisecond,
+		Padding:       200 * time.Millisecond,
+		MaxSegment:    15 * time.Second,
+	}
+}
+
+// EnergyVAD classifies frames using short-term energy and zero-crossing rate
+// against an adaptive noise floor
+type EnergyVAD struct {
+	// Frames quieter than this RMS (0..1) are never speech
+	MinEnergy float64
+
+	// Frames must exceed the noise floor by this factor to be speech
+	NoiseFactor float64
+
+	// Frames above this zero-crossing rate are treated as noise unless they are loud
+	MaxZeroCrossingRate float64
+
+	noiseFloor float64
+}
+
+// NewEnergyVAD creates an energy/zero-crossing classifier with default thresholds
+func NewEnergyVAD() *EnergyVAD {
+	return &EnergyVAD{
+		MinEnergy:           0.01, // about -40 dBFS
+		NoiseFactor:         3,
+		MaxZeroCrossingRate: 0.35,
+	}
+}
+
+// IsSpeech implements FrameClassifier
+func (v *EnergyVAD) IsSpeech(frame []byte) bool {
+	energy, zcr := frameStats(frame)
+
+	threshold := math.Max(v.MinEnergy, v.noiseFloor*v.NoiseFactor)
+	speech := energy > threshold && (zcr < v.MaxZeroCrossingRate || energy > 4*threshold)
+
+	// Track the noise floor on non-speech frames: fall quickly, rise slowly
+	if !speech {
+		switch {
+		case v.noiseFloor == 0 || energy < v.noiseFloor:
+			v.noiseFloor = energy
+		default:
+			v.noiseFloor = 0.95*v.noiseFloor + 0.05*energy
+		}
+	}
+
+	return speech
+}
+
+// frameStats returns the RMS energy (0..1) and zero-crossing rate of a PCM16 frame
+func frameStats(frame []byte) (energy float64, zcr float64) {
+	samples := len(frame) / 2
+	if samples == 0 {
+		return 0, 0
+	}
+
+	var sum float64
+	var crossings int
+	var prev int16
+	for i := 0; i < samples; i++ {
+		sample := int16(binary.LittleEndian.Uint16(frame[i*2:]))
+		value := float64(sample) / 32768
+		sum += value * value
+		if i > 0 && (sample >= 0) != (prev >= 0) {
+			crossings++
+		}
+		prev = sample
+	}
+
+	return math.Sqrt(sum / float64(samples)), float64(crossings) / float64(samples)
+}
+
+// SpeechEvent marks the start or end of a speech segment. Offsets are relative
+// to the first byte written to the Segmenter. Audio is only set on SpeechEnd and
+// holds the segment's samples, including padding.
+type SpeechEvent struct {
+	Type  string
+	Start time.Duration
+	End   time.Duration
+	Audio []byte
+}
+
+// Segmenter cuts a stream of PCM16 mono audio into speech segments, dropping silence
+type Segmenter struct {
+	opts       VADOptions
+	classifier FrameClassifier
+
+	frameSize      int
+	minSpeechRun   int
+	minSilenceRun  int
+	paddingFrames  int
+	maxSegmentSize int
+
+	// Bytes left over from the previous write that don't fill a frame
+	pending []byte
+
+	// Index of the next frame to be classified
+	frameIndex int
+
+	// Recent frames kept while in silence so segments can start early
+	history [][]byte
+
+	inSpeech   bool
+	speechRun  int
+	silenceRun int
+
+	// Current segment
+	segmentStart int // frame index
+	segment      []byte
+	lastSpeech   int // length of segment up to the end of its last speech frame
+}
+
+// NewSegmenter creates a segmenter; zero-valued options take their defaults
+func NewSegmenter(opts VADOptions) *Segmenter {
+	defaults := DefaultVADOptions()
+	if opts.SampleRate <= 0 {
+		opts.SampleRate = defaults.SampleRate
+	}
+	if opts.FrameDuration <= 0 {
+		opts.FrameDuration = defaults.FrameDuration
+	}
+	if opts.MinSpeech <= 0 {
+		opts.MinSpeech = defaults.MinSpeech
+	}
+	if opts.MinSilence <= 0 {
+		opts.MinSilence = defaults.MinSilence
+	}
+	if opts.Padding < 0 {
+		opts.Padding = 0
+	}
+	if opts.MaxSegment <= 0 {
+		opts.MaxSegment = defaults.MaxSegment
+	}
+
+	classifier := opts.Classifier
+	if classifier == nil {
+		classifier = NewEnergyVAD()
+	}
+
+	frames := func(d time.Duration) int {
+		n := int(d / opts.FrameDuration)
+		if n < 1 {
+			n = 1
+		}
+		return n
+	}
+
+	frameSize := int(int64(opts.SampleRate)*int64(opts.FrameDuration)/int64(time.Second)) * 2
+
+	return &Segmenter{
+		opts:           opts,
+		classifier:     classifier,
+		frameSize:      frameSize,
+		minSpeechRun:   frames(opts.MinSpeech),
+		minSilenceRun:  frames(opts.MinSilence),
+		paddingFrames:  int(opts.Padding / opts.FrameDuration),
+		maxSegmentSize: frames(opts.MaxSegment) * frameSize,
+	}
+}
+
+// Write feeds audio to the segmenter and returns the speech events it completed
+func (s *Segmenter) Write(pcm []byte) []SpeechEvent {
+	var events []SpeechEvent
+
+	data := append(s.pending, pcm...)
+	for len(data) >= s.frameSize {
+		frame := data[:s.frameSize:s.frameSize]
+		data = data[s.frameSize:]
+		events = append(events, s.processFrame(frame)...)
+	}
+	s.pending = append([]byte(nil), data...)
+
+	return events
+}
+
+// Flush ends any open segment, as if the stream were followed by silence
+func (s *Segmenter) Flush() []SpeechEvent {
+	s.pending = nil
+	if !s.inSpeech {
+		return nil
+	}
+	return []SpeechEvent{s.endSegment()}
+}
+
+// Open returns the start and a copy of the audio so far of the segment in
+// progress, if speech has started and not yet ended, so it can be transcribed
+// before it closes
+func (s *Segmenter) Open() (start time.Duration, audio []byte, ok bool) {
+	if !s.inSpeech || len(s.segment) == 0 {
+		return 0, nil, false
+	}
+	return s.frameOffset(s.segmentStart), append([]byte(nil), s.segment...), true
+}
+
+// processFrame classifies one frame and advances the state machine
+func (s *Segmenter) processFrame(frame []byte) []SpeechEvent {
+	speech := s.classifier.IsSpeech(frame)
+	s.frameIndex++
+
+	if !s.inSpeech {
+		s.history = append(s.history, frame)
+		if speech {
+			s.speechRun++
+		} else {
+			s.speechRun = 0
+		}
+
+		if s.speechRun < s.minSpeechRun {
+			// Keep enough history for the padding plus a speech run in progress
+			if keep := s.paddingFrames + s.minSpeechRun; len(s.history) > keep {
+				s.history = s.history[len(s.history)-keep:]
+			}
+			return nil
+		}
+
+		// Speech confirmed: start the segment with padding before the first speech frame
+		lead := s.speechRun + s.paddingFrames
+		if lead > len(s.history) {
+			lead = len(s.history)
+		}
+		s.inSpeech = true
+		s.silenceRun = 0
+		s.segmentStart = s.frameIndex - lead
+		s.segment = nil
+		for _, f := range s.history[len(s.history)-lead:] {
+			s.segment = append(s.segment, f...)
+		}
+		s.lastSpeech = len(s.segment)
+		s.history = nil
+
+		return []SpeechEvent{{Type: SpeechStart, Start: s.frameOffset(s.segmentStart)}}
+	}
+
+	s.segment = append(s.segment, frame...)
+	if speech {
+		s.silenceRun = 0
+		s.lastSpeech = len(s.segment)
+	} else {
+		s.silenceRun++
+	}
+
+	if s.silenceRun >= s.minSilenceRun {
+		return []SpeechEvent{s.endSegment()}
+	}
+
+	if len(s.segment) >= s.maxSegmentSize {
+		// Cut long speech and carry on with a new segment straight away
+		end := s.endSegment()
+		s.inSpeech = true
+		s.segmentStart = s.frameIndex
+		s.lastSpeech = 0
+		return []SpeechEvent{end, {Type: SpeechStart, Start: s.frameOffset(s.segmentStart)}}
+	}
+
+	return nil
+}
+
+// endSegment closes the current segment, trimming trailing silence beyond the padding
+func (s *Segmenter) endSegment() SpeechEvent {
+	size := s.lastSpeech + s.paddingFrames*s.frameSize
+	if size > len(s.segment) {
+		size = len(s.segment)
+	}
+	audio := s.segment[:size]
+
+	// Frames after the kept audio count as history for the next segment
+	s.history = nil
+	for rest := s.segment[size:]; len(rest) >= s.frameSize; rest = rest[s.frameSize:] {
+		s.history = append(s.history, rest[:s.frameSize:s.frameSize])
+	}
+
+	event := SpeechEvent{
+		Type:  SpeechEnd,
+		Start: s.frameOffset(s.segmentStart),
+		End:   s.frameOffset(s.segmentStart) + s.bytesDuration(len(audio)),
+		Audio: audio,
+	}
+
+	s.inSpeech = false
+	s.speechRun = 0
+	s.silenceRun = 0
+	s.segment = nil
+
+	return event
+}
+
+// frameOffset returns the stream offset of a frame index
+func (s *Segmenter) frameOffset(frame int) time.Duration {
+	return time.Duration(frame) * s.opts.FrameDuration
+}
+
+// bytesDuration returns the playback duration of n bytes of PCM16 mono audio
+func (s *Segmenter) bytesDuration(n int) time.Duration {
+	return time.Duration(n/2) * time.Second / time.Duration(s.opts.SampleRate)
+}
+
+// TrimSilence removes silence from PCM16 mono audio, keeping only speech segments
+// (with padding) in their original order
+func TrimSilence(pcm []byte, sampleRate int) []byte {
+	opts := DefaultVADOptions()
+	opts.SampleRate = sampleRate
+	segmenter := NewSegmenter(opts)
+
+	var trimmed []byte
+	for _, event := range append(segmenter.Write(pcm), segmenter.Flush()...) {
+		if event.Type == SpeechEnd {
+			trimmed = append(trimmed, event.Audio...)
+		}
+	}
+	return trimmed
+}
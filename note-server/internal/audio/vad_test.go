@@ -0,0 +1,203 @@
+package audio
+
+import (
+	"encoding/binary"
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+// tone returns d of a 16kHz sine wave at the given amplitude (0..1)
+func tone(d time.Duration, amplitude float64) []byte {
+	samples := int(d.Seconds() * 16000)
+	pcm := make([]byte, samples*2)
+	for i := 0; i < samples; i++ {
+		value := amplitude * math.Sin(2*math.Pi*220*float64(i)/16000)
+		binary.LittleEndian.PutUint16(pcm[i*2:], uint16(int16(value*32767)))
+	}
+	return pcm
+}
+
+// noise returns d of low-level white noise, standing in for room tone
+func noise(d time.Duration, rng *rand.Rand) []byte {
+	samples := int(d.Seconds() * 16000)
+	pcm := make([]byte, samples*2)
+	for i := 0; i < samples; i++ {
+		binary.LittleEndian.PutUint16(pcm[i*2:], uint16(int16(rng.Intn(200)-100)))
+	}
+	return pcm
+}
+
+func concat(parts ...[]byte) []byte {
+	var out []byte
+	for _, part := range parts {
+		out = append(out, part...)
+	}
+	return out
+}
+
+// within reports whether got is within tolerance of want
+func within(got, want, tolerance time.Duration) bool {
+	diff := got - want
+	if diff < 0 {
+		diff = -diff
+	}
+	return diff <= tolerance
+}
+
+func TestEnergyVAD(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	vad := NewEnergyVAD()
+
+	if vad.IsSpeech(noise(20*time.Millisecond, rng)) {
+		t.Error("Expected room noise not to be speech")
+	}
+	if vad.IsSpeech(make([]byte, 640)) {
+		t.Error("Expected digital silence not to be speech")
+	}
+	if !vad.IsSpeech(tone(20*time.Millisecond, 0.3)) {
+		t.Error("Expected a voiced tone to be speech")
+	}
+}
+
+func TestSegmenter(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	pcm := concat(
+		noise(1*time.Second, rng),
+		tone(1*time.Second, 0.3),
+		noise(1*time.Second, rng),
+		tone(500*time.Millisecond, 0.5),
+		noise(1*time.Second, rng),
+	)
+
+	segmenter := NewSegmenter(DefaultVADOptions())
+
+	// Feed odd-sized writes to exercise frame reassembly
+	var events []SpeechEvent
+	for len(pcm) > 0 {
+		n := 1234
+		if n > len(pcm) {
+			n = len(pcm)
+		}
+		events = append(events, segmenter.Write(pcm[:n])...)
+		pcm = pcm[n:]
+	}
+	events = append(events, segmenter.Flush()...)
+
+	var types []string
+	for _, event := range events {
+		types = append(types, event.Type)
+	}
+	want := []string{SpeechStart, SpeechEnd, SpeechStart, SpeechEnd}
+	if len(types) != len(want) {
+		t.Fatalf("Expected events %v, got %v", want, types)
+	}
+	for i := range want {
+		if types[i] != want[i] {
+			t.Fatalf("Expected events %v, got %v", want, types)
+		}
+	}
+
+	tolerance := 60 * time.Millisecond
+	padding := DefaultVADOptions().Padding
+
+	first := events[1]
+	if !within(first.Start, 1*time.Second-padding, tolerance) || !within(first.End, 2*time.Second+padding, tolerance) {
+		t.Errorf("First segment: expected about %v-%v, got %v-%v", 1*time.Second-padding, 2*time.Second+padding, first.Start, first.End)
+	}
+	if events[0].Start != first.Start {
+		t.Errorf("Expected speech_start offset %v to match segment start %v", events[0].Start, first.Start)
+	}
+
+	second := events[3]
+	if !within(second.Start, 3*time.Second-padding, tolerance) || !within(second.End, 3500*time.Millisecond+padding, tolerance) {
+		t.Errorf("Second segment: expected about %v-%v, got %v-%v", 3*time.Second-padding, 3500*time.Millisecond+padding, second.Start, second.End)
+	}
+
+	wantBytes := int((second.End - second.Start).Seconds() * 16000 * 2)
+	if len(second.Audio) != wantBytes {
+		t.Errorf("Expected segment audio of %d bytes, got %d", wantBytes, len(second.Audio))
+	}
+}
+
+func TestSegmenter_MaxSegment(t *testing.T) {
+	opts := DefaultVADOptions()
+	opts.MaxSegment = 1 * time.Second
+	segmenter := NewSegmenter(opts)
+
+	events := append(segmenter.Write(tone(2500*time.Millisecond, 0.3)), segmenter.Flush()...)
+
+	ends := 0
+	for _, event := range events {
+		if event.Type == SpeechEnd {
+			ends++
+			if event.End-event.Start > opts.MaxSegment {
+				t.Errorf("Segment %v-%v exceeds the maximum length", event.Start, event.End)
+			}
+		}
+	}
+	if ends != 3 {
+		t.Errorf("Expected continuous speech to be cut into 3 segments, got %d", ends)
+	}
+}
+
+func TestSegmenter_Open(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	segmenter := NewSegmenter(DefaultVADOptions())
+
+	segmenter.Write(noise(time.Second, rng))
+	if _, _, ok := segmenter.Open(); ok {
+		t.Fatal("Expected no open segment before speech")
+	}
+
+	segmenter.Write(tone(time.Second, 0.3))
+	start, audio, ok := segmenter.Open()
+	if !ok || !within(start, time.Second, 250*time.Millisecond) || len(audio) < len(tone(900*time.Millisecond, 0)) {
+		t.Fatalf("Expected about 1s of open speech from 1s, got %d bytes from %v (%v)", len(audio), start, ok)
+	}
+
+	// The segment keeps growing until it closes
+	segmenter.Write(tone(500*time.Millisecond, 0.3))
+	if _, more, _ := segmenter.Open(); len(more) <= len(audio) {
+		t.Errorf("Expected the open segment to grow past %d bytes, got %d", len(audio), len(more))
+	}
+	segmenter.Write(noise(time.Second, rng))
+	if _, _, ok := segmenter.Open(); ok {
+		t.Error("Expected no open segment after the speech ended")
+	}
+}
+
+func TestTrimSilence(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	speech := tone(500*time.Millisecond, 0.3)
+	pcm := concat(noise(2*time.Second, rng), speech, noise(2*time.Second, rng))
+
+	trimmed := TrimSilence(pcm, 16000)
+
+	// Speech plus up to the padding on either side
+	maxLen := len(speech) + 2*int(DefaultVADOptions().Padding.Seconds()*16000*2)
+	if len(trimmed) < len(speech) || len(trimmed) > maxLen {
+		t.Errorf("Expected trimmed audio between %d and %d bytes, got %d", len(speech), maxLen, len(trimmed))
+	}
+
+	if got := TrimSilence(noise(2*time.Second, rng), 16000); len(got) != 0 {
+		t.Errorf("Expected silence to be trimmed away entirely, got %d bytes", len(got))
+	}
+}
+
+func TestDecodeWAV(t *testing.T) {
+	pcm := tone(100*time.Millisecond, 0.3)
+
+	decoded, sampleRate, channels, err := DecodeWAV(EncodeWAV(pcm, 16000, 1))
+	if err != nil {
+		t.Fatalf("DecodeWAV() error = %v", err)
+	}
+	if sampleRate != 16000 || channels != 1 || len(decoded) != len(pcm) {
+		t.Errorf("Expected 16000 Hz mono with %d bytes, got %d Hz %d channels with %d bytes", len(pcm), sampleRate, channels, len(decoded))
+	}
+
+	if _, _, _, err := DecodeWAV([]byte("not a wav file")); err == nil {
+		t.Error("Expected error for non-WAV data")
+	}
+}
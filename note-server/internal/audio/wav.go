@@ -0,0 +1,134 @@
+// Package audio provides helpers for working with raw PCM audio
+package audio
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// Size of a canonical 44-byte WAV header
+const wavHeaderSize = 44
+
+// WAVWriter streams 16-bit PCM samples into a WAV file. The header is written
+// up front with placeholder sizes and patched on Close.
+type WAVWriter struct {
+	w          io.WriteSeeker
+	sampleRate int
+	channels   int
+	dataSize   int64
+}
+
+// NewWAVWriter writes a WAV header to w and returns a writer for PCM16 data
+func NewWAVWriter(w io.WriteSeeker, sampleRate, channels int) (*WAVWriter, error) {
+	ww := &WAVWriter{w: w, sampleRate: sampleRate, channels: channels}
+	if err := ww.writeHeader(); err != nil {
+		return nil, err
+	}
+	return ww, nil
+}
+
+// Write appends little-endian PCM16 samples
+func (ww *WAVWriter) Write(p []byte) (int, error) {
+	n, err := ww.w.Write(p)
+	ww.dataSize += int64(n)
+	return n, err
+}
+
+// DataSize returns the number of PCM bytes written so far
+func (ww *WAVWriter) DataSize() int64 {
+	return ww.dataSize
+}
+
+// Close patches the header with the final sizes. It does not close the underlying writer.
+func (ww *WAVWriter) Close() error {
+	if _, err := ww.w.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to seek to WAV header: %w", err)
+	}
+	if err := ww.writeHeader(); err != nil {
+		return err
+	}
+	_, err := ww.w.Seek(0, io.SeekEnd)
+	return err
+}
+
+// writeHeader writes the RIFF/WAVE header for the current data size
+func (ww *WAVWriter) writeHeader() error {
+	header := WAVHeader(ww.sampleRate, ww.channels, ww.dataSize)
+	if _, err := ww.w.Write(header); err != nil {
+		return fmt.Errorf("failed to write WAV header: %w", err)
+	}
+	return nil
+}
+
+// WAVHeader returns a canonical PCM16 WAV header for dataSize bytes of samples
+func WAVHeader(sampleRate, channels int, dataSize int64) []byte {
+	blockAlign := channels * 2
+	byteRate := sampleRate * blockAlign
+
+	header := make([]byte, wavHeaderSize)
+	copy(header[0:4], "RIFF")
+	binary.LittleEndian.PutUint32(header[4:8], uint32(36+dataSize))
+	copy(header[8:12], "WAVE")
+	copy(header[12:16], "fmt ")
+	binary.LittleEndian.PutUint32(header[16:20], 16) // fmt chunk size
+	binary.LittleEndian.PutUint16(header[20:22], 1)  // PCM
+	binary.LittleEndian.PutUint16(header[22:24], uint16(channels))
+	binary.LittleEndian.PutUint32(header[24:28], uint32(sampleRate))
+	binary.LittleEndian.PutUint32(header[28:32], uint32(byteRate))
+	binary.LittleEndian.PutUint16(header[32:34], uint16(blockAlign))
+	binary.LittleEndian.PutUint16(header[34:36], 16) // bits per sample
+	copy(header[36:40], "data")
+	binary.LittleEndian.PutUint32(header[40:44], uint32(dataSize))
+	return header
+}
+
+// EncodeWAV wraps PCM16 samples in a WAV container
+func EncodeWAV(pcm []byte, sampleRate, channels int) []byte {
+	return append(WAVHeader(sampleRate, channels, int64(len(pcm))), pcm...)
+}
+
+// DecodeWAV extracts the PCM16 samples and format from a WAV file
+func DecodeWAV(data []byte) (pcm []byte, sampleRate, channels int, err error) {
+	if len(data) < 12 || string(data[0:4]) != "RIFF" || string(data[8:12]) != "WAVE" {
+		return nil, 0, 0, fmt.Errorf("not a WAV file")
+	}
+
+	var haveFormat bool
+	for offset := 12; offset+8 <= len(data); {
+		chunkID := string(data[offset : offset+4])
+		chunkSize := int(binary.LittleEndian.Uint32(data[offset+4 : offset+8]))
+		body := data[offset+8:]
+		if chunkSize > len(body) {
+			// ffmpeg leaves the data size unset when writing to a pipe
+			chunkSize = len(body)
+		}
+
+		switch chunkID {
+		case "fmt ":
+			if chunkSize < 16 {
+				return nil, 0, 0, fmt.Errorf("invalid WAV format chunk")
+			}
+			if format := binary.LittleEndian.Uint16(body[0:2]); format != 1 {
+				return nil, 0, 0, fmt.Errorf("unsupported WAV encoding %d", format)
+			}
+			if bits := binary.LittleEndian.Uint16(body[14:16]); bits != 16 {
+				return nil, 0, 0, fmt.Errorf("unsupported WAV bit depth %d", bits)
+			}
+			channels = int(binary.LittleEndian.Uint16(body[2:4]))
+			sampleRate = int(binary.LittleEndian.Uint32(body[4:8]))
+			haveFormat = true
+
+		case "data":
+			if !haveFormat {
+				return nil, 0, 0, fmt.Errorf("WAV data before format chunk")
+			}
+			return body[:chunkSize], sampleRate, channels, nil
+		}
+
+		// Chunks are padded to an even size
+		offset += 8 + chunkSize + chunkSize%2
+	}
+
+	return nil, 0, 0, fmt.Errorf("WAV file has no data chunk")
+}
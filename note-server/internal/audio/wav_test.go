@@ -0,0 +1,57 @@
+package audio
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWAVWriter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.wav")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	ww, err := NewWAVWriter(f, 16000, 1)
+	if err != nil {
+		t.Fatalf("NewWAVWriter() error = %v", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		if _, err := ww.Write(make([]byte, 3200)); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+	}
+
+	if err := ww.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(data) != wavHeaderSize+9600 {
+		t.Fatalf("Expected %d bytes, got %d", wavHeaderSize+9600, len(data))
+	}
+
+	if string(data[0:4]) != "RIFF" || string(data[8:12]) != "WAVE" || string(data[36:40]) != "data" {
+		t.Error("Expected canonical RIFF/WAVE header")
+	}
+
+	if got := binary.LittleEndian.Uint32(data[4:8]); got != 36+9600 {
+		t.Errorf("Expected RIFF size %d, got %d", 36+9600, got)
+	}
+
+	if got := binary.LittleEndian.Uint32(data[40:44]); got != 9600 {
+		t.Errorf("Expected data size 9600, got %d", got)
+	}
+
+	if got := binary.LittleEndian.Uint32(data[24:28]); got != 16000 {
+		t.Errorf("Expected sample rate 16000, got %d", got)
+	}
+}
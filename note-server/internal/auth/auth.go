@@ -0,0 +1,363 @@
+// Package auth authenticates API and WebSocket clients.
+//
+// Clients present credentials in one of four ways:
+//   - An "Authorization: Bearer <token>" header
+//   - A "bearer.<token>" WebSocket subprotocol, for browsers that cannot set
+//     headers on a WebSocket handshake. The "note.v1" subprotocol must be
+//     offered alongside it so the server has a protocol to accept.
+//   - A short-lived, single-use "ticket" query parameter issued by
+//     POST /api/v1/ws-ticket, or the equivalent "ticket.<ticket>" subprotocol
+//   - The "note_session" cookie set by POST /api/auth/login
+//
+// Bearer tokens are static API tokens from the configuration, personal API
+// tokens created through /api/tokens, or session tokens returned by login.
+// Static tokens act as administrators; personal tokens act as their user,
+// limited to the scopes they were granted.
+//
+// When authentication is not required, requests without credentials are
+// treated as the anonymous principal. Authentication becomes required as soon
+// as the first user account exists. Invalid credentials are always rejected.
+package auth
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Subprotocol is the WebSocket subprotocol spoken by the server
+const Subprotocol = "note.v1"
+
+// Authentication methods recorded on a Principal
+const (
+	MethodNone     = "none"
+	MethodToken    = "token"
+	MethodTicket   = "ticket"
+	MethodSession  = "session"
+	MethodAPIToken = "api_token"
+)
+
+// APITokenPrefix starts every personal API token, telling them apart from
+// session tokens and making leaked tokens easy to search for
+const APITokenPrefix = "note_"
+
+// Default lifetime of WebSocket tickets
+const defaultTicketTTL = 60 * time.Second
+
+var (
+	ErrNoCredentials      = errors.New("authentication required")
+	ErrInvalidCredentials = errors.New("invalid credentials")
+	ErrExpiredTicket      = errors.New("ticket expired")
+)
+
+// Principal is the authenticated identity behind a request
+type Principal struct {
+	ID     string `json:"id"`
+	Method string `json:"method"`
+
+	// Database ID of a user account; 0 for static tokens and anonymous requests
+	UserID int64 `json:"userId,omitempty"`
+
+	// Administrators see every user's data and manage the server configuration
+	Admin bool `json:"admin,omitempty"`
+
+	// Permissions of a personal API token; 0 means unrestricted
+	Scopes Scope `json:"scopes,omitempty"`
+}
+
+// Anonymous is the principal of unauthenticated requests when authentication is optional
+var Anonymous = Principal{ID: "anonymous", Method: MethodNone}
+
+// IsAnonymous reports whether the principal is unauthenticated
+func (p Principal) IsAnonymous() bool {
+	return p.Method == MethodNone
+}
+
+// Can reports whether the principal has been granted scope. Only personal API
+// tokens are restricted; every other principal can do anything its user can.
+func (p Principal) Can(scope Scope) bool {
+	return p.Scopes == 0 || p.Scopes&scope != 0
+}
+
+// Options configures an Authenticator
+type Options struct {
+	// Reject requests that carry no credentials
+	Required bool
+
+	// Static API tokens by user ID
+	Tokens map[string]string
+
+	// Key used to sign tickets; a random key is generated when empty, which
+	// invalidates outstanding tickets on restart
+	TicketSecret []byte
+
+	// Lifetime of issued tickets
+	TicketTTL time.Duration
+
+	// Resolves session tokens issued at login; user accounts are disabled when nil
+	Sessions SessionStore
+
+	// Resolves personal API tokens; they are rejected when nil
+	APITokens TokenStore
+
+	// Lifetime of login sessions
+	SessionTTL time.Duration
+}
+
+// Authenticator verifies client credentials and issues WebSocket tickets
+type Authenticator struct {
+	required  bool
+	tokens    map[string]string
+	secret    []byte
+	ticketTTL time.Duration
+
+	sessions   SessionStore
+	sessionTTL time.Duration
+	apiTokens  TokenStore
+
+	// Nonces of redeemed tickets, kept until the ticket would have expired
+	usedMutex sync.Mutex
+	used      map[string]time.Time
+}
+
+// New creates an Authenticator
+func New(opts Options) *Authenticator {
+	secret := opts.TicketSecret
+	if len(secret) == 0 {
+		secret = make([]byte, 32)
+		rand.Read(secret)
+	}
+
+	ttl := opts.TicketTTL
+	if ttl <= 0 {
+		ttl = defaultTicketTTL
+	}
+
+	sessionTTL := opts.SessionTTL
+	if sessionTTL <= 0 {
+		sessionTTL = defaultSessionTTL
+	}
+
+	tokens := make(map[string]string, len(opts.Tokens))
+	for user, token := range opts.Tokens {
+		tokens[user] = token
+	}
+
+	return &Authenticator{
+		required:   opts.Required,
+		tokens:     tokens,
+		secret:     secret,
+		ticketTTL:  ttl,
+		sessions:   opts.Sessions,
+		sessionTTL: sessionTTL,
+		apiTokens:  opts.APITokens,
+		used:       make(map[string]time.Time),
+	}
+}
+
+// Required reports whether requests must carry credentials, either because
+// the configuration says so or because user accounts exist
+func (a *Authenticator) Required() bool {
+	return a.required || (a.sessions != nil && a.sessions.HasUsers())
+}
+
+// Sessions returns the store login sessions are kept in, or nil when user
+// accounts are disabled
+func (a *Authenticator) Sessions() SessionStore {
+	return a.sessions
+}
+
+// SessionTTL returns the lifetime of login sessions
+func (a *Authenticator) SessionTTL() time.Duration {
+	return a.sessionTTL
+}
+
+// Authenticate identifies the client behind a request from its Authorization
+// header, WebSocket subprotocols, ticket query parameter or session cookie
+func (a *Authenticator) Authenticate(r *http.Request) (Principal, error) {
+	if header := r.Header.Get("Authorization"); header != "" {
+		token, ok := strings.CutPrefix(header, "Bearer ")
+		if !ok {
+			return Principal{}, ErrInvalidCredentials
+		}
+		return a.AuthenticateToken(strings.TrimSpace(token))
+	}
+
+	for _, protocol := range subprotocols(r) {
+		if token, ok := strings.CutPrefix(protocol, "bearer."); ok {
+			return a.AuthenticateToken(token)
+		}
+		if ticket, ok := strings.CutPrefix(protocol, "ticket."); ok {
+			return a.RedeemTicket(ticket)
+		}
+	}
+
+	if ticket := r.URL.Query().Get("ticket"); ticket != "" {
+		return a.RedeemTicket(ticket)
+	}
+
+	if cookie, err := r.Cookie(SessionCookie); err == nil && cookie.Value != "" {
+		return a.AuthenticateSession(cookie.Value)
+	}
+
+	if a.Required() {
+		return Principal{}, ErrNoCredentials
+	}
+	return Anonymous, nil
+}
+
+// AuthenticateToken resolves a static API token, personal API token or
+// session token to its user
+func (a *Authenticator) AuthenticateToken(token string) (Principal, error) {
+	if token == "" {
+		return Principal{}, ErrInvalidCredentials
+	}
+
+	if strings.HasPrefix(token, APITokenPrefix) {
+		if a.apiTokens == nil {
+			return Principal{}, ErrInvalidCredentials
+		}
+		return a.apiTokens.LookupAPIToken(token)
+	}
+
+	// Compare against every token so timing doesn't reveal which one matched
+	var match string
+	for user, candidate := range a.tokens {
+		if subtle.ConstantTimeCompare([]byte(candidate), []byte(token)) == 1 {
+			match = user
+		}
+	}
+	if match == "" {
+		return a.AuthenticateSession(token)
+	}
+	return Principal{ID: match, Method: MethodToken, Admin: true}, nil
+}
+
+// ticketClaims is the signed payload of a ticket
+type ticketClaims struct {
+	Subject string `json:"sub"`
+	UserID  int64  `json:"uid,omitempty"`
+	Admin   bool   `json:"adm,omitempty"`
+	Scopes  Scope  `json:"scp,omitempty"`
+	Expires int64  `json:"exp"`
+	Nonce   string `json:"nonce"`
+}
+
+// IssueTicket returns a signed, single-use ticket for p and its expiry time
+func (a *Authenticator) IssueTicket(p Principal) (string, time.Time) {
+	nonce := make([]byte, 16)
+	rand.Read(nonce)
+
+	expires := time.Now().Add(a.ticketTTL)
+	payload, _ := json.Marshal(ticketClaims{
+		Subject: p.ID,
+		UserID:  p.UserID,
+		Admin:   p.Admin,
+		Scopes:  p.Scopes,
+		Expires: expires.Unix(),
+		Nonce:   hex.EncodeToString(nonce),
+	})
+
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + base64.RawURLEncoding.EncodeToString(a.sign(encoded)), expires
+}
+
+// RedeemTicket verifies a ticket and marks it used
+func (a *Authenticator) RedeemTicket(ticket string) (Principal, error) {
+	encoded, signature, ok := strings.Cut(ticket, ".")
+	if !ok {
+		return Principal{}, ErrInvalidCredentials
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(sig, a.sign(encoded)) {
+		return Principal{}, ErrInvalidCredentials
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return Principal{}, ErrInvalidCredentials
+	}
+	var claims ticketClaims
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.Subject == "" || claims.Nonce == "" {
+		return Principal{}, ErrInvalidCredentials
+	}
+
+	now := time.Now()
+	expires := time.Unix(claims.Expires, 0)
+	if !now.Before(expires) {
+		return Principal{}, ErrExpiredTicket
+	}
+
+	a.usedMutex.Lock()
+	defer a.usedMutex.Unlock()
+
+	for nonce, expiry := range a.used {
+		if !now.Before(expiry) {
+			delete(a.used, nonce)
+		}
+	}
+	if _, seen := a.used[claims.Nonce]; seen {
+		return Principal{}, ErrInvalidCredentials
+	}
+	a.used[claims.Nonce] = expires
+
+	return Principal{
+		ID:     claims.Subject,
+		Method: MethodTicket,
+		UserID: claims.UserID,
+		Admin:  claims.Admin,
+		Scopes: claims.Scopes,
+	}, nil
+}
+
+// sign returns the HMAC of a ticket payload
+func (a *Authenticator) sign(payload string) []byte {
+	mac := hmac.New(sha256.New, a.secret)
+	mac.Write([]byte(payload))
+	return mac.Sum(nil)
+}
+
+// subprotocols returns the WebSocket subprotocols offered by the client
+func subprotocols(r *http.Request) []string {
+	var protocols []string
+	for _, header := range r.Header.Values("Sec-WebSocket-Protocol") {
+		for _, protocol := range strings.Split(header, ",") {
+			if protocol = strings.TrimSpace(protocol); protocol != "" {
+				protocols = append(protocols, protocol)
+			}
+		}
+	}
+	return protocols
+}
+
+type contextKey struct{}
+
+// WithPrincipal returns a context carrying p
+func WithPrincipal(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, contextKey{}, p)
+}
+
+// FromContext returns the principal stored in ctx, or Anonymous
+func FromContext(ctx context.Context) Principal {
+	if p, ok := Lookup(ctx); ok {
+		return p
+	}
+	return Anonymous
+}
+
+// Lookup returns the principal stored in ctx, reporting whether there was one
+func Lookup(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(contextKey{}).(Principal)
+	return p, ok
+}
@@ -0,0 +1,288 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestAuthenticator(required bool) *Authenticator {
+	return New(Options{
+		Required:     required,
+		Tokens:       map[string]string{"alice": "alice-token", "bob": "bob-token"},
+		TicketSecret: []byte("test-secret"),
+	})
+}
+
+func TestAuthenticate(t *testing.T) {
+	a := newTestAuthenticator(true)
+	ticket, _ := a.IssueTicket(Principal{ID: "bob", Method: MethodToken})
+
+	tests := []struct {
+		name        string
+		header      string
+		protocols   string
+		query       string
+		expectedID  string
+		expectedErr error
+	}{
+		{"bearer header", "Bearer alice-token", "", "", "alice", nil},
+		{"bearer subprotocol", "", "note.v1, bearer.bob-token", "", "bob", nil},
+		{"ticket query", "", "", "?ticket=" + ticket, "bob", nil},
+		{"invalid bearer header", "Bearer nope", "", "", "", ErrInvalidCredentials},
+		{"non-bearer header", "Basic YWxpY2U6", "", "", "", ErrInvalidCredentials},
+		{"invalid subprotocol token", "", "note.v1, bearer.nope", "", "", ErrInvalidCredentials},
+		{"no credentials", "", "note.v1", "", "", ErrNoCredentials},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/ws/transcribe"+tt.query, nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			if tt.protocols != "" {
+				req.Header.Set("Sec-WebSocket-Protocol", tt.protocols)
+			}
+
+			principal, err := a.Authenticate(req)
+			if !errors.Is(err, tt.expectedErr) {
+				t.Fatalf("Expected error %v, got %v", tt.expectedErr, err)
+			}
+			if principal.ID != tt.expectedID {
+				t.Errorf("Expected principal %q, got %q", tt.expectedID, principal.ID)
+			}
+		})
+	}
+}
+
+func TestAuthenticate_Optional(t *testing.T) {
+	a := newTestAuthenticator(false)
+
+	principal, err := a.Authenticate(httptest.NewRequest("GET", "/ws/transcribe", nil))
+	if err != nil || !principal.IsAnonymous() {
+		t.Errorf("Expected anonymous principal, got %+v (%v)", principal, err)
+	}
+
+	// Bad credentials are rejected even when authentication is optional
+	req := httptest.NewRequest("GET", "/ws/transcribe", nil)
+	req.Header.Set("Authorization", "Bearer nope")
+	if _, err := a.Authenticate(req); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("Expected ErrInvalidCredentials, got %v", err)
+	}
+}
+
+func TestRedeemTicket(t *testing.T) {
+	a := newTestAuthenticator(true)
+
+	t.Run("single use", func(t *testing.T) {
+		ticket, expires := a.IssueTicket(Principal{ID: "alice"})
+		if time.Until(expires) > defaultTicketTTL {
+			t.Errorf("Expected ticket to expire within %v, got %v", defaultTicketTTL, time.Until(expires))
+		}
+
+		principal, err := a.RedeemTicket(ticket)
+		if err != nil || principal.ID != "alice" || principal.Method != MethodTicket {
+			t.Fatalf("Expected ticket principal alice, got %+v (%v)", principal, err)
+		}
+		if _, err := a.RedeemTicket(ticket); !errors.Is(err, ErrInvalidCredentials) {
+			t.Errorf("Expected replayed ticket to be rejected, got %v", err)
+		}
+	})
+
+	t.Run("tampered", func(t *testing.T) {
+		ticket, _ := a.IssueTicket(Principal{ID: "alice"})
+		payload, signature, _ := strings.Cut(ticket, ".")
+		forged, _ := newTestAuthenticator(true).IssueTicket(Principal{ID: "mallory"})
+		forgedPayload, _, _ := strings.Cut(forged, ".")
+
+		for _, bad := range []string{forgedPayload + "." + signature, payload + ".AAAA", payload, ""} {
+			if _, err := a.RedeemTicket(bad); !errors.Is(err, ErrInvalidCredentials) {
+				t.Errorf("Expected %q to be rejected, got %v", bad, err)
+			}
+		}
+	})
+
+	t.Run("other secret", func(t *testing.T) {
+		other := New(Options{TicketSecret: []byte("other-secret")})
+		ticket, _ := other.IssueTicket(Principal{ID: "alice"})
+		if _, err := a.RedeemTicket(ticket); !errors.Is(err, ErrInvalidCredentials) {
+			t.Errorf("Expected ticket signed with another secret to be rejected, got %v", err)
+		}
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		short := New(Options{TicketSecret: []byte("test-secret"), TicketTTL: time.Nanosecond})
+		ticket, _ := short.IssueTicket(Principal{ID: "alice"})
+		time.Sleep(time.Millisecond)
+		if _, err := short.RedeemTicket(ticket); !errors.Is(err, ErrExpiredTicket) {
+			t.Errorf("Expected ErrExpiredTicket, got %v", err)
+		}
+	})
+}
+
+func TestPrincipalContext(t *testing.T) {
+	if p := FromContext(context.Background()); p != Anonymous {
+		t.Errorf("Expected anonymous principal, got %+v", p)
+	}
+
+	alice := Principal{ID: "alice", Method: MethodToken}
+	if p := FromContext(WithPrincipal(context.Background(), alice)); p != alice {
+		t.Errorf("Expected %+v, got %+v", alice, p)
+	}
+}
+
+// memorySessions is a SessionStore backed by a map of token hashes
+type memorySessions map[string]Principal
+
+func (m memorySessions) LookupSession(token string) (Principal, error) {
+	if p, ok := m[HashToken(token)]; ok {
+		return p, nil
+	}
+	return Principal{}, ErrInvalidCredentials
+}
+
+func (m memorySessions) HasUsers() bool {
+	return len(m) > 0
+}
+
+func TestAuthenticateSession(t *testing.T) {
+	sessions := memorySessions{}
+	a := New(Options{Tokens: map[string]string{"ci": "ci-token"}, Sessions: sessions})
+
+	if a.Required() {
+		t.Error("Expected authentication to be optional without users")
+	}
+
+	token, hash := NewSessionToken()
+	carol := Principal{ID: "carol", Method: MethodSession, UserID: 3}
+	sessions[hash] = carol
+
+	if !a.Required() {
+		t.Error("Expected authentication to be required once users exist")
+	}
+
+	tests := []struct {
+		name        string
+		header      string
+		cookie      string
+		expected    Principal
+		expectedErr error
+	}{
+		{"session cookie", "", token, carol, nil},
+		{"session bearer", "Bearer " + token, "", carol, nil},
+		{"static token is admin", "Bearer ci-token", "", Principal{ID: "ci", Method: MethodToken, Admin: true}, nil},
+		{"unknown session", "", "nope", Principal{}, ErrInvalidCredentials},
+		{"no credentials", "", "", Principal{}, ErrNoCredentials},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/api/recordings", nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			if tt.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: SessionCookie, Value: tt.cookie})
+			}
+
+			principal, err := a.Authenticate(req)
+			if !errors.Is(err, tt.expectedErr) {
+				t.Fatalf("Expected error %v, got %v", tt.expectedErr, err)
+			}
+			if principal != tt.expected {
+				t.Errorf("Expected principal %+v, got %+v", tt.expected, principal)
+			}
+		})
+	}
+
+	t.Run("tickets keep the user", func(t *testing.T) {
+		ticket, _ := a.IssueTicket(carol)
+		principal, err := a.RedeemTicket(ticket)
+		if err != nil || principal.UserID != carol.UserID || principal.ID != carol.ID {
+			t.Errorf("Expected ticket for %+v, got %+v (%v)", carol, principal, err)
+		}
+	})
+}
+
+func TestPasswords(t *testing.T) {
+	if _, err := HashPassword("short"); !errors.Is(err, ErrWeakPassword) {
+		t.Errorf("Expected ErrWeakPassword, got %v", err)
+	}
+
+	hash, err := HashPassword("correct horse")
+	if err != nil {
+		t.Fatalf("HashPassword() error = %v", err)
+	}
+	if !CheckPassword(hash, "correct horse") {
+		t.Error("Expected the password to match its hash")
+	}
+	if CheckPassword(hash, "wrong horse") {
+		t.Error("Expected a wrong password to be rejected")
+	}
+	if CheckPassword("", "correct horse") {
+		t.Error("Expected an unknown user's password to be rejected")
+	}
+}
+
+// memoryTokens is a TokenStore backed by a map of token hashes
+type memoryTokens map[string]Principal
+
+func (m memoryTokens) LookupAPIToken(token string) (Principal, error) {
+	if p, ok := m[HashToken(token)]; ok {
+		return p, nil
+	}
+	return Principal{}, ErrInvalidCredentials
+}
+
+func TestAuthenticateAPIToken(t *testing.T) {
+	tokens := memoryTokens{}
+	token, hash := NewAPIToken()
+	if !strings.HasPrefix(token, APITokenPrefix) {
+		t.Fatalf("Expected token to start with %q, got %q", APITokenPrefix, token)
+	}
+	kiosk := Principal{ID: "kiosk", Method: MethodAPIToken, UserID: 4, Scopes: ScopeWrite | ScopeTranscribe}
+	tokens[hash] = kiosk
+
+	a := New(Options{APITokens: tokens, Sessions: memorySessions{}})
+
+	req := httptest.NewRequest("POST", "/api/upload-recording", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	principal, err := a.Authenticate(req)
+	if err != nil || principal != kiosk {
+		t.Fatalf("Expected %+v, got %+v (%v)", kiosk, principal, err)
+	}
+	if !principal.Can(ScopeWrite) || principal.Can(ScopeRead) || principal.Can(ScopeAdmin) {
+		t.Errorf("Expected only the granted scopes, got %v", principal.Scopes)
+	}
+
+	// Unknown tokens with the prefix never fall through to sessions
+	req.Header.Set("Authorization", "Bearer "+APITokenPrefix+"nope")
+	if _, err := a.Authenticate(req); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("Expected ErrInvalidCredentials, got %v", err)
+	}
+
+	// Tickets keep the token's restrictions
+	ticket, _ := a.IssueTicket(principal)
+	if redeemed, err := a.RedeemTicket(ticket); err != nil || redeemed.Scopes != kiosk.Scopes {
+		t.Errorf("Expected ticket scopes %v, got %+v (%v)", kiosk.Scopes, redeemed, err)
+	}
+
+	if !(Principal{ID: "alice", Method: MethodSession}).Can(ScopeAdmin) {
+		t.Error("Expected sessions to be unrestricted")
+	}
+}
+
+func TestParseScopes(t *testing.T) {
+	scopes, err := ParseScopes([]string{"read", " transcribe"})
+	if err != nil || scopes != ScopeRead|ScopeTranscribe || scopes.String() != "read,transcribe" {
+		t.Errorf("Expected read,transcribe, got %v (%v)", scopes, err)
+	}
+	if _, err := ParseScopes([]string{"read", "root"}); err == nil {
+		t.Error("Expected an unknown scope to be rejected")
+	}
+}
@@ -0,0 +1,88 @@
+package auth
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Scope is a set of permissions granted to an API token
+type Scope uint8
+
+// Scopes an API token can be granted
+const (
+	// Read recordings, notes, jobs and events
+	ScopeRead Scope = 1 << iota
+	// Upload recordings and create notes
+	ScopeWrite
+	// Administer the server and manage API tokens
+	ScopeAdmin
+	// Transcribe and summarize audio, including live sessions
+	ScopeTranscribe
+)
+
+// scopeNames maps each scope to its name in requests and the database
+var scopeNames = []struct {
+	scope Scope
+	name  string
+}{
+	{ScopeRead, "read"},
+	{ScopeWrite, "write"},
+	{ScopeAdmin, "admin"},
+	{ScopeTranscribe, "transcribe"},
+}
+
+// ParseScopes converts scope names to a Scope, rejecting unknown names
+func ParseScopes(names []string) (Scope, error) {
+	var scopes Scope
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		found := false
+		for _, s := range scopeNames {
+			if s.name == name {
+				scopes |= s.scope
+				found = true
+				break
+			}
+		}
+		if !found {
+			return 0, fmt.Errorf("unknown scope %q", name)
+		}
+	}
+	return scopes, nil
+}
+
+// Names returns the names of the scopes in s
+func (s Scope) Names() []string {
+	names := []string{}
+	for _, scope := range scopeNames {
+		if s&scope.scope != 0 {
+			names = append(names, scope.name)
+		}
+	}
+	return names
+}
+
+// String returns the comma-separated names of the scopes in s
+func (s Scope) String() string {
+	return strings.Join(s.Names(), ",")
+}
+
+// MarshalJSON encodes s as a list of scope names
+func (s Scope) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.Names())
+}
+
+// UnmarshalJSON decodes a list of scope names
+func (s *Scope) UnmarshalJSON(data []byte) error {
+	var names []string
+	if err := json.Unmarshal(data, &names); err != nil {
+		return err
+	}
+	scopes, err := ParseScopes(names)
+	if err != nil {
+		return err
+	}
+	*s = scopes
+	return nil
+}
@@ -0,0 +1,99 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// SessionCookie is the cookie carrying a browser's session token
+const SessionCookie = "note_session"
+
+// Default lifetime of login sessions
+const defaultSessionTTL = 30 * 24 * time.Hour
+
+// Shortest password accepted for a user account
+const MinPasswordLength = 8
+
+var ErrWeakPassword = errors.New("password must be at least 8 characters")
+
+// SessionStore resolves session tokens to the users they were issued to
+type SessionStore interface {
+	// LookupSession returns the principal of an unexpired session, or
+	// ErrInvalidCredentials when the token is unknown or expired
+	LookupSession(token string) (Principal, error)
+
+	// HasUsers reports whether any user account exists
+	HasUsers() bool
+}
+
+// TokenStore resolves personal API tokens to the users they belong to
+type TokenStore interface {
+	// LookupAPIToken returns the principal of an unexpired, unrevoked token, or
+	// ErrInvalidCredentials when there is none
+	LookupAPIToken(token string) (Principal, error)
+}
+
+// AuthenticateSession resolves a session token to its user
+func (a *Authenticator) AuthenticateSession(token string) (Principal, error) {
+	if a.sessions == nil || token == "" {
+		return Principal{}, ErrInvalidCredentials
+	}
+	return a.sessions.LookupSession(token)
+}
+
+// HashPassword returns a bcrypt hash of password
+func HashPassword(password string) (string, error) {
+	if len(password) < MinPasswordLength {
+		return "", ErrWeakPassword
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// CheckPassword reports whether password matches a hash from HashPassword. An
+// empty hash, for an unknown user, never matches but takes as long to check,
+// so response times don't reveal which usernames exist.
+func CheckPassword(hash, password string) bool {
+	if hash == "" {
+		bcrypt.CompareHashAndPassword(unknownUserHash(), []byte(password))
+		return false
+	}
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
+
+var unknownUserHash = sync.OnceValue(func() []byte {
+	hash, _ := bcrypt.GenerateFromPassword([]byte("unknown user"), bcrypt.DefaultCost)
+	return hash
+})
+
+// NewSessionToken returns a random session token and the hash to store for it.
+// Only the hash is kept server-side, so a leaked database can't be replayed.
+func NewSessionToken() (token, hash string) {
+	b := make([]byte, 32)
+	rand.Read(b)
+	token = base64.RawURLEncoding.EncodeToString(b)
+	return token, HashToken(token)
+}
+
+// NewAPIToken returns a random personal API token and the hash to store for it
+func NewAPIToken() (token, hash string) {
+	token, _ = NewSessionToken()
+	token = APITokenPrefix + token
+	return token, HashToken(token)
+}
+
+// HashToken returns the stored form of a session or API token
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
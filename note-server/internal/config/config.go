@@ -2,63 +2,258 @@ package config
 
 import (
 	"fmt"
-
-	"github.com/kelseyhightower/envconfig"
+	"net"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
 )
 
-// Config holds all configuration for the application
+// Config holds the server settings. Each setting is layered from, lowest to
+// highest precedence: its default, the server config file, its environment
+// variable and its command-line flag. The file key is the `config` tag and
+// the flag is the same name with dashes (e.g. --media-tmp-dir).
 type Config struct {
 	// Server configuration
-	Port string `envconfig:"PORT" default:"8080"`
-	Host string `envconfig:"HOST" default:"localhost"`
+	Port        string `config:"port" env:"PORT" default:"8080" usage:"Port to listen on"`
+	Host        string `config:"host" env:"HOST" default:"localhost" usage:"Host or IP address to listen on"`
+	TLSCertFile string `config:"tls_cert_file" env:"TLS_CERT_FILE" usage:"TLS certificate; serves HTTPS when set with tls_key_file"`
+	TLSKeyFile  string `config:"tls_key_file" env:"TLS_KEY_FILE" usage:"TLS private key"`
+	UnixSocket  string `config:"unix_socket" env:"UNIX_SOCKET" usage:"Also listen on this Unix domain socket, e.g. for a local proxy"`
+
+	// Reverse proxies whose X-Forwarded-For and X-Real-IP headers name the
+	// client; other peers are identified by their own address
+	TrustedProxies []string `config:"trusted_proxies" env:"TRUSTED_PROXIES" usage:"Comma-separated addresses or CIDR ranges of reverse proxies trusted to report the client's IP address"`
+
+	// How often the TLS certificate and key are checked for renewal; 0 disables
+	TLSReloadInterval time.Duration `config:"tls_reload_interval" env:"TLS_RELOAD_INTERVAL" default:"30s" usage:"How often the TLS files are checked for changes; 0 disables"`
+
+	// HTTP server limits
+	ReadHeaderTimeout time.Duration `config:"read_header_timeout" env:"READ_HEADER_TIMEOUT" default:"10s" usage:"Time allowed to read request headers"`
+	IdleTimeout       time.Duration `config:"idle_timeout" env:"IDLE_TIMEOUT" default:"120s" usage:"How long an idle keep-alive connection stays open"`
+	MaxHeaderBytes    int           `config:"max_header_bytes" env:"MAX_HEADER_BYTES" default:"1048576" usage:"Largest request header accepted (bytes)"`
 
-	// OpenAI configuration
-	OpenAIKey string `envconfig:"OPENAI_KEY"`
+	// Storage
+	DBPath string `config:"db_path" env:"DB_PATH" default:"~/.noteai/notes.db" usage:"SQLite database file"`
+
+	// OpenAI configuration; the key saved through /api/config takes precedence
+	OpenAIKey string `config:"openai_key" env:"OPENAI_KEY" secret:"true" usage:"OpenAI API key"`
+
+	// Default AI providers, used where /api/config doesn't choose one
+	TranscriptionProvider string `config:"transcription_provider" env:"TRANSCRIPTION_PROVIDER" usage:"Default transcription provider"`
+	TranscriptionModel    string `config:"transcription_model" env:"TRANSCRIPTION_MODEL" usage:"Default transcription model"`
+	SummaryProvider       string `config:"summary_provider" env:"SUMMARY_PROVIDER" usage:"Default summary provider"`
+	SummaryModel          string `config:"summary_model" env:"SUMMARY_MODEL" usage:"Default summary model"`
 
 	// Media and file handling
-	MediaTmpDir string `envconfig:"MEDIA_TMP_DIR" default:"/tmp/note-media"`
+	MediaDir    string `config:"media_dir" env:"MEDIA_DIR" default:"~/.noteai/media" usage:"Directory where uploaded and recorded media is kept"`
+	MediaTmpDir string `config:"media_tmp_dir" env:"MEDIA_TMP_DIR" default:"/tmp/note-media" usage:"Directory for temporary media files"`
 
 	// Logging configuration
-	LogLevel string `envconfig:"LOG_LEVEL" default:"info"`
+	LogLevel string `config:"log_level" env:"LOG_LEVEL" default:"info" usage:"debug, info, warn or error"`
+
+	// Tracing; spans are exported over OTLP/HTTP when the exporter is "otlp"
+	TracingExporter    string  `config:"tracing_exporter" env:"TRACING_EXPORTER" default:"none" usage:"none or otlp"`
+	TracingEndpoint    string  `config:"tracing_endpoint" env:"TRACING_ENDPOINT" usage:"OTLP/HTTP collector URL, e.g. http://localhost:4318; defaults to OTEL_EXPORTER_OTLP_ENDPOINT"`
+	TracingSampleRatio float64 `config:"tracing_sample_ratio" env:"TRACING_SAMPLE_RATIO" default:"1" usage:"Fraction of new traces recorded, 0 to 1; traced callers decide for their requests"`
+
+	// Time each check behind /readyz and /api/system/health gets to finish
+	HealthCheckTimeout time.Duration `config:"health_check_timeout" env:"HEALTH_CHECK_TIMEOUT" default:"3s" usage:"Time allowed for each health check"`
 
 	// WebSocket configuration
-	WSReadBufferSize  int `envconfig:"WS_READ_BUFFER_SIZE" default:"1024"`
-	WSWriteBufferSize int `envconfig:"WS_WRITE_BUFFER_SIZE" default:"1024"`
+	WSReadBufferSize  int           `config:"ws_read_buffer_size" env:"WS_READ_BUFFER_SIZE" default:"1024" usage:"WebSocket read buffer (bytes)"`
+	WSWriteBufferSize int           `config:"ws_write_buffer_size" env:"WS_WRITE_BUFFER_SIZE" default:"1024" usage:"WebSocket write buffer (bytes)"`
+	WSMaxConnections  int           `config:"ws_max_connections" env:"WS_MAX_CONNECTIONS" default:"100" usage:"Concurrent transcription connections"`
+	WSMaxMessageSize  int64         `config:"ws_max_message_size" env:"WS_MAX_MESSAGE_SIZE" default:"1048576" usage:"Largest WebSocket message accepted (bytes)"`
+	WSReadTimeout     time.Duration `config:"ws_read_timeout" env:"WS_READ_TIMEOUT" default:"60s" usage:"Idle time before a silent WebSocket client is dropped"`
+	WSWriteTimeout    time.Duration `config:"ws_write_timeout" env:"WS_WRITE_TIMEOUT" default:"10s" usage:"Time allowed for each WebSocket write"`
+	WSAllowedOrigins  []string      `config:"ws_allowed_origins" env:"WS_ALLOWED_ORIGINS" usage:"Comma-separated browser origins allowed to connect besides the server's own, or * for any"`
+
+	// Authentication configuration
+	AuthRequired     bool              `config:"auth_required" env:"AUTH_REQUIRED" default:"false" usage:"Reject requests without credentials"`
+	AuthTokens       map[string]string `config:"auth_tokens" env:"AUTH_TOKENS" secret:"true" usage:"Static tokens as user:token,user2:token2"`
+	AuthTicketSecret string            `config:"auth_ticket_secret" env:"AUTH_TICKET_SECRET" secret:"true" usage:"Key for signing WebSocket tickets"`
+	AuthTicketTTL    time.Duration     `config:"auth_ticket_ttl" env:"AUTH_TICKET_TTL" default:"60s" usage:"Lifetime of a WebSocket ticket"`
+	AuthSessionTTL   time.Duration     `config:"auth_session_ttl" env:"AUTH_SESSION_TTL" default:"720h" usage:"Lifetime of a login session"`
+
+	// Limits on transcription and summarization, which spend provider
+	// credits. Requests are limited per user, or per IP address for anonymous
+	// callers; quotas reset at midnight UTC and on the first of the month, and
+	// 0 is unlimited.
+	RateLimitRPS                     float64 `config:"rate_limit_rps" env:"RATE_LIMIT_RPS" default:"2" usage:"Transcription and summarization requests per second per client; 0 disables"`
+	RateLimitBurst                   int     `config:"rate_limit_burst" env:"RATE_LIMIT_BURST" default:"10" usage:"Requests a client may make at once before RATE_LIMIT_RPS applies"`
+	QuotaTranscriptionMinutesDaily   float64 `config:"quota_transcription_minutes_daily" env:"QUOTA_TRANSCRIPTION_MINUTES_DAILY" default:"0" usage:"Minutes of audio each client may transcribe per day; 0 is unlimited"`
+	QuotaTranscriptionMinutesMonthly float64 `config:"quota_transcription_minutes_monthly" env:"QUOTA_TRANSCRIPTION_MINUTES_MONTHLY" default:"0" usage:"Minutes of audio each client may transcribe per month; 0 is unlimited"`
+	QuotaSummaryTokensDaily          float64 `config:"quota_summary_tokens_daily" env:"QUOTA_SUMMARY_TOKENS_DAILY" default:"0" usage:"Estimated summary tokens each client may use per day; 0 is unlimited"`
+	QuotaSummaryTokensMonthly        float64 `config:"quota_summary_tokens_monthly" env:"QUOTA_SUMMARY_TOKENS_MONTHLY" default:"0" usage:"Estimated summary tokens each client may use per month; 0 is unlimited"`
 
 	// Audio processing configuration
-	MaxAudioDuration int    `envconfig:"MAX_AUDIO_DURATION" default:"300"` // seconds
-	AudioFormat      string `envconfig:"AUDIO_FORMAT" default:"wav"`
+	MaxAudioDuration int    `config:"max_audio_duration" env:"MAX_AUDIO_DURATION" default:"300" usage:"Longest audio accepted (seconds)"`
+	AudioFormat      string `config:"audio_format" env:"AUDIO_FORMAT" default:"wav" usage:"Default audio format"`
+
+	// How often ~/.noteai/config.json is checked for outside edits; 0 disables
+	ConfigWatchInterval time.Duration `config:"config_watch_interval" env:"CONFIG_WATCH_INTERVAL" default:"2s" usage:"How often the AI settings file is checked for edits; 0 disables"`
 
 	// Development mode
-	DevMode bool `envconfig:"DEV_MODE" default:"false"`
+	DevMode bool `config:"dev_mode" env:"DEV_MODE" default:"false" usage:"Development mode"`
+
+	// Where each setting's value came from, by config key
+	Sources map[string]Source `config:"-"`
+
+	// The server config file that was read, if any
+	File string `config:"-"`
 }
 
-// Load loads configuration from environment variables
-func Load() (*Config, error) {
-	var cfg Config
-	err := envconfig.Process("", &cfg)
+// Load builds the configuration from defaults, the server config file,
+// environment variables and the command-line flags in args, in increasing
+// order of precedence
+func Load(args []string) (*Config, error) {
+	cfg, err := load(args, os.LookupEnv)
 	if err != nil {
 		return nil, fmt.Errorf("failed to load config: %w", err)
 	}
-	return &cfg, nil
+	return cfg, nil
 }
 
 // Validate validates the configuration
 func (c *Config) Validate() error {
 	// OpenAI key is now optional and managed through JSON config
-	
+
 	if c.Port == "" {
 		return fmt.Errorf("PORT cannot be empty")
 	}
 
+	if c.DBPath == "" {
+		return fmt.Errorf("DB_PATH cannot be empty")
+	}
+
+	if c.MediaDir == "" {
+		return fmt.Errorf("MEDIA_DIR cannot be empty")
+	}
+
 	if c.MediaTmpDir == "" {
 		return fmt.Errorf("MEDIA_TMP_DIR cannot be empty")
 	}
 
+	if (c.TLSCertFile == "") != (c.TLSKeyFile == "") {
+		return fmt.Errorf("TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+	}
+
+	if c.TLSReloadInterval < 0 {
+		return fmt.Errorf("TLS_RELOAD_INTERVAL cannot be negative")
+	}
+
+	if c.ReadHeaderTimeout <= 0 || c.IdleTimeout <= 0 {
+		return fmt.Errorf("READ_HEADER_TIMEOUT and IDLE_TIMEOUT must be positive")
+	}
+
+	if c.MaxHeaderBytes <= 0 {
+		return fmt.Errorf("MAX_HEADER_BYTES must be positive")
+	}
+
+	if _, err := c.TrustedProxyPrefixes(); err != nil {
+		return err
+	}
+
+	if err := validateModel("transcription", TranscriptionModels, c.TranscriptionProvider, c.TranscriptionModel); err != nil {
+		return err
+	}
+	if err := validateModel("summary", SummaryModels, c.SummaryProvider, c.SummaryModel); err != nil {
+		return err
+	}
+
+	if c.WSMaxConnections <= 0 {
+		return fmt.Errorf("WS_MAX_CONNECTIONS must be positive")
+	}
+
+	if c.AuthSessionTTL <= 0 {
+		return fmt.Errorf("AUTH_SESSION_TTL must be positive")
+	}
+
+	if c.ConfigWatchInterval < 0 {
+		return fmt.Errorf("CONFIG_WATCH_INTERVAL cannot be negative")
+	}
+
+	if c.WSReadTimeout <= 0 || c.WSWriteTimeout <= 0 {
+		return fmt.Errorf("WS_READ_TIMEOUT and WS_WRITE_TIMEOUT must be positive")
+	}
+
+	if c.TracingExporter != "none" && c.TracingExporter != "otlp" {
+		return fmt.Errorf("TRACING_EXPORTER must be none or otlp")
+	}
+
+	if c.TracingSampleRatio < 0 || c.TracingSampleRatio > 1 {
+		return fmt.Errorf("TRACING_SAMPLE_RATIO must be between 0 and 1")
+	}
+
+	if c.HealthCheckTimeout <= 0 {
+		return fmt.Errorf("HEALTH_CHECK_TIMEOUT must be positive")
+	}
+
+	if c.RateLimitRPS < 0 {
+		return fmt.Errorf("RATE_LIMIT_RPS cannot be negative")
+	}
+
+	if c.RateLimitRPS > 0 && c.RateLimitBurst < 1 {
+		return fmt.Errorf("RATE_LIMIT_BURST must be at least 1")
+	}
+
+	if c.QuotaTranscriptionMinutesDaily < 0 || c.QuotaTranscriptionMinutesMonthly < 0 ||
+		c.QuotaSummaryTokensDaily < 0 || c.QuotaSummaryTokensMonthly < 0 {
+		return fmt.Errorf("QUOTA_* limits cannot be negative")
+	}
+
 	return nil
 }
 
-// Address returns the full server address
+// Address returns the TCP address to listen on, bracketing IPv6 hosts
 func (c *Config) Address() string {
-	return fmt.Sprintf("%s:%s", c.Host, c.Port)
+	return net.JoinHostPort(c.Host, c.Port)
+}
+
+// TrustedProxyPrefixes parses TrustedProxies. Single addresses become
+// prefixes covering just that address.
+func (c *Config) TrustedProxyPrefixes() ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(c.TrustedProxies))
+	for _, proxy := range c.TrustedProxies {
+		if strings.Contains(proxy, "/") {
+			prefix, err := netip.ParsePrefix(proxy)
+			if err != nil {
+				return nil, fmt.Errorf("TRUSTED_PROXIES: invalid CIDR range %q", proxy)
+			}
+			prefixes = append(prefixes, prefix.Masked())
+			continue
+		}
+		addr, err := netip.ParseAddr(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("TRUSTED_PROXIES: invalid address %q", proxy)
+		}
+		prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+	return prefixes, nil
+}
+
+// ProviderDefaults returns the AI settings that apply where the JSON
+// application config leaves them empty
+func (c *Config) ProviderDefaults() AppConfig {
+	return AppConfig{
+		OpenAIKey:             c.OpenAIKey,
+		TranscriptionProvider: c.TranscriptionProvider,
+		TranscriptionModel:    c.TranscriptionModel,
+		SummaryProvider:       c.SummaryProvider,
+		SummaryModel:          c.SummaryModel,
+	}
+}
+
+// expandHome replaces a leading ~ in path with the user's home directory
+func expandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(homeDir, path[1:])
 }
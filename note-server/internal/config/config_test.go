@@ -0,0 +1,273 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// env returns a lookup function over fixed environment variables
+func env(vars map[string]string) func(string) (string, bool) {
+	return func(name string) (string, bool) {
+		value, ok := vars[name]
+		return value, ok
+	}
+}
+
+func TestLoad_Defaults(t *testing.T) {
+	cfg, err := load(nil, env(map[string]string{ServerConfigEnv: ""}))
+	if err != nil {
+		t.Fatalf("load() error = %v", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Validate() error = %v", err)
+	}
+
+	home, _ := os.UserHomeDir()
+	if cfg.Port != "8080" || cfg.WSReadTimeout != 60*time.Second || cfg.DBPath != filepath.Join(home, ".noteai", "notes.db") {
+		t.Errorf("Expected defaults, got %+v", cfg)
+	}
+	if source := cfg.Sources["port"]; source.Layer != LayerDefault {
+		t.Errorf("Expected port from the defaults, got %s", source)
+	}
+}
+
+func TestLoad_Precedence(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "server.yaml")
+	os.WriteFile(path, []byte(`
+port: 9000
+host: 0.0.0.0
+log_level: debug
+ws_read_timeout: 30s
+tracing_sample_ratio: 0.25
+ws_allowed_origins: [http://a.example, http://b.example]
+auth_tokens:
+  alice: s3cret
+`), 0600)
+
+	cfg, err := load(
+		[]string{"--config", path, "--port", "9200", "--dev-mode"},
+		env(map[string]string{"PORT": "9100", "LOG_LEVEL": "warn"}),
+	)
+	if err != nil {
+		t.Fatalf("load() error = %v", err)
+	}
+
+	home, _ := os.UserHomeDir()
+	tests := []struct {
+		key      string
+		got      any
+		expected any
+		source   Source
+	}{
+		{"port", cfg.Port, "9200", Source{LayerFlag, "--port"}},
+		{"log_level", cfg.LogLevel, "warn", Source{LayerEnv, "LOG_LEVEL"}},
+		{"host", cfg.Host, "0.0.0.0", Source{LayerFile, path}},
+		{"ws_read_timeout", cfg.WSReadTimeout, 30 * time.Second, Source{LayerFile, path}},
+		{"ws_allowed_origins", strings.Join(cfg.WSAllowedOrigins, ","), "http://a.example,http://b.example", Source{LayerFile, path}},
+		{"auth_tokens", cfg.AuthTokens["alice"], "s3cret", Source{LayerFile, path}},
+		{"dev_mode", cfg.DevMode, true, Source{LayerFlag, "--dev-mode"}},
+		{"media_dir", cfg.MediaDir, filepath.Join(home, ".noteai", "media"), Source{LayerDefault, ""}},
+		{"media_tmp_dir", cfg.MediaTmpDir, "/tmp/note-media", Source{LayerDefault, ""}},
+		{"tracing_sample_ratio", cfg.TracingSampleRatio, 0.25, Source{LayerFile, path}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			if tt.got != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, tt.got)
+			}
+			if cfg.Sources[tt.key] != tt.source {
+				t.Errorf("Expected source %s, got %s", tt.source, cfg.Sources[tt.key])
+			}
+		})
+	}
+}
+
+func TestLoad_FileFormats(t *testing.T) {
+	files := map[string]string{
+		"server.json": `{"port": "9000", "ws_max_connections": 5, "auth_required": true}`,
+		"server.yml":  "port: \"9000\"\nws_max_connections: 5\nauth_required: true\n",
+		"server.toml": "port = \"9000\"\nws_max_connections = 5\nauth_required = true\n",
+	}
+	for name, contents := range files {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), name)
+			os.WriteFile(path, []byte(contents), 0600)
+
+			cfg, err := load(nil, env(map[string]string{ServerConfigEnv: path}))
+			if err != nil {
+				t.Fatalf("load() error = %v", err)
+			}
+			if cfg.Port != "9000" || cfg.WSMaxConnections != 5 || !cfg.AuthRequired || cfg.File != path {
+				t.Errorf("Expected the file's settings, got %+v", cfg)
+			}
+		})
+	}
+}
+
+func TestLoad_Errors(t *testing.T) {
+	dir := t.TempDir()
+	unknown := filepath.Join(dir, "unknown.json")
+	os.WriteFile(unknown, []byte(`{"prot": "9000"}`), 0600)
+
+	tests := []struct {
+		name string
+		args []string
+		env  map[string]string
+	}{
+		{"unknown file key", []string{"--config", unknown}, nil},
+		{"missing file", []string{"--config", filepath.Join(dir, "missing.yaml")}, nil},
+		{"unsupported format", []string{"--config", filepath.Join(dir, "server.ini")}, nil},
+		{"bad env value", nil, map[string]string{"WS_MAX_CONNECTIONS": "many"}},
+		{"bad float value", nil, map[string]string{"TRACING_SAMPLE_RATIO": "half"}},
+		{"bad flag value", []string{"--ws-read-timeout", "soon"}, nil},
+		{"unknown flag", []string{"--prot", "9000"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vars := map[string]string{ServerConfigEnv: ""}
+			for name, value := range tt.env {
+				vars[name] = value
+			}
+			if _, err := load(tt.args, env(vars)); err == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+}
+
+func TestConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name   string
+		args   []string
+		errMsg string
+	}{
+		{"TLS certificate without key", []string{"--tls-cert-file", "cert.pem"}, "TLS_CERT_FILE and TLS_KEY_FILE"},
+		{"unknown provider", []string{"--summary-provider", "acme"}, "unknown summary provider"},
+		{"negative watch interval", []string{"--config-watch-interval", "-1s"}, "CONFIG_WATCH_INTERVAL"},
+		{"negative TLS reload interval", []string{"--tls-reload-interval", "-1s"}, "TLS_RELOAD_INTERVAL"},
+		{"no header timeout", []string{"--read-header-timeout", "0"}, "READ_HEADER_TIMEOUT"},
+		{"no header limit", []string{"--max-header-bytes", "0"}, "MAX_HEADER_BYTES"},
+		{"unknown tracing exporter", []string{"--tracing-exporter", "zipkin"}, "TRACING_EXPORTER"},
+		{"sample ratio above 1", []string{"--tracing-sample-ratio", "1.5"}, "TRACING_SAMPLE_RATIO"},
+		{"no health check timeout", []string{"--health-check-timeout", "0"}, "HEALTH_CHECK_TIMEOUT"},
+		{"negative rate limit", []string{"--rate-limit-rps", "-1"}, "RATE_LIMIT_RPS"},
+		{"no rate limit burst", []string{"--rate-limit-burst", "0"}, "RATE_LIMIT_BURST"},
+		{"negative quota", []string{"--quota-summary-tokens-monthly", "-100"}, "QUOTA_"},
+		{"invalid trusted proxy", []string{"--trusted-proxies", "10.0.0.1,proxy.internal"}, "TRUSTED_PROXIES"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := load(tt.args, env(map[string]string{ServerConfigEnv: ""}))
+			if err != nil {
+				t.Fatalf("load() error = %v", err)
+			}
+			if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("Expected error containing %q, got %v", tt.errMsg, err)
+			}
+		})
+	}
+}
+
+func TestConfig_Address(t *testing.T) {
+	tests := []struct {
+		host, port, want string
+	}{
+		{"localhost", "8080", "localhost:8080"},
+		{"0.0.0.0", "80", "0.0.0.0:80"},
+		{"::1", "8443", "[::1]:8443"},
+		{"", "8080", ":8080"},
+	}
+	for _, tt := range tests {
+		cfg := &Config{Host: tt.host, Port: tt.port}
+		if got := cfg.Address(); got != tt.want {
+			t.Errorf("Address() for %q = %q, want %q", tt.host, got, tt.want)
+		}
+	}
+}
+
+func TestConfig_Print(t *testing.T) {
+	cfg, err := load([]string{"--openai-key", "sk-secret"}, env(map[string]string{ServerConfigEnv: "", "HOST": "0.0.0.0"}))
+	if err != nil {
+		t.Fatalf("load() error = %v", err)
+	}
+
+	var out bytes.Buffer
+	if err := cfg.Print(&out); err != nil {
+		t.Fatalf("Print() error = %v", err)
+	}
+	for _, line := range []string{"host", "0.0.0.0", "env HOST", "flag --openai-key", "********"} {
+		if !strings.Contains(out.String(), line) {
+			t.Errorf("Expected %q in output:\n%s", line, out.String())
+		}
+	}
+	if strings.Contains(out.String(), "sk-secret") {
+		t.Errorf("Expected secrets masked, got:\n%s", out.String())
+	}
+}
+
+func TestConfigManager_Effective(t *testing.T) {
+	t.Setenv(KeyEnv, "")
+	dir := t.TempDir()
+	manager := NewConfigManagerWithPaths(filepath.Join(dir, "config.json"), filepath.Join(dir, "config.key"))
+	manager.SetDefaults(AppConfig{OpenAIKey: "sk-env", SummaryProvider: "openai", SummaryModel: "gpt-4", TranscriptionProvider: "openai"})
+
+	if err := manager.SetConfig(AppConfig{SummaryProvider: "google", SummaryModel: "gemini-1.5-pro"}); err != nil {
+		t.Fatalf("SetConfig() error = %v", err)
+	}
+
+	effective := manager.Effective()
+	if effective.OpenAIKey != "sk-env" || effective.TranscriptionProvider != "openai" || effective.SummaryModel != "gemini-1.5-pro" {
+		t.Errorf("Expected saved settings over defaults, got %+v", effective)
+	}
+	if saved := manager.GetConfig(); saved.OpenAIKey != "" || saved.TranscriptionProvider != "" {
+		t.Errorf("Expected the defaults kept out of the saved config, got %+v", saved)
+	}
+}
+
+func TestConfigManager_SetConfigFailures(t *testing.T) {
+	t.Setenv(KeyEnv, "")
+	dir := filepath.Join(t.TempDir(), "config")
+	manager := NewConfigManagerWithPaths(filepath.Join(dir, "config.json"), filepath.Join(t.TempDir(), "config.key"))
+	saved := AppConfig{OpenAIKey: "sk-saved", SummaryProvider: "openai", SummaryModel: "gpt-4"}
+	if err := manager.SetConfig(saved); err != nil {
+		t.Fatalf("SetConfig() error = %v", err)
+	}
+
+	var notified int
+	unsubscribe := manager.Subscribe(func(previous, current AppConfig) {
+		notified++
+	})
+	defer unsubscribe()
+
+	// An invalid configuration is refused before it's saved
+	if err := manager.SetConfig(AppConfig{SummaryProvider: "openai", SummaryModel: "gpt-99"}); !errors.Is(err, ErrInvalidConfig) {
+		t.Errorf("Expected ErrInvalidConfig, got %v", err)
+	}
+
+	// A configuration that can't be written isn't kept either
+	if err := os.RemoveAll(dir); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(dir, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := manager.SetConfig(AppConfig{SummaryProvider: "openai", SummaryModel: "gpt-4o"}); err == nil {
+		t.Error("Expected an error saving the config")
+	}
+	if err := manager.SetOpenAIKey("sk-unsaved"); err == nil {
+		t.Error("Expected an error saving the OpenAI key")
+	}
+
+	if config := manager.GetConfig(); config.OpenAIKey != "sk-saved" || config.SummaryModel != "gpt-4" {
+		t.Errorf("Expected the saved config kept, got %+v", config)
+	}
+	if notified != 0 {
+		t.Errorf("Expected no notifications, got %d", notified)
+	}
+}
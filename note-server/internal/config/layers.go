@@ -0,0 +1,388 @@
+package config
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// ServerConfigEnv names the server config file, overriding the search of
+// ~/.noteai for server.yaml, server.yml, server.toml and server.json
+const ServerConfigEnv = "NOTE_SERVER_CONFIG"
+
+// serverConfigNames are the files looked for in ~/.noteai, in order
+var serverConfigNames = []string{"server.yaml", "server.yml", "server.toml", "server.json"}
+
+// Layers a setting can come from, lowest precedence first
+const (
+	LayerDefault = "default"
+	LayerFile    = "file"
+	LayerEnv     = "env"
+	LayerFlag    = "flag"
+)
+
+// Source records which layer set a setting, and the file, variable or flag
+// within it
+type Source struct {
+	Layer string `json:"layer"`
+	Name  string `json:"name,omitempty"`
+}
+
+// String describes the source, e.g. "env PORT" or "flag --port"
+func (s Source) String() string {
+	if s.Name == "" {
+		return s.Layer
+	}
+	return s.Layer + " " + s.Name
+}
+
+// setting describes one field of Config
+type setting struct {
+	key    string
+	env    string
+	def    string
+	usage  string
+	secret bool
+	index  int
+}
+
+// flagName returns the command-line flag for the setting
+func (s setting) flagName() string {
+	return strings.ReplaceAll(s.key, "_", "-")
+}
+
+// settings lists the fields of Config that are layered, in declaration order
+func settings() []setting {
+	t := reflect.TypeOf(Config{})
+	var list []setting
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		key := field.Tag.Get("config")
+		if key == "" || key == "-" {
+			continue
+		}
+		list = append(list, setting{
+			key:    key,
+			env:    field.Tag.Get("env"),
+			def:    field.Tag.Get("default"),
+			usage:  field.Tag.Get("usage"),
+			secret: field.Tag.Get("secret") == "true",
+			index:  i,
+		})
+	}
+	return list
+}
+
+// load layers the configuration, looking environment variables up with lookupEnv
+func load(args []string, lookupEnv func(string) (string, bool)) (*Config, error) {
+	list := settings()
+
+	// Flags are parsed first to find --config, and applied last
+	flags := flag.NewFlagSet("note-server", flag.ContinueOnError)
+	configFile := flags.String("config", "", "Server config file (JSON, YAML or TOML)")
+	flagValues := map[string]string{}
+	for _, s := range list {
+		s := s
+		set := func(value string) error {
+			flagValues[s.key] = value
+			return nil
+		}
+		if reflect.TypeOf(Config{}).Field(s.index).Type.Kind() == reflect.Bool {
+			flags.BoolFunc(s.flagName(), s.usage, func(value string) error { return set(value) })
+		} else {
+			flags.Func(s.flagName(), s.usage, set)
+		}
+	}
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+	if flags.NArg() > 0 {
+		return nil, fmt.Errorf("unexpected argument %q", flags.Arg(0))
+	}
+
+	cfg := &Config{Sources: make(map[string]Source, len(list))}
+	value := reflect.ValueOf(cfg).Elem()
+
+	for _, s := range list {
+		if err := setValue(value.Field(s.index), s.def); err != nil {
+			return nil, fmt.Errorf("default for %s: %w", s.key, err)
+		}
+		cfg.Sources[s.key] = Source{Layer: LayerDefault}
+	}
+
+	path, explicit := *configFile, *configFile != ""
+	if !explicit {
+		path, explicit = lookupEnv(ServerConfigEnv)
+	}
+	if !explicit {
+		path = findServerConfig()
+	}
+	if path != "" {
+		values, err := readServerConfig(path)
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range list {
+			raw, ok := values[s.key]
+			if !ok {
+				continue
+			}
+			if err := setValue(value.Field(s.index), raw); err != nil {
+				return nil, fmt.Errorf("%s in %s: %w", s.key, path, err)
+			}
+			cfg.Sources[s.key] = Source{Layer: LayerFile, Name: path}
+		}
+		cfg.File = path
+	}
+
+	for _, s := range list {
+		raw, ok := lookupEnv(s.env)
+		if !ok || raw == "" {
+			continue
+		}
+		if err := setValue(value.Field(s.index), raw); err != nil {
+			return nil, fmt.Errorf("%s: %w", s.env, err)
+		}
+		cfg.Sources[s.key] = Source{Layer: LayerEnv, Name: s.env}
+	}
+
+	for _, s := range list {
+		raw, ok := flagValues[s.key]
+		if !ok {
+			continue
+		}
+		if err := setValue(value.Field(s.index), raw); err != nil {
+			return nil, fmt.Errorf("--%s: %w", s.flagName(), err)
+		}
+		cfg.Sources[s.key] = Source{Layer: LayerFlag, Name: "--" + s.flagName()}
+	}
+
+	cfg.DBPath = expandHome(cfg.DBPath)
+	cfg.MediaDir = expandHome(cfg.MediaDir)
+	cfg.MediaTmpDir = expandHome(cfg.MediaTmpDir)
+	cfg.TLSCertFile = expandHome(cfg.TLSCertFile)
+	cfg.TLSKeyFile = expandHome(cfg.TLSKeyFile)
+	cfg.UnixSocket = expandHome(cfg.UnixSocket)
+
+	return cfg, nil
+}
+
+// findServerConfig returns the first server config file in ~/.noteai, or ""
+func findServerConfig() string {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	for _, name := range serverConfigNames {
+		path := filepath.Join(homeDir, ".noteai", name)
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return ""
+}
+
+// readServerConfig reads a JSON, YAML or TOML server config file, chosen by
+// extension, as raw setting values by key. Unknown keys are rejected.
+func readServerConfig(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read server config: %w", err)
+	}
+
+	var document map[string]any
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		err = json.Unmarshal(data, &document)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &document)
+	case ".toml":
+		err = toml.Unmarshal(data, &document)
+	default:
+		return nil, fmt.Errorf("server config %s must be .json, .yaml, .yml or .toml", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	known := map[string]bool{}
+	for _, s := range settings() {
+		known[s.key] = true
+	}
+
+	values := make(map[string]string, len(document))
+	for key, v := range document {
+		if !known[key] {
+			return nil, fmt.Errorf("unknown setting %q in %s", key, path)
+		}
+		raw, err := rawValue(v)
+		if err != nil {
+			return nil, fmt.Errorf("%s in %s: %w", key, path, err)
+		}
+		values[key] = raw
+	}
+	return values, nil
+}
+
+// rawValue converts a decoded file value to the form environment variables
+// use: lists are comma-separated and maps are key:value pairs
+func rawValue(v any) (string, error) {
+	switch v := v.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case bool, int, int64, float64, uint64:
+		return fmt.Sprint(v), nil
+	case []any:
+		items := make([]string, 0, len(v))
+		for _, item := range v {
+			raw, err := rawValue(item)
+			if err != nil {
+				return "", err
+			}
+			items = append(items, raw)
+		}
+		return strings.Join(items, ","), nil
+	case map[string]any:
+		pairs := make([]string, 0, len(v))
+		for key, item := range v {
+			raw, err := rawValue(item)
+			if err != nil {
+				return "", err
+			}
+			pairs = append(pairs, key+":"+raw)
+		}
+		sort.Strings(pairs)
+		return strings.Join(pairs, ","), nil
+	default:
+		return "", fmt.Errorf("unsupported value %v", v)
+	}
+}
+
+// setValue parses raw into a Config field
+func setValue(field reflect.Value, raw string) error {
+	if field.Type() == reflect.TypeOf(time.Duration(0)) {
+		if raw == "" {
+			field.SetInt(0)
+			return nil
+		}
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(d))
+		return nil
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(raw)
+	case reflect.Int, reflect.Int64:
+		if raw == "" {
+			field.SetInt(0)
+			return nil
+		}
+		n, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid number %q", raw)
+		}
+		field.SetInt(n)
+	case reflect.Float64:
+		if raw == "" {
+			field.SetFloat(0)
+			return nil
+		}
+		f, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return fmt.Errorf("invalid number %q", raw)
+		}
+		field.SetFloat(f)
+	case reflect.Bool:
+		if raw == "" {
+			field.SetBool(false)
+			return nil
+		}
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", raw)
+		}
+		field.SetBool(b)
+	case reflect.Slice:
+		var items []string
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		field.Set(reflect.ValueOf(items))
+	case reflect.Map:
+		pairs := map[string]string{}
+		for _, pair := range strings.Split(raw, ",") {
+			if pair = strings.TrimSpace(pair); pair == "" {
+				continue
+			}
+			key, value, ok := strings.Cut(pair, ":")
+			if !ok {
+				return fmt.Errorf("invalid key:value pair %q", pair)
+			}
+			pairs[key] = value
+		}
+		field.Set(reflect.ValueOf(pairs))
+	default:
+		return fmt.Errorf("unsupported setting type %s", field.Type())
+	}
+	return nil
+}
+
+// formatValue renders a Config field for display
+func formatValue(field reflect.Value) string {
+	switch v := field.Interface().(type) {
+	case time.Duration:
+		return v.String()
+	case []string:
+		return strings.Join(v, ",")
+	case map[string]string:
+		pairs := make([]string, 0, len(v))
+		for key, value := range v {
+			pairs = append(pairs, key+":"+value)
+		}
+		sort.Strings(pairs)
+		return strings.Join(pairs, ",")
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// Print writes every setting's effective value and where it came from.
+// Secrets are masked.
+func (c *Config) Print(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "KEY\tVALUE\tSOURCE")
+
+	value := reflect.ValueOf(c).Elem()
+	for _, s := range settings() {
+		display := formatValue(value.Field(s.index))
+		if s.secret && display != "" {
+			display = "********"
+		}
+		if display == "" {
+			display = `""`
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", s.key, display, c.Sources[s.key])
+	}
+
+	return tw.Flush()
+}
@@ -13,9 +13,9 @@ type AppConfig struct {
 	// OpenAI Configuration
 	OpenAIKey string `json:"openai_key,omitempty"`
 	
-	// Other AI providers can be added here
-	// GoogleProjectID string `json:"google_project_id,omitempty"`
-	// GoogleLocation   string `json:"google_location,omitempty"`
+	// Credentials for other AI providers, by name (e.g. "google_api_key").
+	// Like the OpenAI key, they are encrypted in the JSON file.
+	Secrets map[string]string `json:"secrets,omitempty"`
 	
 	// Model configurations
 	TranscriptionProvider string `json:"transcription_provider,omitempty"`
@@ -27,8 +27,19 @@ type AppConfig struct {
 // ConfigManager handles application configuration persistence
 type ConfigManager struct {
 	configPath string
+	keyPath    string
 	config     *AppConfig
 	mutex      sync.RWMutex
+	
+	// Settings from the layered server config used where config leaves them empty
+	defaults AppConfig
+	
+	// Version of the file last loaded or saved, to spot outside edits
+	file fileState
+	
+	subscribers      map[int]func(previous, current AppConfig)
+	nextSubscriber   int
+	subscribersMutex sync.Mutex
 }
 
 // NewConfigManager creates a new configuration manager
@@ -43,8 +54,23 @@ func NewConfigManager() *ConfigManager {
 	configDir := filepath.Join(homeDir, ".noteai")
 	configPath := filepath.Join(configDir, "config.json")
 	
+	// Secrets are encrypted with a key kept beside the config unless
+	// configured otherwise
+	keyPath := os.Getenv(KeyFileEnv)
+	if keyPath == "" {
+		keyPath = filepath.Join(configDir, "config.key")
+	}
+	
+	return NewConfigManagerWithPaths(configPath, keyPath)
+}
+
+// NewConfigManagerWithPaths creates a configuration manager for the config file
+// at configPath, encrypting secrets with the key in the file at keyPath when
+// NOTE_CONFIG_KEY isn't set
+func NewConfigManagerWithPaths(configPath, keyPath string) *ConfigManager {
 	return &ConfigManager{
 		configPath: configPath,
+		keyPath:    keyPath,
 		config:     &AppConfig{},
 	}
 }
@@ -67,21 +93,75 @@ func (cm *ConfigManager) Load() error {
 		return fmt.Errorf("failed to read config file: %w", err)
 	}
 	
+	config, err := cm.parse(data)
+	if err != nil {
+		return err
+	}
+	
+	cm.config = config
+	cm.recordFile(data)
+	return nil
+}
+
+// parse decodes the contents of the config file and decrypts its secrets
+func (cm *ConfigManager) parse(data []byte) (*AppConfig, error) {
 	// Parse JSON
 	var config AppConfig
 	if err := json.Unmarshal(data, &config); err != nil {
-		return fmt.Errorf("failed to parse config file: %w", err)
+		return nil, fmt.Errorf("failed to parse config file: %w", err)
 	}
 	
-	cm.config = &config
-	return nil
+	// Decrypt secrets; plaintext ones from older files are encrypted on the
+	// next save
+	if hasEncryptedSecrets(&config) {
+		key, _, err := cm.loadKey()
+		if err != nil {
+			return nil, err
+		}
+		err = transformSecrets(&config, func(field, value string) (string, error) {
+			return decryptSecret(key, field, value)
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	
+	return &config, nil
 }
 
-// Save saves the current configuration to the JSON file
+// Save saves the current configuration to the JSON file, encrypting its secrets
 func (cm *ConfigManager) Save() error {
 	cm.mutex.Lock()
 	defer cm.mutex.Unlock()
 	
+	return cm.saveLocked()
+}
+
+// saveLocked saves the configuration, creating a key if its secrets need one.
+// The caller holds the lock.
+func (cm *ConfigManager) saveLocked() error {
+	var key []byte
+	if hasSecrets(cm.config) {
+		var err error
+		key, err = cm.encryptionKey()
+		if err != nil {
+			return err
+		}
+	}
+	return cm.save(key)
+}
+
+// save writes the configuration with its secrets encrypted with key. The
+// caller holds the lock.
+func (cm *ConfigManager) save(key []byte) error {
+	config := cm.config.clone()
+	err := transformSecrets(&config, func(field, value string) (string, error) {
+		return encryptSecret(key, field, value)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encrypt secrets: %w", err)
+	}
+	
 	// Ensure config directory exists
 	configDir := filepath.Dir(cm.configPath)
 	if err := os.MkdirAll(configDir, 0755); err != nil {
@@ -89,15 +169,16 @@ func (cm *ConfigManager) Save() error {
 	}
 	
 	// Marshal config to JSON
-	data, err := json.MarshalIndent(cm.config, "", "  ")
+	data, err := json.MarshalIndent(config, "", "  ")
 	if err != nil {
 		return fmt.Errorf("failed to marshal config: %w", err)
 	}
 	
-	// Write to file
-	if err := os.WriteFile(cm.configPath, data, 0600); err != nil {
+	// Replace the file in one step so it is never left half-written
+	if err := writeFileAtomic(cm.configPath, data); err != nil {
 		return fmt.Errorf("failed to write config file: %w", err)
 	}
+	cm.recordFile(data)
 	
 	return nil
 }
@@ -108,17 +189,88 @@ func (cm *ConfigManager) GetConfig() AppConfig {
 	defer cm.mutex.RUnlock()
 	
 	// Return a copy to prevent external modification
-	config := *cm.config
+	return cm.config.clone()
+}
+
+// clone returns a copy of the configuration that shares no maps with it
+func (c *AppConfig) clone() AppConfig {
+	config := *c
+	if c.Secrets != nil {
+		config.Secrets = make(map[string]string, len(c.Secrets))
+		for name, secret := range c.Secrets {
+			config.Secrets[name] = secret
+		}
+	}
 	return config
 }
 
-// SetConfig updates the configuration
+// hasSecrets reports whether config holds any secret that needs encrypting
+func hasSecrets(config *AppConfig) bool {
+	return config.OpenAIKey != "" || len(config.Secrets) > 0
+}
+
+// SetConfig validates and saves config in place of the current configuration.
+// Nothing changes if either fails.
 func (cm *ConfigManager) SetConfig(config AppConfig) error {
+	_, _, err := cm.UpdateConfig(func(AppConfig) (AppConfig, error) {
+		return config.clone(), nil
+	})
+	return err
+}
+
+// UpdateConfig replaces the configuration with the result of applying update
+// to a copy of it, validating and saving the result. Concurrent updates don't
+// overwrite each other. The configuration before and after is returned.
+func (cm *ConfigManager) UpdateConfig(update func(current AppConfig) (AppConfig, error)) (previous, updated AppConfig, err error) {
 	cm.mutex.Lock()
-	cm.config = &config
+	previous = cm.config.clone()
+	updated, err = update(cm.config.clone())
+	if err == nil {
+		err = updated.Validate()
+	}
+	if err == nil {
+		cm.config = &updated
+		if err = cm.saveLocked(); err != nil {
+			cm.config = &previous
+		}
+	}
 	cm.mutex.Unlock()
 	
-	return cm.Save()
+	if err != nil {
+		return previous, previous, err
+	}
+	cm.notify(previous, updated.clone())
+	return previous, updated.clone(), nil
+}
+
+// SetDefaults sets the AI settings that apply where the JSON configuration
+// leaves them empty, usually from Config.ProviderDefaults
+func (cm *ConfigManager) SetDefaults(defaults AppConfig) {
+	cm.mutex.Lock()
+	defer cm.mutex.Unlock()
+	
+	cm.defaults = defaults.clone()
+}
+
+// Effective returns the configuration with empty settings filled in from the
+// defaults. The defaults are never saved to the JSON file.
+func (cm *ConfigManager) Effective() AppConfig {
+	cm.mutex.RLock()
+	defer cm.mutex.RUnlock()
+	
+	config := cm.config.clone()
+	if config.OpenAIKey == "" {
+		config.OpenAIKey = cm.defaults.OpenAIKey
+	}
+	if config.TranscriptionProvider == "" {
+		config.TranscriptionProvider = cm.defaults.TranscriptionProvider
+		config.TranscriptionModel = cm.defaults.TranscriptionModel
+	}
+	if config.SummaryProvider == "" {
+		config.SummaryProvider = cm.defaults.SummaryProvider
+		config.SummaryModel = cm.defaults.SummaryModel
+	}
+	return config
 }
 
 // GetOpenAIKey returns the OpenAI API key if configured
@@ -129,13 +281,22 @@ func (cm *ConfigManager) GetOpenAIKey() string {
 	return cm.config.OpenAIKey
 }
 
-// SetOpenAIKey updates the OpenAI API key
+// SetOpenAIKey updates the OpenAI API key, keeping the current one if the
+// configuration can't be saved
 func (cm *ConfigManager) SetOpenAIKey(key string) error {
-	cm.mutex.Lock()
-	cm.config.OpenAIKey = key
-	cm.mutex.Unlock()
+	_, _, err := cm.UpdateConfig(func(config AppConfig) (AppConfig, error) {
+		config.OpenAIKey = key
+		return config, nil
+	})
+	return err
+}
+
+// GetSecret returns a provider secret from the secrets section, or "" if unset
+func (cm *ConfigManager) GetSecret(name string) string {
+	cm.mutex.RLock()
+	defer cm.mutex.RUnlock()
 	
-	return cm.Save()
+	return cm.config.Secrets[name]
 }
 
 // HasOpenAIKey returns true if OpenAI key is configured
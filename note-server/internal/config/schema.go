@@ -0,0 +1,251 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ErrInvalidConfig wraps the errors of configurations rejected by Validate or MergePatch
+var ErrInvalidConfig = errors.New("invalid config")
+
+// TranscriptionModels lists the models each transcription provider offers
+var TranscriptionModels = map[string][]string{
+	"openai": {"whisper-1", "gpt-4o-transcribe", "gpt-4o-mini-transcribe"},
+	"google": {"chirp", "chirp_2", "latest_long"},
+}
+
+// SummaryModels lists the models each summary provider offers
+var SummaryModels = map[string][]string{
+	"openai": {"gpt-4", "gpt-4-turbo", "gpt-4o", "gpt-4o-mini", "gpt-3.5-turbo"},
+	"google": {"gemini-1.5-pro", "gemini-1.5-flash"},
+}
+
+// Validate checks the providers and models. Empty settings are allowed and
+// fall back to the server's defaults.
+func (c *AppConfig) Validate() error {
+	if err := c.validate(); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidConfig, err)
+	}
+	return nil
+}
+
+// validate checks the configuration for Validate
+func (c *AppConfig) validate() error {
+	if err := validateModel("transcription", TranscriptionModels, c.TranscriptionProvider, c.TranscriptionModel); err != nil {
+		return err
+	}
+	if err := validateModel("summary", SummaryModels, c.SummaryProvider, c.SummaryModel); err != nil {
+		return err
+	}
+	for name := range c.Secrets {
+		if name == "" || strings.ContainsAny(name, ". ") {
+			return fmt.Errorf("invalid secret name %q", name)
+		}
+	}
+	return nil
+}
+
+// validateModel checks that provider is known and offers model
+func validateModel(kind string, models map[string][]string, provider, model string) error {
+	if provider == "" {
+		if model != "" {
+			return fmt.Errorf("%s_model requires %s_provider", kind, kind)
+		}
+		return nil
+	}
+
+	offered, ok := models[provider]
+	if !ok {
+		return fmt.Errorf("unknown %s provider %q", kind, provider)
+	}
+	if model == "" {
+		return nil
+	}
+	for _, m := range offered {
+		if m == model {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown %s model %q for provider %q", kind, model, provider)
+}
+
+// MaskSecret hides all but the ends of a secret
+func MaskSecret(secret string) string {
+	if secret == "" {
+		return ""
+	}
+	if len(secret) > 8 {
+		return secret[:4] + "..." + secret[len(secret)-4:]
+	}
+	return "***"
+}
+
+// Masked returns a copy of the configuration with its secrets masked
+func (c *AppConfig) Masked() AppConfig {
+	config := c.clone()
+	transformSecrets(&config, func(field, value string) (string, error) {
+		return MaskSecret(value), nil
+	})
+	return config
+}
+
+// isMasked reports whether value is the masked form of secret, which clients
+// send back to mean "unchanged"
+func isMasked(value, secret string) bool {
+	return secret != "" && value == MaskSecret(secret)
+}
+
+// PreserveSecrets keeps the current value of every secret that c omits or
+// sends masked, so a PUT of the masked configuration doesn't wipe the keys
+func (c *AppConfig) PreserveSecrets(current AppConfig) {
+	if c.OpenAIKey == "" || isMasked(c.OpenAIKey, current.OpenAIKey) {
+		c.OpenAIKey = current.OpenAIKey
+	}
+
+	if c.Secrets == nil && len(current.Secrets) > 0 {
+		c.Secrets = make(map[string]string, len(current.Secrets))
+	}
+	for name, secret := range current.Secrets {
+		if value, ok := c.Secrets[name]; !ok || isMasked(value, secret) {
+			c.Secrets[name] = secret
+		}
+	}
+}
+
+// MergePatch applies a JSON merge patch (RFC 7396) to the configuration: fields
+// in patch replace the current ones, null removes them and absent fields are
+// left alone. Masked secrets mean "unchanged". Unknown fields are rejected.
+func (c *AppConfig) MergePatch(patch []byte) (AppConfig, error) {
+	var changes map[string]any
+	if err := json.Unmarshal(patch, &changes); err != nil || changes == nil {
+		return AppConfig{}, fmt.Errorf("%w: merge patch must be a JSON object", ErrInvalidConfig)
+	}
+
+	// Drop masked secrets before merging
+	if value, ok := changes["openai_key"].(string); ok && isMasked(value, c.OpenAIKey) {
+		delete(changes, "openai_key")
+	}
+	if secrets, ok := changes["secrets"].(map[string]any); ok {
+		for name, value := range secrets {
+			if value, ok := value.(string); ok && isMasked(value, c.Secrets[name]) {
+				delete(secrets, name)
+			}
+		}
+	}
+
+	current, err := c.fields()
+	if err != nil {
+		return AppConfig{}, err
+	}
+	merged, err := json.Marshal(mergePatch(current, changes))
+	if err != nil {
+		return AppConfig{}, fmt.Errorf("failed to apply merge patch: %w", err)
+	}
+
+	var config AppConfig
+	decoder := json.NewDecoder(bytes.NewReader(merged))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&config); err != nil {
+		return AppConfig{}, fmt.Errorf("%w: %v", ErrInvalidConfig, err)
+	}
+	return config, nil
+}
+
+// mergePatch merges patch into target as RFC 7396 describes
+func mergePatch(target map[string]any, patch map[string]any) map[string]any {
+	if target == nil {
+		target = map[string]any{}
+	}
+	for key, value := range patch {
+		switch value := value.(type) {
+		case nil:
+			delete(target, key)
+		case map[string]any:
+			existing, _ := target[key].(map[string]any)
+			target[key] = mergePatch(existing, value)
+		default:
+			target[key] = value
+		}
+	}
+	return target
+}
+
+// fields returns the configuration as a JSON object
+func (c *AppConfig) fields() (map[string]any, error) {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal config: %w", err)
+	}
+	var fields map[string]any
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, fmt.Errorf("failed to marshal config: %w", err)
+	}
+	return fields, nil
+}
+
+// Change is one field that differs between two configurations. Secret values
+// are never included; only that they changed.
+type Change struct {
+	Field  string `json:"field"`
+	Old    string `json:"old,omitempty"`
+	New    string `json:"new,omitempty"`
+	Secret bool   `json:"secret,omitempty"`
+}
+
+// Diff lists the fields that differ between old and new, sorted by name.
+// Secrets in the secrets section appear as "secrets.<name>".
+func Diff(old, new AppConfig) []Change {
+	oldFields, newFields := flatten(old), flatten(new)
+
+	names := map[string]bool{}
+	for name := range oldFields {
+		names[name] = true
+	}
+	for name := range newFields {
+		names[name] = true
+	}
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+
+	changes := []Change{}
+	for _, name := range sorted {
+		if oldFields[name] == newFields[name] {
+			continue
+		}
+		change := Change{Field: name}
+		if name == "openai_key" || strings.HasPrefix(name, "secrets.") {
+			change.Secret = true
+		} else {
+			change.Old, change.New = oldFields[name], newFields[name]
+		}
+		changes = append(changes, change)
+	}
+	return changes
+}
+
+// flatten returns the non-empty string settings of config by field name
+func flatten(config AppConfig) map[string]string {
+	fields := map[string]string{
+		"openai_key":             config.OpenAIKey,
+		"transcription_provider": config.TranscriptionProvider,
+		"transcription_model":    config.TranscriptionModel,
+		"summary_provider":       config.SummaryProvider,
+		"summary_model":          config.SummaryModel,
+	}
+	for name, secret := range config.Secrets {
+		fields["secrets."+name] = secret
+	}
+	for name, value := range fields {
+		if value == "" {
+			delete(fields, name)
+		}
+	}
+	return fields
+}
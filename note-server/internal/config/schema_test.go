@@ -0,0 +1,125 @@
+package config
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestAppConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  AppConfig
+		wantErr bool
+	}{
+		{"empty", AppConfig{}, false},
+		{"known models", AppConfig{TranscriptionProvider: "openai", TranscriptionModel: "whisper-1", SummaryProvider: "google", SummaryModel: "gemini-1.5-pro"}, false},
+		{"provider without model", AppConfig{SummaryProvider: "openai"}, false},
+		{"unknown provider", AppConfig{TranscriptionProvider: "acme"}, true},
+		{"unknown model", AppConfig{SummaryProvider: "openai", SummaryModel: "gpt-99"}, true},
+		{"model of another provider", AppConfig{TranscriptionProvider: "google", TranscriptionModel: "whisper-1"}, true},
+		{"model without provider", AppConfig{SummaryModel: "gpt-4"}, true},
+		{"dotted secret name", AppConfig{Secrets: map[string]string{"google.key": "x"}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidConfig) {
+				t.Errorf("Expected ErrInvalidConfig, got %v", err)
+			}
+		})
+	}
+}
+
+func TestAppConfig_MergePatch(t *testing.T) {
+	current := AppConfig{
+		OpenAIKey:             "sk-current-openai-key",
+		Secrets:               map[string]string{"google_api_key": "google-secret-key", "other": "other-secret"},
+		TranscriptionProvider: "openai",
+		TranscriptionModel:    "whisper-1",
+	}
+	masked := current.Masked()
+
+	tests := []struct {
+		name     string
+		patch    string
+		expected AppConfig
+		wantErr  bool
+	}{
+		{
+			name:  "change one field",
+			patch: `{"summary_provider": "openai"}`,
+			expected: AppConfig{OpenAIKey: current.OpenAIKey, Secrets: current.Secrets,
+				TranscriptionProvider: "openai", TranscriptionModel: "whisper-1", SummaryProvider: "openai"},
+		},
+		{
+			name:     "masked values are unchanged",
+			patch:    `{"openai_key": "` + masked.OpenAIKey + `", "secrets": {"google_api_key": "` + masked.Secrets["google_api_key"] + `"}}`,
+			expected: current,
+		},
+		{
+			name:  "null clears",
+			patch: `{"transcription_model": null, "secrets": {"other": null}}`,
+			expected: AppConfig{OpenAIKey: current.OpenAIKey, Secrets: map[string]string{"google_api_key": "google-secret-key"},
+				TranscriptionProvider: "openai"},
+		},
+		{name: "unknown field", patch: `{"openai_kee": "sk-typo"}`, wantErr: true},
+		{name: "not an object", patch: `["openai"]`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			patched, err := current.MergePatch([]byte(tt.patch))
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidConfig) {
+					t.Errorf("Expected ErrInvalidConfig, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("MergePatch() error = %v", err)
+			}
+			if changes := Diff(tt.expected, patched); len(changes) != 0 {
+				t.Errorf("Expected %+v, got %+v (%+v)", tt.expected, patched, changes)
+			}
+		})
+	}
+}
+
+func TestAppConfig_PreserveSecrets(t *testing.T) {
+	current := AppConfig{OpenAIKey: "sk-current-openai-key", Secrets: map[string]string{"google_api_key": "google-secret-key"}}
+
+	// A PUT without the secrets, as a settings form that only edits models sends
+	replacement := AppConfig{SummaryProvider: "openai"}
+	replacement.PreserveSecrets(current)
+	if replacement.OpenAIKey != current.OpenAIKey || replacement.Secrets["google_api_key"] != "google-secret-key" {
+		t.Errorf("Expected the secrets kept, got %+v", replacement)
+	}
+
+	replacement = AppConfig{OpenAIKey: "sk-new-openai-key"}
+	replacement.PreserveSecrets(current)
+	if replacement.OpenAIKey != "sk-new-openai-key" {
+		t.Errorf("Expected the new key, got %q", replacement.OpenAIKey)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	old := AppConfig{OpenAIKey: "sk-old", TranscriptionProvider: "openai"}
+	new := AppConfig{OpenAIKey: "sk-new", TranscriptionProvider: "google", Secrets: map[string]string{"google_api_key": "secret"}}
+
+	expected := []Change{
+		{Field: "openai_key", Secret: true},
+		{Field: "secrets.google_api_key", Secret: true},
+		{Field: "transcription_provider", Old: "openai", New: "google"},
+	}
+	changes := Diff(old, new)
+	if len(changes) != len(expected) {
+		t.Fatalf("Expected %+v, got %+v", expected, changes)
+	}
+	for i := range expected {
+		if changes[i] != expected[i] {
+			t.Errorf("Expected %+v, got %+v", expected[i], changes[i])
+		}
+	}
+}
@@ -0,0 +1,251 @@
+package config
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Environment variables that supply the key secrets in the JSON config are
+// encrypted with. NOTE_CONFIG_KEY takes precedence over the keyfile.
+const (
+	KeyEnv     = "NOTE_CONFIG_KEY"
+	KeyFileEnv = "NOTE_CONFIG_KEY_FILE"
+)
+
+// encryptedPrefix marks a value in the JSON config as encrypted. The rest is
+// the base64 nonce and AES-256-GCM ciphertext.
+const encryptedPrefix = "enc:v1:"
+
+// keySize is the length of an AES-256 key
+const keySize = 32
+
+// ErrNoKey is returned when the config holds encrypted secrets but no key is configured
+var ErrNoKey = errors.New("config has encrypted secrets but no key; set " + KeyEnv + " or " + KeyFileEnv)
+
+// NewKey returns a random key for encrypting secrets
+func NewKey() []byte {
+	key := make([]byte, keySize)
+	rand.Read(key)
+	return key
+}
+
+// EncodeKey returns the form of a key stored in NOTE_CONFIG_KEY or a keyfile
+func EncodeKey(key []byte) string {
+	return base64.StdEncoding.EncodeToString(key)
+}
+
+// ParseKey decodes a base64 key, as stored in NOTE_CONFIG_KEY or a keyfile
+func ParseKey(encoded string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return nil, fmt.Errorf("invalid config key: %w", err)
+	}
+	if len(key) != keySize {
+		return nil, fmt.Errorf("invalid config key: want %d bytes, got %d", keySize, len(key))
+	}
+	return key, nil
+}
+
+// IsEncrypted reports whether a config value is an encrypted secret
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, encryptedPrefix)
+}
+
+// encryptSecret seals a value with key. The field name is bound to the
+// ciphertext so encrypted values can't be swapped between fields.
+func encryptSecret(key []byte, field, value string) (string, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(value), []byte(field))
+	return encryptedPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// decryptSecret opens a value sealed by encryptSecret. Plaintext values, as
+// written before encryption was introduced, are returned unchanged.
+func decryptSecret(key []byte, field, value string) (string, error) {
+	if !IsEncrypted(value) {
+		return value, nil
+	}
+	if key == nil {
+		return "", ErrNoKey
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, encryptedPrefix))
+	if err != nil {
+		return "", fmt.Errorf("failed to decode %s: %w", field, err)
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return "", err
+	}
+	if len(sealed) < aead.NonceSize() {
+		return "", fmt.Errorf("failed to decrypt %s: ciphertext too short", field)
+	}
+	plaintext, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], []byte(field))
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt %s: wrong key or corrupted value", field)
+	}
+	return string(plaintext), nil
+}
+
+// newAEAD returns AES-256-GCM keyed with key
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid config key: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+// transformSecrets applies fn to every secret in config, in place. Secrets
+// are the OpenAI key and the values of the secrets section.
+func transformSecrets(config *AppConfig, fn func(field, value string) (string, error)) error {
+	if config.OpenAIKey != "" {
+		value, err := fn("openai_key", config.OpenAIKey)
+		if err != nil {
+			return err
+		}
+		config.OpenAIKey = value
+	}
+
+	if len(config.Secrets) > 0 {
+		secrets := make(map[string]string, len(config.Secrets))
+		for name, secret := range config.Secrets {
+			value, err := fn("secrets."+name, secret)
+			if err != nil {
+				return err
+			}
+			secrets[name] = value
+		}
+		config.Secrets = secrets
+	}
+
+	return nil
+}
+
+// hasEncryptedSecrets reports whether any secret in config is encrypted
+func hasEncryptedSecrets(config *AppConfig) bool {
+	found := false
+	transformSecrets(config, func(field, value string) (string, error) {
+		found = found || IsEncrypted(value)
+		return value, nil
+	})
+	return found
+}
+
+// loadKey returns the key from NOTE_CONFIG_KEY or the keyfile, or nil when
+// neither is set. fromEnv reports whether the key came from the environment.
+func (cm *ConfigManager) loadKey() (key []byte, fromEnv bool, err error) {
+	if encoded := os.Getenv(KeyEnv); encoded != "" {
+		key, err := ParseKey(encoded)
+		if err != nil {
+			return nil, true, fmt.Errorf("%s: %w", KeyEnv, err)
+		}
+		return key, true, nil
+	}
+
+	data, err := os.ReadFile(cm.keyPath)
+	if os.IsNotExist(err) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read key file: %w", err)
+	}
+	key, err = ParseKey(string(data))
+	if err != nil {
+		return nil, false, fmt.Errorf("%s: %w", cm.keyPath, err)
+	}
+	return key, false, nil
+}
+
+// encryptionKey returns the key to encrypt secrets with, creating the keyfile
+// on first use when no key is configured
+func (cm *ConfigManager) encryptionKey() ([]byte, error) {
+	key, _, err := cm.loadKey()
+	if err != nil || key != nil {
+		return key, err
+	}
+
+	key = NewKey()
+	if err := writeFileAtomic(cm.keyPath, []byte(EncodeKey(key)+"\n")); err != nil {
+		return nil, fmt.Errorf("failed to write key file: %w", err)
+	}
+	return key, nil
+}
+
+// RotateKey re-encrypts the config's secrets with a new random key. A keyfile
+// is replaced with the new key. When the key comes from NOTE_CONFIG_KEY the
+// environment can't be updated, so the new key is returned for the operator
+// to set before the next start; envKey reports which happened.
+func (cm *ConfigManager) RotateKey() (newKey []byte, envKey bool, err error) {
+	if err := cm.Load(); err != nil {
+		return nil, false, err
+	}
+	_, envKey, err = cm.loadKey()
+	if err != nil {
+		return nil, false, err
+	}
+
+	cm.mutex.Lock()
+	defer cm.mutex.Unlock()
+
+	// Write the new key beside the old one, so a failed rotation leaves the
+	// config readable with the old key
+	newKey = NewKey()
+	pendingKeyPath := cm.keyPath + ".new"
+	if !envKey {
+		if err := writeFileAtomic(pendingKeyPath, []byte(EncodeKey(newKey)+"\n")); err != nil {
+			return nil, false, fmt.Errorf("failed to write key file: %w", err)
+		}
+	}
+
+	if err := cm.save(newKey); err != nil {
+		os.Remove(pendingKeyPath)
+		return nil, false, err
+	}
+
+	if !envKey {
+		if err := os.Rename(pendingKeyPath, cm.keyPath); err != nil {
+			return nil, false, fmt.Errorf("failed to replace key file; the new key is in %s: %w", pendingKeyPath, err)
+		}
+	}
+	return newKey, envKey, nil
+}
+
+// writeFileAtomic replaces path with data, readable only by the owner
+func writeFileAtomic(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := tmp.Chmod(0600); err != nil {
+		tmp.Close()
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
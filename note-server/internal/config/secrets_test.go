@@ -0,0 +1,124 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestConfigManager_EncryptsSecrets(t *testing.T) {
+	t.Setenv(KeyEnv, "")
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "config.json")
+	keyPath := filepath.Join(dir, "config.key")
+
+	manager := NewConfigManagerWithPaths(configPath, keyPath)
+	err := manager.SetConfig(AppConfig{
+		OpenAIKey:             "sk-test-openai-key",
+		Secrets:               map[string]string{"google_api_key": "google-secret"},
+		TranscriptionProvider: "openai",
+		TranscriptionModel:    "whisper-1",
+	})
+	if err != nil {
+		t.Fatalf("SetConfig() error = %v", err)
+	}
+
+	data, _ := os.ReadFile(configPath)
+	for _, secret := range []string{"sk-test-openai-key", "google-secret"} {
+		if strings.Contains(string(data), secret) {
+			t.Errorf("Expected %q to be encrypted at rest, got %s", secret, data)
+		}
+	}
+	if !strings.Contains(string(data), "whisper-1") {
+		t.Errorf("Expected other settings in plaintext, got %s", data)
+	}
+	if info, err := os.Stat(keyPath); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("Expected a 0600 key file, got %v (%v)", info, err)
+	}
+
+	// A fresh manager decrypts transparently
+	loaded := NewConfigManagerWithPaths(configPath, keyPath)
+	if err := loaded.Load(); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if loaded.GetOpenAIKey() != "sk-test-openai-key" || loaded.GetSecret("google_api_key") != "google-secret" {
+		t.Errorf("Expected decrypted secrets, got %+v", loaded.GetConfig())
+	}
+
+	// Without the key the secrets can't be read
+	if err := NewConfigManagerWithPaths(configPath, filepath.Join(dir, "missing.key")).Load(); err != ErrNoKey {
+		t.Errorf("Expected ErrNoKey, got %v", err)
+	}
+	t.Setenv(KeyEnv, EncodeKey(NewKey()))
+	if err := NewConfigManagerWithPaths(configPath, keyPath).Load(); err == nil {
+		t.Error("Expected an error decrypting with the wrong key")
+	}
+}
+
+func TestConfigManager_ReadsPlaintextConfig(t *testing.T) {
+	t.Setenv(KeyEnv, "")
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "config.json")
+	keyPath := filepath.Join(dir, "config.key")
+	os.WriteFile(configPath, []byte(`{"openai_key": "sk-plaintext"}`), 0600)
+
+	manager := NewConfigManagerWithPaths(configPath, keyPath)
+	if err := manager.Load(); err != nil || manager.GetOpenAIKey() != "sk-plaintext" {
+		t.Fatalf("Expected the plaintext key, got %q (%v)", manager.GetOpenAIKey(), err)
+	}
+
+	// The next save encrypts it
+	if err := manager.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	if data, _ := os.ReadFile(configPath); strings.Contains(string(data), "sk-plaintext") {
+		t.Errorf("Expected the key encrypted after saving, got %s", data)
+	}
+}
+
+func TestConfigManager_RotateKey(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "config.json")
+	keyPath := filepath.Join(dir, "config.key")
+
+	tests := []struct {
+		name   string
+		envKey bool
+	}{
+		{"key file", false},
+		{"environment key", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(KeyEnv, "")
+			if tt.envKey {
+				t.Setenv(KeyEnv, EncodeKey(NewKey()))
+			}
+			manager := NewConfigManagerWithPaths(configPath, keyPath)
+			if err := manager.SetConfig(AppConfig{Secrets: map[string]string{"google_api_key": "google-secret"}}); err != nil {
+				t.Fatalf("SetConfig() error = %v", err)
+			}
+			oldKey, _ := os.ReadFile(keyPath)
+
+			newKey, envKey, err := manager.RotateKey()
+			if err != nil {
+				t.Fatalf("RotateKey() error = %v", err)
+			}
+			if envKey != tt.envKey {
+				t.Errorf("Expected envKey %v, got %v", tt.envKey, envKey)
+			}
+
+			if tt.envKey {
+				t.Setenv(KeyEnv, EncodeKey(newKey))
+			} else if data, _ := os.ReadFile(keyPath); string(data) == string(oldKey) {
+				t.Error("Expected the key file to be replaced")
+			}
+
+			loaded := NewConfigManagerWithPaths(configPath, keyPath)
+			if err := loaded.Load(); err != nil || loaded.GetSecret("google_api_key") != "google-secret" {
+				t.Errorf("Expected the secret readable with the new key, got %q (%v)", loaded.GetSecret("google_api_key"), err)
+			}
+		})
+	}
+}
@@ -0,0 +1,146 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// fileState identifies a version of the config file. Size and modification
+// time are checked first so an unchanged file isn't read on every poll; the
+// hash catches rewrites with identical contents.
+type fileState struct {
+	modTime time.Time
+	size    int64
+	hash    [sha256.Size]byte
+}
+
+// Subscribe registers fn to be called after every change to the configuration,
+// whether saved through the manager or reloaded from the file. fn runs on the
+// goroutine that made the change and must not block. The returned function
+// unsubscribes.
+func (cm *ConfigManager) Subscribe(fn func(previous, current AppConfig)) (unsubscribe func()) {
+	cm.subscribersMutex.Lock()
+	defer cm.subscribersMutex.Unlock()
+
+	if cm.subscribers == nil {
+		cm.subscribers = make(map[int]func(previous, current AppConfig))
+	}
+	id := cm.nextSubscriber
+	cm.nextSubscriber++
+	cm.subscribers[id] = fn
+
+	return func() {
+		cm.subscribersMutex.Lock()
+		defer cm.subscribersMutex.Unlock()
+		delete(cm.subscribers, id)
+	}
+}
+
+// notify tells subscribers about a change. It is called without cm.mutex held
+// so subscribers can read the configuration.
+func (cm *ConfigManager) notify(previous, current AppConfig) {
+	if len(Diff(previous, current)) == 0 {
+		return
+	}
+
+	cm.subscribersMutex.Lock()
+	subscribers := make([]func(previous, current AppConfig), 0, len(cm.subscribers))
+	for _, fn := range cm.subscribers {
+		subscribers = append(subscribers, fn)
+	}
+	cm.subscribersMutex.Unlock()
+
+	for _, fn := range subscribers {
+		fn(previous.clone(), current.clone())
+	}
+}
+
+// Reload re-reads the config file if it changed since it was last loaded or
+// saved, reporting whether the configuration was replaced. A file that can't
+// be parsed, decrypted or validated is rejected and the current configuration
+// kept; the same broken version isn't reported twice. A deleted file leaves
+// the configuration as it is.
+func (cm *ConfigManager) Reload() (bool, error) {
+	cm.mutex.Lock()
+
+	info, err := os.Stat(cm.configPath)
+	if os.IsNotExist(err) {
+		cm.mutex.Unlock()
+		return false, nil
+	}
+	if err != nil {
+		cm.mutex.Unlock()
+		return false, fmt.Errorf("failed to stat config file: %w", err)
+	}
+	if info.ModTime().Equal(cm.file.modTime) && info.Size() == cm.file.size {
+		cm.mutex.Unlock()
+		return false, nil
+	}
+
+	data, err := os.ReadFile(cm.configPath)
+	if err != nil {
+		cm.mutex.Unlock()
+		return false, fmt.Errorf("failed to read config file: %w", err)
+	}
+	state := fileState{modTime: info.ModTime(), size: info.Size(), hash: sha256.Sum256(data)}
+	if bytes.Equal(state.hash[:], cm.file.hash[:]) {
+		cm.file = state
+		cm.mutex.Unlock()
+		return false, nil
+	}
+	cm.file = state
+
+	config, err := cm.parse(data)
+	if err == nil {
+		err = config.Validate()
+	}
+	if err != nil {
+		cm.mutex.Unlock()
+		return false, fmt.Errorf("rejected config file change, keeping the current configuration: %w", err)
+	}
+
+	previous := cm.config.clone()
+	cm.config = config
+	cm.mutex.Unlock()
+
+	cm.notify(previous, config.clone())
+	return true, nil
+}
+
+// Watch polls the config file every interval and reloads it when it changes,
+// until ctx is done. Rejected changes are logged to ctx's logger.
+func (cm *ConfigManager) Watch(ctx context.Context, interval time.Duration) {
+	logger := zerolog.Ctx(ctx)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			changed, err := cm.Reload()
+			if err != nil {
+				logger.Error().Err(err).Str("path", cm.configPath).Msg("Config reload failed")
+			} else if changed {
+				logger.Info().Str("path", cm.configPath).Msg("Reloaded configuration")
+			}
+		}
+	}
+}
+
+// recordFile remembers the version of the config file just written or read,
+// so Reload doesn't treat it as an outside change. The caller holds the lock.
+func (cm *ConfigManager) recordFile(data []byte) {
+	info, err := os.Stat(cm.configPath)
+	if err != nil {
+		return
+	}
+	cm.file = fileState{modTime: info.ModTime(), size: info.Size(), hash: sha256.Sum256(data)}
+}
@@ -0,0 +1,118 @@
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeConfig replaces the config file as another program would, with a
+// modification time that differs from the last version
+func writeConfig(t *testing.T, path, data string, modTime time.Time) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestConfigManager_Reload(t *testing.T) {
+	t.Setenv(KeyEnv, "")
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "config.json")
+	manager := NewConfigManagerWithPaths(configPath, filepath.Join(dir, "config.key"))
+
+	if err := manager.SetConfig(AppConfig{SummaryProvider: "openai", SummaryModel: "gpt-4"}); err != nil {
+		t.Fatalf("SetConfig() error = %v", err)
+	}
+
+	var notified []AppConfig
+	unsubscribe := manager.Subscribe(func(previous, current AppConfig) {
+		notified = append(notified, current)
+	})
+	defer unsubscribe()
+
+	// The manager's own save isn't an outside change
+	if changed, err := manager.Reload(); err != nil || changed {
+		t.Errorf("Expected no change after saving, got %v (%v)", changed, err)
+	}
+
+	modTime := time.Now().Add(time.Minute)
+	writeConfig(t, configPath, `{"summary_provider": "openai", "summary_model": "gpt-4o"}`, modTime)
+	if changed, err := manager.Reload(); err != nil || !changed {
+		t.Fatalf("Expected the edit to be reloaded, got %v (%v)", changed, err)
+	}
+	if manager.GetConfig().SummaryModel != "gpt-4o" || len(notified) != 1 || notified[0].SummaryModel != "gpt-4o" {
+		t.Errorf("Expected subscribers told about gpt-4o, got %+v", notified)
+	}
+
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"malformed JSON", `{"summary_model": `},
+		{"unknown model", `{"summary_provider": "openai", "summary_model": "gpt-99"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			modTime = modTime.Add(time.Minute)
+			writeConfig(t, configPath, tt.data, modTime)
+			if changed, err := manager.Reload(); err == nil || changed {
+				t.Errorf("Expected the edit to be rejected, got %v (%v)", changed, err)
+			}
+			if manager.GetConfig().SummaryModel != "gpt-4o" {
+				t.Errorf("Expected the previous config retained, got %+v", manager.GetConfig())
+			}
+
+			// The same broken file is reported once
+			if _, err := manager.Reload(); err != nil {
+				t.Errorf("Expected an unchanged broken file to be ignored, got %v", err)
+			}
+		})
+	}
+
+	// Touching the file without changing it isn't a change
+	writeConfig(t, configPath, `{"summary_provider": "openai", "summary_model": "gpt-4o"}`, modTime.Add(time.Minute))
+	manager.Reload()
+	writeConfig(t, configPath, `{"summary_provider": "openai", "summary_model": "gpt-4o"}`, modTime.Add(2*time.Minute))
+	if changed, err := manager.Reload(); err != nil || changed {
+		t.Errorf("Expected identical contents to be ignored, got %v (%v)", changed, err)
+	}
+	// Restoring the settings that were in effect changes nothing
+	if len(notified) != 1 {
+		t.Errorf("Expected 1 notification, got %d", len(notified))
+	}
+}
+
+func TestConfigManager_Watch(t *testing.T) {
+	t.Setenv(KeyEnv, "")
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "config.json")
+	manager := NewConfigManagerWithPaths(configPath, filepath.Join(dir, "config.key"))
+	if err := manager.Load(); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	changes := make(chan AppConfig, 1)
+	manager.Subscribe(func(previous, current AppConfig) {
+		changes <- current
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go manager.Watch(ctx, 10*time.Millisecond)
+
+	writeConfig(t, configPath, `{"transcription_provider": "google"}`, time.Now())
+	select {
+	case current := <-changes:
+		if current.TranscriptionProvider != "google" {
+			t.Errorf("Expected google, got %+v", current)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for the watcher to reload")
+	}
+}
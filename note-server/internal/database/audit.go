@@ -0,0 +1,77 @@
+package database
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Actions recorded in the configuration audit log
+const (
+	AuditConfigUpdate = "update"
+	AuditConfigReveal = "reveal"
+)
+
+// ConfigAuditEntry records who changed or revealed the configuration, and
+// which fields changed. Secret values are never recorded.
+type ConfigAuditEntry struct {
+	ID        int64           `json:"id"`
+	Actor     string          `json:"actor"`
+	Action    string          `json:"action"`
+	Changes   json.RawMessage `json:"changes"`
+	CreatedAt time.Time       `json:"createdAt"`
+}
+
+// createAuditTable creates the config_audit table
+func createAuditTable() error {
+	createAuditTableSQL := `CREATE TABLE IF NOT EXISTS config_audit (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		actor TEXT NOT NULL,
+		action TEXT NOT NULL,
+		changes TEXT NOT NULL,
+		created_at TEXT NOT NULL
+	);`
+	if _, err := db.Exec(createAuditTableSQL); err != nil {
+		return fmt.Errorf("failed to create config_audit table: %v", err)
+	}
+	return nil
+}
+
+// AddConfigAudit records an action on the configuration. changes is stored as
+// JSON and must not contain secret values.
+func AddConfigAudit(actor, action string, changes any) error {
+	data, err := json.Marshal(changes)
+	if err != nil {
+		return fmt.Errorf("failed to marshal config changes: %v", err)
+	}
+
+	_, err = db.Exec("INSERT INTO config_audit (actor, action, changes, created_at) VALUES (?, ?, ?, ?)",
+		actor, action, string(data), time.Now().UTC().Format(time.RFC3339))
+	if err != nil {
+		return fmt.Errorf("failed to record config audit: %v", err)
+	}
+	return nil
+}
+
+// GetConfigAudit retrieves the most recent configuration audit entries, newest first
+func GetConfigAudit(limit int) ([]ConfigAuditEntry, error) {
+	rows, err := db.Query("SELECT id, actor, action, changes, created_at FROM config_audit ORDER BY id DESC LIMIT ?", limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query config audit: %v", err)
+	}
+	defer rows.Close()
+
+	entries := []ConfigAuditEntry{}
+	for rows.Next() {
+		var entry ConfigAuditEntry
+		var changes, createdAt string
+		if err := rows.Scan(&entry.ID, &entry.Actor, &entry.Action, &changes, &createdAt); err != nil {
+			return nil, fmt.Errorf("failed to scan config audit: %v", err)
+		}
+		entry.Changes = json.RawMessage(changes)
+		entry.CreatedAt, _ = time.Parse(time.RFC3339, createdAt)
+		entries = append(entries, entry)
+	}
+
+	return entries, rows.Err()
+}
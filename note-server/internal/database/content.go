@@ -0,0 +1,173 @@
+package database
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// The notes, meetings and interviews tables, matching the schema the web app
+// creates. Whichever side starts first creates them.
+const (
+	createNotesTableSQL = `CREATE TABLE IF NOT EXISTS notes (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		title TEXT NOT NULL,
+		content TEXT NOT NULL,
+		summary TEXT DEFAULT '',
+		tags TEXT DEFAULT '',
+		recording_id INTEGER,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (recording_id) REFERENCES recordings(id) ON DELETE SET NULL
+	);`
+
+	createMeetingsTableSQL = `CREATE TABLE IF NOT EXISTS meetings (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		title TEXT NOT NULL,
+		content TEXT NOT NULL,
+		summary TEXT DEFAULT '',
+		attendees TEXT DEFAULT '',
+		location TEXT DEFAULT '',
+		tags TEXT DEFAULT '',
+		recording_id INTEGER,
+		meeting_date TEXT,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (recording_id) REFERENCES recordings(id) ON DELETE SET NULL
+	);`
+
+	createInterviewsTableSQL = `CREATE TABLE IF NOT EXISTS interviews (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		title TEXT NOT NULL,
+		content TEXT NOT NULL,
+		summary TEXT DEFAULT '',
+		interviewee TEXT DEFAULT '',
+		interviewer TEXT DEFAULT '',
+		company TEXT DEFAULT '',
+		position TEXT DEFAULT '',
+		tags TEXT DEFAULT '',
+		recording_id INTEGER,
+		interview_date TEXT,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (recording_id) REFERENCES recordings(id) ON DELETE SET NULL
+	);`
+)
+
+// GetNotes retrieves the notes visible to owner, newest first
+func GetNotes(owner Owner) ([]map[string]any, error) {
+	return queryOwned(ResourceNote, owner, "")
+}
+
+// GetMeetings retrieves the meetings visible to owner, newest first
+func GetMeetings(owner Owner) ([]map[string]any, error) {
+	return queryOwned(ResourceMeeting, owner, "")
+}
+
+// GetMeeting retrieves a meeting by ID, or nil if it doesn't exist or isn't visible to owner
+func GetMeeting(id int, owner Owner) (map[string]any, error) {
+	meetings, err := queryOwned(ResourceMeeting, owner, "id = ?", id)
+	if err != nil || len(meetings) == 0 {
+		return nil, err
+	}
+	return meetings[0], nil
+}
+
+// GetInterviews retrieves the interviews visible to owner, newest first
+func GetInterviews(owner Owner) ([]map[string]any, error) {
+	return queryOwned(ResourceInterview, owner, "")
+}
+
+// UpdateDocument changes the text fields of a note, meeting or interview that
+// are set in fields, reporting whether the row exists. Access is checked by
+// the caller with ResourceRole.
+func UpdateDocument(kind string, id int64, fields DocumentFields) (bool, error) {
+	table, ok := resourceTables[kind]
+	if !ok || kind == ResourceRecording {
+		return false, fmt.Errorf("unknown document type %q", kind)
+	}
+
+	sets := []string{"updated_at = CURRENT_TIMESTAMP"}
+	var args []any
+	for _, field := range []struct {
+		column string
+		value  *string
+	}{
+		{"title", fields.Title},
+		{"content", fields.Content},
+		{"summary", fields.Summary},
+		{"tags", fields.Tags},
+	} {
+		if field.value != nil {
+			sets = append(sets, field.column+" = ?")
+			args = append(args, *field.value)
+		}
+	}
+
+	result, err := db.Exec(fmt.Sprintf("UPDATE %s SET %s WHERE id = ?", table, strings.Join(sets, ", ")), append(args, id)...)
+	if err != nil {
+		return false, fmt.Errorf("failed to update %s: %v", kind, err)
+	}
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to update %s: %v", kind, err)
+	}
+	return updated > 0, nil
+}
+
+// DocumentFields are the editable fields of notes, meetings and interviews.
+// Nil fields are left unchanged.
+type DocumentFields struct {
+	Title   *string `json:"title,omitempty"`
+	Content *string `json:"content,omitempty"`
+	Summary *string `json:"summary,omitempty"`
+	Tags    *string `json:"tags,omitempty"`
+}
+
+// queryOwned selects every column of the rows of a resource table visible to
+// owner, optionally narrowed by a further condition. Rows are returned keyed
+// by column name.
+func queryOwned(kind string, owner Owner, condition string, args ...any) ([]map[string]any, error) {
+	table := resourceTables[kind]
+	where, ownerArgs := owner.where(kind)
+	if condition != "" {
+		where = condition + " AND " + where
+	}
+	rows, err := db.Query(fmt.Sprintf("SELECT * FROM %s WHERE %s ORDER BY created_at DESC, id DESC", table, where), append(args, ownerArgs...)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query %s: %v", table, err)
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s columns: %v", table, err)
+	}
+
+	results := []map[string]any{}
+	for rows.Next() {
+		values := make([]any, len(columns))
+		pointers := make([]any, len(columns))
+		for i := range values {
+			pointers[i] = &values[i]
+		}
+		if err := rows.Scan(pointers...); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %v", err)
+		}
+
+		result := make(map[string]any, len(columns))
+		for i, column := range columns {
+			switch value := values[i].(type) {
+			case []byte:
+				result[column] = string(value)
+			case time.Time:
+				result[column] = value.Format(time.RFC3339)
+			default:
+				result[column] = value
+			}
+		}
+		results = append(results, result)
+	}
+
+	return results, rows.Err()
+}
@@ -1,21 +1,28 @@
 package database
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"strings"
 	"time"
 	_ "github.com/mattn/go-sqlite3"
 )
 
-var db *sql.DB
+var db instrumentedDB
 
-// InitDB initializes the database connection
+// InitDB initializes the database connection. Foreign keys are enforced on
+// every connection, so deleting a user or recording cascades as the schema says.
 func InitDB(dataSourceName string) error {
-	var err error
-	db, err = sql.Open("sqlite3", dataSourceName)
+	separator := "?"
+	if strings.Contains(dataSourceName, "?") {
+		separator = "&"
+	}
+	sqlDB, err := sql.Open("sqlite3", dataSourceName+separator+"_foreign_keys=on")
 	if err != nil {
 		return fmt.Errorf("failed to open database: %v", err)
 	}
+	db = instrumentedDB{sqlDB}
 
 	// Create table if it doesn't exist
 	createTableSQL := `CREATE TABLE IF NOT EXISTS recordings (
@@ -36,12 +43,103 @@ func InitDB(dataSourceName string) error {
 		return fmt.Errorf("failed to create table: %v", err)
 	}
 
+	createSegmentsTableSQL := `CREATE TABLE IF NOT EXISTS transcript_segments (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		recording_id INTEGER NOT NULL,
+		segment INTEGER NOT NULL,
+		start_ms INTEGER NOT NULL,
+		end_ms INTEGER NOT NULL,
+		text TEXT NOT NULL,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (recording_id) REFERENCES recordings(id) ON DELETE CASCADE
+	);`
+	_, err = db.Exec(createSegmentsTableSQL)
+	if err != nil {
+		return fmt.Errorf("failed to create transcript_segments table: %v", err)
+	}
+
+	// Tables the web app also creates, so owner columns can be added to them
+	for _, createSQL := range []string{createNotesTableSQL, createMeetingsTableSQL, createInterviewsTableSQL} {
+		if _, err := db.Exec(createSQL); err != nil {
+			return fmt.Errorf("failed to create table: %v", err)
+		}
+	}
+
+	if err := createUserTables(); err != nil {
+		return err
+	}
+
+	if err := createShareTables(); err != nil {
+		return err
+	}
+
+	if err := createAuditTable(); err != nil {
+		return err
+	}
+
+	if err := createHealthTable(); err != nil {
+		return err
+	}
+
+	if err := createUsageTable(); err != nil {
+		return err
+	}
+
+	// Databases created before user accounts have no owner columns yet
+	for _, table := range ownedTables {
+		if err := ensureColumn(table, "owner_id", "INTEGER REFERENCES users(id) ON DELETE SET NULL"); err != nil {
+			return err
+		}
+		if _, err := db.Exec(fmt.Sprintf("CREATE INDEX IF NOT EXISTS idx_%s_owner ON %s (owner_id)", table, table)); err != nil {
+			return fmt.Errorf("failed to index %s owners: %v", table, err)
+		}
+	}
+
+	return nil
+}
+
+// ensureColumn adds a column to a table that was created without it
+func ensureColumn(table, column, definition string) error {
+	rows, err := db.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return fmt.Errorf("failed to inspect %s: %v", table, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var cid, notNull, pk int
+		var name, columnType string
+		var defaultValue sql.NullString
+		if err := rows.Scan(&cid, &name, &columnType, &notNull, &defaultValue, &pk); err != nil {
+			return fmt.Errorf("failed to scan %s column: %v", table, err)
+		}
+		if name == column {
+			return nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to inspect %s: %v", table, err)
+	}
+	rows.Close()
+
+	if _, err := db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition)); err != nil {
+		return fmt.Errorf("failed to add %s.%s: %v", table, column, err)
+	}
 	return nil
 }
 
-// GetRecordings retrieves all recordings from the database
-func GetRecordings() ([]map[string]any, error) {
-	rows, err := db.Query("SELECT id, filename, file_path, start_time, end_time, duration, file_size, format, sample_rate, channels, created_at FROM recordings")
+// TranscriptSegment is one timed piece of a recording's transcript
+type TranscriptSegment struct {
+	Segment int    `json:"segment"`
+	StartMs int64  `json:"start_ms"`
+	EndMs   int64  `json:"end_ms"`
+	Text    string `json:"text"`
+}
+
+// GetRecordings retrieves the recordings visible to owner
+func GetRecordings(ctx context.Context, owner Owner) ([]map[string]any, error) {
+	where, args := owner.where(ResourceRecording)
+	rows, err := db.QueryContext(ctx, "SELECT id, filename, file_path, start_time, end_time, duration, file_size, format, sample_rate, channels, created_at FROM recordings WHERE "+where, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query recordings: %v", err)
 	}
@@ -75,15 +173,11 @@ func GetRecordings() ([]map[string]any, error) {
 	return recordings, nil
 }
 
-// AddRecording inserts a new recording into the database
-func AddRecording(filename, filePath string, startTime, endTime time.Time, duration, fileSize int, format string, sampleRate, channels int) (int64, error) {
-	stmt, err := db.Prepare(`INSERT INTO recordings (filename, file_path, start_time, end_time, duration, file_size, format, sample_rate, channels) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`)
-	if err != nil {
-		return 0, fmt.Errorf("failed to prepare statement: %v", err)
-	}
-	defer stmt.Close()
-
-	result, err := stmt.Exec(filename, filePath, startTime.Format(time.RFC3339), endTime.Format(time.RFC3339), duration, fileSize, format, sampleRate, channels)
+// AddRecording inserts a new recording owned by the user ownerID into the
+// database. An ownerID of 0 leaves the recording without an owner, visible
+// only to administrators once user accounts exist.
+func AddRecording(ctx context.Context, ownerID int64, filename, filePath string, startTime, endTime time.Time, duration, fileSize int, format string, sampleRate, channels int) (int64, error) {
+	result, err := db.ExecContext(ctx, `INSERT INTO recordings (owner_id, filename, file_path, start_time, end_time, duration, file_size, format, sample_rate, channels) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`, nullID(ownerID), filename, filePath, startTime.Format(time.RFC3339), endTime.Format(time.RFC3339), duration, fileSize, format, sampleRate, channels)
 	if err != nil {
 		return 0, fmt.Errorf("failed to execute insert: %v", err)
 	}
@@ -96,9 +190,35 @@ func AddRecording(filename, filePath string, startTime, endTime time.Time, durat
 	return id, nil
 }
 
-// GetRecording retrieves a specific recording by ID from the database
-func GetRecording(id int) (map[string]any, error) {
-	row := db.QueryRow("SELECT id, filename, file_path, start_time, end_time, duration, file_size, format, sample_rate, channels, created_at FROM recordings WHERE id = ?", id)
+// FinishRecording fills in the times, duration and size of a recording added
+// before its audio was complete, such as a live session being recorded
+func FinishRecording(ctx context.Context, id int64, startTime, endTime time.Time, duration, fileSize int) error {
+	_, err := db.ExecContext(ctx, `UPDATE recordings SET start_time = ?, end_time = ?, duration = ?, file_size = ? WHERE id = ?`, startTime.Format(time.RFC3339), endTime.Format(time.RFC3339), duration, fileSize, id)
+	if err != nil {
+		return fmt.Errorf("failed to execute update: %v", err)
+	}
+	return nil
+}
+
+// DeleteRecording removes a recording with its transcript and shares
+func DeleteRecording(ctx context.Context, id int64) error {
+	if _, err := db.ExecContext(ctx, `DELETE FROM transcript_segments WHERE recording_id = ?`, id); err != nil {
+		return fmt.Errorf("failed to delete transcript: %v", err)
+	}
+	if _, err := db.ExecContext(ctx, `DELETE FROM shares WHERE resource_type = ? AND resource_id = ?`, ResourceRecording, id); err != nil {
+		return fmt.Errorf("failed to delete shares: %v", err)
+	}
+	if _, err := db.ExecContext(ctx, `DELETE FROM recordings WHERE id = ?`, id); err != nil {
+		return fmt.Errorf("failed to delete recording: %v", err)
+	}
+	return nil
+}
+
+// GetRecording retrieves a specific recording by ID from the database. Recordings
+// not visible to owner are reported as not found.
+func GetRecording(ctx context.Context, id int, owner Owner) (map[string]any, error) {
+	where, args := owner.where(ResourceRecording)
+	row := db.QueryRowContext(ctx, "SELECT id, filename, file_path, start_time, end_time, duration, file_size, format, sample_rate, channels, created_at FROM recordings WHERE id = ? AND "+where, append([]any{id}, args...)...)
 
 	var recordingID int
 	var filename, file_path, start_time, end_time, format string
@@ -129,3 +249,50 @@ func GetRecording(id int) (map[string]any, error) {
 
 	return recording, nil
 }
+
+// AddTranscriptSegments attaches transcript segments to a recording
+func AddTranscriptSegments(ctx context.Context, recordingID int64, segments []TranscriptSegment) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, `INSERT INTO transcript_segments (recording_id, segment, start_ms, end_ms, text) VALUES (?, ?, ?, ?, ?)`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare statement: %v", err)
+	}
+	defer stmt.Close()
+
+	for _, segment := range segments {
+		if _, err := stmt.ExecContext(ctx, recordingID, segment.Segment, segment.StartMs, segment.EndMs, segment.Text); err != nil {
+			return fmt.Errorf("failed to insert segment: %v", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit segments: %v", err)
+	}
+
+	return nil
+}
+
+// GetTranscriptSegments retrieves the transcript segments of a recording in order
+func GetTranscriptSegments(ctx context.Context, recordingID int) ([]TranscriptSegment, error) {
+	rows, err := db.QueryContext(ctx, "SELECT segment, start_ms, end_ms, text FROM transcript_segments WHERE recording_id = ? ORDER BY segment, id", recordingID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query transcript segments: %v", err)
+	}
+	defer rows.Close()
+
+	segments := []TranscriptSegment{}
+	for rows.Next() {
+		var segment TranscriptSegment
+		if err := rows.Scan(&segment.Segment, &segment.StartMs, &segment.EndMs, &segment.Text); err != nil {
+			return nil, fmt.Errorf("failed to scan segment: %v", err)
+		}
+		segments = append(segments, segment)
+	}
+
+	return segments, rows.Err()
+}
@@ -0,0 +1,534 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/your-org/note-server/internal/auth"
+	"github.com/your-org/note-server/internal/tracing"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestTranscriptSegments(t *testing.T) {
+	if err := InitDB(filepath.Join(t.TempDir(), "test.db")); err != nil {
+		t.Fatalf("InitDB() error = %v", err)
+	}
+
+	start := time.Now()
+	recordingID, err := AddRecording(context.Background(), 0, "live.wav", "/tmp/live.wav", start, start.Add(2*time.Second), 2, 64044, "wav", 16000, 1)
+	if err != nil {
+		t.Fatalf("AddRecording() error = %v", err)
+	}
+
+	want := []TranscriptSegment{
+		{Segment: 0, StartMs: 0, EndMs: 1000, Text: "hello"},
+		{Segment: 1, StartMs: 1000, EndMs: 2000, Text: "world"},
+	}
+	if err := AddTranscriptSegments(context.Background(), recordingID, want); err != nil {
+		t.Fatalf("AddTranscriptSegments() error = %v", err)
+	}
+
+	got, err := GetTranscriptSegments(context.Background(), int(recordingID))
+	if err != nil {
+		t.Fatalf("GetTranscriptSegments() error = %v", err)
+	}
+
+	if len(got) != len(want) {
+		t.Fatalf("Expected %d segments, got %d", len(want), len(got))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Segment %d: expected %+v, got %+v", i, want[i], got[i])
+		}
+	}
+
+	empty, err := GetTranscriptSegments(context.Background(), int(recordingID)+1)
+	if err != nil {
+		t.Fatalf("GetTranscriptSegments() error = %v", err)
+	}
+	if len(empty) != 0 {
+		t.Errorf("Expected no segments for unknown recording, got %d", len(empty))
+	}
+}
+
+func TestUsersAndSessions(t *testing.T) {
+	if err := InitDB(filepath.Join(t.TempDir(), "test.db")); err != nil {
+		t.Fatalf("InitDB() error = %v", err)
+	}
+
+	store := NewSessionStore()
+	if store.HasUsers() {
+		t.Error("Expected no users in a new database")
+	}
+
+	// The first user is an administrator whatever was asked for
+	alice, err := CreateUser("alice", "hash-a", false, false)
+	if err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+	// After that only administrators add users, and choose whether they're admins
+	if _, err := CreateUser("mallory", "hash", true, false); err != ErrRegistrationClosed {
+		t.Errorf("Expected ErrRegistrationClosed for a caller who isn't an admin, got %v", err)
+	}
+	bob, err := CreateUser("bob", "hash-b", false, true)
+	if err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+	if !alice.Admin || bob.Admin {
+		t.Errorf("Expected only the first user to be an admin, got %+v and %+v", alice, bob)
+	}
+	if _, err := CreateUser("Alice", "hash", false, true); err != ErrUserExists {
+		t.Errorf("Expected ErrUserExists for a taken username, got %v", err)
+	}
+	if !store.HasUsers() {
+		t.Error("Expected users to exist")
+	}
+
+	user, err := GetUserByUsername("BOB")
+	if err != nil || user == nil || user.ID != bob.ID || user.PasswordHash != "hash-b" {
+		t.Errorf("Expected to find bob, got %+v (%v)", user, err)
+	}
+	if user, err := GetUserByUsername("carol"); err != nil || user != nil {
+		t.Errorf("Expected no user, got %+v (%v)", user, err)
+	}
+
+	token, hash := auth.NewSessionToken()
+	if err := CreateSession(hash, bob.ID, time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("CreateSession() error = %v", err)
+	}
+	_, expiredHash := auth.NewSessionToken()
+	if err := CreateSession(expiredHash, bob.ID, time.Now().Add(-time.Second)); err != nil {
+		t.Fatalf("CreateSession() error = %v", err)
+	}
+
+	principal, err := store.LookupSession(token)
+	if err != nil || principal.ID != "bob" || principal.UserID != bob.ID || principal.Method != auth.MethodSession {
+		t.Errorf("Expected bob's session, got %+v (%v)", principal, err)
+	}
+	if user, err := GetSessionUser(expiredHash); err != nil || user != nil {
+		t.Errorf("Expected expired session to be ignored, got %+v (%v)", user, err)
+	}
+
+	if err := DeleteSession(hash); err != nil {
+		t.Fatalf("DeleteSession() error = %v", err)
+	}
+	if _, err := store.LookupSession(token); err != auth.ErrInvalidCredentials {
+		t.Errorf("Expected ErrInvalidCredentials after logout, got %v", err)
+	}
+}
+
+func TestForeignKeys(t *testing.T) {
+	if err := InitDB(filepath.Join(t.TempDir(), "test.db")); err != nil {
+		t.Fatalf("InitDB() error = %v", err)
+	}
+
+	alice, _ := CreateUser("alice", "hash-a", false, true)
+	bob, _ := CreateUser("bob", "hash-b", false, true)
+	_, hash := auth.NewSessionToken()
+	if err := CreateSession(hash, bob.ID, time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("CreateSession() error = %v", err)
+	}
+	if _, err := CreateAPIToken(bob.ID, "cli", "token-hash", 0, time.Time{}); err != nil {
+		t.Fatalf("CreateAPIToken() error = %v", err)
+	}
+	recordingID, err := AddRecording(context.Background(), bob.ID, "a.wav", "/tmp/a.wav", time.Now(), time.Now(), 1, 1, "wav", 16000, 1)
+	if err != nil {
+		t.Fatalf("AddRecording() error = %v", err)
+	}
+	if _, err := GrantShare(ResourceRecording, recordingID, alice.ID, RoleViewer); err != nil {
+		t.Fatalf("GrantShare() error = %v", err)
+	}
+	if _, err := GrantShare(ResourceRecording, recordingID, bob.ID, RoleViewer); err != nil {
+		t.Fatalf("GrantShare() error = %v", err)
+	}
+
+	// Deleting a user takes their sessions, tokens and shares with them and
+	// leaves their recordings without an owner
+	if _, err := db.Exec("DELETE FROM users WHERE id = ?", bob.ID); err != nil {
+		t.Fatalf("Failed to delete user: %v", err)
+	}
+	for _, table := range []string{"sessions", "api_tokens", "shares"} {
+		var n int
+		if err := db.QueryRow("SELECT COUNT(*) FROM "+table+" WHERE user_id = ?", bob.ID).Scan(&n); err != nil {
+			t.Fatal(err)
+		}
+		if n != 0 {
+			t.Errorf("Expected no %s left for a deleted user, got %d", table, n)
+		}
+	}
+	var owner sql.NullInt64
+	if err := db.QueryRow("SELECT owner_id FROM recordings WHERE id = ?", recordingID).Scan(&owner); err != nil {
+		t.Fatal(err)
+	}
+	if owner.Valid {
+		t.Errorf("Expected the recording's owner to be cleared, got %d", owner.Int64)
+	}
+}
+
+func TestRecordingOwners(t *testing.T) {
+	if err := InitDB(filepath.Join(t.TempDir(), "test.db")); err != nil {
+		t.Fatalf("InitDB() error = %v", err)
+	}
+
+	// Owners 1 and 2
+	for _, name := range []string{"alice", "bob"} {
+		if _, err := CreateUser(name, "hash", false, true); err != nil {
+			t.Fatalf("CreateUser() error = %v", err)
+		}
+	}
+
+	start := time.Now()
+	ids := map[int64]int64{}
+	for _, ownerID := range []int64{1, 2, 0} {
+		id, err := AddRecording(context.Background(), ownerID, "owned.wav", "/tmp/owned.wav", start, start, 0, 0, "wav", 16000, 1)
+		if err != nil {
+			t.Fatalf("AddRecording() error = %v", err)
+		}
+		ids[ownerID] = id
+	}
+
+	tests := []struct {
+		name     string
+		owner    Owner
+		expected int
+	}{
+		{"owner", OwnedBy(1), 1},
+		{"other owner", OwnedBy(2), 1},
+		{"any owner", AnyOwner, 3},
+		{"zero value", Owner{}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recordings, err := GetRecordings(context.Background(), tt.owner)
+			if err != nil {
+				t.Fatalf("GetRecordings() error = %v", err)
+			}
+			if len(recordings) != tt.expected {
+				t.Errorf("Expected %d recordings, got %d", tt.expected, len(recordings))
+			}
+		})
+	}
+
+	if recording, err := GetRecording(context.Background(), int(ids[1]), OwnedBy(2)); err != nil || recording != nil {
+		t.Errorf("Expected another user's recording to be hidden, got %+v (%v)", recording, err)
+	}
+	if recording, err := GetRecording(context.Background(), int(ids[1]), OwnedBy(1)); err != nil || recording == nil {
+		t.Errorf("Expected the owner to see their recording, got %v", err)
+	}
+
+	// Tables shared with the web app get owner columns too
+	if _, err := db.Exec("INSERT INTO meetings (title, content, owner_id) VALUES ('standup', '', 1)"); err != nil {
+		t.Fatalf("failed to insert meeting: %v", err)
+	}
+	if meetings, err := GetMeetings(OwnedBy(2)); err != nil || len(meetings) != 0 {
+		t.Errorf("Expected no meetings for another user, got %v (%v)", meetings, err)
+	}
+	meeting, err := GetMeeting(1, OwnedBy(1))
+	if err != nil || meeting == nil || meeting["title"] != "standup" {
+		t.Errorf("Expected the owner's meeting, got %v (%v)", meeting, err)
+	}
+}
+
+func TestInitDB_AddsOwnerColumns(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+
+	// A database created by the web app before user accounts existed
+	legacy, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := legacy.Exec(`CREATE TABLE notes (id INTEGER PRIMARY KEY AUTOINCREMENT, title TEXT NOT NULL, content TEXT NOT NULL, created_at DATETIME DEFAULT CURRENT_TIMESTAMP);
+		INSERT INTO notes (title, content) VALUES ('old', 'note');`); err != nil {
+		t.Fatal(err)
+	}
+	legacy.Close()
+
+	if err := InitDB(path); err != nil {
+		t.Fatalf("InitDB() error = %v", err)
+	}
+	if err := InitDB(path); err != nil {
+		t.Fatalf("InitDB() on a migrated database error = %v", err)
+	}
+
+	if notes, err := GetNotes(AnyOwner); err != nil || len(notes) != 1 || notes[0]["owner_id"] != nil {
+		t.Errorf("Expected the old note without an owner, got %v (%v)", notes, err)
+	}
+	if notes, err := GetNotes(OwnedBy(1)); err != nil || len(notes) != 0 {
+		t.Errorf("Expected unowned notes to be hidden from users, got %v (%v)", notes, err)
+	}
+}
+
+func TestAPITokens(t *testing.T) {
+	if err := InitDB(filepath.Join(t.TempDir(), "test.db")); err != nil {
+		t.Fatalf("InitDB() error = %v", err)
+	}
+
+	alice, _ := CreateUser("alice", "hash", false, true)
+	bob, _ := CreateUser("bob", "hash", false, true)
+	store := NewTokenStore()
+
+	token, hash := auth.NewAPIToken()
+	created, err := CreateAPIToken(bob.ID, "kiosk", hash, auth.ScopeWrite|auth.ScopeAdmin, time.Time{})
+	if err != nil {
+		t.Fatalf("CreateAPIToken() error = %v", err)
+	}
+
+	principal, err := store.LookupAPIToken(token)
+	if err != nil {
+		t.Fatalf("LookupAPIToken() error = %v", err)
+	}
+	expected := auth.Principal{ID: "bob", Method: auth.MethodAPIToken, UserID: bob.ID, Scopes: auth.ScopeWrite | auth.ScopeAdmin}
+	if principal != expected {
+		t.Errorf("Expected %+v, got %+v", expected, principal)
+	}
+
+	// The admin scope only makes administrators of administrators
+	adminToken, adminHash := auth.NewAPIToken()
+	CreateAPIToken(alice.ID, "ci", adminHash, auth.ScopeAdmin, time.Time{})
+	if principal, err := store.LookupAPIToken(adminToken); err != nil || !principal.Admin {
+		t.Errorf("Expected an admin principal for alice's admin token, got %+v (%v)", principal, err)
+	}
+
+	expiredToken, expiredHash := auth.NewAPIToken()
+	CreateAPIToken(bob.ID, "old", expiredHash, auth.ScopeRead, time.Now().Add(-time.Minute))
+	if _, err := store.LookupAPIToken(expiredToken); err != auth.ErrInvalidCredentials {
+		t.Errorf("Expected ErrInvalidCredentials for an expired token, got %v", err)
+	}
+
+	tokens, err := GetAPITokens(bob.ID)
+	if err != nil || len(tokens) != 2 {
+		t.Fatalf("Expected 2 tokens for bob, got %d (%v)", len(tokens), err)
+	}
+	if kiosk := tokens[1]; kiosk.ID != created.ID || kiosk.Scopes != created.Scopes || kiosk.LastUsedAt == nil || kiosk.ExpiresAt != nil {
+		t.Errorf("Expected the kiosk token to record its use, got %+v", kiosk)
+	}
+
+	if deleted, err := DeleteAPIToken(created.ID, alice.ID); err != nil || deleted {
+		t.Errorf("Expected alice not to revoke bob's token, got %v (%v)", deleted, err)
+	}
+	if deleted, err := DeleteAPIToken(created.ID, bob.ID); err != nil || !deleted {
+		t.Errorf("Expected bob to revoke his token, got %v (%v)", deleted, err)
+	}
+	if _, err := store.LookupAPIToken(token); err != auth.ErrInvalidCredentials {
+		t.Errorf("Expected ErrInvalidCredentials for a revoked token, got %v", err)
+	}
+}
+
+func TestShares(t *testing.T) {
+	if err := InitDB(filepath.Join(t.TempDir(), "test.db")); err != nil {
+		t.Fatalf("InitDB() error = %v", err)
+	}
+
+	alice, _ := CreateUser("alice", "hash", false, true)
+	bob, _ := CreateUser("bob", "hash", false, true)
+
+	start := time.Now()
+	recordingID, err := AddRecording(context.Background(), alice.ID, "standup.wav", "/tmp/standup.wav", start, start, 0, 0, "wav", 16000, 1)
+	if err != nil {
+		t.Fatalf("AddRecording() error = %v", err)
+	}
+	if _, err := db.Exec("INSERT INTO meetings (title, content, recording_id, owner_id) VALUES ('standup', '', ?, ?)", recordingID, alice.ID); err != nil {
+		t.Fatalf("failed to insert meeting: %v", err)
+	}
+
+	if role, err := ResourceRole(ResourceMeeting, 1, OwnedBy(bob.ID)); err != nil || role != RoleNone {
+		t.Errorf("Expected no access before sharing, got %q (%v)", role, err)
+	}
+
+	share, err := GrantShare(ResourceMeeting, 1, bob.ID, RoleViewer)
+	if err != nil || share == nil || share.Username != "bob" || share.Role != RoleViewer {
+		t.Fatalf("GrantShare() = %+v, %v", share, err)
+	}
+
+	// The meeting's recording comes with it, read-only
+	if meetings, err := GetMeetings(OwnedBy(bob.ID)); err != nil || len(meetings) != 1 {
+		t.Errorf("Expected the shared meeting, got %v (%v)", meetings, err)
+	}
+	if recording, err := GetRecording(context.Background(), int(recordingID), OwnedBy(bob.ID)); err != nil || recording == nil {
+		t.Errorf("Expected the shared meeting's recording, got %v", err)
+	}
+	if role, _ := ResourceRole(ResourceRecording, recordingID, OwnedBy(bob.ID)); role != RoleViewer {
+		t.Errorf("Expected viewer access to the recording, got %q", role)
+	}
+
+	// Granting again replaces the role
+	if share, err := GrantShare(ResourceMeeting, 1, bob.ID, RoleEditor); err != nil || share.Role != RoleEditor {
+		t.Errorf("Expected bob to become an editor, got %+v (%v)", share, err)
+	}
+	if shares, _ := GetShares(ResourceMeeting, 1); len(shares) != 1 {
+		t.Errorf("Expected one share, got %+v", shares)
+	}
+	if role, _ := ResourceRole(ResourceMeeting, 1, OwnedBy(alice.ID)); role != RoleOwner {
+		t.Errorf("Expected alice to own the meeting, got %q", role)
+	}
+
+	if err := DeleteShare(share.ID); err != nil {
+		t.Fatalf("DeleteShare() error = %v", err)
+	}
+	if recording, err := GetRecording(context.Background(), int(recordingID), OwnedBy(bob.ID)); err != nil || recording != nil {
+		t.Errorf("Expected the recording hidden after revoking, got %v (%v)", recording, err)
+	}
+}
+
+func TestShareLinks(t *testing.T) {
+	if err := InitDB(filepath.Join(t.TempDir(), "test.db")); err != nil {
+		t.Fatalf("InitDB() error = %v", err)
+	}
+
+	start := time.Now()
+	recordingID, _ := AddRecording(context.Background(), 0, "talk.wav", "/tmp/talk.wav", start, start, 0, 0, "wav", 16000, 1)
+
+	link, err := CreateShareLink(recordingID, 0, "live-hash", time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("CreateShareLink() error = %v", err)
+	}
+	CreateShareLink(recordingID, 0, "expired-hash", time.Now().Add(-time.Minute))
+
+	if id, err := GetShareLinkRecording("live-hash"); err != nil || id != recordingID {
+		t.Errorf("Expected the link to resolve to recording %d, got %d (%v)", recordingID, id, err)
+	}
+	if id, err := GetShareLinkRecording("expired-hash"); err != nil || id != 0 {
+		t.Errorf("Expected an expired link not to resolve, got %d (%v)", id, err)
+	}
+	if links, err := GetShareLinks(recordingID); err != nil || len(links) != 1 || links[0].ID != link.ID {
+		t.Errorf("Expected only the live link, got %+v (%v)", links, err)
+	}
+
+	if deleted, err := DeleteShareLink(link.ID, recordingID+1); err != nil || deleted {
+		t.Errorf("Expected a link not to be revoked through another recording, got %v (%v)", deleted, err)
+	}
+	if deleted, err := DeleteShareLink(link.ID, recordingID); err != nil || !deleted {
+		t.Errorf("Expected the link to be revoked, got %v (%v)", deleted, err)
+	}
+	if id, _ := GetShareLinkRecording("live-hash"); id != 0 {
+		t.Errorf("Expected a revoked link not to resolve, got %d", id)
+	}
+}
+
+func TestConfigAudit(t *testing.T) {
+	if err := InitDB(filepath.Join(t.TempDir(), "test.db")); err != nil {
+		t.Fatalf("InitDB() error = %v", err)
+	}
+
+	if err := AddConfigAudit("alice", AuditConfigUpdate, []map[string]string{{"field": "summary_model"}}); err != nil {
+		t.Fatalf("AddConfigAudit() error = %v", err)
+	}
+	AddConfigAudit("bob", AuditConfigReveal, []map[string]string{})
+
+	entries, err := GetConfigAudit(10)
+	if err != nil || len(entries) != 2 {
+		t.Fatalf("Expected 2 entries, got %d (%v)", len(entries), err)
+	}
+	if entries[0].Actor != "bob" || entries[1].Action != AuditConfigUpdate || string(entries[1].Changes) != `[{"field":"summary_model"}]` {
+		t.Errorf("Expected newest first with their changes, got %+v", entries)
+	}
+	if entries, _ := GetConfigAudit(1); len(entries) != 1 {
+		t.Errorf("Expected the limit to apply, got %d entries", len(entries))
+	}
+}
+
+func TestQueryTracing(t *testing.T) {
+	if err := InitDB(filepath.Join(t.TempDir(), "test.db")); err != nil {
+		t.Fatalf("InitDB() error = %v", err)
+	}
+	exporter := tracetest.NewInMemoryExporter()
+	shutdown := tracing.Setup(tracing.Options{SampleRatio: 1, Exporter: exporter, Sync: true})
+	defer func() {
+		shutdown(context.Background())
+		tracing.Setup(tracing.Options{})
+	}()
+
+	// Statements outside a trace aren't traced
+	GetRecordings(context.Background(), AnyOwner)
+	if spans := exporter.GetSpans(); len(spans) != 0 {
+		t.Fatalf("Expected no spans outside a trace, got %d", len(spans))
+	}
+
+	ctx, parent := tracing.Start(context.Background(), "request")
+	if recording, err := GetRecording(ctx, 404, AnyOwner); err != nil || recording != nil {
+		t.Fatalf("Expected no recording, got %v (%v)", recording, err)
+	}
+	parent.End()
+
+	spans := exporter.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("Expected the query and its parent, got %d spans", len(spans))
+	}
+	query := spans[0]
+	if query.Name != "db GetRecording" || query.Parent.SpanID() != parent.SpanContext().SpanID() {
+		t.Errorf("Expected db GetRecording under the request, got %q", query.Name)
+	}
+	attrs := map[string]string{}
+	for _, attr := range query.Attributes {
+		attrs[string(attr.Key)] = attr.Value.AsString()
+	}
+	if attrs["db.system"] != "sqlite" || attrs["db.query.text"] == "" {
+		t.Errorf("Expected the database and statement, got %v", attrs)
+	}
+}
+
+func TestHealthChecks(t *testing.T) {
+	if err := InitDB(filepath.Join(t.TempDir(), "test.db")); err != nil {
+		t.Fatalf("InitDB() error = %v", err)
+	}
+	ctx := context.Background()
+
+	if err := Ping(ctx); err != nil {
+		t.Errorf("Ping() error = %v", err)
+	}
+	// The second write updates the row the first created
+	for i := 0; i < 2; i++ {
+		if err := CheckWrite(ctx); err != nil {
+			t.Fatalf("CheckWrite() error = %v", err)
+		}
+	}
+	var rows int
+	if err := db.QueryRow("SELECT COUNT(*) FROM health_checks").Scan(&rows); err != nil || rows != 1 {
+		t.Errorf("Expected one health_checks row, got %d (%v)", rows, err)
+	}
+
+	db.Close()
+	if err := CheckWrite(ctx); err == nil {
+		t.Error("Expected CheckWrite() to fail on a closed database")
+	}
+}
+
+func TestUsage(t *testing.T) {
+	if err := InitDB(filepath.Join(t.TempDir(), "test.db")); err != nil {
+		t.Fatalf("InitDB() error = %v", err)
+	}
+	ctx := context.Background()
+	store := NewUsageStore()
+	day := time.Date(2026, time.October, 18, 9, 30, 0, 0, time.UTC)
+
+	for _, usage := range []struct {
+		client, metric string
+		day            time.Time
+		amount         float64
+	}{
+		{"user:alice", "transcription_minutes", day, 1.5},
+		{"user:alice", "transcription_minutes", day.Add(time.Hour), 2},
+		{"user:alice", "transcription_minutes", day.AddDate(0, 0, -1), 4},
+		{"user:alice", "transcription_minutes", day.AddDate(0, -1, 0), 8},
+		{"user:alice", "summary_tokens", day, 100},
+		{"user:bob", "transcription_minutes", day, 16},
+	} {
+		if err := store.AddUsage(ctx, usage.client, usage.metric, usage.day, usage.amount); err != nil {
+			t.Fatalf("AddUsage() error = %v", err)
+		}
+	}
+
+	daily, monthly, err := store.Usage(ctx, "user:alice", "transcription_minutes", day)
+	if err != nil {
+		t.Fatalf("Usage() error = %v", err)
+	}
+	if daily != 3.5 || monthly != 7.5 {
+		t.Errorf("Expected 3.5 today and 7.5 this month, got %v and %v", daily, monthly)
+	}
+	if daily, monthly, _ := store.Usage(ctx, "user:carol", "transcription_minutes", day); daily != 0 || monthly != 0 {
+		t.Errorf("Expected no usage for a new client, got %v and %v", daily, monthly)
+	}
+}
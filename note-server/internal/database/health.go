@@ -0,0 +1,49 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// errNotInitialized is returned by health checks before InitDB
+var errNotInitialized = errors.New("database not initialized")
+
+// createHealthTable creates the health_checks table, which holds the single
+// row CheckWrite updates
+func createHealthTable() error {
+	createHealthTableSQL := `CREATE TABLE IF NOT EXISTS health_checks (
+		id INTEGER PRIMARY KEY CHECK (id = 1),
+		checked_at TEXT NOT NULL
+	);`
+	if _, err := db.Exec(createHealthTableSQL); err != nil {
+		return fmt.Errorf("failed to create health_checks table: %v", err)
+	}
+	return nil
+}
+
+// Ping checks that the database can be reached
+func Ping(ctx context.Context) error {
+	if db.DB == nil {
+		return errNotInitialized
+	}
+	if err := db.PingContext(ctx); err != nil {
+		return fmt.Errorf("failed to ping database: %v", err)
+	}
+	return nil
+}
+
+// CheckWrite checks that the database accepts writes by updating the
+// health_checks row, catching read-only files and full disks that Ping misses
+func CheckWrite(ctx context.Context) error {
+	if db.DB == nil {
+		return errNotInitialized
+	}
+	_, err := db.ExecContext(ctx, `INSERT INTO health_checks (id, checked_at) VALUES (1, ?)
+		ON CONFLICT (id) DO UPDATE SET checked_at = excluded.checked_at`, time.Now().UTC().Format(time.RFC3339))
+	if err != nil {
+		return fmt.Errorf("failed to write to database: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,155 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/your-org/note-server/internal/metrics"
+	"github.com/your-org/note-server/internal/tracing"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// queryDuration records how long statements take, by the database function
+// that ran them
+var queryDuration = metrics.NewHistogramVec(
+	"note_db_query_duration_seconds",
+	"Time taken by database statements, by the function that ran them.",
+	metrics.DefBuckets,
+	"function",
+)
+
+// instrumentedDB times the statements run through it, and traces those run
+// with a context that is part of a trace. Query is timed until the rows are
+// returned, not until they are read; statements run through a prepared
+// statement or transaction count only the Prepare or Begin.
+type instrumentedDB struct {
+	*sql.DB
+}
+
+// Exec runs a statement, timing it
+func (d instrumentedDB) Exec(query string, args ...any) (sql.Result, error) {
+	done := startQuery(context.Background(), query)
+	result, err := d.DB.Exec(query, args...)
+	done(err)
+	return result, err
+}
+
+// ExecContext runs a statement, timing and tracing it
+func (d instrumentedDB) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	done := startQuery(ctx, query)
+	result, err := d.DB.ExecContext(ctx, query, args...)
+	done(err)
+	return result, err
+}
+
+// Query runs a query, timing it
+func (d instrumentedDB) Query(query string, args ...any) (*sql.Rows, error) {
+	done := startQuery(context.Background(), query)
+	rows, err := d.DB.Query(query, args...)
+	done(err)
+	return rows, err
+}
+
+// QueryContext runs a query, timing and tracing it
+func (d instrumentedDB) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	done := startQuery(ctx, query)
+	rows, err := d.DB.QueryContext(ctx, query, args...)
+	done(err)
+	return rows, err
+}
+
+// QueryRow runs a query expected to return one row, timing it
+func (d instrumentedDB) QueryRow(query string, args ...any) *sql.Row {
+	done := startQuery(context.Background(), query)
+	row := d.DB.QueryRow(query, args...)
+	done(row.Err())
+	return row
+}
+
+// QueryRowContext runs a query expected to return one row, timing and
+// tracing it
+func (d instrumentedDB) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	done := startQuery(ctx, query)
+	row := d.DB.QueryRowContext(ctx, query, args...)
+	done(row.Err())
+	return row
+}
+
+// Prepare prepares a statement, timing the preparation
+func (d instrumentedDB) Prepare(query string) (*sql.Stmt, error) {
+	done := startQuery(context.Background(), query)
+	stmt, err := d.DB.Prepare(query)
+	done(err)
+	return stmt, err
+}
+
+// Begin starts a transaction, timing the start
+func (d instrumentedDB) Begin() (*sql.Tx, error) {
+	done := startQuery(context.Background(), "BEGIN")
+	tx, err := d.DB.Begin()
+	done(err)
+	return tx, err
+}
+
+// BeginTx starts a transaction, timing and tracing the start
+func (d instrumentedDB) BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error) {
+	done := startQuery(ctx, "BEGIN")
+	tx, err := d.DB.BeginTx(ctx, opts)
+	done(err)
+	return tx, err
+}
+
+// startQuery starts timing a statement for the function that called the
+// instrumentedDB method, with a span if ctx is part of a trace. The returned
+// function records the statement's duration and ends the span.
+func startQuery(ctx context.Context, query string) func(error) {
+	start := time.Now()
+	function := callerName(3)
+
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		return func(error) {
+			queryDuration.WithLabelValues(function).ObserveSince(start)
+		}
+	}
+
+	_, span := tracing.Tracer().Start(ctx, "db "+function,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemSqlite,
+			semconv.DBQueryText(query),
+		),
+	)
+	return func(err error) {
+		queryDuration.WithLabelValues(function).ObserveSince(start)
+		if errors.Is(err, sql.ErrNoRows) {
+			err = nil
+		}
+		tracing.End(span, err)
+	}
+}
+
+// callerName returns the name of the function skip frames up the stack
+// without its package path, e.g. "GetNotes" or "(*SessionStore).Lookup".
+// Closures are attributed to the function they are declared in.
+func callerName(skip int) string {
+	pc, _, _, ok := runtime.Caller(skip)
+	if !ok {
+		return "unknown"
+	}
+	fn := runtime.FuncForPC(pc)
+	if fn == nil {
+		return "unknown"
+	}
+	name := fn.Name()
+	name = name[strings.LastIndex(name, "/")+1:]
+	name = strings.TrimPrefix(name, "database.")
+	if i := strings.Index(name, ".func"); i > 0 {
+		name = name[:i]
+	}
+	return name
+}
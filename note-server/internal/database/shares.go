@@ -0,0 +1,334 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Kinds of resource that belong to a user and can be shared
+const (
+	ResourceRecording = "recording"
+	ResourceNote      = "note"
+	ResourceMeeting   = "meeting"
+	ResourceInterview = "interview"
+)
+
+// resourceTables maps each kind of resource to its table
+var resourceTables = map[string]string{
+	ResourceRecording: "recordings",
+	ResourceNote:      "notes",
+	ResourceMeeting:   "meetings",
+	ResourceInterview: "interviews",
+}
+
+// documentKinds are the resources that can link to a recording. Sharing one
+// lets the grantee see its recording too.
+var documentKinds = []string{ResourceNote, ResourceMeeting, ResourceInterview}
+
+// ValidResource reports whether kind is a kind of resource that can be shared
+func ValidResource(kind string) bool {
+	_, ok := resourceTables[kind]
+	return ok
+}
+
+// Role is a user's access to a resource
+type Role string
+
+// Roles, from least to most access
+const (
+	RoleNone   Role = ""
+	RoleViewer Role = "viewer"
+	RoleEditor Role = "editor"
+	RoleOwner  Role = "owner"
+)
+
+// CanView reports whether the role may read the resource
+func (r Role) CanView() bool {
+	return r != RoleNone
+}
+
+// CanEdit reports whether the role may change the resource
+func (r Role) CanEdit() bool {
+	return r == RoleEditor || r == RoleOwner
+}
+
+// Grantable reports whether the role can be given to another user
+func (r Role) Grantable() bool {
+	return r == RoleViewer || r == RoleEditor
+}
+
+// Owner limits queries to the rows one user may see: their own and those shared
+// with them. The zero value sees nothing.
+type Owner struct {
+	userID int64
+	any    bool
+}
+
+// AnyOwner sees every row, for administrators and single-user setups
+var AnyOwner = Owner{any: true}
+
+// OwnedBy sees the rows owned by or shared with the user userID
+func OwnedBy(userID int64) Owner {
+	return Owner{userID: userID}
+}
+
+// where returns the SQL condition selecting the rows of a kind of resource the
+// owner may see, and its arguments
+func (o Owner) where(kind string) (string, []any) {
+	if o.any {
+		return "1 = 1", nil
+	}
+
+	conditions := []string{
+		"owner_id = ?",
+		"id IN (SELECT resource_id FROM shares WHERE resource_type = ? AND user_id = ?)",
+	}
+	args := []any{o.userID, kind, o.userID}
+
+	// Recordings of shared documents come with them
+	if kind == ResourceRecording {
+		for _, document := range documentKinds {
+			conditions = append(conditions, fmt.Sprintf(
+				"id IN (SELECT recording_id FROM %s WHERE id IN (SELECT resource_id FROM shares WHERE resource_type = ? AND user_id = ?))",
+				resourceTables[document]))
+			args = append(args, document, o.userID)
+		}
+	}
+
+	return "(" + strings.Join(conditions, " OR ") + ")", args
+}
+
+// Share grants a user access to another user's resource
+type Share struct {
+	ID           int64  `json:"id"`
+	ResourceType string `json:"resourceType"`
+	ResourceID   int64  `json:"resourceId"`
+	UserID       int64  `json:"userId"`
+	Username     string `json:"username"`
+	Role         Role   `json:"role"`
+	CreatedAt    string `json:"createdAt"`
+}
+
+// ShareLink gives anyone holding its token read-only access to a recording's
+// transcript and audio until it expires
+type ShareLink struct {
+	ID          int64     `json:"id"`
+	RecordingID int64     `json:"recordingId"`
+	ExpiresAt   time.Time `json:"expiresAt"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+// createShareTables creates the shares and share_links tables
+func createShareTables() error {
+	createSharesTableSQL := `CREATE TABLE IF NOT EXISTS shares (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		resource_type TEXT NOT NULL,
+		resource_id INTEGER NOT NULL,
+		user_id INTEGER NOT NULL,
+		role TEXT NOT NULL,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE (resource_type, resource_id, user_id),
+		FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+	);`
+	if _, err := db.Exec(createSharesTableSQL); err != nil {
+		return fmt.Errorf("failed to create shares table: %v", err)
+	}
+	if _, err := db.Exec("CREATE INDEX IF NOT EXISTS idx_shares_user ON shares (user_id, resource_type)"); err != nil {
+		return fmt.Errorf("failed to index shares: %v", err)
+	}
+
+	createShareLinksTableSQL := `CREATE TABLE IF NOT EXISTS share_links (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		token_hash TEXT NOT NULL UNIQUE,
+		recording_id INTEGER NOT NULL,
+		created_by INTEGER,
+		expires_at TEXT NOT NULL,
+		created_at TEXT NOT NULL,
+		FOREIGN KEY (recording_id) REFERENCES recordings(id) ON DELETE CASCADE
+	);`
+	if _, err := db.Exec(createShareLinksTableSQL); err != nil {
+		return fmt.Errorf("failed to create share_links table: %v", err)
+	}
+
+	return nil
+}
+
+// ResourceRole returns the access owner has to a resource, or RoleNone when the
+// resource doesn't exist or isn't visible to them
+func ResourceRole(kind string, id int64, owner Owner) (Role, error) {
+	table, ok := resourceTables[kind]
+	if !ok {
+		return RoleNone, fmt.Errorf("unknown resource type %q", kind)
+	}
+
+	var ownerID sql.NullInt64
+	err := db.QueryRow(fmt.Sprintf("SELECT owner_id FROM %s WHERE id = ?", table), id).Scan(&ownerID)
+	if err == sql.ErrNoRows {
+		return RoleNone, nil
+	}
+	if err != nil {
+		return RoleNone, fmt.Errorf("failed to get %s owner: %v", kind, err)
+	}
+
+	if owner.any || (ownerID.Valid && ownerID.Int64 == owner.userID) {
+		return RoleOwner, nil
+	}
+
+	var role string
+	err = db.QueryRow("SELECT role FROM shares WHERE resource_type = ? AND resource_id = ? AND user_id = ?", kind, id, owner.userID).Scan(&role)
+	if err == nil {
+		return Role(role), nil
+	}
+	if err != sql.ErrNoRows {
+		return RoleNone, fmt.Errorf("failed to get share: %v", err)
+	}
+
+	// A recording is also visible through the documents shared with the user
+	if kind == ResourceRecording {
+		where, args := owner.where(kind)
+		var visible bool
+		err := db.QueryRow("SELECT EXISTS (SELECT 1 FROM recordings WHERE id = ? AND "+where+")", append([]any{id}, args...)...).Scan(&visible)
+		if err != nil {
+			return RoleNone, fmt.Errorf("failed to check recording access: %v", err)
+		}
+		if visible {
+			return RoleViewer, nil
+		}
+	}
+
+	return RoleNone, nil
+}
+
+// GrantShare gives a user a role on a resource, replacing any role they had
+func GrantShare(kind string, id, userID int64, role Role) (*Share, error) {
+	_, err := db.Exec(`INSERT INTO shares (resource_type, resource_id, user_id, role) VALUES (?, ?, ?, ?)
+		ON CONFLICT (resource_type, resource_id, user_id) DO UPDATE SET role = excluded.role`,
+		kind, id, userID, string(role))
+	if err != nil {
+		return nil, fmt.Errorf("failed to grant share: %v", err)
+	}
+
+	shares, err := queryShares("s.resource_type = ? AND s.resource_id = ? AND s.user_id = ?", kind, id, userID)
+	if err != nil || len(shares) == 0 {
+		return nil, err
+	}
+	return &shares[0], nil
+}
+
+// GetShares retrieves the grants on a resource
+func GetShares(kind string, id int64) ([]Share, error) {
+	return queryShares("s.resource_type = ? AND s.resource_id = ?", kind, id)
+}
+
+// GetShare retrieves a grant by ID, or nil if there is none
+func GetShare(shareID int64) (*Share, error) {
+	shares, err := queryShares("s.id = ?", shareID)
+	if err != nil || len(shares) == 0 {
+		return nil, err
+	}
+	return &shares[0], nil
+}
+
+// DeleteShare revokes a grant
+func DeleteShare(shareID int64) error {
+	if _, err := db.Exec("DELETE FROM shares WHERE id = ?", shareID); err != nil {
+		return fmt.Errorf("failed to delete share: %v", err)
+	}
+	return nil
+}
+
+// queryShares retrieves the grants matching a condition, with their usernames
+func queryShares(where string, args ...any) ([]Share, error) {
+	rows, err := db.Query(`SELECT s.id, s.resource_type, s.resource_id, s.user_id, u.username, s.role, s.created_at
+		FROM shares s JOIN users u ON u.id = s.user_id WHERE `+where+` ORDER BY s.id`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query shares: %v", err)
+	}
+	defer rows.Close()
+
+	shares := []Share{}
+	for rows.Next() {
+		var share Share
+		if err := rows.Scan(&share.ID, &share.ResourceType, &share.ResourceID, &share.UserID, &share.Username, &share.Role, &share.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan share: %v", err)
+		}
+		shares = append(shares, share)
+	}
+
+	return shares, rows.Err()
+}
+
+// CreateShareLink stores a link to a recording under the hash of its token
+func CreateShareLink(recordingID, createdBy int64, tokenHash string, expiresAt time.Time) (*ShareLink, error) {
+	link := &ShareLink{
+		RecordingID: recordingID,
+		ExpiresAt:   expiresAt.UTC().Truncate(time.Second),
+		CreatedAt:   time.Now().UTC().Truncate(time.Second),
+	}
+
+	result, err := db.Exec("INSERT INTO share_links (token_hash, recording_id, created_by, expires_at, created_at) VALUES (?, ?, ?, ?, ?)",
+		tokenHash, recordingID, nullID(createdBy), link.ExpiresAt.Format(time.RFC3339), link.CreatedAt.Format(time.RFC3339))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create share link: %v", err)
+	}
+
+	link.ID, err = result.LastInsertId()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get last insert id: %v", err)
+	}
+	return link, nil
+}
+
+// GetShareLinks retrieves the unexpired links to a recording
+func GetShareLinks(recordingID int64) ([]ShareLink, error) {
+	rows, err := db.Query("SELECT id, recording_id, expires_at, created_at FROM share_links WHERE recording_id = ? AND expires_at > ? ORDER BY id",
+		recordingID, time.Now().UTC().Format(time.RFC3339))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query share links: %v", err)
+	}
+	defer rows.Close()
+
+	links := []ShareLink{}
+	for rows.Next() {
+		var link ShareLink
+		var expiresAt, createdAt string
+		if err := rows.Scan(&link.ID, &link.RecordingID, &expiresAt, &createdAt); err != nil {
+			return nil, fmt.Errorf("failed to scan share link: %v", err)
+		}
+		link.ExpiresAt, _ = time.Parse(time.RFC3339, expiresAt)
+		link.CreatedAt, _ = time.Parse(time.RFC3339, createdAt)
+		links = append(links, link)
+	}
+
+	return links, rows.Err()
+}
+
+// GetShareLinkRecording returns the recording an unexpired link gives access
+// to, or 0 when there is no such link
+func GetShareLinkRecording(tokenHash string) (int64, error) {
+	var recordingID int64
+	err := db.QueryRow("SELECT recording_id FROM share_links WHERE token_hash = ? AND expires_at > ?",
+		tokenHash, time.Now().UTC().Format(time.RFC3339)).Scan(&recordingID)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to look up share link: %v", err)
+	}
+	return recordingID, nil
+}
+
+// DeleteShareLink revokes a link to a recording, reporting whether it existed
+func DeleteShareLink(id, recordingID int64) (bool, error) {
+	result, err := db.Exec("DELETE FROM share_links WHERE id = ? AND recording_id = ?", id, recordingID)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete share link: %v", err)
+	}
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to delete share link: %v", err)
+	}
+	return deleted > 0, nil
+}
@@ -0,0 +1,173 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/your-org/note-server/internal/auth"
+)
+
+// How stale last_used_at may get before a request updates it, so busy tokens
+// don't cause a write on every request
+const tokenUsageInterval = time.Minute
+
+// APIToken describes a personal API token. The token itself is only known
+// when it is created.
+type APIToken struct {
+	ID         int64      `json:"id"`
+	UserID     int64      `json:"-"`
+	Name       string     `json:"name"`
+	Scopes     auth.Scope `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expiresAt,omitempty"`
+	LastUsedAt *time.Time `json:"lastUsedAt,omitempty"`
+	CreatedAt  time.Time  `json:"createdAt"`
+}
+
+// createTokensTable creates the api_tokens table
+func createTokensTable() error {
+	createTokensTableSQL := `CREATE TABLE IF NOT EXISTS api_tokens (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL,
+		name TEXT NOT NULL,
+		token_hash TEXT NOT NULL UNIQUE,
+		scopes TEXT NOT NULL,
+		expires_at TEXT,
+		last_used_at TEXT,
+		created_at TEXT NOT NULL,
+		FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+	);`
+	if _, err := db.Exec(createTokensTableSQL); err != nil {
+		return fmt.Errorf("failed to create api_tokens table: %v", err)
+	}
+	return nil
+}
+
+// CreateAPIToken stores a personal API token for a user under the hash of the
+// token. A zero expiresAt never expires.
+func CreateAPIToken(userID int64, name, tokenHash string, scopes auth.Scope, expiresAt time.Time) (*APIToken, error) {
+	token := &APIToken{
+		UserID:    userID,
+		Name:      name,
+		Scopes:    scopes,
+		CreatedAt: time.Now().UTC().Truncate(time.Second),
+	}
+	var expires sql.NullString
+	if !expiresAt.IsZero() {
+		expiresAt = expiresAt.UTC().Truncate(time.Second)
+		token.ExpiresAt = &expiresAt
+		expires = sql.NullString{String: expiresAt.Format(time.RFC3339), Valid: true}
+	}
+
+	result, err := db.Exec("INSERT INTO api_tokens (user_id, name, token_hash, scopes, expires_at, created_at) VALUES (?, ?, ?, ?, ?, ?)",
+		userID, name, tokenHash, scopes.String(), expires, token.CreatedAt.Format(time.RFC3339))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create API token: %v", err)
+	}
+
+	token.ID, err = result.LastInsertId()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get last insert id: %v", err)
+	}
+	return token, nil
+}
+
+// GetAPITokens retrieves a user's API tokens, newest first
+func GetAPITokens(userID int64) ([]APIToken, error) {
+	rows, err := db.Query("SELECT id, user_id, name, scopes, expires_at, last_used_at, created_at FROM api_tokens WHERE user_id = ? ORDER BY id DESC", userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query API tokens: %v", err)
+	}
+	defer rows.Close()
+
+	tokens := []APIToken{}
+	for rows.Next() {
+		var token APIToken
+		var scopes, createdAt string
+		var expiresAt, lastUsedAt sql.NullString
+		if err := rows.Scan(&token.ID, &token.UserID, &token.Name, &scopes, &expiresAt, &lastUsedAt, &createdAt); err != nil {
+			return nil, fmt.Errorf("failed to scan API token: %v", err)
+		}
+		token.Scopes, _ = auth.ParseScopes(strings.Split(scopes, ","))
+		token.ExpiresAt = parseTime(expiresAt)
+		token.LastUsedAt = parseTime(lastUsedAt)
+		if created := parseTime(sql.NullString{String: createdAt, Valid: true}); created != nil {
+			token.CreatedAt = *created
+		}
+		tokens = append(tokens, token)
+	}
+
+	return tokens, rows.Err()
+}
+
+// DeleteAPIToken revokes one of a user's API tokens, reporting whether it existed
+func DeleteAPIToken(id, userID int64) (bool, error) {
+	result, err := db.Exec("DELETE FROM api_tokens WHERE id = ? AND user_id = ?", id, userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete API token: %v", err)
+	}
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to delete API token: %v", err)
+	}
+	return deleted > 0, nil
+}
+
+// parseTime converts an optional RFC 3339 column to a time
+func parseTime(value sql.NullString) *time.Time {
+	if !value.Valid {
+		return nil
+	}
+	parsed, err := time.Parse(time.RFC3339, value.String)
+	if err != nil {
+		return nil
+	}
+	return &parsed
+}
+
+// TokenStore resolves personal API tokens for an auth.Authenticator
+type TokenStore struct{}
+
+// NewTokenStore creates an API token store backed by the database
+func NewTokenStore() *TokenStore {
+	return &TokenStore{}
+}
+
+// LookupAPIToken implements auth.TokenStore. It records when the token was
+// last used.
+func (s *TokenStore) LookupAPIToken(token string) (auth.Principal, error) {
+	now := time.Now().UTC()
+
+	var tokenID int64
+	var scopes string
+	var user User
+	err := db.QueryRow(`SELECT t.id, t.scopes, u.id, u.username, u.password_hash, u.admin, u.created_at
+		FROM api_tokens t JOIN users u ON u.id = t.user_id
+		WHERE t.token_hash = ? AND (t.expires_at IS NULL OR t.expires_at > ?)`,
+		auth.HashToken(token), now.Format(time.RFC3339)).
+		Scan(&tokenID, &scopes, &user.ID, &user.Username, &user.PasswordHash, &user.Admin, &user.CreatedAt)
+	if err == sql.ErrNoRows {
+		return auth.Principal{}, auth.ErrInvalidCredentials
+	}
+	if err != nil {
+		return auth.Principal{}, fmt.Errorf("failed to look up API token: %v", err)
+	}
+
+	granted, err := auth.ParseScopes(strings.Split(scopes, ","))
+	if err != nil || granted == 0 {
+		return auth.Principal{}, auth.ErrInvalidCredentials
+	}
+
+	_, err = db.Exec("UPDATE api_tokens SET last_used_at = ? WHERE id = ? AND (last_used_at IS NULL OR last_used_at < ?)",
+		now.Format(time.RFC3339), tokenID, now.Add(-tokenUsageInterval).Format(time.RFC3339))
+	if err != nil {
+		return auth.Principal{}, fmt.Errorf("failed to record API token use: %v", err)
+	}
+
+	principal := user.Principal(auth.MethodAPIToken)
+	principal.Scopes = granted
+	// Administrator rights need the admin scope as well as an admin user
+	principal.Admin = user.Admin && granted&auth.ScopeAdmin != 0
+	return principal, nil
+}
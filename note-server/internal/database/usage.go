@@ -0,0 +1,59 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// createUsageTable creates the usage table, which holds each client's metered
+// work per UTC day; monthly usage is the sum of a month's days
+func createUsageTable() error {
+	createUsageTableSQL := `CREATE TABLE IF NOT EXISTS usage (
+		client TEXT NOT NULL,
+		metric TEXT NOT NULL,
+		day TEXT NOT NULL,
+		amount REAL NOT NULL DEFAULT 0,
+		PRIMARY KEY (client, metric, day)
+	);`
+	if _, err := db.Exec(createUsageTableSQL); err != nil {
+		return fmt.Errorf("failed to create usage table: %v", err)
+	}
+	return nil
+}
+
+// UsageStore keeps metered usage for a ratelimit.Meter
+type UsageStore struct{}
+
+// NewUsageStore creates a usage store backed by the database
+func NewUsageStore() *UsageStore {
+	return &UsageStore{}
+}
+
+// AddUsage implements ratelimit.Store
+func (s *UsageStore) AddUsage(ctx context.Context, client, metric string, day time.Time, amount float64) error {
+	_, err := db.ExecContext(ctx, `INSERT INTO usage (client, metric, day, amount) VALUES (?, ?, ?, ?)
+		ON CONFLICT (client, metric, day) DO UPDATE SET amount = amount + excluded.amount`,
+		client, metric, day.UTC().Format(time.DateOnly), amount)
+	if err != nil {
+		return fmt.Errorf("failed to record usage: %v", err)
+	}
+	return nil
+}
+
+// Usage implements ratelimit.Store
+func (s *UsageStore) Usage(ctx context.Context, client, metric string, day time.Time) (daily, monthly float64, err error) {
+	day = day.UTC()
+	today := day.Format(time.DateOnly)
+	monthStart := time.Date(day.Year(), day.Month(), 1, 0, 0, 0, 0, time.UTC).Format(time.DateOnly)
+
+	err = db.QueryRowContext(ctx, `SELECT
+			COALESCE(SUM(CASE WHEN day = ? THEN amount END), 0),
+			COALESCE(SUM(amount), 0)
+		FROM usage WHERE client = ? AND metric = ? AND day >= ? AND day <= ?`,
+		today, client, metric, monthStart, today).Scan(&daily, &monthly)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to query usage: %v", err)
+	}
+	return daily, monthly, nil
+}
@@ -0,0 +1,196 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/your-org/note-server/internal/auth"
+)
+
+// ErrUserExists is returned when creating a user whose username is taken
+var ErrUserExists = errors.New("username already taken")
+
+// ErrRegistrationClosed is returned when someone other than an administrator
+// creates a user once the first account exists
+var ErrRegistrationClosed = errors.New("only administrators can add users")
+
+// Tables whose rows belong to a user
+var ownedTables = []string{"recordings", "notes", "meetings", "interviews"}
+
+// User is a user account. The password hash is never serialized.
+type User struct {
+	ID           int64  `json:"id"`
+	Username     string `json:"username"`
+	PasswordHash string `json:"-"`
+	Admin        bool   `json:"admin"`
+	CreatedAt    string `json:"createdAt"`
+}
+
+// Principal returns the identity requests authenticated as u carry
+func (u *User) Principal(method string) auth.Principal {
+	return auth.Principal{ID: u.Username, Method: method, UserID: u.ID, Admin: u.Admin}
+}
+
+// nullID stores an ID of 0 as NULL
+func nullID(id int64) sql.NullInt64 {
+	return sql.NullInt64{Int64: id, Valid: id != 0}
+}
+
+// createUserTables creates the users, sessions and api_tokens tables
+func createUserTables() error {
+	createUsersTableSQL := `CREATE TABLE IF NOT EXISTS users (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		username TEXT NOT NULL UNIQUE COLLATE NOCASE,
+		password_hash TEXT NOT NULL,
+		admin INTEGER NOT NULL DEFAULT 0,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);`
+	if _, err := db.Exec(createUsersTableSQL); err != nil {
+		return fmt.Errorf("failed to create users table: %v", err)
+	}
+
+	createSessionsTableSQL := `CREATE TABLE IF NOT EXISTS sessions (
+		token_hash TEXT PRIMARY KEY,
+		user_id INTEGER NOT NULL,
+		expires_at TEXT NOT NULL,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+	);`
+	if _, err := db.Exec(createSessionsTableSQL); err != nil {
+		return fmt.Errorf("failed to create sessions table: %v", err)
+	}
+
+	return createTokensTable()
+}
+
+// CreateUser adds a user account on behalf of a caller, byAdmin telling
+// whether the caller is an administrator. The first account is always an
+// administrator, so a fresh install can be claimed by whoever registers first;
+// after that only administrators may add users, and only they may make them
+// administrators, or ErrRegistrationClosed is returned. Both rules are checked
+// in the insert itself, so concurrent registrations can't both claim a fresh
+// install.
+func CreateUser(username, passwordHash string, admin, byAdmin bool) (*User, error) {
+	result, err := db.Exec(`INSERT INTO users (username, password_hash, admin)
+		SELECT ?, ?, (? AND ?) OR NOT EXISTS (SELECT 1 FROM users)
+		WHERE ? OR NOT EXISTS (SELECT 1 FROM users)`, username, passwordHash, admin, byAdmin, byAdmin)
+	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE constraint failed") {
+			return nil, ErrUserExists
+		}
+		return nil, fmt.Errorf("failed to create user: %v", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rows affected: %v", err)
+	}
+	if rows == 0 {
+		return nil, ErrRegistrationClosed
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get last insert id: %v", err)
+	}
+
+	return getUser("id = ?", id)
+}
+
+// GetUserByUsername retrieves a user by username, ignoring case. It returns
+// nil when there is no such user.
+func GetUserByUsername(username string) (*User, error) {
+	return getUser("username = ?", username)
+}
+
+// CountUsers returns the number of user accounts
+func CountUsers() (int, error) {
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM users").Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count users: %v", err)
+	}
+	return count, nil
+}
+
+// getUser retrieves the user matching a condition on the users table
+func getUser(where string, args ...any) (*User, error) {
+	var user User
+	err := db.QueryRow("SELECT id, username, password_hash, admin, created_at FROM users WHERE "+where, args...).
+		Scan(&user.ID, &user.Username, &user.PasswordHash, &user.Admin, &user.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan user: %v", err)
+	}
+	return &user, nil
+}
+
+// CreateSession stores a login session for a user under the hash of its token
+func CreateSession(tokenHash string, userID int64, expiresAt time.Time) error {
+	_, err := db.Exec("INSERT INTO sessions (token_hash, user_id, expires_at) VALUES (?, ?, ?)",
+		tokenHash, userID, expiresAt.UTC().Format(time.RFC3339))
+	if err != nil {
+		return fmt.Errorf("failed to create session: %v", err)
+	}
+	return nil
+}
+
+// GetSessionUser returns the user of an unexpired session, or nil
+func GetSessionUser(tokenHash string) (*User, error) {
+	return getUser("id = (SELECT user_id FROM sessions WHERE token_hash = ? AND expires_at > ?)",
+		tokenHash, time.Now().UTC().Format(time.RFC3339))
+}
+
+// DeleteSession ends a login session, along with any sessions that have expired
+func DeleteSession(tokenHash string) error {
+	_, err := db.Exec("DELETE FROM sessions WHERE token_hash = ? OR expires_at <= ?",
+		tokenHash, time.Now().UTC().Format(time.RFC3339))
+	if err != nil {
+		return fmt.Errorf("failed to delete session: %v", err)
+	}
+	return nil
+}
+
+// SessionStore resolves login sessions for an auth.Authenticator
+type SessionStore struct {
+	// Accounts are never deleted, so once a user exists the answer can't change
+	hasUsers atomic.Bool
+}
+
+// NewSessionStore creates a session store backed by the database
+func NewSessionStore() *SessionStore {
+	return &SessionStore{}
+}
+
+// LookupSession implements auth.SessionStore
+func (s *SessionStore) LookupSession(token string) (auth.Principal, error) {
+	user, err := GetSessionUser(auth.HashToken(token))
+	if err != nil {
+		return auth.Principal{}, err
+	}
+	if user == nil {
+		return auth.Principal{}, auth.ErrInvalidCredentials
+	}
+	return user.Principal(auth.MethodSession), nil
+}
+
+// HasUsers implements auth.SessionStore
+func (s *SessionStore) HasUsers() bool {
+	if s.hasUsers.Load() {
+		return true
+	}
+	count, err := CountUsers()
+	if err != nil {
+		// Fail closed: require credentials if we can't tell
+		return true
+	}
+	if count > 0 {
+		s.hasUsers.Store(true)
+	}
+	return count > 0
+}
@@ -0,0 +1,355 @@
+// Package events provides an in-process publish/subscribe bus for server-side
+// events that clients follow live, e.g. over the /ws event channel.
+package events
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/your-org/note-server/internal/logging"
+)
+
+// Topics published by the server
+const (
+	TopicRecordingCreated = "recording.created"
+	TopicJobProgress      = "job.progress"
+	TopicTranscriptReady  = "transcript.ready"
+	TopicNoteUpdated      = "note.updated"
+	TopicConfigChanged    = "config.changed"
+)
+
+// Topics lists every topic the server publishes
+var Topics = []string{
+	TopicRecordingCreated,
+	TopicJobProgress,
+	TopicTranscriptReady,
+	TopicNoteUpdated,
+	TopicConfigChanged,
+}
+
+// Events queued per subscriber before further events are dropped for it
+const subscriberBuffer = 64
+
+// Recent events kept for clients resuming after a disconnect
+const defaultHistorySize = 1024
+
+// Event is a single published event. IDs increase monotonically per bus.
+type Event struct {
+	ID    int64     `json:"id"`
+	Topic string    `json:"topic"`
+	Time  time.Time `json:"time"`
+	Data  any       `json:"data,omitempty"`
+
+	// User the event concerns, 0 for events anyone may see. Never sent to clients.
+	Owner int64 `json:"-"`
+}
+
+// Audience limits the events a subscription receives to those its subscriber
+// may see: events published for a user reach only that user and AllUsers,
+// other events reach everyone
+type Audience struct {
+	all    bool
+	userID int64
+}
+
+// AllUsers receives every event, for administrators and the server itself
+var AllUsers = Audience{all: true}
+
+// ForUser receives events anyone may see and those published for userID
+func ForUser(userID int64) Audience {
+	return Audience{userID: userID}
+}
+
+// Includes reports whether the audience may see what belongs to owner, where
+// 0 means anyone
+func (a Audience) Includes(owner int64) bool {
+	return a.all || owner == 0 || owner == a.userID
+}
+
+// Bus fans published events out to subscribers. Publishing never blocks: a
+// subscriber that falls behind misses events rather than slowing the publisher.
+type Bus struct {
+	mutex       sync.RWMutex
+	nextID      int64
+	subscribers map[*Subscription]bool
+
+	// Ring buffer of the most recent events, oldest at start
+	history []Event
+	start   int
+	count   int
+}
+
+// Subscription receives events matching its topic patterns on C
+type Subscription struct {
+	bus *Bus
+	C   <-chan Event
+
+	events   chan Event
+	closed   bool
+	audience Audience
+
+	// Guarded by bus.mutex
+	patterns map[string]bool
+	dropped  int64
+}
+
+// NewBus creates an empty event bus that remembers the last 1024 events
+func NewBus() *Bus {
+	return NewBusWithHistory(defaultHistorySize)
+}
+
+// NewBusWithHistory creates an empty event bus that remembers the last size events
+func NewBusWithHistory(size int) *Bus {
+	if size < 1 {
+		size = 1
+	}
+	return &Bus{
+		subscribers: make(map[*Subscription]bool),
+		history:     make([]Event, size),
+	}
+}
+
+// Publish sends an event anyone may see on topic to every matching subscriber
+// and returns it
+func (b *Bus) Publish(topic string, data any) Event {
+	return b.PublishFor(0, topic, data)
+}
+
+// PublishFor sends an event concerning the user owner on topic to the
+// matching subscribers that may see it and returns it. An owner of 0 makes
+// the event visible to everyone.
+func (b *Bus) PublishFor(owner int64, topic string, data any) Event {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.nextID++
+	event := Event{
+		ID:    b.nextID,
+		Topic: topic,
+		Time:  time.Now().UTC(),
+		Data:  data,
+		Owner: owner,
+	}
+	b.remember(event)
+
+	for sub := range b.subscribers {
+		if !sub.matches(topic) || !sub.audience.Includes(owner) {
+			continue
+		}
+		select {
+		case sub.events <- event:
+		default:
+			sub.dropped++
+			logging.Default().Warn().
+				Str("topic", topic).
+				Int64("event_id", event.ID).
+				Msg("Dropping event for slow subscriber")
+		}
+	}
+
+	return event
+}
+
+// Subscribe returns a subscription to the given topic patterns that receives
+// events for all users. A pattern is a topic, a prefix ending in ".*" such as
+// "job.*", or "*" for everything.
+func (b *Bus) Subscribe(patterns ...string) *Subscription {
+	return b.SubscribeAs(AllUsers, patterns...)
+}
+
+// SubscribeAs subscribes like Subscribe, limited to the events audience may see
+func (b *Bus) SubscribeAs(audience Audience, patterns ...string) *Subscription {
+	events := make(chan Event, subscriberBuffer)
+	sub := &Subscription{
+		bus:      b,
+		C:        events,
+		events:   events,
+		audience: audience,
+		patterns: make(map[string]bool),
+	}
+	for _, pattern := range patterns {
+		sub.patterns[pattern] = true
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.subscribers[sub] = true
+	return sub
+}
+
+// SubscribeSince subscribes like Subscribe and also returns the remembered
+// events after lastID that match the patterns, so a client can resume without
+// gaps or duplicates. complete is false when events after lastID have already
+// been forgotten, or lastID is from before a restart, and the client should
+// resynchronise its state.
+func (b *Bus) SubscribeSince(lastID int64, patterns ...string) (sub *Subscription, replay []Event, complete bool) {
+	return b.SubscribeSinceAs(AllUsers, lastID, patterns...)
+}
+
+// SubscribeSinceAs subscribes like SubscribeSince, limited to the events
+// audience may see
+func (b *Bus) SubscribeSinceAs(audience Audience, lastID int64, patterns ...string) (sub *Subscription, replay []Event, complete bool) {
+	sub = b.SubscribeAs(audience)
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	for _, pattern := range patterns {
+		sub.patterns[pattern] = true
+	}
+
+	if lastID > b.nextID {
+		return sub, nil, false
+	}
+
+	complete = true
+	if b.count > 0 {
+		oldest := b.history[b.start].ID
+		complete = lastID >= oldest-1
+	}
+
+	for i := 0; i < b.count; i++ {
+		event := b.history[(b.start+i)%len(b.history)]
+		if event.ID > lastID && sub.matches(event.Topic) && audience.Includes(event.Owner) {
+			replay = append(replay, event)
+		}
+	}
+	return sub, replay, complete
+}
+
+// LastID returns the ID of the most recently published event
+func (b *Bus) LastID() int64 {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
+	return b.nextID
+}
+
+// remember adds an event to the history, evicting the oldest when full.
+// Callers hold b.mutex.
+func (b *Bus) remember(event Event) {
+	if b.count < len(b.history) {
+		b.history[(b.start+b.count)%len(b.history)] = event
+		b.count++
+		return
+	}
+	b.history[b.start] = event
+	b.start = (b.start + 1) % len(b.history)
+}
+
+// Add subscribes to more topic patterns
+func (s *Subscription) Add(patterns ...string) {
+	s.bus.mutex.Lock()
+	defer s.bus.mutex.Unlock()
+
+	for _, pattern := range patterns {
+		s.patterns[pattern] = true
+	}
+}
+
+// Remove unsubscribes from topic patterns
+func (s *Subscription) Remove(patterns ...string) {
+	s.bus.mutex.Lock()
+	defer s.bus.mutex.Unlock()
+
+	for _, pattern := range patterns {
+		delete(s.patterns, pattern)
+	}
+}
+
+// Patterns returns the topic patterns currently subscribed to
+func (s *Subscription) Patterns() []string {
+	s.bus.mutex.RLock()
+	defer s.bus.mutex.RUnlock()
+
+	patterns := make([]string, 0, len(s.patterns))
+	for pattern := range s.patterns {
+		patterns = append(patterns, pattern)
+	}
+	return patterns
+}
+
+// Dropped returns how many events were dropped because the subscriber fell behind
+func (s *Subscription) Dropped() int64 {
+	s.bus.mutex.RLock()
+	defer s.bus.mutex.RUnlock()
+
+	return s.dropped
+}
+
+// Close unsubscribes and closes C. It is safe to call more than once.
+func (s *Subscription) Close() {
+	s.bus.mutex.Lock()
+	defer s.bus.mutex.Unlock()
+
+	if s.closed {
+		return
+	}
+	s.closed = true
+	delete(s.bus.subscribers, s)
+	close(s.events)
+}
+
+// matches reports whether topic is covered by the subscription's patterns.
+// Callers hold bus.mutex.
+func (s *Subscription) matches(topic string) bool {
+	if s.patterns["*"] || s.patterns[topic] {
+		return true
+	}
+	for pattern := range s.patterns {
+		if prefix, ok := strings.CutSuffix(pattern, "*"); ok && strings.HasPrefix(topic, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidPattern reports whether pattern can match a published topic
+func ValidPattern(pattern string) bool {
+	if pattern == "*" {
+		return true
+	}
+	if prefix, ok := strings.CutSuffix(pattern, ".*"); ok {
+		for _, topic := range Topics {
+			if strings.HasPrefix(topic, prefix+".") {
+				return true
+			}
+		}
+		return false
+	}
+	for _, topic := range Topics {
+		if topic == pattern {
+			return true
+		}
+	}
+	return false
+}
+
+// RecordingCreated is the payload of recording.created
+type RecordingCreated struct {
+	RecordingID int64  `json:"recordingId"`
+	Filename    string `json:"filename"`
+
+	// "upload" or "live"
+	Source string `json:"source"`
+}
+
+// TranscriptReady is the payload of transcript.ready
+type TranscriptReady struct {
+	RecordingID int64  `json:"recordingId"`
+	Segments    int    `json:"segments"`
+	JobID       string `json:"jobId,omitempty"`
+}
+
+// NoteUpdated is the payload of note.updated
+type NoteUpdated struct {
+	NoteID int64 `json:"noteId"`
+}
+
+// ConfigChanged is the payload of config.changed. Only the names of changed
+// fields are published, never their values.
+type ConfigChanged struct {
+	Fields []string `json:"fields"`
+}
@@ -0,0 +1,219 @@
+package events
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// receive returns the next event on sub or fails after a timeout
+func receive(t *testing.T, sub *Subscription) Event {
+	t.Helper()
+	select {
+	case event := <-sub.C:
+		return event
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for event")
+		return Event{}
+	}
+}
+
+func TestBus_PublishSubscribe(t *testing.T) {
+	bus := NewBus()
+
+	recordings := bus.Subscribe(TopicRecordingCreated)
+	defer recordings.Close()
+	jobs := bus.Subscribe("job.*")
+	defer jobs.Close()
+	all := bus.Subscribe("*")
+	defer all.Close()
+
+	first := bus.Publish(TopicRecordingCreated, RecordingCreated{RecordingID: 1})
+	second := bus.Publish(TopicJobProgress, nil)
+
+	if second.ID != first.ID+1 {
+		t.Errorf("Expected increasing event IDs, got %d then %d", first.ID, second.ID)
+	}
+
+	if event := receive(t, recordings); event.ID != first.ID {
+		t.Errorf("Expected recording event %d, got %d", first.ID, event.ID)
+	}
+	if event := receive(t, jobs); event.Topic != TopicJobProgress {
+		t.Errorf("Expected %s on job.*, got %s", TopicJobProgress, event.Topic)
+	}
+	if a, b := receive(t, all), receive(t, all); a.ID != first.ID || b.ID != second.ID {
+		t.Errorf("Expected events %d and %d in order, got %d and %d", first.ID, second.ID, a.ID, b.ID)
+	}
+
+	select {
+	case event := <-recordings.C:
+		t.Errorf("Expected no further events for recording.created, got %s", event.Topic)
+	default:
+	}
+}
+
+func TestSubscription_AddRemove(t *testing.T) {
+	bus := NewBus()
+	sub := bus.Subscribe()
+	defer sub.Close()
+
+	bus.Publish(TopicNoteUpdated, nil)
+	sub.Add(TopicNoteUpdated)
+	bus.Publish(TopicNoteUpdated, NoteUpdated{NoteID: 2})
+	sub.Remove(TopicNoteUpdated)
+	bus.Publish(TopicNoteUpdated, nil)
+
+	event := receive(t, sub)
+	if data, ok := event.Data.(NoteUpdated); !ok || data.NoteID != 2 {
+		t.Errorf("Expected only the event published while subscribed, got %+v", event)
+	}
+	select {
+	case event := <-sub.C:
+		t.Errorf("Expected no event after unsubscribing, got %+v", event)
+	default:
+	}
+}
+
+func TestSubscription_SlowSubscriber(t *testing.T) {
+	bus := NewBus()
+	slow := bus.Subscribe("*")
+	defer slow.Close()
+
+	// Publishing never blocks on a subscriber that doesn't read
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < subscriberBuffer+10; i++ {
+			bus.Publish(TopicJobProgress, i)
+		}
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Publish blocked on a slow subscriber")
+	}
+
+	if dropped := slow.Dropped(); dropped != 10 {
+		t.Errorf("Expected 10 dropped events, got %d", dropped)
+	}
+}
+
+func TestSubscription_Close(t *testing.T) {
+	bus := NewBus()
+	sub := bus.Subscribe("*")
+	sub.Close()
+	sub.Close()
+
+	bus.Publish(TopicJobProgress, nil)
+	if _, ok := <-sub.C; ok {
+		t.Error("Expected closed subscription channel")
+	}
+}
+
+func TestValidPattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		valid   bool
+	}{
+		{"*", true},
+		{TopicRecordingCreated, true},
+		{"job.*", true},
+		{"recording.deleted", false},
+		{"nothing.*", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		if got := ValidPattern(tt.pattern); got != tt.valid {
+			t.Errorf("ValidPattern(%q) = %v, expected %v", tt.pattern, got, tt.valid)
+		}
+	}
+}
+
+func TestBus_SubscribeSince(t *testing.T) {
+	bus := NewBusWithHistory(3)
+	for i := 0; i < 2; i++ {
+		bus.Publish(TopicJobProgress, nil)
+		bus.Publish(TopicNoteUpdated, nil)
+	}
+	// History now holds events 2..4
+
+	tests := []struct {
+		name     string
+		lastID   int64
+		patterns []string
+		replay   []int64
+		complete bool
+	}{
+		{"resume within history", 2, []string{"*"}, []int64{3, 4}, true},
+		{"filtered by topic", 1, []string{TopicNoteUpdated}, []int64{2, 4}, true},
+		{"up to date", 4, []string{"*"}, nil, true},
+		{"forgotten events", 0, []string{"*"}, []int64{2, 3, 4}, false},
+		{"from before a restart", 99, []string{"*"}, nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sub, replay, complete := bus.SubscribeSince(tt.lastID, tt.patterns...)
+			defer sub.Close()
+
+			if complete != tt.complete {
+				t.Errorf("Expected complete = %v, got %v", tt.complete, complete)
+			}
+			var ids []int64
+			for _, event := range replay {
+				ids = append(ids, event.ID)
+			}
+			if fmt.Sprint(ids) != fmt.Sprint(tt.replay) {
+				t.Errorf("Expected replay %v, got %v", tt.replay, ids)
+			}
+		})
+	}
+
+	// Events published after subscribing are delivered live, not replayed
+	sub, _, _ := bus.SubscribeSince(4, "*")
+	defer sub.Close()
+	published := bus.Publish(TopicJobProgress, nil)
+	if event := receive(t, sub); event.ID != published.ID {
+		t.Errorf("Expected live event %d, got %d", published.ID, event.ID)
+	}
+	if bus.LastID() != published.ID {
+		t.Errorf("Expected last ID %d, got %d", published.ID, bus.LastID())
+	}
+}
+
+func TestBus_Audience(t *testing.T) {
+	bus := NewBus()
+	all := bus.SubscribeAs(AllUsers, "*")
+	defer all.Close()
+	alice := bus.SubscribeAs(ForUser(1), "*")
+	defer alice.Close()
+
+	bus.PublishFor(2, TopicRecordingCreated, "bob's")
+	public := bus.Publish(TopicConfigChanged, nil)
+	owned := bus.PublishFor(1, TopicJobProgress, "alice's")
+
+	// Other users' events are withheld; public ones and their own arrive
+	for _, want := range []int64{public.ID, owned.ID} {
+		if event := receive(t, alice); event.ID != want {
+			t.Errorf("Expected event %d for alice, got %d", want, event.ID)
+		}
+	}
+	for want := int64(1); want <= 3; want++ {
+		if event := receive(t, all); event.ID != want {
+			t.Errorf("Expected event %d for all users, got %d", want, event.ID)
+		}
+	}
+
+	// Replays are filtered the same way
+	sub, replay, _ := bus.SubscribeSinceAs(ForUser(2), 0, "*")
+	defer sub.Close()
+	var ids []int64
+	for _, event := range replay {
+		ids = append(ids, event.ID)
+	}
+	if fmt.Sprint(ids) != "[1 2]" {
+		t.Errorf("Expected replay [1 2] for bob, got %v", ids)
+	}
+}
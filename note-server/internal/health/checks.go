@@ -0,0 +1,174 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+
+	"github.com/your-org/note-server/internal/database"
+)
+
+// minFreeBytes is the free space below which the media directory check warns
+const minFreeBytes = 1 << 30
+
+// hubWarnRatio is the share of WebSocket slots in use above which the
+// capacity check warns
+const hubWarnRatio = 0.9
+
+// Database checks that the database answers a ping and accepts a write
+func Database() Check {
+	return Check{
+		Name:     "database",
+		Critical: true,
+		Run: func(ctx context.Context) Result {
+			if err := database.Ping(ctx); err != nil {
+				return failed(err)
+			}
+			if err := database.CheckWrite(ctx); err != nil {
+				return failed(err)
+			}
+			return Result{Status: StatusOK}
+		},
+	}
+}
+
+// MediaDir checks that dir exists, or can be created, and is writable, and
+// warns when its filesystem is running out of space
+func MediaDir(dir string) Check {
+	return Check{
+		Name:     "media_dir",
+		Critical: true,
+		Run: func(ctx context.Context) Result {
+			details := map[string]any{"path": dir}
+			if err := os.MkdirAll(dir, 0755); err != nil {
+				return Result{Status: StatusError, Error: err.Error(), Details: details}
+			}
+
+			probe, err := os.CreateTemp(dir, ".health-*")
+			if err != nil {
+				return Result{Status: StatusError, Error: fmt.Sprintf("not writable: %v", err), Details: details}
+			}
+			probe.Close()
+			os.Remove(probe.Name())
+
+			free, ok := freeBytes(dir)
+			if !ok {
+				return Result{Status: StatusOK, Details: details}
+			}
+			details["freeBytes"] = free
+			if free < minFreeBytes {
+				return Result{Status: StatusWarn, Error: "less than 1 GiB free", Details: details}
+			}
+			return Result{Status: StatusOK, Details: details}
+		},
+	}
+}
+
+// Binary checks that the named tool is on the PATH and reports the first
+// line of its -version output, as ffmpeg and ffprobe print it
+func Binary(name string) Check {
+	return Check{
+		Name: name,
+		Run: func(ctx context.Context) Result {
+			path, err := exec.LookPath(name)
+			if err != nil {
+				return Result{Status: StatusMissing, Error: fmt.Sprintf("%s not found on PATH", name)}
+			}
+
+			output, err := exec.CommandContext(ctx, path, "-version").Output()
+			if err != nil {
+				return Result{Status: StatusError, Error: fmt.Sprintf("%s -version failed: %v", name, err), Details: map[string]any{"path": path}}
+			}
+			version, _, _ := strings.Cut(string(output), "\n")
+			return Result{Status: StatusOK, Version: strings.TrimSpace(version), Details: map[string]any{"path": path}}
+		},
+	}
+}
+
+// ProviderEndpoints are the URLs requested to check that each AI provider
+// can be reached. Any HTTP response counts; the API key isn't checked.
+var ProviderEndpoints = map[string]string{
+	"openai": "https://api.openai.com/v1/models",
+	"google": "https://generativelanguage.googleapis.com/",
+}
+
+// Providers checks that the providers returned by configured, those the
+// current configuration uses, can be reached at their endpoints with client
+func Providers(client *http.Client, endpoints map[string]string, configured func() []string) Check {
+	return Check{
+		Name: "providers",
+		Run: func(ctx context.Context) Result {
+			names := configured()
+			sort.Strings(names)
+
+			result := Result{Status: StatusOK, Details: map[string]any{}}
+			var failures []string
+			for _, name := range names {
+				if name == "" {
+					continue
+				}
+				err := reach(ctx, client, endpoints[name])
+				if err != nil {
+					result.Details[name] = err.Error()
+					failures = append(failures, name)
+					continue
+				}
+				result.Details[name] = StatusOK
+			}
+			if len(failures) > 0 {
+				result.Status = StatusError
+				result.Error = "unreachable: " + strings.Join(failures, ", ")
+			}
+			return result
+		},
+	}
+}
+
+// reach makes a GET request to endpoint, succeeding on any response
+func reach(ctx context.Context, client *http.Client, endpoint string) error {
+	if endpoint == "" {
+		return errors.New("no endpoint known")
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// Capacity is implemented by components with a limited number of slots, such
+// as the WebSocket hub
+type Capacity interface {
+	Capacity() (used, max int)
+}
+
+// Hub checks how many of the transcription hub's connection slots are in use,
+// warning when it is nearly full
+func Hub(hub Capacity) Check {
+	return Check{
+		Name: "transcribe_hub",
+		Run: func(ctx context.Context) Result {
+			used, max := hub.Capacity()
+			details := map[string]any{"connections": used, "maxConnections": max}
+			if float64(used) >= hubWarnRatio*float64(max) {
+				return Result{Status: StatusWarn, Error: "nearly out of connection slots", Details: details}
+			}
+			return Result{Status: StatusOK, Details: details}
+		},
+	}
+}
+
+// failed reports err as a failed check
+func failed(err error) Result {
+	return Result{Status: StatusError, Error: err.Error()}
+}
@@ -0,0 +1,8 @@
+//go:build !linux && !darwin
+
+package health
+
+// freeBytes isn't supported on this platform
+func freeBytes(path string) (uint64, bool) {
+	return 0, false
+}
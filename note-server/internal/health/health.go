@@ -0,0 +1,149 @@
+// Package health checks the server's dependencies: the database, the media
+// directory, ffmpeg, the AI providers and WebSocket capacity. Critical checks
+// decide whether the server is ready for traffic; the rest report degraded
+// features, such as transcription without ffmpeg.
+package health
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Check statuses. A check is missing when a tool it looks for isn't installed.
+const (
+	StatusOK      = "ok"
+	StatusWarn    = "warn"
+	StatusMissing = "missing"
+	StatusError   = "error"
+)
+
+// Time a readiness report is reused for, so frequent or hostile probes don't
+// each write to the database and the media directory
+const readinessTTL = 5 * time.Second
+
+// Overall report statuses
+const (
+	ReportOK       = "ok"
+	ReportDegraded = "degraded"
+	ReportDown     = "down"
+)
+
+// Result is the outcome of one check
+type Result struct {
+	Name       string         `json:"name"`
+	Status     string         `json:"status"`
+	Critical   bool           `json:"critical"`
+	Version    string         `json:"version,omitempty"`
+	Error      string         `json:"error,omitempty"`
+	Details    map[string]any `json:"details,omitempty"`
+	DurationMs int64          `json:"durationMs"`
+}
+
+// Check is a named check. Critical checks must pass for the server to be
+// ready.
+type Check struct {
+	Name     string
+	Critical bool
+	Run      func(ctx context.Context) Result
+}
+
+// Report is the outcome of every check
+type Report struct {
+	Status    string    `json:"status"`
+	Checks    []Result  `json:"checks"`
+	CheckedAt time.Time `json:"checkedAt"`
+}
+
+// Ready reports whether every critical check passed
+func (r Report) Ready() bool {
+	return r.Status != ReportDown
+}
+
+// Checker runs checks concurrently, each limited to a timeout
+type Checker struct {
+	timeout time.Duration
+	checks  []Check
+
+	// Last readiness report, reused until it's readinessTTL old
+	readinessMutex sync.Mutex
+	readinessTTL   time.Duration
+	readiness      *Report
+}
+
+// NewChecker creates a checker that gives each check timeout to finish
+func NewChecker(timeout time.Duration, checks ...Check) *Checker {
+	return &Checker{timeout: timeout, checks: checks, readinessTTL: readinessTTL}
+}
+
+// Readiness runs the critical checks like Run, but reuses the last report for
+// a few seconds. Concurrent callers share one run, which a caller going away
+// doesn't cancel.
+func (c *Checker) Readiness(ctx context.Context) Report {
+	c.readinessMutex.Lock()
+	defer c.readinessMutex.Unlock()
+
+	if c.readiness == nil || time.Since(c.readiness.CheckedAt) >= c.readinessTTL {
+		report := c.Run(context.WithoutCancel(ctx), true)
+		c.readiness = &report
+	}
+	return *c.readiness
+}
+
+// Run runs every check, or only the critical ones, and reports the results
+// in the order the checks were given
+func (c *Checker) Run(ctx context.Context, criticalOnly bool) Report {
+	var checks []Check
+	for _, check := range c.checks {
+		if check.Critical || !criticalOnly {
+			checks = append(checks, check)
+		}
+	}
+
+	results := make([]Result, len(checks))
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = c.run(ctx, check)
+		}()
+	}
+	wg.Wait()
+
+	report := Report{Status: ReportOK, Checks: results, CheckedAt: time.Now().UTC()}
+	for _, result := range results {
+		switch {
+		case result.Status == StatusOK:
+		case result.Critical && result.Status != StatusWarn:
+			report.Status = ReportDown
+		case report.Status == ReportOK:
+			report.Status = ReportDegraded
+		}
+	}
+	return report
+}
+
+// run runs one check within the timeout. A check that overruns it is
+// reported as failed while it finishes in the background.
+func (c *Checker) run(ctx context.Context, check Check) Result {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	start := time.Now()
+	done := make(chan Result, 1)
+	go func() {
+		done <- check.Run(ctx)
+	}()
+
+	var result Result
+	select {
+	case result = <-done:
+	case <-ctx.Done():
+		result = Result{Status: StatusError, Error: "check timed out"}
+	}
+	result.Name = check.Name
+	result.Critical = check.Critical
+	result.DurationMs = time.Since(start).Milliseconds()
+	return result
+}
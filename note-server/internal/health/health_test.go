@@ -0,0 +1,186 @@
+package health
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fixed returns a check that reports status
+func fixed(name string, critical bool, status string) Check {
+	return Check{Name: name, Critical: critical, Run: func(ctx context.Context) Result {
+		return Result{Status: status}
+	}}
+}
+
+func TestChecker_Run(t *testing.T) {
+	tests := []struct {
+		name     string
+		checks   []Check
+		expected string
+	}{
+		{"all ok", []Check{fixed("db", true, StatusOK), fixed("ffmpeg", false, StatusOK)}, ReportOK},
+		{"optional check missing", []Check{fixed("db", true, StatusOK), fixed("ffmpeg", false, StatusMissing)}, ReportDegraded},
+		{"critical check warns", []Check{fixed("disk", true, StatusWarn)}, ReportDegraded},
+		{"critical check fails", []Check{fixed("db", true, StatusError), fixed("ffmpeg", false, StatusMissing)}, ReportDown},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := NewChecker(time.Second, tt.checks...).Run(context.Background(), false)
+			if report.Status != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, report.Status)
+			}
+			if report.Ready() != (tt.expected != ReportDown) {
+				t.Errorf("Ready() = %v for a %s report", report.Ready(), report.Status)
+			}
+			for i, result := range report.Checks {
+				if result.Name != tt.checks[i].Name || result.Critical != tt.checks[i].Critical {
+					t.Errorf("Expected results in check order, got %q at %d", result.Name, i)
+				}
+			}
+		})
+	}
+}
+
+func TestChecker_CriticalOnly(t *testing.T) {
+	checker := NewChecker(time.Second, fixed("db", true, StatusOK), fixed("ffmpeg", false, StatusMissing))
+
+	report := checker.Run(context.Background(), true)
+	if len(report.Checks) != 1 || report.Checks[0].Name != "db" || report.Status != ReportOK {
+		t.Errorf("Expected only the critical check, got %+v", report)
+	}
+}
+
+func TestChecker_Readiness(t *testing.T) {
+	var runs atomic.Int32
+	counted := Check{Name: "db", Critical: true, Run: func(ctx context.Context) Result {
+		runs.Add(1)
+		return Result{Status: StatusOK}
+	}}
+	checker := NewChecker(time.Second, counted, fixed("ffmpeg", false, StatusMissing))
+
+	// Probes within the TTL reuse the report
+	for i := 0; i < 3; i++ {
+		if report := checker.Readiness(context.Background()); len(report.Checks) != 1 || report.Status != ReportOK {
+			t.Fatalf("Expected only the critical check, got %+v", report)
+		}
+	}
+	if n := runs.Load(); n != 1 {
+		t.Errorf("Expected one run within the TTL, got %d", n)
+	}
+
+	checker.readinessTTL = 0
+	checker.Readiness(context.Background())
+	if n := runs.Load(); n != 2 {
+		t.Errorf("Expected the checks to run again once the report expired, got %d runs", n)
+	}
+}
+
+func TestChecker_Timeout(t *testing.T) {
+	hang := Check{Name: "slow", Critical: true, Run: func(ctx context.Context) Result {
+		time.Sleep(time.Second)
+		return Result{Status: StatusOK}
+	}}
+
+	start := time.Now()
+	report := NewChecker(20*time.Millisecond, hang).Run(context.Background(), false)
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("Expected the check to be cut off, took %v", elapsed)
+	}
+	if result := report.Checks[0]; result.Status != StatusError || result.Error != "check timed out" {
+		t.Errorf("Expected a timed out check, got %+v", result)
+	}
+	if report.Status != ReportDown {
+		t.Errorf("Expected down, got %s", report.Status)
+	}
+}
+
+func TestBinary_Missing(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+
+	result := Binary("ffmpeg").Run(context.Background())
+	if result.Status != StatusMissing {
+		t.Errorf("Expected missing, got %+v", result)
+	}
+}
+
+func TestMediaDir(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "media")
+
+	result := MediaDir(dir).Run(context.Background())
+	if result.Status == StatusError {
+		t.Fatalf("Expected a writable directory, got %+v", result)
+	}
+	if _, err := os.Stat(dir); err != nil {
+		t.Errorf("Expected the directory to be created: %v", err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("Expected the probe file to be removed, found %d entries", len(entries))
+	}
+
+	// A file in the way can't be used as the directory
+	blocked := filepath.Join(t.TempDir(), "file")
+	os.WriteFile(blocked, nil, 0644)
+	if result := MediaDir(blocked).Run(context.Background()); result.Status != StatusError {
+		t.Errorf("Expected an error, got %+v", result)
+	}
+}
+
+func TestProviders(t *testing.T) {
+	// Any response counts, even an authentication failure
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+	endpoints := map[string]string{"openai": server.URL, "google": "http://127.0.0.1:1"}
+
+	tests := []struct {
+		name       string
+		configured []string
+		expected   string
+	}{
+		{"reachable", []string{"openai"}, StatusOK},
+		{"unconfigured providers skipped", []string{"openai", ""}, StatusOK},
+		{"unreachable", []string{"openai", "google"}, StatusError},
+		{"unknown provider", []string{"acme"}, StatusError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			check := Providers(server.Client(), endpoints, func() []string { return tt.configured })
+			result := check.Run(context.Background())
+			if result.Status != tt.expected {
+				t.Errorf("Expected %s, got %+v", tt.expected, result)
+			}
+			if result.Details["openai"] != nil && result.Details["openai"] != StatusOK {
+				t.Errorf("Expected openai to be reachable, got %v", result.Details["openai"])
+			}
+		})
+	}
+}
+
+type slots struct{ used, max int }
+
+func (s slots) Capacity() (int, int) { return s.used, s.max }
+
+func TestHub(t *testing.T) {
+	tests := []struct {
+		used     int
+		expected string
+	}{
+		{0, StatusOK},
+		{89, StatusOK},
+		{90, StatusWarn},
+		{100, StatusWarn},
+	}
+	for _, tt := range tests {
+		result := Hub(slots{tt.used, 100}).Run(context.Background())
+		if result.Status != tt.expected {
+			t.Errorf("%d of 100 used: expected %s, got %s", tt.used, tt.expected, result.Status)
+		}
+	}
+}
@@ -0,0 +1,321 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/your-org/note-server/internal/auth"
+	"github.com/your-org/note-server/internal/database"
+	"github.com/your-org/note-server/internal/events"
+	"github.com/your-org/note-server/pkg/response"
+)
+
+// Credentials is the request body of POST /api/auth/register and /api/auth/login
+type Credentials struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+	Admin    bool   `json:"admin,omitempty"`
+}
+
+// Authenticate is middleware that identifies the caller and stores the principal
+// in the request context. Requests without valid credentials are rejected
+// once authentication is required.
+func (h *Handlers) Authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal, err := h.authenticator.Authenticate(r)
+		if err != nil {
+			writeUnauthorized(w, r, err)
+			return
+		}
+		ctx := withUser(auth.WithPrincipal(r.Context(), principal), principal.ID)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// RequireAdmin is middleware that only lets administrators through. Without
+// user accounts or required authentication every caller is anonymous and
+// owns the server, so anonymous callers are let through too.
+func (h *Handlers) RequireAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal, ok := h.principal(w, r)
+		if !ok {
+			return
+		}
+		if !principal.Admin && !principal.IsAnonymous() {
+			response.Error(w, r, http.StatusForbidden, "Administrator access required")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// RequireScope is middleware that rejects personal API tokens not granted scope
+func (h *Handlers) RequireScope(scope auth.Scope) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal, ok := h.principal(w, r)
+			if !ok {
+				return
+			}
+			if !principal.Can(scope) {
+				response.Error(w, r, http.StatusForbidden, fmt.Sprintf("Token lacks the %s scope", scope))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// principal returns the caller set by the Authenticate middleware, or
+// authenticates the request itself when called without it. It writes a 401
+// and returns false when the caller can't be identified.
+func (h *Handlers) principal(w http.ResponseWriter, r *http.Request) (auth.Principal, bool) {
+	if principal, ok := auth.Lookup(r.Context()); ok {
+		return principal, true
+	}
+	principal, err := h.authenticator.Authenticate(r)
+	if err != nil {
+		writeUnauthorized(w, r, err)
+		return auth.Principal{}, false
+	}
+	return principal, true
+}
+
+// owner returns the rows of user data a principal may see. Administrators, and
+// the anonymous caller of a server without accounts, see everything.
+func owner(principal auth.Principal) database.Owner {
+	if principal.Admin || principal.IsAnonymous() {
+		return database.AnyOwner
+	}
+	return database.OwnedBy(principal.UserID)
+}
+
+// audience returns the events and jobs a principal may see, like owner does
+// for rows of user data
+func audience(principal auth.Principal) events.Audience {
+	if principal.Admin || principal.IsAnonymous() {
+		return events.AllUsers
+	}
+	return events.ForUser(principal.UserID)
+}
+
+// writeUnauthorized responds 401 with a Bearer challenge
+func writeUnauthorized(w http.ResponseWriter, r *http.Request, err error) {
+	w.Header().Set("WWW-Authenticate", `Bearer realm="note"`)
+	response.Error(w, r, http.StatusUnauthorized, err.Error())
+}
+
+// accountsEnabled reports whether the authenticator resolves login sessions,
+// writing a 404 when user accounts are disabled
+func (h *Handlers) accountsEnabled(w http.ResponseWriter, r *http.Request) bool {
+	if h.authenticator.Sessions() == nil {
+		response.Error(w, r, http.StatusNotFound, "User accounts are disabled")
+		return false
+	}
+	return true
+}
+
+// readCredentials decodes and checks the body of a register or login request
+func readCredentials(w http.ResponseWriter, r *http.Request) (Credentials, bool) {
+	var credentials Credentials
+	if err := json.NewDecoder(r.Body).Decode(&credentials); err != nil {
+		response.Error(w, r, http.StatusBadRequest, "Invalid JSON format")
+		return credentials, false
+	}
+	credentials.Username = strings.TrimSpace(credentials.Username)
+	var missing []response.FieldError
+	if credentials.Username == "" {
+		missing = append(missing, response.FieldError{Field: "username", Message: "is required"})
+	}
+	if credentials.Password == "" {
+		missing = append(missing, response.FieldError{Field: "password", Message: "is required"})
+	}
+	if len(missing) > 0 {
+		response.Invalid(w, r, "Username and password are required", missing...)
+		return credentials, false
+	}
+	return credentials, true
+}
+
+// Register handles POST /api/auth/register requests. Anyone may create the
+// first account, which becomes an administrator; after that only
+// administrators can add users.
+func (h *Handlers) Register(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		response.Error(w, r, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	if !h.accountsEnabled(w, r) {
+		return
+	}
+
+	// Callers without credentials may only claim a fresh install
+	principal, err := h.authenticator.Authenticate(r)
+	if err != nil {
+		principal = auth.Anonymous
+	}
+	if !principal.Admin {
+		count, err := database.CountUsers()
+		if err != nil {
+			response.Error(w, r, http.StatusInternalServerError, fmt.Sprintf("Failed to check users: %v", err))
+			return
+		}
+		if count > 0 {
+			registrationClosed(w, r, principal)
+			return
+		}
+	}
+
+	credentials, ok := readCredentials(w, r)
+	if !ok {
+		return
+	}
+
+	hash, err := auth.HashPassword(credentials.Password)
+	if err != nil {
+		response.Invalid(w, r, err.Error(), response.FieldError{Field: "password", Message: err.Error()})
+		return
+	}
+
+	// Only administrators choose whether a new user is one; CreateUser makes
+	// the first user an administrator regardless
+	user, err := database.CreateUser(credentials.Username, hash, credentials.Admin && principal.Admin, principal.Admin)
+	if errors.Is(err, database.ErrUserExists) {
+		response.Error(w, r, http.StatusConflict, err.Error())
+		return
+	}
+	if errors.Is(err, database.ErrRegistrationClosed) {
+		// Another registration claimed the install first
+		registrationClosed(w, r, principal)
+		return
+	}
+	if err != nil {
+		response.Error(w, r, http.StatusInternalServerError, fmt.Sprintf("Failed to create user: %v", err))
+		return
+	}
+
+	response.Created(w, r, map[string]any{
+		"user": user,
+	})
+}
+
+// registrationClosed refuses to add a user for a caller who isn't an
+// administrator: 401 without credentials, 403 with them
+func registrationClosed(w http.ResponseWriter, r *http.Request, principal auth.Principal) {
+	if principal.IsAnonymous() {
+		writeUnauthorized(w, r, auth.ErrNoCredentials)
+		return
+	}
+	response.Error(w, r, http.StatusForbidden, "Administrator access required")
+}
+
+// Login handles POST /api/auth/login requests. It starts a session, setting the
+// session cookie for browsers and returning the token for API clients to send
+// as "Authorization: Bearer <token>".
+func (h *Handlers) Login(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		response.Error(w, r, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	if !h.accountsEnabled(w, r) {
+		return
+	}
+
+	credentials, ok := readCredentials(w, r)
+	if !ok {
+		return
+	}
+
+	user, err := database.GetUserByUsername(credentials.Username)
+	if err != nil {
+		response.Error(w, r, http.StatusInternalServerError, fmt.Sprintf("Failed to get user: %v", err))
+		return
+	}
+
+	var hash string
+	if user != nil {
+		hash = user.PasswordHash
+	}
+	if !auth.CheckPassword(hash, credentials.Password) {
+		response.Error(w, r, http.StatusUnauthorized, "Invalid username or password")
+		return
+	}
+
+	token, tokenHash := auth.NewSessionToken()
+	expiresAt := time.Now().Add(h.authenticator.SessionTTL())
+	if err := database.CreateSession(tokenHash, user.ID, expiresAt); err != nil {
+		response.Error(w, r, http.StatusInternalServerError, fmt.Sprintf("Failed to start session: %v", err))
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     auth.SessionCookie,
+		Value:    token,
+		Path:     "/",
+		Expires:  expiresAt,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	response.OK(w, r, map[string]any{
+		"user":      user,
+		"token":     token,
+		"expiresAt": expiresAt.UTC().Format(time.RFC3339),
+	})
+}
+
+// Logout handles POST /api/auth/logout requests, ending the caller's session
+func (h *Handlers) Logout(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		response.Error(w, r, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if cookie, err := r.Cookie(auth.SessionCookie); err == nil {
+		token = cookie.Value
+	}
+	if token != "" {
+		if err := database.DeleteSession(auth.HashToken(token)); err != nil {
+			response.Error(w, r, http.StatusInternalServerError, fmt.Sprintf("Failed to end session: %v", err))
+			return
+		}
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     auth.SessionCookie,
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	response.OK(w, r, map[string]any{})
+}
+
+// Me handles GET /api/auth/me requests, describing the caller
+func (h *Handlers) Me(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		response.Error(w, r, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	principal, ok := h.principal(w, r)
+	if !ok {
+		return
+	}
+
+	response.OK(w, r, map[string]any{
+		"user":         principal,
+		"authRequired": h.authenticator.Required(),
+	})
+}
@@ -0,0 +1,300 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/your-org/note-server/internal/events"
+	"github.com/your-org/note-server/internal/jobs"
+	"github.com/your-org/note-server/internal/ws"
+	"github.com/your-org/note-server/pkg/response"
+)
+
+// Interval between SSE heartbeat comments, which keep proxies from timing out idle streams
+const defaultSSEHeartbeat = 15 * time.Second
+
+// sseStream writes Server-Sent Events, flushing each one through any wrapping
+// middleware so nothing is held back in a buffer
+type sseStream struct {
+	w          http.ResponseWriter
+	controller *http.ResponseController
+}
+
+// startSSE sends the event stream headers. It fails if the response can't be flushed.
+func startSSE(w http.ResponseWriter) (*sseStream, error) {
+	controller := http.NewResponseController(w)
+
+	// Streams are long-lived; don't let a server write timeout cut them off
+	controller.SetWriteDeadline(time.Time{})
+
+	header := w.Header()
+	header.Set("Content-Type", "text/event-stream")
+	header.Set("Cache-Control", "no-cache")
+	header.Set("Connection", "keep-alive")
+	header.Set("X-Accel-Buffering", "no") // nginx
+
+	w.WriteHeader(http.StatusOK)
+	stream := &sseStream{w: w, controller: controller}
+	return stream, stream.flush()
+}
+
+// event writes one event. An id of 0 omits the id field so the client's
+// Last-Event-ID is left unchanged.
+func (s *sseStream) event(id int64, name string, data any) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event: %v", err)
+	}
+
+	var b strings.Builder
+	if id != 0 {
+		fmt.Fprintf(&b, "id: %d\n", id)
+	}
+	if name != "" {
+		fmt.Fprintf(&b, "event: %s\n", name)
+	}
+	fmt.Fprintf(&b, "data: %s\n\n", payload)
+
+	if _, err := s.w.Write([]byte(b.String())); err != nil {
+		return err
+	}
+	return s.flush()
+}
+
+// comment writes an SSE comment line, used for heartbeats
+func (s *sseStream) comment(text string) error {
+	if _, err := fmt.Fprintf(s.w, ": %s\n\n", text); err != nil {
+		return err
+	}
+	return s.flush()
+}
+
+func (s *sseStream) flush() error {
+	return s.controller.Flush()
+}
+
+// busEvent writes a bus event with the same payload the /ws event channel sends
+func (s *sseStream) busEvent(event events.Event) error {
+	return s.event(event.ID, event.Topic, ws.EventMessage{
+		Type:  ws.MessageTypeEvent,
+		ID:    event.ID,
+		Topic: event.Topic,
+		Time:  event.Time,
+		Data:  event.Data,
+	})
+}
+
+// jobSnapshot writes a job's current state. It has no bus event of its own, so
+// it carries the bus position it is current as of, or no ID when it's 0.
+func (s *sseStream) jobSnapshot(id int64, job jobs.Job) error {
+	return s.event(id, events.TopicJobProgress, ws.EventMessage{
+		Type:  ws.MessageTypeEvent,
+		Topic: events.TopicJobProgress,
+		Time:  job.UpdatedAt,
+		Data:  job,
+	})
+}
+
+// resync tells a resuming client that events were missed and it should refetch state
+func (s *sseStream) resync() error {
+	return s.event(0, "resync", map[string]any{"type": "resync"})
+}
+
+// lastEventID returns the ID a client is resuming from, from the Last-Event-ID
+// header or, for clients that can't set headers, the lastEventId query parameter
+func lastEventID(r *http.Request) (int64, bool) {
+	value := r.Header.Get("Last-Event-ID")
+	if value == "" {
+		value = r.URL.Query().Get("lastEventId")
+	}
+	if value == "" {
+		return 0, false
+	}
+	id, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || id < 0 {
+		return 0, false
+	}
+	return id, true
+}
+
+// StreamEvents handles GET /api/events, a Server-Sent Events fallback for the
+// /ws event channel. Events carry the bus event ID, so a reconnecting
+// EventSource resumes from Last-Event-ID; ?topics=a,b filters topics (default all).
+// Events about a user's recordings and jobs only reach that user and
+// administrators.
+//
+// EventSource can't set headers, so browsers authenticate with the session
+// cookie, which also authenticates its automatic reconnects. Tickets from
+// /api/ws-ticket work as ?ticket= but are single-use: a client using one must
+// close the EventSource when it errors and open a new one with a fresh ticket,
+// passing the last event ID it saw as ?lastEventId=.
+func (h *Handlers) StreamEvents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		response.Error(w, r, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	principal, ok := h.principal(w, r)
+	if !ok {
+		return
+	}
+
+	patterns := []string{"*"}
+	if topics := r.URL.Query().Get("topics"); topics != "" {
+		patterns = strings.Split(topics, ",")
+		for _, pattern := range patterns {
+			if !events.ValidPattern(pattern) {
+				response.Error(w, r, http.StatusBadRequest, fmt.Sprintf("Unknown topic %q", pattern))
+				return
+			}
+		}
+	}
+
+	var sub *events.Subscription
+	var replay []events.Event
+	complete := true
+	if lastID, ok := lastEventID(r); ok {
+		sub, replay, complete = h.events.SubscribeSinceAs(audience(principal), lastID, patterns...)
+	} else {
+		sub = h.events.SubscribeAs(audience(principal), patterns...)
+	}
+	defer sub.Close()
+
+	stream, err := startSSE(w)
+	if err != nil {
+		return
+	}
+
+	if !complete {
+		if err := stream.resync(); err != nil {
+			return
+		}
+	}
+	for _, event := range replay {
+		if err := stream.busEvent(event); err != nil {
+			return
+		}
+	}
+
+	h.pumpSSE(r, stream, sub, func(event events.Event) (bool, error) {
+		return true, stream.busEvent(event)
+	})
+}
+
+// StreamJobEvents handles GET /api/jobs/{id}/events, streaming a job's
+// job.progress events until it finishes. Jobs of other users are not found,
+// except for administrators. Without Last-Event-ID the stream
+// starts with the job's current state. Clients authenticate as for
+// StreamEvents. An EventSource reconnecting after it has seen the job finish
+// gets 204 No Content, which stops it from reconnecting again.
+func (h *Handlers) StreamJobEvents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		response.Error(w, r, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		response.Error(w, r, http.StatusBadRequest, "Job ID is required")
+		return
+	}
+
+	principal, ok := h.principal(w, r)
+	if !ok {
+		return
+	}
+
+	lastID, resuming := lastEventID(r)
+	sub, replay, complete := h.events.SubscribeSinceAs(audience(principal), lastID, events.TopicJobProgress)
+	defer sub.Close()
+
+	// Read the job after subscribing so no update can fall in between
+	job, ok := h.jobs.Get(id)
+	if !ok || !audience(principal).Includes(job.Owner) {
+		response.Error(w, r, http.StatusNotFound, "Job not found")
+		return
+	}
+
+	isJob := func(event events.Event) bool {
+		update, ok := event.Data.(jobs.Job)
+		return ok && update.ID == id
+	}
+
+	if resuming && complete && job.Done() && !slices.ContainsFunc(replay, isJob) {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	stream, err := startSSE(w)
+	if err != nil {
+		return
+	}
+
+	// The final state of a finished job carries an ID, so the reconnect that
+	// follows the end of the stream resumes after it and gets 204
+	var snapshotID int64
+	if job.Done() {
+		snapshotID = h.events.LastID()
+	}
+
+	if !resuming || !complete {
+		if err := stream.jobSnapshot(snapshotID, job); err != nil || job.Done() {
+			return
+		}
+	} else {
+		for _, event := range replay {
+			if !isJob(event) {
+				continue
+			}
+			if err := stream.busEvent(event); err != nil || event.Data.(jobs.Job).Done() {
+				return
+			}
+		}
+		if job.Done() {
+			// Finished after the replayed events; report the final state
+			stream.jobSnapshot(snapshotID, job)
+			return
+		}
+	}
+
+	h.pumpSSE(r, stream, sub, func(event events.Event) (bool, error) {
+		if !isJob(event) {
+			return true, nil
+		}
+		return !event.Data.(jobs.Job).Done(), stream.busEvent(event)
+	})
+}
+
+// pumpSSE delivers subscription events through send, with heartbeats, until the
+// client goes away, a write fails or send reports the stream is finished
+func (h *Handlers) pumpSSE(r *http.Request, stream *sseStream, sub *events.Subscription, send func(events.Event) (bool, error)) {
+	heartbeat := time.NewTicker(h.sseHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+
+		case event, ok := <-sub.C:
+			if !ok {
+				return
+			}
+			more, err := send(event)
+			if err != nil || !more {
+				return
+			}
+
+		case <-heartbeat.C:
+			if err := stream.comment("heartbeat"); err != nil {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,310 @@
+package http
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/your-org/note-server/internal/auth"
+	"github.com/your-org/note-server/internal/database"
+	"github.com/your-org/note-server/internal/events"
+	"github.com/your-org/note-server/internal/jobs"
+	"github.com/your-org/note-server/internal/service"
+	"github.com/your-org/note-server/internal/ws"
+)
+
+// sseEvent is one parsed Server-Sent Event
+type sseEvent struct {
+	ID   string
+	Name string
+	Data ws.EventMessage
+}
+
+// sseClient reads events from a streaming response
+type sseClient struct {
+	t        *testing.T
+	resp     *http.Response
+	scanner  *bufio.Scanner
+	comments int
+}
+
+// newSSETestServer serves the full router, middleware included, with a short heartbeat
+func newSSETestServer(t *testing.T) (*httptest.Server, *Handlers, *events.Bus) {
+	t.Helper()
+	bus := events.NewBusWithHistory(4)
+	handlers := NewHandlers()
+	handlers.SetEvents(bus, nil)
+	handlers.sseHeartbeat = 20 * time.Millisecond
+
+	hub := ws.NewTranscribeHub(service.NewTranscribeServiceWithTranscriber(&MockTranscriber{}))
+	server := httptest.NewServer(NewRouterWithHandlers(hub, handlers))
+	t.Cleanup(server.Close)
+	return server, handlers, bus
+}
+
+// openSSE starts a stream and checks its headers
+func openSSE(t *testing.T, ctx context.Context, url string, lastEventID string) *sseClient {
+	t.Helper()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("failed to open event stream: %v", err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, resp.StatusCode)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("expected text/event-stream, got %q", ct)
+	}
+	return &sseClient{t: t, resp: resp, scanner: bufio.NewScanner(resp.Body)}
+}
+
+// next returns the next event, counting heartbeat comments on the way
+func (c *sseClient) next() (sseEvent, bool) {
+	c.t.Helper()
+	var event sseEvent
+	var data string
+	for c.scanner.Scan() {
+		line := c.scanner.Text()
+		switch {
+		case line == "":
+			if data == "" {
+				continue
+			}
+			if err := json.Unmarshal([]byte(data), &event.Data); err != nil {
+				c.t.Fatalf("invalid event data %q: %v", data, err)
+			}
+			return event, true
+		case strings.HasPrefix(line, ":"):
+			c.comments++
+		case strings.HasPrefix(line, "id: "):
+			event.ID = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "event: "):
+			event.Name = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			data = strings.TrimPrefix(line, "data: ")
+		}
+	}
+	return event, false
+}
+
+func TestStreamEvents(t *testing.T) {
+	server, _, bus := newSSETestServer(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	client := openSSE(t, ctx, server.URL+"/api/events?topics=recording.created", "")
+
+	// Events published after connecting arrive flushed, despite the middleware
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		bus.Publish(events.TopicJobProgress, nil)
+		bus.Publish(events.TopicRecordingCreated, events.RecordingCreated{RecordingID: 7})
+	}()
+
+	event, ok := client.next()
+	if !ok {
+		t.Fatal("stream ended before an event arrived")
+	}
+	if event.Name != events.TopicRecordingCreated || event.ID != "2" || event.Data.Type != ws.MessageTypeEvent || event.Data.ID != 2 {
+		t.Errorf("unexpected event: %+v", event)
+	}
+	if data, _ := event.Data.Data.(map[string]any); data["recordingId"] != float64(7) {
+		t.Errorf("expected the same payload as the WebSocket channel, got %+v", event.Data)
+	}
+	if client.comments == 0 {
+		t.Error("expected heartbeat comments while idle")
+	}
+}
+
+func TestStreamEvents_Resume(t *testing.T) {
+	server, _, bus := newSSETestServer(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	for i := 0; i < 3; i++ {
+		bus.Publish(events.TopicNoteUpdated, events.NoteUpdated{NoteID: int64(i)})
+	}
+
+	t.Run("replays events after Last-Event-ID", func(t *testing.T) {
+		client := openSSE(t, ctx, server.URL+"/api/events", "1")
+		for _, want := range []string{"2", "3"} {
+			event, ok := client.next()
+			if !ok || event.ID != want {
+				t.Fatalf("expected replayed event %s, got %+v", want, event)
+			}
+		}
+	})
+
+	// The history holds 4 events, so event 2 is forgotten after 3 more
+	for i := 0; i < 3; i++ {
+		bus.Publish(events.TopicNoteUpdated, nil)
+	}
+
+	t.Run("asks to resync after a gap", func(t *testing.T) {
+		client := openSSE(t, ctx, server.URL+"/api/events", "1")
+		event, ok := client.next()
+		if !ok || event.Name != "resync" {
+			t.Fatalf("expected resync, got %+v", event)
+		}
+		if event, ok := client.next(); !ok || event.ID != "3" {
+			t.Errorf("expected oldest remembered event 3, got %+v", event)
+		}
+	})
+
+	t.Run("rejects unknown topics", func(t *testing.T) {
+		resp, err := http.Get(server.URL + "/api/events?topics=bogus")
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("expected status %d, got %d", http.StatusBadRequest, resp.StatusCode)
+		}
+	})
+}
+
+func TestStreamJobEvents(t *testing.T) {
+	server, handlers, _ := newSSETestServer(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	release := make(chan struct{})
+	job := handlers.jobs.Submit(context.Background(), 0, "test", func(ctx context.Context, progress jobs.ProgressFunc) (any, error) {
+		<-release
+		progress(0.5, "Halfway")
+		return "done", nil
+	})
+
+	client := openSSE(t, ctx, server.URL+"/api/jobs/"+job.ID+"/events", "")
+	close(release)
+
+	// Current state first, then updates until the job completes and the stream ends
+	var statuses []string
+	var lastID string
+	for {
+		event, ok := client.next()
+		if !ok {
+			break
+		}
+		if event.ID != "" {
+			lastID = event.ID
+		}
+		data, _ := json.Marshal(event.Data.Data)
+		var update jobs.Job
+		json.Unmarshal(data, &update)
+		if update.ID != job.ID {
+			t.Errorf("expected events for job %s only, got %+v", job.ID, update)
+		}
+		statuses = append(statuses, update.Status)
+	}
+
+	if len(statuses) < 2 || statuses[len(statuses)-1] != jobs.StatusCompleted {
+		t.Errorf("expected stream to end on completion, got %v", statuses)
+	}
+
+	// EventSource reconnects when the stream ends; once it has seen the job
+	// finish it's told to stop
+	reconnect := func(t *testing.T, lastEventID string) {
+		t.Helper()
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/api/jobs/"+job.ID+"/events", nil)
+		req.Header.Set("Last-Event-ID", lastEventID)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusNoContent {
+			t.Errorf("expected status %d on reconnect, got %d", http.StatusNoContent, resp.StatusCode)
+		}
+	}
+
+	t.Run("reconnect after completion", func(t *testing.T) {
+		if lastID == "" {
+			t.Fatal("expected the completion event to carry an ID")
+		}
+		reconnect(t, lastID)
+	})
+
+	t.Run("finished job", func(t *testing.T) {
+		client := openSSE(t, ctx, server.URL+"/api/jobs/"+job.ID+"/events", "")
+		event, ok := client.next()
+		if !ok || !strings.Contains(event.Data.Topic, "job.progress") {
+			t.Fatalf("expected final job state, got %+v", event)
+		}
+		if _, ok := client.next(); ok {
+			t.Error("expected stream to end after a finished job's state")
+		}
+		if event.ID == "" {
+			t.Fatal("expected a finished job's state to carry an ID")
+		}
+		reconnect(t, event.ID)
+	})
+
+	t.Run("unknown job", func(t *testing.T) {
+		resp, err := http.Get(server.URL + "/api/jobs/missing/events")
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusNotFound {
+			t.Errorf("expected status %d, got %d", http.StatusNotFound, resp.StatusCode)
+		}
+	})
+}
+
+func TestJobOwnership(t *testing.T) {
+	if err := database.InitDB(filepath.Join(t.TempDir(), "test.db")); err != nil {
+		t.Fatalf("failed to initialize database: %v", err)
+	}
+
+	handlers := createHandlersWithMocks(&MockTranscriber{}, &MockSummarizer{})
+	handlers.SetAuthenticator(auth.New(auth.Options{Sessions: database.NewSessionStore()}))
+	router := NewRouterWithHandlers(createMockTranscribeHub(), handlers)
+
+	sendJSON(router, http.MethodPost, "/api/auth/register", Credentials{Username: "alice", Password: "alice-password"}, "", nil)
+	aliceToken, _ := loginAs(t, router, "alice", "alice-password")
+	sendJSON(router, http.MethodPost, "/api/auth/register", Credentials{Username: "bob", Password: "bob-password"}, aliceToken, nil)
+	bobToken, _ := loginAs(t, router, "bob", "bob-password")
+	sendJSON(router, http.MethodPost, "/api/auth/register", Credentials{Username: "carol", Password: "carol-password"}, aliceToken, nil)
+	carolToken, _ := loginAs(t, router, "carol", "carol-password")
+
+	// bob's job is hidden from carol, but not from alice, the administrator
+	bob, _ := database.GetUserByUsername("bob")
+	job := handlers.jobs.Submit(context.Background(), bob.ID, "test", func(ctx context.Context, progress jobs.ProgressFunc) (any, error) {
+		return nil, nil
+	})
+
+	tests := []struct {
+		name           string
+		path           string
+		token          string
+		expectedStatus int
+	}{
+		{"owner", "/api/jobs/" + job.ID, bobToken, http.StatusOK},
+		{"administrator", "/api/jobs/" + job.ID, aliceToken, http.StatusOK},
+		{"other user", "/api/jobs/" + job.ID, carolToken, http.StatusNotFound},
+		{"other user's events", "/api/jobs/" + job.ID + "/events", carolToken, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if w := sendJSON(router, http.MethodGet, tt.path, nil, tt.token, nil); w.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+		})
+	}
+}
@@ -2,20 +2,33 @@ package http
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"net/netip"
 	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog"
+	"github.com/your-org/note-server/internal/auth"
 	"github.com/your-org/note-server/internal/config"
 	"github.com/your-org/note-server/internal/database"
+	"github.com/your-org/note-server/internal/events"
+	"github.com/your-org/note-server/internal/health"
+	"github.com/your-org/note-server/internal/jobs"
+	"github.com/your-org/note-server/internal/ratelimit"
 	"github.com/your-org/note-server/internal/service"
-	"github.com/your-org/note-server/internal/util"
+	"github.com/your-org/note-server/internal/tracing"
+	"github.com/your-org/note-server/pkg/response"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // Handlers holds the service dependencies
@@ -23,30 +36,87 @@ type Handlers struct {
 	transcribeService *service.TranscribeService
 	summarizeService  *service.SummarizeService
 	configManager     *config.ConfigManager
+	authenticator     *auth.Authenticator
+	events            *events.Bus
+	jobs              *jobs.Manager
+	health            *health.Checker
+	sseHeartbeat      time.Duration
+	validateAPI       bool
+
+	// Where uploaded recordings are stored
+	recordingsDir string
+
+	// Reverse proxies trusted to report the client's address; see RealIP
+	trustedProxies []netip.Prefix
+
+	// Limits on the routes that spend provider credits; nil is unlimited
+	limiter *ratelimit.Limiter
+	meter   *ratelimit.Meter
 }
 
 // NewHandlers creates a new handlers instance
 func NewHandlers() *Handlers {
-	return &Handlers{
+	h := &Handlers{
 		transcribeService: service.NewTranscribeService(),
 		summarizeService:  service.NewSummarizeService(),
 		configManager:     config.GetManager(),
+		authenticator:     auth.New(auth.Options{}),
+		health:            health.NewChecker(defaultHealthCheckTimeout, health.Database()),
+		sseHeartbeat:      defaultSSEHeartbeat,
+		recordingsDir:     filepath.Join(os.TempDir(), "recordings"),
 	}
+	h.SetEvents(events.NewBus(), nil)
+	return h
 }
 
-// NewHandlersWithServices creates handlers with injected services for testing
+// NewHandlersWithServices creates handlers with the given services, such as the
+// transcription service the WebSocket hub uses, or mocks in tests
 func NewHandlersWithServices(transcribeService *service.TranscribeService, summarizeService *service.SummarizeService) *Handlers {
-	return &Handlers{
+	h := &Handlers{
 		transcribeService: transcribeService,
 		summarizeService:  summarizeService,
 		configManager:     config.GetManager(),
+		authenticator:     auth.New(auth.Options{}),
+		health:            health.NewChecker(defaultHealthCheckTimeout, health.Database()),
+		sseHeartbeat:      defaultSSEHeartbeat,
+		recordingsDir:     filepath.Join(os.TempDir(), "recordings"),
 	}
+	h.SetEvents(events.NewBus(), nil)
+	return h
 }
 
-// HealthHandler responds with the server's health status
+// SetRecordingsDir sets the directory uploaded recordings are stored in. The
+// server keeps them with other media, in MEDIA_DIR/recordings.
+func (h *Handlers) SetRecordingsDir(dir string) {
+	h.recordingsDir = dir
+}
+
+// SetAuthenticator replaces the authenticator used to identify callers and issue
+// WebSocket tickets. It must be the one the WebSocket hub verifies tickets with.
+func (h *Handlers) SetAuthenticator(authenticator *auth.Authenticator) {
+	h.authenticator = authenticator
+}
+
+// SetConfigManager replaces the manager of the JSON application configuration
+func (h *Handlers) SetConfigManager(configManager *config.ConfigManager) {
+	h.configManager = configManager
+}
+
+// SetEvents replaces the bus handlers publish events on and the manager that
+// runs background jobs. A nil manager gets a new one publishing to bus.
+func (h *Handlers) SetEvents(bus *events.Bus, jobManager *jobs.Manager) {
+	if jobManager == nil {
+		jobManager = jobs.NewManager(bus)
+	}
+	h.events = bus
+	h.jobs = jobManager
+}
+
+// HealthHandler reports that the process is alive. It checks no dependencies;
+// see ReadyHandler.
 func (h *Handlers) HealthHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		util.WriteJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		response.Error(w, r, http.StatusMethodNotAllowed, "Method not allowed")
 		return
 	}
 	w.WriteHeader(http.StatusOK)
@@ -56,21 +126,26 @@ func (h *Handlers) HealthHandler(w http.ResponseWriter, r *http.Request) {
 // TranscribeHandler handles requests for transcription
 func (h *Handlers) TranscribeHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		util.WriteJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		response.Error(w, r, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	key := client(r)
+	if !h.checkQuota(w, r, key, ratelimit.TranscriptionMinutes) {
 		return
 	}
 
 	// Parse multipart form
 	err := r.ParseMultipartForm(32 << 20) // 32 MB max
 	if err != nil {
-		util.WriteJSONError(w, http.StatusBadRequest, "Failed to parse multipart form")
+		response.Error(w, r, http.StatusBadRequest, "Failed to parse multipart form")
 		return
 	}
 
 	// Get the file from form
 	file, header, err := r.FormFile("file")
 	if err != nil {
-		util.WriteJSONError(w, http.StatusBadRequest, "No file provided")
+		response.Error(w, r, http.StatusBadRequest, "No file provided")
 		return
 	}
 	defer file.Close()
@@ -79,7 +154,7 @@ func (h *Handlers) TranscribeHandler(w http.ResponseWriter, r *http.Request) {
 	tmpDir := os.TempDir()
 	tmpFile, err := os.CreateTemp(tmpDir, "audio_*"+filepath.Ext(header.Filename))
 	if err != nil {
-		util.WriteJSONError(w, http.StatusInternalServerError, "Failed to create temporary file")
+		response.Error(w, r, http.StatusInternalServerError, "Failed to create temporary file")
 		return
 	}
 	defer os.Remove(tmpFile.Name())
@@ -88,7 +163,7 @@ func (h *Handlers) TranscribeHandler(w http.ResponseWriter, r *http.Request) {
 	// Copy uploaded file to temporary file
 	_, err = io.Copy(tmpFile, file)
 	if err != nil {
-		util.WriteJSONError(w, http.StatusInternalServerError, "Failed to save file")
+		response.Error(w, r, http.StatusInternalServerError, "Failed to save file")
 		return
 	}
 
@@ -96,7 +171,7 @@ func (h *Handlers) TranscribeHandler(w http.ResponseWriter, r *http.Request) {
 	tmpFile.Seek(0, 0)
 	audioData, err := io.ReadAll(tmpFile)
 	if err != nil {
-		util.WriteJSONError(w, http.StatusInternalServerError, "Failed to read file data")
+		response.Error(w, r, http.StatusInternalServerError, "Failed to read file data")
 		return
 	}
 
@@ -104,10 +179,14 @@ func (h *Handlers) TranscribeHandler(w http.ResponseWriter, r *http.Request) {
 	startTime := time.Now()
 
 	// Call transcription service
-	ctx := context.Background()
+	// The request's trace, logger and ID carry into the service, but a client
+	// that goes away doesn't cancel work the provider is already billing for
+	usage := &service.Usage{}
+	ctx := service.WithUsage(context.WithoutCancel(r.Context()), usage)
 	text, err := h.transcribeService.TranscribeAudio(ctx, audioData)
+	h.recordUsage(r.Context(), key, usage)
 	if err != nil {
-		util.WriteJSONError(w, http.StatusInternalServerError, fmt.Sprintf("Transcription failed: %v", err))
+		response.Error(w, r, http.StatusInternalServerError, fmt.Sprintf("Transcription failed: %v", err))
 		return
 	}
 
@@ -115,12 +194,12 @@ func (h *Handlers) TranscribeHandler(w http.ResponseWriter, r *http.Request) {
 	durationMs := time.Since(startTime).Milliseconds()
 
 	// Prepare response
-	response := map[string]any{
+	data := map[string]any{
 		"text":        text,
 		"duration_ms": durationMs,
 	}
 
-	util.WriteJSONSuccess(w, response)
+	response.OK(w, r, data)
 }
 
 // SummarizeRequest represents the request body for summarization
@@ -131,7 +210,7 @@ type SummarizeRequest struct {
 // SummarizeHandler handles requests for summarization
 func (h *Handlers) SummarizeHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		util.WriteJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		response.Error(w, r, http.StatusMethodNotAllowed, "Method not allowed")
 		return
 	}
 
@@ -139,241 +218,354 @@ func (h *Handlers) SummarizeHandler(w http.ResponseWriter, r *http.Request) {
 	var req SummarizeRequest
 	err := json.NewDecoder(r.Body).Decode(&req)
 	if err != nil {
-		util.WriteJSONError(w, http.StatusBadRequest, "Invalid JSON body")
+		response.Error(w, r, http.StatusBadRequest, "Invalid JSON body")
 		return
 	}
 
 	if req.Text == "" {
-		util.WriteJSONError(w, http.StatusBadRequest, "Text field is required")
+		response.Invalid(w, r, "Text field is required", response.FieldError{Field: "text", Message: "is required"})
+		return
+	}
+
+	key := client(r)
+	if !h.checkQuota(w, r, key, ratelimit.SummaryTokens) {
 		return
 	}
 
 	// Call summarization service
-	ctx := context.Background()
+	// Continue the request's trace without being cancelled with it, as above
+	usage := &service.Usage{}
+	ctx := service.WithUsage(context.WithoutCancel(r.Context()), usage)
 	summary, err := h.summarizeService.SummarizeText(ctx, req.Text)
+	h.recordUsage(r.Context(), key, usage)
 	if err != nil {
-		util.WriteJSONError(w, http.StatusInternalServerError, fmt.Sprintf("Summarization failed: %v", err))
+		response.Error(w, r, http.StatusInternalServerError, fmt.Sprintf("Summarization failed: %v", err))
 		return
 	}
 
 	// Prepare response
-	response := map[string]any{
+	data := map[string]any{
 		"summary": summary,
 	}
 
-	util.WriteJSONSuccess(w, response)
+	response.OK(w, r, data)
 }
 
 // GetNotes handles GET /api/notes requests
 func (h *Handlers) GetNotes(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		util.WriteJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		response.Error(w, r, http.StatusMethodNotAllowed, "Method not allowed")
 		return
 	}
 
-	// TODO: Implement database query for notes
-	response := map[string]any{
-		"success": true,
-		"notes":   []any{}, // Empty for now
+	principal, ok := h.principal(w, r)
+	if !ok {
+		return
+	}
+
+	notes, err := database.GetNotes(owner(principal))
+	if err != nil {
+		response.Error(w, r, http.StatusInternalServerError, fmt.Sprintf("Failed to get notes: %v", err))
+		return
 	}
 
-	util.WriteJSONSuccess(w, response)
+	data := map[string]any{
+		"notes": notes,
+	}
+
+	response.OK(w, r, data)
 }
 
 // CreateNote handles POST /api/notes requests
 func (h *Handlers) CreateNote(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		util.WriteJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		response.Error(w, r, http.StatusMethodNotAllowed, "Method not allowed")
 		return
 	}
 
 	// TODO: Implement note creation
-	response := map[string]any{
-		"success": true,
-		"message": "Note creation not yet implemented",
-	}
-
-	util.WriteJSONSuccess(w, response)
+	response.Write(w, r, http.StatusOK, response.Envelope{
+		Success: true,
+		Message: "Note creation not yet implemented",
+	})
 }
 
 // GetMeetings handles GET /api/meetings requests
 func (h *Handlers) GetMeetings(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		util.WriteJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		response.Error(w, r, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	principal, ok := h.principal(w, r)
+	if !ok {
 		return
 	}
 
-	// TODO: Implement database query for meetings
-	response := map[string]any{
-		"success":  true,
-		"meetings": []any{}, // Empty for now
+	meetings, err := database.GetMeetings(owner(principal))
+	if err != nil {
+		response.Error(w, r, http.StatusInternalServerError, fmt.Sprintf("Failed to get meetings: %v", err))
+		return
 	}
 
-	util.WriteJSONSuccess(w, response)
+	data := map[string]any{
+		"meetings": meetings,
+	}
+
+	response.OK(w, r, data)
 }
 
 // GetMeeting handles GET /api/meetings/{id} requests
 func (h *Handlers) GetMeeting(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		util.WriteJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		response.Error(w, r, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		response.Error(w, r, http.StatusBadRequest, "Invalid meeting ID")
+		return
+	}
+
+	principal, ok := h.principal(w, r)
+	if !ok {
 		return
 	}
 
-	// TODO: Extract ID from URL and query database
-	util.WriteJSONError(w, http.StatusNotFound, "Meeting not found")
+	meeting, err := database.GetMeeting(id, owner(principal))
+	if err != nil {
+		response.Error(w, r, http.StatusInternalServerError, fmt.Sprintf("Failed to get meeting: %v", err))
+		return
+	}
+
+	if meeting == nil {
+		response.Error(w, r, http.StatusNotFound, "Meeting not found")
+		return
+	}
+
+	data := map[string]any{
+		"meeting": meeting,
+	}
+
+	response.OK(w, r, data)
 }
 
 // GetInterviews handles GET /api/interviews requests
 func (h *Handlers) GetInterviews(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		util.WriteJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		response.Error(w, r, http.StatusMethodNotAllowed, "Method not allowed")
 		return
 	}
 
-	// TODO: Implement database query for interviews
-	response := map[string]any{
-		"success":    true,
-		"interviews": []any{}, // Empty for now
+	principal, ok := h.principal(w, r)
+	if !ok {
+		return
 	}
 
-	util.WriteJSONSuccess(w, response)
+	interviews, err := database.GetInterviews(owner(principal))
+	if err != nil {
+		response.Error(w, r, http.StatusInternalServerError, fmt.Sprintf("Failed to get interviews: %v", err))
+		return
+	}
+
+	data := map[string]any{
+		"interviews": interviews,
+	}
+
+	response.OK(w, r, data)
 }
 
 // GetRecordings handles GET /api/recordings requests
 func (h *Handlers) GetRecordings(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		util.WriteJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		response.Error(w, r, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	principal, ok := h.principal(w, r)
+	if !ok {
 		return
 	}
 
-	// Query recordings from database
-	recordings, err := database.GetRecordings()
+	// Query the caller's recordings from database
+	recordings, err := database.GetRecordings(r.Context(), owner(principal))
 	if err != nil {
-		util.WriteJSONError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to get recordings: %v", err))
+		response.Error(w, r, http.StatusInternalServerError, fmt.Sprintf("Failed to get recordings: %v", err))
 		return
 	}
 
-	response := map[string]any{
-		"success":    true,
+	data := map[string]any{
 		"recordings": recordings,
 	}
 
-	// Write response directly without extra wrapping
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(response)
+	response.OK(w, r, data)
 }
 
 // GetRecording handles GET /api/recordings/{id} requests
 func (h *Handlers) GetRecording(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		util.WriteJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		response.Error(w, r, http.StatusMethodNotAllowed, "Method not allowed")
 		return
 	}
 
-	// Extract ID from URL path
-	path := r.URL.Path
-	idStr := ""
-	if len(path) > len("/api/recordings/") {
-		idStr = path[len("/api/recordings/"):]
-		// Remove any trailing path segments (like /audio)
-		if slashIndex := strings.Index(idStr, "/"); slashIndex != -1 {
-			idStr = idStr[:slashIndex]
-		}
-	}
+	idStr := chi.URLParam(r, "id")
 
 	if idStr == "" {
-		util.WriteJSONError(w, http.StatusBadRequest, "Recording ID is required")
+		response.Error(w, r, http.StatusBadRequest, "Recording ID is required")
 		return
 	}
 
 	// Parse ID to integer
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		util.WriteJSONError(w, http.StatusBadRequest, "Invalid recording ID")
+		response.Error(w, r, http.StatusBadRequest, "Invalid recording ID")
+		return
+	}
+
+	principal, ok := h.principal(w, r)
+	if !ok {
 		return
 	}
 
 	// Query recording from database
-	recording, err := database.GetRecording(id)
+	recording, err := database.GetRecording(r.Context(), id, owner(principal))
 	if err != nil {
-		util.WriteJSONError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to get recording: %v", err))
+		response.Error(w, r, http.StatusInternalServerError, fmt.Sprintf("Failed to get recording: %v", err))
 		return
 	}
 
 	if recording == nil {
-		util.WriteJSONError(w, http.StatusNotFound, "Recording not found")
+		response.Error(w, r, http.StatusNotFound, "Recording not found")
 		return
 	}
 
-	response := map[string]any{
-		"success":   true,
+	data := map[string]any{
 		"recording": recording,
 	}
 
-	// Write response directly without extra wrapping
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(response)
+	response.OK(w, r, data)
 }
 
 // GetRecordingAudio handles GET /api/recordings/{id}/audio requests
 func (h *Handlers) GetRecordingAudio(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		util.WriteJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		response.Error(w, r, http.StatusMethodNotAllowed, "Method not allowed")
 		return
 	}
 
-	// Extract ID from URL path
-	path := r.URL.Path
-	idStr := ""
-	if len(path) > len("/api/recordings/") {
-		idStr = path[len("/api/recordings/"):]
-		// Remove the /audio suffix
-		if strings.HasSuffix(idStr, "/audio") {
-			idStr = idStr[:len(idStr)-6]
-		}
-	}
+	idStr := chi.URLParam(r, "id")
 
 	if idStr == "" {
-		util.WriteJSONError(w, http.StatusBadRequest, "Recording ID is required")
+		response.Error(w, r, http.StatusBadRequest, "Recording ID is required")
 		return
 	}
 
 	// Parse ID to integer
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		util.WriteJSONError(w, http.StatusBadRequest, "Invalid recording ID")
+		response.Error(w, r, http.StatusBadRequest, "Invalid recording ID")
+		return
+	}
+
+	principal, ok := h.principal(w, r)
+	if !ok {
 		return
 	}
 
 	// Query recording from database to get file path
-	recording, err := database.GetRecording(id)
+	recording, err := database.GetRecording(r.Context(), id, owner(principal))
 	if err != nil {
-		util.WriteJSONError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to get recording: %v", err))
+		response.Error(w, r, http.StatusInternalServerError, fmt.Sprintf("Failed to get recording: %v", err))
 		return
 	}
 
 	if recording == nil {
-		util.WriteJSONError(w, http.StatusNotFound, "Recording not found")
+		response.Error(w, r, http.StatusNotFound, "Recording not found")
+		return
+	}
+
+	serveRecordingAudio(w, r, recording)
+}
+
+// GetRecordingTranscript handles GET /api/recordings/{id}/transcript requests
+func (h *Handlers) GetRecordingTranscript(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		response.Error(w, r, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	idStr := chi.URLParam(r, "id")
+
+	if idStr == "" {
+		response.Error(w, r, http.StatusBadRequest, "Recording ID is required")
+		return
+	}
+
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		response.Error(w, r, http.StatusBadRequest, "Invalid recording ID")
+		return
+	}
+
+	principal, ok := h.principal(w, r)
+	if !ok {
+		return
+	}
+
+	recording, err := database.GetRecording(r.Context(), id, owner(principal))
+	if err != nil {
+		response.Error(w, r, http.StatusInternalServerError, fmt.Sprintf("Failed to get recording: %v", err))
+		return
+	}
+
+	if recording == nil {
+		response.Error(w, r, http.StatusNotFound, "Recording not found")
+		return
+	}
+
+	writeTranscript(w, r, id)
+}
+
+// writeTranscript responds with a recording's transcript segments and full text
+func writeTranscript(w http.ResponseWriter, r *http.Request, id int) {
+	segments, err := database.GetTranscriptSegments(r.Context(), id)
+	if err != nil {
+		response.Error(w, r, http.StatusInternalServerError, fmt.Sprintf("Failed to get transcript: %v", err))
 		return
 	}
 
+	texts := make([]string, 0, len(segments))
+	for _, segment := range segments {
+		texts = append(texts, segment.Text)
+	}
+
+	data := map[string]any{
+		"recordingId": id,
+		"segments":    segments,
+		"text":        strings.Join(texts, " "),
+	}
+
+	response.OK(w, r, data)
+}
+
+// serveRecordingAudio streams a recording's audio file
+func serveRecordingAudio(w http.ResponseWriter, r *http.Request, recording map[string]any) {
 	// For now, since we don't have actual file storage, return a mock response
 	// In a real implementation, you would:
 	// 1. Get the file_path from the recording
 	// 2. Open and stream the audio file
 	// 3. Set appropriate headers (Content-Type, Content-Length, etc.)
-	
+
 	filePath, ok := recording["file_path"].(string)
 	if !ok {
-		util.WriteJSONError(w, http.StatusInternalServerError, "Invalid file path")
+		response.Error(w, r, http.StatusInternalServerError, "Invalid file path")
 		return
 	}
 
 	// Check if file exists
 	if _, err := os.Stat(filePath); os.IsNotExist(err) {
-		util.WriteJSONError(w, http.StatusNotFound, "Audio file not found")
+		response.Error(w, r, http.StatusNotFound, "Audio file not found")
 		return
 	}
 
@@ -402,7 +594,7 @@ func (h *Handlers) GetRecordingAudio(w http.ResponseWriter, r *http.Request) {
 	// For development: Check if file contains actual audio data or just text
 	fileInfo, err := os.Stat(filePath)
 	if err != nil {
-		util.WriteJSONError(w, http.StatusInternalServerError, "Could not read file info")
+		response.Error(w, r, http.StatusInternalServerError, "Could not read file info")
 		return
 	}
 
@@ -412,6 +604,7 @@ func (h *Handlers) GetRecordingAudio(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", contentType)
 		w.Header().Set("Content-Disposition", fmt.Sprintf("inline; filename=\"%s\"", filename))
 		w.Header().Set("Content-Length", "0")
+		w.Header().Set("Cache-Control", "private, no-store")
 		w.WriteHeader(http.StatusOK)
 		// Don't write any content - the audio player will handle the empty stream gracefully
 		return
@@ -421,11 +614,9 @@ func (h *Handlers) GetRecordingAudio(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", contentType)
 	w.Header().Set("Content-Disposition", fmt.Sprintf("inline; filename=\"%s\"", filename))
 	w.Header().Set("Accept-Ranges", "bytes")
-	w.Header().Set("Cache-Control", "public, max-age=3600")
-	// Add CORS headers for audio playback
-	w.Header().Set("Access-Control-Allow-Origin", "*")
-	w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
-	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Range")
+	// Recordings are private or shared by links that can be revoked, so
+	// neither the browser nor a shared cache may keep a copy
+	w.Header().Set("Cache-Control", "private, no-store")
 
 	// Serve the file
 	http.ServeFile(w, r, filePath)
@@ -434,21 +625,38 @@ func (h *Handlers) GetRecordingAudio(w http.ResponseWriter, r *http.Request) {
 // UploadRecording handles POST /api/upload-recording requests
 func (h *Handlers) UploadRecording(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		util.WriteJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		response.Error(w, r, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	principal, ok := h.principal(w, r)
+	if !ok {
 		return
 	}
 
-	// Parse multipart form (32MB max)
+	// Parse multipart form (32MB max); the span shows how long the upload took
+	// to arrive
+	_, span := tracing.Start(r.Context(), "receive upload", attribute.Int64("http.request.body.size", r.ContentLength))
 	err := r.ParseMultipartForm(32 << 20)
+	tracing.End(span, err)
 	if err != nil {
-		util.WriteJSONError(w, http.StatusBadRequest, "Failed to parse multipart form")
+		response.Error(w, r, http.StatusBadRequest, "Failed to parse multipart form")
+		return
+	}
+
+	transcribe, _ := strconv.ParseBool(r.FormValue("transcribe"))
+	if transcribe && !principal.Can(auth.ScopeTranscribe) {
+		response.Error(w, r, http.StatusForbidden, "Token lacks the transcribe scope")
+		return
+	}
+	if transcribe && !h.checkQuota(w, r, client(r), ratelimit.TranscriptionMinutes) {
 		return
 	}
 
 	// Get the audio file from the form
 	file, header, err := r.FormFile("audio")
 	if err != nil {
-		util.WriteJSONError(w, http.StatusBadRequest, "No audio file provided")
+		response.Error(w, r, http.StatusBadRequest, "No audio file provided")
 		return
 	}
 	defer file.Close()
@@ -479,9 +687,10 @@ func (h *Handlers) UploadRecording(w http.ResponseWriter, r *http.Request) {
 		endTime = time.Now()
 	}
 
-	// Generate filename with timestamp
+	// Every user's uploads share the directory, so the timestamp is followed by
+	// a random suffix that keeps uploads in the same second apart
 	timestamp := time.Now().Format("2006-01-02_15-04-05")
-	filename := fmt.Sprintf("recording_%s.webm", timestamp)
+	filename := fmt.Sprintf("recording_%s_%s.webm", timestamp, randomSuffix())
 
 	// Calculate duration
 	durationMs := endTime.Sub(startTime).Milliseconds()
@@ -491,19 +700,19 @@ func (h *Handlers) UploadRecording(w http.ResponseWriter, r *http.Request) {
 	durationSeconds := int(durationMs / 1000)
 
 	// Create recordings directory if it doesn't exist
-	recordingsDir := "/tmp/recordings"
+	recordingsDir := h.recordingsDir
 	if err := os.MkdirAll(recordingsDir, 0755); err != nil {
-		util.WriteJSONError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to create recordings directory: %v", err))
+		response.Error(w, r, http.StatusInternalServerError, fmt.Sprintf("Failed to create recordings directory: %v", err))
 		return
 	}
 
 	// Create file path
 	filePath := filepath.Join(recordingsDir, filename)
 
-	// Save the uploaded file to disk
-	outFile, err := os.Create(filePath)
+	// Save the uploaded file to disk, never over another recording
+	outFile, err := os.OpenFile(filePath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
 	if err != nil {
-		util.WriteJSONError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to create file: %v", err))
+		response.Error(w, r, http.StatusInternalServerError, fmt.Sprintf("Failed to create file: %v", err))
 		return
 	}
 	defer outFile.Close()
@@ -514,12 +723,14 @@ func (h *Handlers) UploadRecording(w http.ResponseWriter, r *http.Request) {
 	// Copy the uploaded file content to disk
 	_, err = io.Copy(outFile, file)
 	if err != nil {
-		util.WriteJSONError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to save file: %v", err))
+		response.Error(w, r, http.StatusInternalServerError, fmt.Sprintf("Failed to save file: %v", err))
 		return
 	}
 
 	// Save recording metadata to database
 	recordingID, err := database.AddRecording(
+		r.Context(),
+		principal.UserID,
 		filename,
 		filePath,
 		startTime,
@@ -533,12 +744,17 @@ func (h *Handlers) UploadRecording(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		// Clean up the file if database save fails
 		os.Remove(filePath)
-		util.WriteJSONError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to save recording metadata: %v", err))
+		response.Error(w, r, http.StatusInternalServerError, fmt.Sprintf("Failed to save recording metadata: %v", err))
 		return
 	}
 
-	response := map[string]any{
-		"success":     true,
+	h.events.PublishFor(principal.UserID, events.TopicRecordingCreated, events.RecordingCreated{
+		RecordingID: recordingID,
+		Filename:    filename,
+		Source:      "upload",
+	})
+
+	data := map[string]any{
 		"filename":    filename,
 		"recordingId": recordingID,
 		"size":        header.Size,
@@ -546,80 +762,264 @@ func (h *Handlers) UploadRecording(w http.ResponseWriter, r *http.Request) {
 		"message":     "Recording metadata saved to database",
 	}
 
-	util.WriteJSONSuccess(w, response)
+	// Optionally transcribe in the background; progress is published as job.progress
+	if transcribe {
+		logger := zerolog.Ctx(r.Context()).With().Int64("recording_id", recordingID).Logger()
+		job := h.jobs.Submit(logger.WithContext(r.Context()), principal.UserID, "transcribe", h.transcribeRecordingJob(client(r), principal.UserID, recordingID, filePath, durationMs))
+		data["jobId"] = job.ID
+	}
+
+	response.OK(w, r, data)
+}
+
+// randomSuffix returns 8 random hex characters for making file names unique
+func randomSuffix() string {
+	b := make([]byte, 4)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// transcribeRecordingJob returns a job that transcribes a saved recording, stores
+// the transcript and announces it to the user owner as transcript.ready. The
+// audio transcribed counts against the usage of client.
+func (h *Handlers) transcribeRecordingJob(client string, owner int64, recordingID int64, filePath string, durationMs int64) jobs.Func {
+	return func(ctx context.Context, progress jobs.ProgressFunc) (any, error) {
+		progress(0.1, "Reading audio")
+		audioData, err := os.ReadFile(filePath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read recording: %v", err)
+		}
+
+		progress(0.3, "Transcribing")
+		usage := &service.Usage{}
+		text, err := h.transcribeService.TranscribeAudio(service.WithUsage(ctx, usage), audioData)
+		h.recordUsage(ctx, client, usage)
+		if err != nil {
+			return nil, fmt.Errorf("transcription failed: %v", err)
+		}
+
+		var segments []database.TranscriptSegment
+		if text != "" {
+			segments = append(segments, database.TranscriptSegment{Segment: 0, StartMs: 0, EndMs: durationMs, Text: text})
+		}
+
+		progress(0.9, "Saving transcript")
+		if err := database.AddTranscriptSegments(ctx, recordingID, segments); err != nil {
+			return nil, err
+		}
+
+		result := events.TranscriptReady{RecordingID: recordingID, Segments: len(segments)}
+		h.events.PublishFor(owner, events.TopicTranscriptReady, result)
+		return result, nil
+	}
 }
 
-// WebSocketHandler handles WebSocket connections
-func WebSocketHandler(w http.ResponseWriter, r *http.Request) {
-	// Implement your WebSocket logic here
+// GetJob handles GET /api/jobs/{id} requests
+func (h *Handlers) GetJob(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		response.Error(w, r, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		response.Error(w, r, http.StatusBadRequest, "Job ID is required")
+		return
+	}
+
+	principal, ok := h.principal(w, r)
+	if !ok {
+		return
+	}
+
+	job, ok := h.jobs.Get(id)
+	if !ok || !audience(principal).Includes(job.Owner) {
+		response.Error(w, r, http.StatusNotFound, "Job not found")
+		return
+	}
+
+	response.OK(w, r, job)
+}
+
+// CreateWSTicket handles POST /api/ws-ticket requests, issuing a short-lived,
+// single-use ticket browsers can pass as ?ticket= when opening a WebSocket
+func (h *Handlers) CreateWSTicket(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		response.Error(w, r, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	principal, ok := h.principal(w, r)
+	if !ok {
+		return
+	}
+
+	ticket, expiresAt := h.authenticator.IssueTicket(principal)
+
+	data := map[string]any{
+		"ticket":    ticket,
+		"expiresAt": expiresAt.UTC().Format(time.RFC3339),
+		"user":      principal.ID,
+	}
+
+	response.OK(w, r, data)
 }
 
 // GetConfig handles GET /api/config requests
 func (h *Handlers) GetConfig(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		util.WriteJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		response.Error(w, r, http.StatusMethodNotAllowed, "Method not allowed")
 		return
 	}
 
+	// Mask the OpenAI key and provider secrets for security
 	appConfig := h.configManager.GetConfig()
+	effective := h.configManager.Effective()
 
-	// Mask the OpenAI key for security
-	if appConfig.OpenAIKey != "" {
-		if len(appConfig.OpenAIKey) > 8 {
-			appConfig.OpenAIKey = appConfig.OpenAIKey[:4] + "..." + appConfig.OpenAIKey[len(appConfig.OpenAIKey)-4:]
-		} else {
-			appConfig.OpenAIKey = "***"
-		}
+	data := map[string]any{
+		"config":    appConfig.Masked(),
+		"effective": effective.Masked(),
 	}
 
-	response := map[string]any{
-		"success": true,
-		"config":  appConfig,
-	}
-
-	util.WriteJSONSuccess(w, response)
+	response.OK(w, r, data)
 }
 
-// SetConfig handles PUT /api/config requests
+// SetConfig handles PUT /api/config requests, replacing the configuration.
+// Secrets that are omitted or sent masked keep their current value.
 func (h *Handlers) SetConfig(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPut {
-		util.WriteJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		response.Error(w, r, http.StatusMethodNotAllowed, "Method not allowed")
 		return
 	}
 
 	var newConfig config.AppConfig
-	if err := json.NewDecoder(r.Body).Decode(&newConfig); err != nil {
-		util.WriteJSONError(w, http.StatusBadRequest, "Invalid JSON format")
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&newConfig); err != nil {
+		response.Error(w, r, http.StatusBadRequest, fmt.Sprintf("Invalid configuration: %v", err))
 		return
 	}
 
-	// Save the new configuration
-	if err := h.configManager.SetConfig(newConfig); err != nil {
-		util.WriteJSONError(w, http.StatusInternalServerError, "Failed to save configuration: "+err.Error())
+	h.updateConfig(w, r, func(current config.AppConfig) (config.AppConfig, error) {
+		newConfig.PreserveSecrets(current)
+		return newConfig, nil
+	})
+}
+
+// PatchConfig handles PATCH /api/config requests with a JSON merge patch
+// (RFC 7396). Fields set to null are cleared; masked secrets are unchanged.
+func (h *Handlers) PatchConfig(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPatch {
+		response.Error(w, r, http.StatusMethodNotAllowed, "Method not allowed")
 		return
 	}
 
-	response := map[string]any{
-		"success": true,
-		"message": "Configuration saved successfully",
+	patch, err := io.ReadAll(r.Body)
+	if err != nil {
+		response.Error(w, r, http.StatusBadRequest, "Failed to read request body")
+		return
 	}
 
-	util.WriteJSONSuccess(w, response)
+	h.updateConfig(w, r, func(current config.AppConfig) (config.AppConfig, error) {
+		return current.MergePatch(patch)
+	})
 }
 
-// GetConfigRaw handles GET /api/config/raw requests (returns unmasked config for editing)
+// updateConfig applies a validated change to the configuration, records it in
+// the audit log and responds with the masked result
+func (h *Handlers) updateConfig(w http.ResponseWriter, r *http.Request, update func(config.AppConfig) (config.AppConfig, error)) {
+	principal, ok := h.principal(w, r)
+	if !ok {
+		return
+	}
+
+	previous, updated, err := h.configManager.UpdateConfig(update)
+	if errors.Is(err, config.ErrInvalidConfig) {
+		response.Invalid(w, r, err.Error())
+		return
+	}
+	if err != nil {
+		response.Error(w, r, http.StatusInternalServerError, "Failed to save configuration: "+err.Error())
+		return
+	}
+
+	changes := config.Diff(previous, updated)
+	if len(changes) > 0 {
+		if err := database.AddConfigAudit(principal.ID, database.AuditConfigUpdate, changes); err != nil {
+			response.Error(w, r, http.StatusInternalServerError, fmt.Sprintf("Configuration saved but not audited: %v", err))
+			return
+		}
+	}
+
+	response.Write(w, r, http.StatusOK, response.Envelope{
+		Success: true,
+		Message: "Configuration saved successfully",
+		Data: map[string]any{
+			"config":  updated.Masked(),
+			"changes": changes,
+		},
+	})
+}
+
+// GetConfigRaw handles GET /api/config/raw requests. Secrets stay masked unless
+// the request asks for ?reveal=true, which is recorded in the audit log.
 func (h *Handlers) GetConfigRaw(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		util.WriteJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		response.Error(w, r, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	principal, ok := h.principal(w, r)
+	if !ok {
 		return
 	}
 
 	appConfig := h.configManager.GetConfig()
+	reveal := r.URL.Query().Get("reveal") == "true"
+	if reveal {
+		if err := database.AddConfigAudit(principal.ID, database.AuditConfigReveal, []config.Change{}); err != nil {
+			response.Error(w, r, http.StatusInternalServerError, fmt.Sprintf("Failed to audit configuration access: %v", err))
+			return
+		}
+	} else {
+		appConfig = appConfig.Masked()
+	}
+
+	data := map[string]any{
+		"config":   appConfig,
+		"revealed": reveal,
+	}
+
+	response.OK(w, r, data)
+}
+
+// GetConfigAudit handles GET /api/config/audit requests, listing recent
+// configuration changes and reveals
+func (h *Handlers) GetConfigAudit(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		response.Error(w, r, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	limit := 100
+	if value := r.URL.Query().Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 {
+			response.Invalid(w, r, "limit must be a positive integer", response.FieldError{Field: "limit", Message: "must be a positive integer"})
+			return
+		}
+		limit = parsed
+	}
+
+	entries, err := database.GetConfigAudit(limit)
+	if err != nil {
+		response.Error(w, r, http.StatusInternalServerError, fmt.Sprintf("Failed to get config audit: %v", err))
+		return
+	}
 
-	response := map[string]any{
-		"success": true,
-		"config":  appConfig,
+	data := map[string]any{
+		"entries": entries,
 	}
 
-	util.WriteJSONSuccess(w, response)
+	response.OK(w, r, data)
 }
@@ -3,16 +3,35 @@ package http
 import (
 	"bytes"
 	"context"
+	"database/sql"
 	"encoding/json"
 	"fmt"
+	"io"
 	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
-
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/rs/zerolog"
+	"github.com/your-org/note-server/internal/auth"
+	"github.com/your-org/note-server/internal/config"
+	"github.com/your-org/note-server/internal/database"
+	"github.com/your-org/note-server/internal/events"
+	"github.com/your-org/note-server/internal/health"
+	"github.com/your-org/note-server/internal/metrics"
 	"github.com/your-org/note-server/internal/service"
+	"github.com/your-org/note-server/internal/tracing"
 	"github.com/your-org/note-server/internal/ws"
+	"github.com/your-org/note-server/pkg/response"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // Mock Transcriber implementation
@@ -337,6 +356,691 @@ func TestSummarizeHandler(t *testing.T) {
 	})
 }
 
+func TestCreateWSTicket(t *testing.T) {
+	authenticator := auth.New(auth.Options{
+		Required: true,
+		Tokens:   map[string]string{"alice": "secret-token"},
+	})
+
+	tests := []struct {
+		name           string
+		method         string
+		authorization  string
+		expectedStatus int
+	}{
+		{"valid token", http.MethodPost, "Bearer secret-token", http.StatusOK},
+		{"missing token", http.MethodPost, "", http.StatusUnauthorized},
+		{"invalid token", http.MethodPost, "Bearer wrong", http.StatusUnauthorized},
+		{"method not allowed", http.MethodGet, "Bearer secret-token", http.StatusMethodNotAllowed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handlers := NewHandlers()
+			handlers.SetAuthenticator(authenticator)
+
+			req := httptest.NewRequest(tt.method, "/api/ws-ticket", nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			w := httptest.NewRecorder()
+
+			handlers.CreateWSTicket(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+			if w.Code != http.StatusOK {
+				return
+			}
+
+			var body struct {
+				Data struct {
+					Ticket string `json:"ticket"`
+					User   string `json:"user"`
+				} `json:"data"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatalf("failed to unmarshal response: %v", err)
+			}
+			response := body.Data
+			if response.User != "alice" {
+				t.Errorf("expected user alice, got %s", response.User)
+			}
+
+			// The ticket authenticates the same user exactly once
+			principal, err := authenticator.RedeemTicket(response.Ticket)
+			if err != nil || principal.ID != "alice" {
+				t.Errorf("expected ticket for alice, got %+v (%v)", principal, err)
+			}
+		})
+	}
+}
+
+func TestUploadRecording_Transcribe(t *testing.T) {
+	if err := database.InitDB(filepath.Join(t.TempDir(), "test.db")); err != nil {
+		t.Fatalf("failed to initialize database: %v", err)
+	}
+
+	handlers := createHandlersWithMocks(&MockTranscriber{
+		TranscribeAudioFunc: func(ctx context.Context, audioData []byte) (string, error) {
+			return "uploaded transcript", nil
+		},
+	}, &MockSummarizer{})
+
+	bus := events.NewBus()
+	sub := bus.Subscribe("*")
+	defer sub.Close()
+	handlers.SetEvents(bus, nil)
+	recordingsDir := filepath.Join(t.TempDir(), "recordings")
+	handlers.SetRecordingsDir(recordingsDir)
+
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	part, _ := writer.CreateFormFile("audio", "test.webm")
+	part.Write([]byte("fake webm audio"))
+	writer.WriteField("transcribe", "true")
+	writer.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/upload-recording", body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	w := httptest.NewRecorder()
+
+	handlers.UploadRecording(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	var response struct {
+		Data struct {
+			RecordingID int64  `json:"recordingId"`
+			Filename    string `json:"filename"`
+			JobID       string `json:"jobId"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(recordingsDir, response.Data.Filename)); err != nil {
+		t.Errorf("expected the upload in the recordings directory: %v", err)
+	}
+
+	// Another upload in the same second gets its own file
+	other := &bytes.Buffer{}
+	otherWriter := multipart.NewWriter(other)
+	otherPart, _ := otherWriter.CreateFormFile("audio", "other.webm")
+	otherPart.Write([]byte("other webm audio"))
+	otherWriter.Close()
+	otherReq := httptest.NewRequest(http.MethodPost, "/api/upload-recording", other)
+	otherReq.Header.Set("Content-Type", otherWriter.FormDataContentType())
+	otherW := httptest.NewRecorder()
+	handlers.UploadRecording(otherW, otherReq)
+	if otherW.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, otherW.Code, otherW.Body.String())
+	}
+	if strings.Contains(otherW.Body.String(), response.Data.Filename) {
+		t.Errorf("expected a second upload not to reuse %s: %s", response.Data.Filename, otherW.Body.String())
+	}
+	if data, _ := os.ReadFile(filepath.Join(recordingsDir, response.Data.Filename)); string(data) != "fake webm audio" {
+		t.Errorf("expected the first upload intact, got %q", data)
+	}
+	if response.Data.JobID == "" {
+		t.Fatal("expected a job ID for a transcribed upload")
+	}
+
+	// The upload is announced, then the job runs and announces the transcript
+	var topics []string
+	timeout := time.After(5 * time.Second)
+	for len(topics) == 0 || topics[len(topics)-1] != events.TopicTranscriptReady {
+		select {
+		case event := <-sub.C:
+			topics = append(topics, event.Topic)
+		case <-timeout:
+			t.Fatalf("timed out waiting for transcript.ready, got %v", topics)
+		}
+	}
+	if topics[0] != events.TopicRecordingCreated {
+		t.Errorf("expected recording.created first, got %v", topics)
+	}
+
+	segments, err := database.GetTranscriptSegments(context.Background(), int(response.Data.RecordingID))
+	if err != nil || len(segments) != 1 || segments[0].Text != "uploaded transcript" {
+		t.Errorf("expected stored transcript, got %+v (%v)", segments, err)
+	}
+
+	// The job can also be polled
+	router := NewRouterWithHandlers(createMockTranscribeHub(), handlers)
+	w = sendJSON(router, http.MethodGet, "/api/v1/jobs/"+response.Data.JobID, nil, "", nil)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"status":"completed"`) {
+		t.Errorf("expected completed job, got %d: %s", w.Code, w.Body.String())
+	}
+
+	w = sendJSON(router, http.MethodGet, "/api/v1/jobs/missing", nil, "", nil)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected status %d, got %d", http.StatusNotFound, w.Code)
+	}
+}
+
+// newAccountsRouter serves the full router with user accounts and API tokens enabled
+func newAccountsRouter() http.Handler {
+	handlers := createHandlersWithMocks(&MockTranscriber{}, &MockSummarizer{})
+	handlers.SetAuthenticator(auth.New(auth.Options{
+		Sessions:  database.NewSessionStore(),
+		APITokens: database.NewTokenStore(),
+	}))
+	return NewRouterWithHandlers(createMockTranscribeHub(), handlers)
+}
+
+// sendJSON makes a request with an optional JSON body, Bearer token and session cookie
+func sendJSON(router http.Handler, method, path string, body any, token string, cookie *http.Cookie) *httptest.ResponseRecorder {
+	var reader io.Reader
+	if body != nil {
+		data, _ := json.Marshal(body)
+		reader = bytes.NewReader(data)
+	}
+	req := httptest.NewRequest(method, path, reader)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	if cookie != nil {
+		req.AddCookie(cookie)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+// loginAs logs in and returns the session token and cookie
+func loginAs(t *testing.T, router http.Handler, username, password string) (string, *http.Cookie) {
+	t.Helper()
+	w := sendJSON(router, http.MethodPost, "/api/auth/login", Credentials{Username: username, Password: password}, "", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected login to succeed, got %d: %s", w.Code, w.Body.String())
+	}
+	var response struct {
+		Data struct {
+			Token string `json:"token"`
+		} `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != auth.SessionCookie || !cookies[0].HttpOnly {
+		t.Fatalf("expected an HttpOnly session cookie, got %+v", cookies)
+	}
+	return response.Data.Token, cookies[0]
+}
+
+func TestUserAccounts(t *testing.T) {
+	if err := database.InitDB(filepath.Join(t.TempDir(), "test.db")); err != nil {
+		t.Fatalf("failed to initialize database: %v", err)
+	}
+
+	router := newAccountsRouter()
+	send := func(method, path string, body any, token string, cookie *http.Cookie) *httptest.ResponseRecorder {
+		return sendJSON(router, method, path, body, token, cookie)
+	}
+	login := func(username, password string) (string, *http.Cookie) {
+		t.Helper()
+		return loginAs(t, router, username, password)
+	}
+
+	// Without accounts the server is open
+	if w := send(http.MethodGet, "/api/recordings", nil, "", nil); w.Code != http.StatusOK {
+		t.Fatalf("expected open access before any user exists, got %d", w.Code)
+	}
+
+	// Anyone may claim the server by creating the first account
+	if w := send(http.MethodPost, "/api/auth/register", Credentials{Username: "alice", Password: "alice-password"}, "", nil); w.Code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		body           any
+		expectedStatus int
+	}{
+		{"anonymous request", http.MethodGet, "/api/recordings", nil, http.StatusUnauthorized},
+		{"anonymous transcription", http.MethodPost, "/transcribe", nil, http.StatusUnauthorized},
+		{"anonymous raw config", http.MethodGet, "/api/config/raw", nil, http.StatusUnauthorized},
+		{"anonymous registration", http.MethodPost, "/api/auth/register", Credentials{Username: "mallory", Password: "mallory-password"}, http.StatusUnauthorized},
+		{"wrong password", http.MethodPost, "/api/auth/login", Credentials{Username: "alice", Password: "wrong-password"}, http.StatusUnauthorized},
+		{"unknown user", http.MethodPost, "/api/auth/login", Credentials{Username: "nobody", Password: "alice-password"}, http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if w := send(tt.method, tt.path, tt.body, "", nil); w.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+		})
+	}
+
+	aliceToken, _ := login("alice", "alice-password")
+
+	// Only administrators add further users
+	if w := send(http.MethodPost, "/api/auth/register", Credentials{Username: "bob", Password: "short"}, aliceToken, nil); w.Code != http.StatusBadRequest {
+		t.Errorf("expected a weak password to be rejected, got %d", w.Code)
+	}
+	if w := send(http.MethodPost, "/api/auth/register", Credentials{Username: "bob", Password: "bob-password"}, aliceToken, nil); w.Code != http.StatusCreated {
+		t.Fatalf("expected admin to create bob, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := send(http.MethodPost, "/api/auth/register", Credentials{Username: "bob", Password: "bob-password"}, aliceToken, nil); w.Code != http.StatusConflict {
+		t.Errorf("expected status %d for a taken username, got %d", http.StatusConflict, w.Code)
+	}
+
+	bobToken, bobCookie := login("bob", "bob-password")
+	if w := send(http.MethodPost, "/api/auth/register", Credentials{Username: "carol", Password: "carol-password"}, bobToken, nil); w.Code != http.StatusForbidden {
+		t.Errorf("expected status %d for a non-admin registering users, got %d", http.StatusForbidden, w.Code)
+	}
+
+	// The unmasked configuration is for administrators
+	if w := send(http.MethodGet, "/api/config/raw", nil, bobToken, nil); w.Code != http.StatusForbidden {
+		t.Errorf("expected status %d for bob, got %d", http.StatusForbidden, w.Code)
+	}
+	if w := send(http.MethodGet, "/api/config/raw", nil, aliceToken, nil); w.Code != http.StatusOK {
+		t.Errorf("expected status %d for alice, got %d", http.StatusOK, w.Code)
+	}
+
+	// Recordings are scoped to their owner; administrators see everything
+	alice, _ := database.GetUserByUsername("alice")
+	start := time.Now()
+	recordingID, err := database.AddRecording(context.Background(), alice.ID, "alice.wav", "/tmp/alice.wav", start, start, 0, 0, "wav", 16000, 1)
+	if err != nil {
+		t.Fatalf("failed to add recording: %v", err)
+	}
+	path := fmt.Sprintf("/api/recordings/%d", recordingID)
+	if w := send(http.MethodGet, path, nil, "", bobCookie); w.Code != http.StatusNotFound {
+		t.Errorf("expected another user's recording to be hidden, got %d", w.Code)
+	}
+	if w := send(http.MethodGet, path, nil, aliceToken, nil); w.Code != http.StatusOK {
+		t.Errorf("expected the owner to see their recording, got %d", w.Code)
+	}
+	if w := send(http.MethodGet, "/api/recordings", nil, "", bobCookie); strings.Contains(w.Body.String(), "alice.wav") {
+		t.Errorf("expected bob's recordings to exclude alice's, got %s", w.Body.String())
+	}
+
+	// The session cookie identifies bob until he logs out
+	w := send(http.MethodGet, "/api/auth/me", nil, "", bobCookie)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"id":"bob"`) {
+		t.Errorf("expected bob, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := send(http.MethodPost, "/api/auth/logout", nil, "", bobCookie); w.Code != http.StatusOK {
+		t.Errorf("expected logout to succeed, got %d", w.Code)
+	}
+	if w := send(http.MethodGet, "/api/auth/me", nil, "", bobCookie); w.Code != http.StatusUnauthorized {
+		t.Errorf("expected status %d after logout, got %d", http.StatusUnauthorized, w.Code)
+	}
+}
+
+func TestRegister_FirstUser(t *testing.T) {
+	if err := database.InitDB(filepath.Join(t.TempDir(), "test.db")); err != nil {
+		t.Fatalf("failed to initialize database: %v", err)
+	}
+	router := newAccountsRouter()
+
+	// Of several anonymous callers racing to claim a fresh install, one wins
+	// and the rest are refused, whatever they ask for
+	codes := make(chan int, 5)
+	for i := range cap(codes) {
+		go func() {
+			credentials := Credentials{Username: fmt.Sprintf("user%d", i), Password: "user-password", Admin: true}
+			codes <- sendJSON(router, http.MethodPost, "/api/auth/register", credentials, "", nil).Code
+		}()
+	}
+	created := 0
+	for range cap(codes) {
+		switch code := <-codes; code {
+		case http.StatusCreated:
+			created++
+		case http.StatusUnauthorized:
+		default:
+			t.Errorf("expected status %d or %d, got %d", http.StatusCreated, http.StatusUnauthorized, code)
+		}
+	}
+	if created != 1 {
+		t.Fatalf("expected one registration to claim the install, got %d", created)
+	}
+	if count, _ := database.CountUsers(); count != 1 {
+		t.Errorf("expected one user, got %d", count)
+	}
+}
+
+func TestAPITokens(t *testing.T) {
+	if err := database.InitDB(filepath.Join(t.TempDir(), "test.db")); err != nil {
+		t.Fatalf("failed to initialize database: %v", err)
+	}
+
+	router := newAccountsRouter()
+	sendJSON(router, http.MethodPost, "/api/auth/register", Credentials{Username: "alice", Password: "alice-password"}, "", nil)
+	_, cookie := loginAs(t, router, "alice", "alice-password")
+
+	tests := []struct {
+		name           string
+		request        CreateAPITokenRequest
+		expectedStatus int
+	}{
+		{"no name", CreateAPITokenRequest{Scopes: []string{"read"}}, http.StatusBadRequest},
+		{"no scopes", CreateAPITokenRequest{Name: "kiosk"}, http.StatusBadRequest},
+		{"unknown scope", CreateAPITokenRequest{Name: "kiosk", Scopes: []string{"root"}}, http.StatusBadRequest},
+		{"past expiry", CreateAPITokenRequest{Name: "kiosk", Scopes: []string{"read"}, ExpiresAt: "2000-01-01T00:00:00Z"}, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if w := sendJSON(router, http.MethodPost, "/api/tokens", tt.request, "", cookie); w.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+		})
+	}
+
+	w := sendJSON(router, http.MethodPost, "/api/tokens", CreateAPITokenRequest{Name: "kiosk", Scopes: []string{"write"}}, "", cookie)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+	var created struct {
+		Data struct {
+			Token    string `json:"token"`
+			APIToken struct {
+				ID     int64    `json:"id"`
+				Scopes []string `json:"scopes"`
+			} `json:"apiToken"`
+		} `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &created)
+	token := created.Data.Token
+	if !strings.HasPrefix(token, auth.APITokenPrefix) || len(created.Data.APIToken.Scopes) != 1 {
+		t.Fatalf("expected a write token, got %s", w.Body.String())
+	}
+
+	// upload sends a recording as the kiosk
+	upload := func(transcribe bool) *httptest.ResponseRecorder {
+		body := &bytes.Buffer{}
+		writer := multipart.NewWriter(body)
+		part, _ := writer.CreateFormFile("audio", "kiosk.webm")
+		part.Write([]byte("fake webm audio"))
+		if transcribe {
+			writer.WriteField("transcribe", "true")
+		}
+		writer.Close()
+
+		req := httptest.NewRequest(http.MethodPost, "/api/upload-recording", body)
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w = upload(false)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected the kiosk to upload, got %d: %s", w.Code, w.Body.String())
+	}
+	var uploaded struct {
+		Data struct {
+			RecordingID int64  `json:"recordingId"`
+			Filename    string `json:"filename"`
+		} `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &uploaded)
+	defer os.Remove(filepath.Join("/tmp/recordings", uploaded.Data.Filename))
+
+	// The upload belongs to the token's user
+	alice, _ := database.GetUserByUsername("alice")
+	if recording, err := database.GetRecording(context.Background(), int(uploaded.Data.RecordingID), database.OwnedBy(alice.ID)); err != nil || recording == nil {
+		t.Errorf("expected the upload to be owned by alice, got %v", err)
+	}
+
+	// The token can do nothing beyond its scopes
+	if w := upload(true); w.Code != http.StatusForbidden {
+		t.Errorf("expected status %d for transcription without the scope, got %d", http.StatusForbidden, w.Code)
+	}
+	for _, path := range []string{"/api/recordings", "/api/tokens", "/api/config/raw"} {
+		if w := sendJSON(router, http.MethodGet, path, nil, token, nil); w.Code != http.StatusForbidden {
+			t.Errorf("expected status %d for %s, got %d", http.StatusForbidden, path, w.Code)
+		}
+	}
+
+	w = sendJSON(router, http.MethodGet, "/api/tokens", nil, "", cookie)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"lastUsedAt"`) || strings.Contains(w.Body.String(), token) {
+		t.Errorf("expected the token listed with its last use and without its secret, got %s", w.Body.String())
+	}
+
+	path := fmt.Sprintf("/api/tokens/%d", created.Data.APIToken.ID)
+	if w := sendJSON(router, http.MethodDelete, path, nil, "", cookie); w.Code != http.StatusOK {
+		t.Errorf("expected the token to be revoked, got %d", w.Code)
+	}
+	if w := sendJSON(router, http.MethodDelete, path, nil, "", cookie); w.Code != http.StatusNotFound {
+		t.Errorf("expected status %d for a revoked token, got %d", http.StatusNotFound, w.Code)
+	}
+	if w := upload(false); w.Code != http.StatusUnauthorized {
+		t.Errorf("expected status %d after revocation, got %d", http.StatusUnauthorized, w.Code)
+	}
+}
+
+func TestSharing(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+	if err := database.InitDB(dbPath); err != nil {
+		t.Fatalf("failed to initialize database: %v", err)
+	}
+
+	router := newAccountsRouter()
+	sendJSON(router, http.MethodPost, "/api/auth/register", Credentials{Username: "alice", Password: "alice-password"}, "", nil)
+	aliceToken, _ := loginAs(t, router, "alice", "alice-password")
+	sendJSON(router, http.MethodPost, "/api/auth/register", Credentials{Username: "bob", Password: "bob-password"}, aliceToken, nil)
+	sendJSON(router, http.MethodPost, "/api/auth/register", Credentials{Username: "carol", Password: "carol-password"}, aliceToken, nil)
+	bobToken, _ := loginAs(t, router, "bob", "bob-password")
+	carolToken, _ := loginAs(t, router, "carol", "carol-password")
+
+	// bob records a meeting and its transcript
+	bob, _ := database.GetUserByUsername("bob")
+	start := time.Now()
+	audioPath := filepath.Join(t.TempDir(), "standup.wav")
+	os.WriteFile(audioPath, make([]byte, 2048), 0644)
+	recordingID, _ := database.AddRecording(context.Background(), bob.ID, "standup.wav", audioPath, start, start, 0, 0, "wav", 16000, 1)
+	database.AddTranscriptSegments(context.Background(), recordingID, []database.TranscriptSegment{{Text: "shipping on friday"}})
+	conn, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if _, err := conn.Exec("INSERT INTO meetings (title, content, recording_id, owner_id) VALUES ('Standup', '', ?, ?)", recordingID, bob.ID); err != nil {
+		t.Fatalf("failed to insert meeting: %v", err)
+	}
+	meetingPath := "/api/meetings/1"
+
+	edit := func(token string) int {
+		title := "Standup notes"
+		return sendJSON(router, http.MethodPatch, meetingPath, database.DocumentFields{Title: &title}, token, nil).Code
+	}
+
+	if w := sendJSON(router, http.MethodGet, meetingPath, nil, carolToken, nil); w.Code != http.StatusNotFound {
+		t.Errorf("expected an unshared meeting to be hidden, got %d", w.Code)
+	}
+	if w := sendJSON(router, http.MethodPost, meetingPath+"/shares", ShareRequest{Username: "carol", Role: database.RoleViewer}, carolToken, nil); w.Code != http.StatusNotFound {
+		t.Errorf("expected carol not to share a meeting she can't see, got %d", w.Code)
+	}
+
+	tests := []struct {
+		name           string
+		request        ShareRequest
+		expectedStatus int
+	}{
+		{"unknown role", ShareRequest{Username: "carol", Role: "admin"}, http.StatusBadRequest},
+		{"owner role", ShareRequest{Username: "carol", Role: database.RoleOwner}, http.StatusBadRequest},
+		{"unknown user", ShareRequest{Username: "nobody", Role: database.RoleViewer}, http.StatusNotFound},
+		{"the owner", ShareRequest{Username: "bob", Role: database.RoleViewer}, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if w := sendJSON(router, http.MethodPost, meetingPath+"/shares", tt.request, bobToken, nil); w.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+		})
+	}
+
+	// A viewer reads the meeting and its recording but can't change or reshare them
+	w := sendJSON(router, http.MethodPost, meetingPath+"/shares", ShareRequest{Username: "carol", Role: database.RoleViewer}, bobToken, nil)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected bob to share the meeting, got %d: %s", w.Code, w.Body.String())
+	}
+	var granted struct {
+		Data struct {
+			Share database.Share `json:"share"`
+		} `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &granted)
+
+	if w := sendJSON(router, http.MethodGet, meetingPath, nil, carolToken, nil); w.Code != http.StatusOK {
+		t.Errorf("expected carol to see the shared meeting, got %d", w.Code)
+	}
+	transcriptPath := fmt.Sprintf("/api/recordings/%d/transcript", recordingID)
+	if w := sendJSON(router, http.MethodGet, transcriptPath, nil, carolToken, nil); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "shipping on friday") {
+		t.Errorf("expected carol to read the meeting's transcript, got %d: %s", w.Code, w.Body.String())
+	}
+	if code := edit(carolToken); code != http.StatusForbidden {
+		t.Errorf("expected status %d for a viewer editing, got %d", http.StatusForbidden, code)
+	}
+	if w := sendJSON(router, http.MethodPost, meetingPath+"/shares", ShareRequest{Username: "alice", Role: database.RoleEditor}, carolToken, nil); w.Code != http.StatusForbidden {
+		t.Errorf("expected status %d for a viewer sharing, got %d", http.StatusForbidden, w.Code)
+	}
+
+	// An editor can change the meeting
+	sendJSON(router, http.MethodPost, meetingPath+"/shares", ShareRequest{Username: "carol", Role: database.RoleEditor}, bobToken, nil)
+	if code := edit(carolToken); code != http.StatusOK {
+		t.Errorf("expected an editor to edit, got %d", code)
+	}
+	if w := sendJSON(router, http.MethodGet, meetingPath, nil, bobToken, nil); !strings.Contains(w.Body.String(), "Standup notes") {
+		t.Errorf("expected the edited title, got %s", w.Body.String())
+	}
+
+	// Revoking the grant hides the meeting again
+	sharePath := fmt.Sprintf("%s/shares/%d", meetingPath, granted.Data.Share.ID)
+	if w := sendJSON(router, http.MethodDelete, sharePath, nil, bobToken, nil); w.Code != http.StatusOK {
+		t.Errorf("expected bob to revoke the share, got %d", w.Code)
+	}
+	if w := sendJSON(router, http.MethodGet, transcriptPath, nil, carolToken, nil); w.Code != http.StatusNotFound {
+		t.Errorf("expected the transcript hidden after revoking, got %d", w.Code)
+	}
+
+	// Share links give anyone read-only access until they expire or are revoked
+	linksPath := fmt.Sprintf("/api/recordings/%d/links", recordingID)
+	if w := sendJSON(router, http.MethodPost, linksPath, CreateShareLinkRequest{ExpiresAt: "2000-01-01T00:00:00Z"}, bobToken, nil); w.Code != http.StatusBadRequest {
+		t.Errorf("expected a past expiry to be rejected, got %d", w.Code)
+	}
+	w = sendJSON(router, http.MethodPost, linksPath, nil, bobToken, nil)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected bob to create a share link, got %d: %s", w.Code, w.Body.String())
+	}
+	var created struct {
+		Data struct {
+			Link          database.ShareLink `json:"link"`
+			TranscriptURL string             `json:"transcriptUrl"`
+			AudioURL      string             `json:"audioUrl"`
+		} `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &created)
+	if w := sendJSON(router, http.MethodGet, created.Data.TranscriptURL, nil, "", nil); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "shipping on friday") {
+		t.Errorf("expected the link to serve the transcript anonymously, got %d: %s", w.Code, w.Body.String())
+	}
+	// Audio behind a link that can be revoked must not be cached
+	w = sendJSON(router, http.MethodGet, created.Data.AudioURL, nil, "", nil)
+	if w.Code != http.StatusOK || w.Header().Get("Cache-Control") != "private, no-store" || w.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Errorf("expected the link to serve uncacheable audio, got %d with headers %v", w.Code, w.Header())
+	}
+	if w := sendJSON(router, http.MethodGet, "/api/shared/guess/transcript", nil, "", nil); w.Code != http.StatusNotFound {
+		t.Errorf("expected status %d for an unknown link, got %d", http.StatusNotFound, w.Code)
+	}
+	if w := sendJSON(router, http.MethodGet, linksPath, nil, carolToken, nil); w.Code != http.StatusNotFound {
+		t.Errorf("expected carol not to list bob's links, got %d", w.Code)
+	}
+	if w := sendJSON(router, http.MethodDelete, fmt.Sprintf("%s/%d", linksPath, created.Data.Link.ID), nil, bobToken, nil); w.Code != http.StatusOK {
+		t.Errorf("expected bob to revoke the link, got %d", w.Code)
+	}
+	if w := sendJSON(router, http.MethodGet, created.Data.TranscriptURL, nil, "", nil); w.Code != http.StatusNotFound {
+		t.Errorf("expected status %d for a revoked link, got %d", http.StatusNotFound, w.Code)
+	}
+}
+
+func TestConfigEndpoints(t *testing.T) {
+	dir := t.TempDir()
+	if err := database.InitDB(filepath.Join(dir, "test.db")); err != nil {
+		t.Fatalf("failed to initialize database: %v", err)
+	}
+	t.Setenv(config.KeyEnv, "")
+
+	handlers := createHandlersWithMocks(&MockTranscriber{}, &MockSummarizer{})
+	handlers.SetAuthenticator(auth.New(auth.Options{Sessions: database.NewSessionStore()}))
+	handlers.SetConfigManager(config.NewConfigManagerWithPaths(filepath.Join(dir, "config.json"), filepath.Join(dir, "config.key")))
+	router := NewRouterWithHandlers(createMockTranscribeHub(), handlers)
+	sendJSON(router, http.MethodPost, "/api/auth/register", Credentials{Username: "alice", Password: "alice-password"}, "", nil)
+	token, _ := loginAs(t, router, "alice", "alice-password")
+
+	const openAIKey = "sk-secret-openai-key"
+	if w := sendJSON(router, http.MethodPut, "/api/config", config.AppConfig{OpenAIKey: openAIKey, TranscriptionProvider: "openai"}, token, nil); w.Code != http.StatusOK {
+		t.Fatalf("expected the config to be saved, got %d: %s", w.Code, w.Body.String())
+	}
+
+	// A partial PUT keeps the key
+	if w := sendJSON(router, http.MethodPut, "/api/config", map[string]any{"summary_provider": "openai"}, token, nil); w.Code != http.StatusOK {
+		t.Fatalf("expected the config to be saved, got %d: %s", w.Code, w.Body.String())
+	}
+	if handlers.configManager.GetOpenAIKey() != openAIKey {
+		t.Errorf("expected a partial PUT to keep the key, got %q", handlers.configManager.GetOpenAIKey())
+	}
+
+	tests := []struct {
+		name           string
+		method         string
+		body           any
+		expectedStatus int
+	}{
+		{"unknown field", http.MethodPut, map[string]any{"openai_kee": "typo"}, http.StatusBadRequest},
+		{"unknown provider", http.MethodPatch, map[string]any{"transcription_provider": "acme"}, http.StatusBadRequest},
+		{"unknown model", http.MethodPatch, map[string]any{"summary_model": "gpt-99"}, http.StatusBadRequest},
+		{"masked key", http.MethodPatch, map[string]any{"openai_key": config.MaskSecret(openAIKey), "summary_model": "gpt-4o"}, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if w := sendJSON(router, tt.method, "/api/config", tt.body, token, nil); w.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+		})
+	}
+	if cfg := handlers.configManager.GetConfig(); cfg.OpenAIKey != openAIKey || cfg.SummaryModel != "gpt-4o" {
+		t.Errorf("expected the masked key unchanged and the model patched, got %+v", cfg)
+	}
+
+	// Reads are masked unless revealing is asked for
+	for path, revealed := range map[string]bool{"/api/config": false, "/api/config/raw": false, "/api/config/raw?reveal=true": true} {
+		w := sendJSON(router, http.MethodGet, path, nil, token, nil)
+		if w.Code != http.StatusOK || strings.Contains(w.Body.String(), openAIKey) != revealed {
+			t.Errorf("expected %s to reveal the key: %v, got %d: %s", path, revealed, w.Code, w.Body.String())
+		}
+	}
+
+	// Every change and reveal is audited without the secret
+	w := sendJSON(router, http.MethodGet, "/api/config/audit", nil, token, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected the audit log, got %d: %s", w.Code, w.Body.String())
+	}
+	var audit struct {
+		Data struct {
+			Entries []database.ConfigAuditEntry `json:"entries"`
+		} `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &audit)
+	if len(audit.Data.Entries) != 4 || strings.Contains(w.Body.String(), openAIKey) {
+		t.Fatalf("expected 3 changes and a reveal without the key, got %s", w.Body.String())
+	}
+	if latest := audit.Data.Entries[0]; latest.Actor != "alice" || latest.Action != database.AuditConfigReveal {
+		t.Errorf("expected alice's reveal first, got %+v", latest)
+	}
+	if first := audit.Data.Entries[3]; !strings.Contains(string(first.Changes), `{"field":"openai_key","secret":true}`) {
+		t.Errorf("expected the key change recorded as secret, got %s", first.Changes)
+	}
+}
+
 // Integration test with the router
 func TestHandlersIntegration(t *testing.T) {
 	t.Run("health endpoint integration", func(t *testing.T) {
@@ -393,3 +1097,331 @@ func BenchmarkSummarizeHandler(b *testing.B) {
 		handlers.SummarizeHandler(w, req)
 	}
 }
+
+func TestLogRequests(t *testing.T) {
+	handlers := NewHandlers()
+	handlers.SetAuthenticator(auth.New(auth.Options{Tokens: map[string]string{"alice": "s3cret"}}))
+
+	r := chi.NewRouter()
+	r.Use(middleware.RequestID, LogRequests)
+	r.With(handlers.Authenticate).Post("/items/{id}", func(w http.ResponseWriter, r *http.Request) {
+		zerolog.Ctx(r.Context()).Info().Msg("Creating item")
+		response.Created(w, r, nil)
+	})
+	r.Get("/panic", func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})
+
+	// send makes a request whose context carries a logger writing to a buffer,
+	// returning the response and the log lines
+	send := func(method, path, token string) (*httptest.ResponseRecorder, []map[string]any) {
+		var out bytes.Buffer
+		logger := zerolog.New(&out)
+		req := httptest.NewRequest(method, path, nil)
+		req = req.WithContext(logger.WithContext(req.Context()))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		var lines []map[string]any
+		for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+			var fields map[string]any
+			if err := json.Unmarshal([]byte(line), &fields); err != nil {
+				t.Fatalf("Expected JSON log lines, got %q", line)
+			}
+			lines = append(lines, fields)
+		}
+		return w, lines
+	}
+
+	t.Run("access log", func(t *testing.T) {
+		w, lines := send(http.MethodPost, "/items/42", "s3cret")
+		if w.Code != http.StatusCreated || len(lines) != 2 {
+			t.Fatalf("Expected 201 with two log lines, got %d: %v", w.Code, lines)
+		}
+
+		requestID := w.Header().Get(middleware.RequestIDHeader)
+		handlerLine, access := lines[0], lines[1]
+		if requestID == "" || handlerLine["request_id"] != requestID || access["request_id"] != requestID {
+			t.Errorf("Expected request ID %q on every line, got %v", requestID, lines)
+		}
+		if handlerLine["user"] != "alice" || access["user"] != "alice" {
+			t.Errorf("Expected the user on every line, got %v", lines)
+		}
+		for key, want := range map[string]any{
+			"method": "POST",
+			"route":  "/items/{id}",
+			"status": float64(http.StatusCreated),
+			"bytes":  float64(w.Body.Len()),
+		} {
+			if access[key] != want {
+				t.Errorf("Expected %s = %v, got %v", key, want, access[key])
+			}
+		}
+		if _, ok := access["latency"]; !ok {
+			t.Errorf("Expected latency in %v", access)
+		}
+	})
+
+	t.Run("panic", func(t *testing.T) {
+		w, lines := send(http.MethodGet, "/panic", "")
+		if w.Code != http.StatusInternalServerError || len(lines) != 2 {
+			t.Fatalf("Expected 500 with two log lines, got %d: %v", w.Code, lines)
+		}
+		if lines[0]["panic"] != "boom" || lines[0]["stack"] == nil {
+			t.Errorf("Expected the panic logged with its stack, got %v", lines[0])
+		}
+		if lines[1]["level"] != "error" || lines[1]["status"] != float64(http.StatusInternalServerError) {
+			t.Errorf("Expected the request logged as an error, got %v", lines[1])
+		}
+	})
+}
+
+func TestMetricsEndpoint(t *testing.T) {
+	if err := database.InitDB(filepath.Join(t.TempDir(), "test.db")); err != nil {
+		t.Fatalf("failed to initialize database: %v", err)
+	}
+
+	router := newAccountsRouter()
+	sendJSON(router, http.MethodGet, "/healthz", nil, "", nil)
+	sendJSON(router, http.MethodGet, "/api/recordings/7", nil, "", nil)
+	sendJSON(router, http.MethodGet, "/no-such-page", nil, "", nil)
+
+	w := sendJSON(router, http.MethodGet, "/metrics", nil, "", nil)
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != metrics.ContentType {
+		t.Fatalf("Expected 200 in the text format, got %d %q", w.Code, w.Header().Get("Content-Type"))
+	}
+	for _, want := range []string{
+		`note_http_request_duration_seconds_count{method="GET",route="/healthz",status="200"}`,
+		`note_http_request_duration_seconds_count{method="GET",route="/api/recordings/{id}",status="404"}`,
+		`note_db_query_duration_seconds_count{function="GetRecording"}`,
+		`note_http_request_duration_seconds_count{method="GET",route="unmatched",status="404"}`,
+		"# TYPE note_ws_connections gauge",
+		"# TYPE note_jobs gauge",
+		"# TYPE note_transcription_duration_seconds histogram",
+	} {
+		if !strings.Contains(w.Body.String(), want) {
+			t.Errorf("Expected %s in:\n%s", want, w.Body.String())
+		}
+	}
+}
+
+func TestTraceRequests(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	shutdown := tracing.Setup(tracing.Options{SampleRatio: 1, Exporter: exporter, Sync: true})
+	defer func() {
+		shutdown(context.Background())
+		tracing.Setup(tracing.Options{})
+	}()
+
+	r := chi.NewRouter()
+	r.Use(middleware.RequestID, TraceRequests, LogRequests)
+	r.Get("/items/{id}", func(w http.ResponseWriter, r *http.Request) {
+		zerolog.Ctx(r.Context()).Info().Msg("Reading item")
+		w.WriteHeader(http.StatusNoContent)
+	})
+	r.Get("/broken", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	})
+
+	t.Run("continues the caller's trace", func(t *testing.T) {
+		exporter.Reset()
+		const traceID, parentID = "4bf92f3577b34da6a3ce929d0e0e4736", "00f067aa0ba902b7"
+
+		var out bytes.Buffer
+		req := httptest.NewRequest(http.MethodGet, "/items/42", nil)
+		req.Header.Set("traceparent", "00-"+traceID+"-"+parentID+"-01")
+		req = req.WithContext(zerolog.New(&out).WithContext(req.Context()))
+		r.ServeHTTP(httptest.NewRecorder(), req)
+
+		spans := exporter.GetSpans()
+		if len(spans) != 1 {
+			t.Fatalf("Expected one server span, got %d", len(spans))
+		}
+		span := spans[0]
+		if span.Name != "GET /items/{id}" || span.SpanKind != trace.SpanKindServer {
+			t.Errorf("Expected a server span named after the route, got %q", span.Name)
+		}
+		if span.SpanContext.TraceID().String() != traceID || span.Parent.SpanID().String() != parentID {
+			t.Errorf("Expected the span under %s/%s, got %s/%s", traceID, parentID, span.SpanContext.TraceID(), span.Parent.SpanID())
+		}
+		// Paths can carry secrets such as share-link tokens; only the route is kept
+		for _, attr := range span.Attributes {
+			if strings.Contains(attr.Value.Emit(), "42") {
+				t.Errorf("Expected no attribute with the request path, got %s=%s", attr.Key, attr.Value.Emit())
+			}
+		}
+		if !strings.Contains(out.String(), `"trace_id":"`+traceID+`"`) {
+			t.Errorf("Expected log lines with the trace ID, got %s", out.String())
+		}
+	})
+
+	t.Run("nests service spans under the request", func(t *testing.T) {
+		exporter.Reset()
+		handlers := createHandlersWithMocks(&MockTranscriber{}, &MockSummarizer{})
+		router := chi.NewRouter()
+		router.Use(TraceRequests)
+		router.Post("/summarize", handlers.SummarizeHandler)
+		w := sendJSON(router, http.MethodPost, "/summarize", SummarizeRequest{Text: "a long meeting"}, "", nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+		}
+
+		spans := exporter.GetSpans()
+		if len(spans) != 2 {
+			t.Fatalf("Expected a summarizer span and a server span, got %d spans", len(spans))
+		}
+		summarizer, server := spans[0], spans[1]
+		if summarizer.Name != "summarizer summarize" || summarizer.Parent.SpanID() != server.SpanContext.SpanID() {
+			t.Errorf("Expected the summarizer span under the request span, got %q under %s", summarizer.Name, summarizer.Parent.SpanID())
+		}
+	})
+
+	t.Run("marks server errors", func(t *testing.T) {
+		exporter.Reset()
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/broken", nil))
+
+		spans := exporter.GetSpans()
+		if len(spans) != 1 || spans[0].Status.Code != codes.Error || spans[0].Parent.IsValid() {
+			t.Fatalf("Expected one failed root span, got %+v", spans)
+		}
+	})
+}
+
+func TestHealthEndpoints(t *testing.T) {
+	if err := database.InitDB(filepath.Join(t.TempDir(), "test.db")); err != nil {
+		t.Fatalf("failed to initialize database: %v", err)
+	}
+	check := func(name string, critical bool, status string) health.Check {
+		return health.Check{Name: name, Critical: critical, Run: func(ctx context.Context) health.Result {
+			return health.Result{Status: status, Error: name + " failed", Details: map[string]any{"path": "/secret"}}
+		}}
+	}
+
+	tests := []struct {
+		name        string
+		checks      []health.Check
+		readyStatus int
+		report      string
+	}{
+		{"healthy", []health.Check{health.Database()}, http.StatusOK, health.ReportOK},
+		{"optional check failing", []health.Check{health.Database(), check("ffmpeg", false, health.StatusMissing)}, http.StatusOK, health.ReportDegraded},
+		{"critical check failing", []health.Check{health.Database(), check("media_dir", true, health.StatusError)}, http.StatusServiceUnavailable, health.ReportDown},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handlers := createHandlersWithMocks(&MockTranscriber{}, &MockSummarizer{})
+			handlers.SetHealthChecker(health.NewChecker(time.Second, tt.checks...))
+			router := NewRouterWithHandlers(createMockTranscribeHub(), handlers)
+
+			// Readiness leaves out optional checks and every error message
+			w := sendJSON(router, http.MethodGet, "/readyz", nil, "", nil)
+			if w.Code != tt.readyStatus {
+				t.Errorf("Expected /readyz to return %d, got %d", tt.readyStatus, w.Code)
+			}
+			if strings.Contains(w.Body.String(), "ffmpeg") || strings.Contains(w.Body.String(), "failed") {
+				t.Errorf("Expected only critical check statuses, got %s", w.Body.String())
+			}
+
+			w = sendJSON(router, http.MethodGet, "/api/system/health", nil, "", nil)
+			if w.Code != http.StatusOK {
+				t.Fatalf("Expected 200, got %d", w.Code)
+			}
+			var response struct {
+				Data health.Report `json:"data"`
+			}
+			json.Unmarshal(w.Body.Bytes(), &response)
+			if response.Data.Status != tt.report || len(response.Data.Checks) != len(tt.checks) {
+				t.Errorf("Expected a %s report of %d checks, got %+v", tt.report, len(tt.checks), response.Data)
+			}
+		})
+	}
+}
+
+func TestResponseEnvelope(t *testing.T) {
+	if err := database.InitDB(filepath.Join(t.TempDir(), "test.db")); err != nil {
+		t.Fatalf("failed to initialize database: %v", err)
+	}
+	start := time.Now()
+	if _, err := database.AddRecording(context.Background(), 0, "standup.wav", "/tmp/standup.wav", start, start, 0, 0, "wav", 16000, 1); err != nil {
+		t.Fatalf("failed to add recording: %v", err)
+	}
+	router := newAccountsRouter()
+
+	type envelope struct {
+		Success   bool                  `json:"success"`
+		Data      map[string]any        `json:"data"`
+		Error     string                `json:"error"`
+		Code      string                `json:"code"`
+		Fields    []response.FieldError `json:"fields"`
+		RequestID string                `json:"requestId"`
+	}
+	decode := func(w *httptest.ResponseRecorder) envelope {
+		t.Helper()
+		var body envelope
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+			t.Fatalf("expected a JSON envelope, got %q: %v", w.Body.String(), err)
+		}
+		if body.RequestID == "" || body.RequestID != w.Header().Get(middleware.RequestIDHeader) {
+			t.Errorf("expected the request ID %q in the body, got %q", w.Header().Get(middleware.RequestIDHeader), body.RequestID)
+		}
+		return body
+	}
+
+	t.Run("success data", func(t *testing.T) {
+		body := decode(sendJSON(router, http.MethodGet, "/api/recordings", nil, "", nil))
+		if !body.Success || body.Data["recordings"] == nil || body.Data["success"] != nil {
+			t.Errorf("expected the recordings under data, got %+v", body)
+		}
+	})
+
+	t.Run("typed errors", func(t *testing.T) {
+		tests := []struct {
+			method string
+			path   string
+			status int
+			code   response.Code
+		}{
+			{http.MethodGet, "/api/recordings/404", http.StatusNotFound, response.CodeNotFound},
+			{http.MethodGet, "/api/recordings/abc", http.StatusBadRequest, response.CodeBadRequest},
+			{http.MethodGet, "/no-such-page", http.StatusNotFound, response.CodeNotFound},
+			{http.MethodDelete, "/healthz", http.StatusMethodNotAllowed, response.CodeMethodNotAllowed},
+		}
+		for _, tt := range tests {
+			w := sendJSON(router, tt.method, tt.path, nil, "", nil)
+			body := decode(w)
+			if w.Code != tt.status || body.Success || body.Code != string(tt.code) || body.Error == "" {
+				t.Errorf("%s %s: expected %d %s, got %d %+v", tt.method, tt.path, tt.status, tt.code, w.Code, body)
+			}
+		}
+	})
+
+	t.Run("validation fields", func(t *testing.T) {
+		w := sendJSON(router, http.MethodPost, "/api/auth/register", Credentials{}, "", nil)
+		body := decode(w)
+		if w.Code != http.StatusBadRequest || body.Code != string(response.CodeValidation) || len(body.Fields) != 2 {
+			t.Fatalf("expected both credentials reported missing, got %d %+v", w.Code, body)
+		}
+		if body.Fields[0].Field != "username" || body.Fields[1].Field != "password" {
+			t.Errorf("expected username and password, got %+v", body.Fields)
+		}
+	})
+
+	t.Run("problem details", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/recordings/404", nil)
+		req.Header.Set("Accept", response.ContentTypeProblem)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		var problem response.Problem
+		json.Unmarshal(w.Body.Bytes(), &problem)
+		if w.Header().Get("Content-Type") != response.ContentTypeProblem || problem.Status != http.StatusNotFound {
+			t.Fatalf("expected problem details, got %q %s", w.Header().Get("Content-Type"), w.Body.String())
+		}
+		if problem.Code != response.CodeNotFound || problem.Detail != "Recording not found" || problem.Instance != "/api/recordings/404" {
+			t.Errorf("unexpected problem %+v", problem)
+		}
+	})
+}
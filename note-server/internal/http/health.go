@@ -0,0 +1,53 @@
+package http
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/your-org/note-server/internal/health"
+	"github.com/your-org/note-server/pkg/response"
+)
+
+// Time each health check gets before it's reported as failed
+const defaultHealthCheckTimeout = 3 * time.Second
+
+// readyCheck is a check as /readyz reports it, without errors or details,
+// since the endpoint is unauthenticated
+type readyCheck struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+}
+
+// SetHealthChecker replaces the checker behind /readyz and /api/system/health
+func (h *Handlers) SetHealthChecker(checker *health.Checker) {
+	h.health = checker
+}
+
+// ReadyHandler reports whether the server can take traffic, running only the
+// critical checks, at most every few seconds. It responds 503 when one of
+// them fails.
+func (h *Handlers) ReadyHandler(w http.ResponseWriter, r *http.Request) {
+	report := h.health.Readiness(r.Context())
+
+	checks := make([]readyCheck, len(report.Checks))
+	for i, result := range report.Checks {
+		checks[i] = readyCheck{Name: result.Name, Status: result.Status}
+	}
+
+	status := http.StatusOK
+	if !report.Ready() {
+		status = http.StatusServiceUnavailable
+	}
+	response.Write(w, r, status, response.Envelope{
+		Success: report.Ready(),
+		Data: map[string]any{
+			"status": report.Status,
+			"checks": checks,
+		},
+	})
+}
+
+// SystemHealthHandler runs every check and returns the full report
+func (h *Handlers) SystemHealthHandler(w http.ResponseWriter, r *http.Request) {
+	response.OK(w, r, h.health.Run(r.Context(), false))
+}
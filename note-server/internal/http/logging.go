@@ -0,0 +1,125 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/rs/zerolog"
+	"github.com/your-org/note-server/pkg/response"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// requestLogKey is the context key of the request's *requestLog
+type requestLogKey struct{}
+
+// requestLog collects what later middleware learns about a request for its
+// access log line
+type requestLog struct {
+	user string
+}
+
+// LogRequests is middleware that gives each request a logger carrying its
+// request ID, and trace ID when traced, available to handlers through
+// zerolog.Ctx, and logs the request as it completes: method, route pattern,
+// status, bytes written and latency. Panics are logged with their stack and
+// answered with a 500. It runs after middleware.RequestID, whose ID it echoes
+// in the X-Request-Id header, and TraceRequests.
+func LogRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		requestID := middleware.GetReqID(r.Context())
+		if requestID != "" {
+			w.Header().Set(middleware.RequestIDHeader, requestID)
+		}
+
+		fields := zerolog.Ctx(r.Context()).With().Str("request_id", requestID)
+		if sc := trace.SpanContextFromContext(r.Context()); sc.IsValid() {
+			fields = fields.Str("trace_id", sc.TraceID().String())
+		}
+		logger := fields.Logger()
+		entry := &requestLog{}
+		ctx := context.WithValue(logger.WithContext(r.Context()), requestLogKey{}, entry)
+
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		defer func() {
+			if rec := recover(); rec != nil {
+				if rec == http.ErrAbortHandler {
+					panic(rec)
+				}
+				logger.Error().
+					Interface("panic", rec).
+					Bytes("stack", debug.Stack()).
+					Msg("Handler panicked")
+				if ww.Status() == 0 && !isUpgrade(r) {
+					response.Error(ww, r, http.StatusInternalServerError, "Internal server error")
+				}
+			}
+
+			status := responseStatus(ww, r)
+			pattern := routePattern(r)
+			if pattern == "" {
+				pattern = r.URL.Path
+			}
+
+			event := logger.Info()
+			if status >= http.StatusInternalServerError {
+				event = logger.Error()
+			}
+			if entry.user != "" {
+				event = event.Str("user", entry.user)
+			}
+			event.
+				Str("method", r.Method).
+				Str("route", pattern).
+				Int("status", status).
+				Int("bytes", ww.BytesWritten()).
+				Dur("latency", time.Since(start)).
+				Str("remote_ip", r.RemoteAddr).
+				Msg("Request")
+		}()
+
+		next.ServeHTTP(ww, r.WithContext(ctx))
+	})
+}
+
+// responseStatus returns the status written to ww, which is 101 for a
+// connection hijacked for a WebSocket and 200 if nothing was written
+func responseStatus(ww middleware.WrapResponseWriter, r *http.Request) int {
+	status := ww.Status()
+	switch {
+	case status == 0 && isUpgrade(r):
+		return http.StatusSwitchingProtocols
+	case status == 0:
+		return http.StatusOK
+	}
+	return status
+}
+
+// routePattern returns the chi route pattern r matched, e.g.
+// "/api/recordings/{id}", or "" if it matched none
+func routePattern(r *http.Request) string {
+	if rctx := chi.RouteContext(r.Context()); rctx != nil {
+		return rctx.RoutePattern()
+	}
+	return ""
+}
+
+// isUpgrade reports whether r asks to switch protocols, e.g. to a WebSocket
+func isUpgrade(r *http.Request) bool {
+	return strings.EqualFold(r.Header.Get("Upgrade"), "websocket")
+}
+
+// withUser adds the authenticated user to the request's logger and access
+// log line
+func withUser(ctx context.Context, user string) context.Context {
+	if entry, ok := ctx.Value(requestLogKey{}).(*requestLog); ok {
+		entry.user = user
+	}
+	logger := zerolog.Ctx(ctx).With().Str("user", user).Logger()
+	return logger.WithContext(ctx)
+}
@@ -0,0 +1,39 @@
+package http
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/your-org/note-server/internal/metrics"
+)
+
+// requestDuration records how long requests take by route pattern, so
+// /api/recordings/1 and /api/recordings/2 share a series
+var requestDuration = metrics.NewHistogramVec(
+	"note_http_request_duration_seconds",
+	"Time to serve HTTP requests, by method, route pattern and status.",
+	metrics.DefBuckets,
+	"method", "route", "status",
+)
+
+// RecordMetrics is middleware that records each request's latency in
+// note_http_request_duration_seconds. Requests that match no route are
+// recorded under the route "unmatched".
+func RecordMetrics(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		defer func() {
+			route := routePattern(r)
+			if route == "" {
+				route = "unmatched"
+			}
+			status := strconv.Itoa(responseStatus(ww, r))
+			requestDuration.WithLabelValues(r.Method, route, status).ObserveSince(start)
+		}()
+
+		next.ServeHTTP(ww, r)
+	})
+}
@@ -0,0 +1,133 @@
+package http
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/rs/zerolog"
+	"github.com/your-org/note-server/internal/openapi"
+	"github.com/your-org/note-server/pkg/response"
+)
+
+// Largest response body ValidateAPI buffers to check; bigger bodies, such as
+// audio, only have their status and content type checked
+const maxValidatedResponse = 1 << 20
+
+// SetValidateAPI turns checking requests and responses against the OpenAPI
+// document on or off. It's meant for development: invalid requests are
+// rejected before reaching handlers, and mismatched responses are logged.
+func (h *Handlers) SetValidateAPI(enabled bool) {
+	h.validateAPI = enabled
+}
+
+// OpenAPIHandler serves the OpenAPI document describing the API
+func (h *Handlers) OpenAPIHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(openapi.Spec())
+}
+
+// ValidateAPI is middleware that checks requests and responses against doc,
+// looking up the operation by the pattern routes matches. Requests that don't
+// match are answered with a 400 listing the fields at fault; responses that
+// don't match are logged as warnings, as are routes missing from doc.
+func ValidateAPI(doc *openapi.Document, routes chi.Routes) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rctx := chi.NewRouteContext()
+			if !routes.Match(rctx, r.Method, r.URL.Path) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			pattern := rctx.RoutePattern()
+			logger := zerolog.Ctx(r.Context()).With().Str("route", pattern).Logger()
+
+			// Deprecated aliases are described by the v1 paths replacing them
+			op := doc.Operation(r.Method, pattern)
+			if op == nil {
+				op = doc.Operation(r.Method, v1Path(pattern))
+			}
+			if op == nil {
+				logger.Warn().Str("method", r.Method).Msg("Route is missing from the OpenAPI document")
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			pathParams := make(map[string]string, len(rctx.URLParams.Keys))
+			for i, key := range rctx.URLParams.Keys {
+				pathParams[key] = rctx.URLParams.Values[i]
+			}
+
+			// Only JSON bodies are read to be checked; uploads are left to stream
+			var body []byte
+			if isJSONRequest(r) {
+				var err error
+				if body, err = io.ReadAll(r.Body); err != nil {
+					response.Error(w, r, http.StatusBadRequest, "Failed to read request body")
+					return
+				}
+				r.Body = io.NopCloser(bytes.NewReader(body))
+			}
+			if fields := doc.ValidateRequest(op, r, pathParams, body); len(fields) > 0 {
+				response.Invalid(w, r, "Request doesn't match the API description", fields...)
+				return
+			}
+
+			if isUpgrade(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			buf := &cappedBuffer{limit: maxValidatedResponse}
+			ww.Tee(buf)
+			next.ServeHTTP(ww, r)
+
+			status := responseStatus(ww, r)
+			contentType := ww.Header().Get("Content-Type")
+			if contentType == "" && buf.Len() > 0 {
+				// As net/http will when it sends the response
+				contentType = http.DetectContentType(buf.Bytes())
+			}
+			if buf.truncated && strings.Contains(contentType, "json") {
+				logger.Debug().Int("status", status).Msg("Response too large to check against the OpenAPI document")
+				return
+			}
+			if err := doc.ValidateResponse(op, status, contentType, buf.Bytes()); err != nil {
+				logger.Warn().Err(err).Str("method", r.Method).Int("status", status).
+					Msg("Response doesn't match the OpenAPI document")
+			}
+		})
+	}
+}
+
+// isJSONRequest reports whether r's body is JSON, or is sent without a
+// content type, which handlers decode as JSON
+func isJSONRequest(r *http.Request) bool {
+	contentType := r.Header.Get("Content-Type")
+	if contentType == "" {
+		return r.ContentLength != 0
+	}
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	mediaType = strings.TrimSpace(mediaType)
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+// cappedBuffer keeps the first limit bytes written to it
+type cappedBuffer struct {
+	bytes.Buffer
+	limit     int
+	truncated bool
+}
+
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	if room := b.limit - b.Len(); len(p) > room {
+		b.truncated = true
+		b.Buffer.Write(p[:max(room, 0)])
+		return len(p), nil
+	}
+	return b.Buffer.Write(p)
+}
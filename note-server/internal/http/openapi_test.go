@@ -0,0 +1,189 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog"
+	"github.com/your-org/note-server/internal/auth"
+	"github.com/your-org/note-server/internal/database"
+	"github.com/your-org/note-server/internal/openapi"
+)
+
+// Every v1 route must be described so the web app's generated client covers it,
+// and every described operation must be routed
+func TestOpenAPICoversRoutes(t *testing.T) {
+	doc, err := openapi.Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	router := NewRouterWithHandlers(createMockTranscribeHub(), createHandlersWithMocks(&MockTranscriber{}, &MockSummarizer{}))
+	routed := map[string]bool{}
+	err = chi.Walk(router.(chi.Routes), func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		routed[method+" "+route] = true
+		described := doc.Operation(method, route) != nil
+		if !described && !strings.HasPrefix(route, "/api/v1/") {
+			// Deprecated aliases are described by the v1 paths replacing them
+			described = doc.Operation(method, v1Path(route)) != nil
+		}
+		if !described {
+			t.Errorf("%s %s is routed but missing from internal/openapi/openapi.json", method, route)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Walk() error = %v", err)
+	}
+
+	for path, item := range doc.Paths {
+		for method, op := range item {
+			if !routed[strings.ToUpper(method)+" "+path] {
+				t.Errorf("%s %s (%s) is described but not routed", strings.ToUpper(method), path, op.OperationID)
+			}
+		}
+	}
+}
+
+func TestOpenAPIHandler(t *testing.T) {
+	router := NewRouterWithHandlers(createMockTranscribeHub(), createHandlersWithMocks(&MockTranscriber{}, &MockSummarizer{}))
+	w := sendJSON(router, http.MethodGet, "/api/v1/openapi.json", nil, "", nil)
+
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "application/json" {
+		t.Fatalf("expected 200 application/json, got %d %q", w.Code, w.Header().Get("Content-Type"))
+	}
+	var doc struct {
+		OpenAPI string         `json:"openapi"`
+		Paths   map[string]any `json:"paths"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if !strings.HasPrefix(doc.OpenAPI, "3.") || doc.Paths["/api/v1/recordings"] == nil {
+		t.Errorf("expected an OpenAPI 3 document describing the API, got version %q", doc.OpenAPI)
+	}
+}
+
+func TestValidateAPI(t *testing.T) {
+	if err := database.InitDB(filepath.Join(t.TempDir(), "test.db")); err != nil {
+		t.Fatalf("failed to initialize database: %v", err)
+	}
+
+	handlers := createHandlersWithMocks(&MockTranscriber{}, &MockSummarizer{})
+	handlers.SetAuthenticator(auth.New(auth.Options{
+		Sessions:  database.NewSessionStore(),
+		APITokens: database.NewTokenStore(),
+	}))
+	handlers.SetValidateAPI(true)
+	router := NewRouterWithHandlers(createMockTranscribeHub(), handlers)
+
+	// Requests carry a logger so mismatched responses can be seen
+	var logs bytes.Buffer
+	send := func(method, path, body, token string) *httptest.ResponseRecorder {
+		var reader io.Reader
+		if body != "" {
+			reader = strings.NewReader(body)
+		}
+		req := httptest.NewRequest(method, path, reader)
+		if body != "" {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		req = req.WithContext(zerolog.New(&logs).WithContext(req.Context()))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := send(http.MethodPost, "/api/v1/auth/register", `{"username":"alice","password":"alice-password"}`, ""); w.Code != http.StatusCreated {
+		t.Fatalf("expected alice to be registered, got %d: %s", w.Code, w.Body.String())
+	}
+	w := send(http.MethodPost, "/api/v1/auth/login", `{"username":"alice","password":"alice-password"}`, "")
+	var login struct {
+		Data struct {
+			Token string `json:"token"`
+		} `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &login)
+	token := login.Data.Token
+	if token == "" {
+		t.Fatalf("expected a session token, got %d: %s", w.Code, w.Body.String())
+	}
+
+	alice, _ := database.GetUserByUsername("alice")
+	start := time.Now()
+	recordingID, err := database.AddRecording(context.Background(), alice.ID, "alice.wav", "/tmp/alice.wav", start, start, 0, 0, "wav", 16000, 1)
+	if err != nil {
+		t.Fatalf("failed to add recording: %v", err)
+	}
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		body           string
+		expectedStatus int
+		expectedField  string
+	}{
+		{"document", http.MethodGet, "/api/v1/openapi.json", "", http.StatusOK, ""},
+		{"liveness", http.MethodGet, "/healthz", "", http.StatusOK, ""},
+		{"readiness", http.MethodGet, "/readyz", "", http.StatusOK, ""},
+		{"current user", http.MethodGet, "/api/v1/auth/me", "", http.StatusOK, ""},
+		{"recordings", http.MethodGet, "/api/v1/recordings", "", http.StatusOK, ""},
+		{"deprecated alias", http.MethodGet, "/api/recordings", "", http.StatusOK, ""},
+		{"recording", http.MethodGet, fmt.Sprintf("/api/v1/recordings/%d", recordingID), "", http.StatusOK, ""},
+		{"transcript", http.MethodGet, fmt.Sprintf("/api/v1/recordings/%d/transcript", recordingID), "", http.StatusOK, ""},
+		{"share link", http.MethodPost, fmt.Sprintf("/api/v1/recordings/%d/links", recordingID), "", http.StatusCreated, ""},
+		{"missing recording", http.MethodGet, "/api/v1/recordings/999", "", http.StatusNotFound, ""},
+		{"notes", http.MethodGet, "/api/v1/notes", "", http.StatusOK, ""},
+		{"API token", http.MethodPost, "/api/v1/tokens", `{"name":"ci","scopes":["read"]}`, http.StatusCreated, ""},
+		{"API tokens", http.MethodGet, "/api/v1/tokens", "", http.StatusOK, ""},
+		{"config", http.MethodGet, "/api/v1/config", "", http.StatusOK, ""},
+		{"system health", http.MethodGet, "/api/v1/system/health", "", http.StatusOK, ""},
+		{"unknown route", http.MethodGet, "/api/v1/unknown", "", http.StatusNotFound, ""},
+		{"missing field", http.MethodPost, "/api/v1/auth/register", `{"username":"bob"}`, http.StatusBadRequest, "password"},
+		{"wrong type", http.MethodPost, "/api/v1/tokens", `{"name":"ci","scopes":"read"}`, http.StatusBadRequest, "scopes"},
+		{"unknown enum value", http.MethodPost, "/api/v1/tokens", `{"name":"ci","scopes":["root"]}`, http.StatusBadRequest, "scopes[0]"},
+		{"malformed JSON", http.MethodPost, "/api/v1/summarize", `{"text":`, http.StatusBadRequest, "body"},
+		{"non-integer path parameter", http.MethodGet, "/api/v1/recordings/abc", "", http.StatusBadRequest, "id"},
+		{"query parameter out of range", http.MethodGet, "/api/v1/config/audit?limit=0", "", http.StatusBadRequest, "limit"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logs.Reset()
+			w := send(tt.method, tt.path, tt.body, token)
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+			if strings.Contains(logs.String(), "OpenAPI") {
+				t.Errorf("expected the response to match the document, got %s", logs.String())
+			}
+			if tt.expectedField == "" {
+				return
+			}
+
+			var body struct {
+				Code   string `json:"code"`
+				Fields []struct {
+					Field string `json:"field"`
+				} `json:"fields"`
+			}
+			json.Unmarshal(w.Body.Bytes(), &body)
+			if body.Code != "validation_failed" || len(body.Fields) == 0 || body.Fields[0].Field != tt.expectedField {
+				t.Errorf("expected a validation error for %s, got %s", tt.expectedField, w.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package http
+
+import (
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// SetTrustedProxies replaces the reverse proxies RealIP takes the client's
+// address from. With none, every request is identified by its peer address.
+func (h *Handlers) SetTrustedProxies(proxies []netip.Prefix) {
+	h.trustedProxies = proxies
+}
+
+// RealIP is middleware that replaces RemoteAddr with the client address
+// reported by a trusted reverse proxy, so logs, rate limits and quotas see
+// the client rather than the proxy. Requests from other peers keep their
+// socket address: the X-Forwarded-For and X-Real-IP headers they send are
+// ignored, as anyone can set them. Peers on the Unix socket are always
+// trusted: it's only reachable by local processes allowed to open it, such as
+// the frontend's proxy, and they have no address of their own.
+//
+// X-Forwarded-For is read from the right, skipping the proxies in the chain;
+// the first address not in proxies is the client. X-Real-IP is used when the
+// proxy doesn't send X-Forwarded-For.
+func RealIP(proxies []netip.Prefix) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if unixSocket(r) || trustedPeer(proxies, r.RemoteAddr) {
+				if client, ok := forwardedClient(r.Header, proxies); ok {
+					r.RemoteAddr = client.String()
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// forwardedClient returns the client address a trusted proxy put in header
+func forwardedClient(header http.Header, proxies []netip.Prefix) (netip.Addr, bool) {
+	var chain []string
+	for _, value := range header.Values("X-Forwarded-For") {
+		chain = append(chain, strings.Split(value, ",")...)
+	}
+	if len(chain) == 0 {
+		return parseAddr(header.Get("X-Real-IP"))
+	}
+
+	var client netip.Addr
+	for i := len(chain) - 1; i >= 0; i-- {
+		addr, ok := parseAddr(chain[i])
+		if !ok {
+			break
+		}
+		client = addr
+		if !trusted(proxies, addr) {
+			break
+		}
+	}
+	return client, client.IsValid()
+}
+
+// unixSocket reports whether r arrived on a Unix domain socket listener
+func unixSocket(r *http.Request) bool {
+	local, ok := r.Context().Value(http.LocalAddrContextKey).(net.Addr)
+	return ok && local.Network() == "unix"
+}
+
+// trustedPeer reports whether the peer at remote is one of proxies
+func trustedPeer(proxies []netip.Prefix, remote string) bool {
+	peer, ok := remoteAddr(remote)
+	return ok && trusted(proxies, peer)
+}
+
+// remoteAddr parses a RemoteAddr, with or without a port
+func remoteAddr(value string) (netip.Addr, bool) {
+	if host, _, err := net.SplitHostPort(value); err == nil {
+		value = host
+	}
+	return parseAddr(value)
+}
+
+// parseAddr parses an IP address, unmapping IPv4 addresses written as IPv6
+func parseAddr(value string) (netip.Addr, bool) {
+	addr, err := netip.ParseAddr(strings.TrimSpace(value))
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return addr.Unmap(), true
+}
+
+// trusted reports whether addr is one of proxies
+func trusted(proxies []netip.Prefix, addr netip.Addr) bool {
+	for _, proxy := range proxies {
+		if proxy.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
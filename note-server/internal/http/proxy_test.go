@@ -0,0 +1,80 @@
+package http
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+)
+
+func TestRealIP(t *testing.T) {
+	proxies := []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8"), netip.MustParsePrefix("192.0.2.1/32")}
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		header     map[string]string
+		expected   string
+	}{
+		{"direct client", "198.51.100.7:4321", nil, "198.51.100.7:4321"},
+		{"spoofed header from an untrusted peer", "198.51.100.7:4321", map[string]string{"X-Forwarded-For": "203.0.113.9"}, "198.51.100.7:4321"},
+		{"spoofed X-Real-IP from an untrusted peer", "198.51.100.7:4321", map[string]string{"X-Real-IP": "203.0.113.9"}, "198.51.100.7:4321"},
+		{"trusted proxy", "192.0.2.1:4321", map[string]string{"X-Forwarded-For": "203.0.113.9"}, "203.0.113.9"},
+		{"client-supplied entries before the proxy's are ignored", "192.0.2.1:4321", map[string]string{"X-Forwarded-For": "1.2.3.4, 203.0.113.9"}, "203.0.113.9"},
+		{"chain of trusted proxies", "192.0.2.1:4321", map[string]string{"X-Forwarded-For": "203.0.113.9, 10.1.2.3"}, "203.0.113.9"},
+		{"X-Real-IP from a trusted proxy", "10.1.2.3:4321", map[string]string{"X-Real-IP": "203.0.113.9"}, "203.0.113.9"},
+		{"malformed header from a trusted proxy", "10.1.2.3:4321", map[string]string{"X-Forwarded-For": "unknown"}, "10.1.2.3:4321"},
+		{"IPv4-mapped proxy address", "[::ffff:10.1.2.3]:4321", map[string]string{"X-Forwarded-For": "203.0.113.9"}, "203.0.113.9"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var remoteAddr string
+			handler := RealIP(proxies)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				remoteAddr = r.RemoteAddr
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			for name, value := range tt.header {
+				req.Header.Set(name, value)
+			}
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+
+			if remoteAddr != tt.expected {
+				t.Errorf("expected RemoteAddr %q, got %q", tt.expected, remoteAddr)
+			}
+		})
+	}
+
+	// Without trusted proxies the headers never apply
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "192.0.2.1:4321"
+	req.Header.Set("X-Forwarded-For", "203.0.113.9")
+	RealIP(nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.RemoteAddr != "192.0.2.1:4321" {
+			t.Errorf("expected the peer address, got %q", r.RemoteAddr)
+		}
+	})).ServeHTTP(httptest.NewRecorder(), req)
+
+	// Peers on the Unix socket have no address and are always trusted
+	socket := &net.UnixAddr{Name: "/run/note/server.sock", Net: "unix"}
+	for _, header := range []map[string]string{
+		{"X-Forwarded-For": "203.0.113.9"},
+		{"X-Forwarded-For": "1.2.3.4, 203.0.113.9"},
+		{"X-Real-IP": "203.0.113.9"},
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req = req.WithContext(context.WithValue(req.Context(), http.LocalAddrContextKey, socket))
+		req.RemoteAddr = "@"
+		for name, value := range header {
+			req.Header.Set(name, value)
+		}
+		RealIP(nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.RemoteAddr != "203.0.113.9" {
+				t.Errorf("expected the forwarded client for %v, got %q", header, r.RemoteAddr)
+			}
+		})).ServeHTTP(httptest.NewRecorder(), req)
+	}
+}
@@ -0,0 +1,96 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/rs/zerolog"
+	"github.com/your-org/note-server/internal/auth"
+	"github.com/your-org/note-server/internal/ratelimit"
+	"github.com/your-org/note-server/internal/service"
+	"github.com/your-org/note-server/pkg/response"
+)
+
+// SetLimits replaces the limiter of requests to the routes wrapped in
+// RateLimit and the meter enforcing usage quotas. Nil disables either.
+func (h *Handlers) SetLimits(limiter *ratelimit.Limiter, meter *ratelimit.Meter) {
+	h.limiter = limiter
+	h.meter = meter
+}
+
+// RateLimit is middleware that limits how often each client may call the
+// routes it wraps, answering 429 with Retry-After once the client's bucket is
+// empty. Callers identified by Authenticate are limited per user and everyone
+// else per IP address; WebSocket handshakes, which authenticate in the hub,
+// are always limited per IP address.
+func (h *Handlers) RateLimit(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ok, wait := h.limiter.Allow(client(r)); !ok {
+			ratelimit.Reject(w, r, "rate", wait, "Too many requests")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// GetUsage handles GET /api/usage requests, reporting the caller's metered
+// usage today and this month against its quotas
+func (h *Handlers) GetUsage(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		response.Error(w, r, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	key := client(r)
+	usage, err := h.meter.Report(r.Context(), key)
+	if err != nil {
+		response.Error(w, r, http.StatusInternalServerError, fmt.Sprintf("Failed to get usage: %v", err))
+		return
+	}
+
+	data := map[string]any{
+		"client": key,
+		"usage":  usage,
+	}
+
+	response.OK(w, r, data)
+}
+
+// client returns the key the caller's limits and quotas are kept under
+func client(r *http.Request) string {
+	return ratelimit.ClientKey(auth.FromContext(r.Context()), r.RemoteAddr)
+}
+
+// checkQuota writes a 429 and returns false if the client has used up its
+// quota of metric
+func (h *Handlers) checkQuota(w http.ResponseWriter, r *http.Request, client, metric string) bool {
+	err := h.meter.Check(r.Context(), client, metric)
+	if err == nil {
+		return true
+	}
+
+	var quotaErr *ratelimit.QuotaError
+	if !errors.As(err, &quotaErr) {
+		response.Error(w, r, http.StatusInternalServerError, fmt.Sprintf("Failed to check usage: %v", err))
+		return false
+	}
+	ratelimit.RejectQuota(w, r, quotaErr)
+	return false
+}
+
+// recordUsage adds the work tallied in usage to the client's usage. Work is
+// recorded even when the request that did it fails, as providers bill for it.
+func (h *Handlers) recordUsage(ctx context.Context, client string, usage *service.Usage) {
+	// A client hanging up doesn't undo the work
+	ctx = context.WithoutCancel(ctx)
+	for metric, amount := range map[string]float64{
+		ratelimit.TranscriptionMinutes: usage.Audio().Minutes(),
+		ratelimit.SummaryTokens:        float64(usage.SummaryTokens()),
+	} {
+		if err := h.meter.Record(ctx, client, metric, amount); err != nil {
+			zerolog.Ctx(ctx).Error().Err(err).Str("metric", metric).Msg("Failed to record usage")
+		}
+	}
+}
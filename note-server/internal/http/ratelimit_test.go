@@ -0,0 +1,127 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/your-org/note-server/internal/database"
+	"github.com/your-org/note-server/internal/ratelimit"
+)
+
+func TestRateLimit(t *testing.T) {
+	handlers := createHandlersWithMocks(&MockTranscriber{}, &MockSummarizer{})
+	handlers.SetLimits(ratelimit.New(1, 2), nil)
+	router := NewRouterWithHandlers(createMockTranscribeHub(), handlers)
+
+	summarize := SummarizeRequest{Text: "hello"}
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		body           any
+		remoteAddr     string
+		expectedStatus int
+	}{
+		{"within the burst", http.MethodPost, "/api/v1/summarize", summarize, "", http.StatusOK},
+		{"deprecated alias shares the bucket", http.MethodPost, "/summarize", summarize, "", http.StatusOK},
+		{"over the limit", http.MethodPost, "/api/v1/summarize", summarize, "", http.StatusTooManyRequests},
+		{"transcription shares the bucket", http.MethodPost, "/api/v1/transcribe", nil, "", http.StatusTooManyRequests},
+		{"recording upload shares the bucket", http.MethodPost, "/api/v1/upload-recording?transcribe=true", nil, "", http.StatusTooManyRequests},
+		{"WebSocket handshake", http.MethodGet, "/api/v1/ws/transcribe", nil, "", http.StatusTooManyRequests},
+		{"another client", http.MethodPost, "/api/v1/summarize", summarize, "198.51.100.7:4321", http.StatusOK},
+		{"unlimited route", http.MethodGet, "/healthz", nil, "", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body *strings.Reader
+			if tt.body != nil {
+				data, _ := json.Marshal(tt.body)
+				body = strings.NewReader(string(data))
+			} else {
+				body = strings.NewReader("")
+			}
+			req := httptest.NewRequest(tt.method, tt.path, body)
+			if tt.remoteAddr != "" {
+				req.RemoteAddr = tt.remoteAddr
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+			if tt.expectedStatus != http.StatusTooManyRequests {
+				return
+			}
+			if retryAfter := w.Header().Get("Retry-After"); retryAfter != "1" {
+				t.Errorf("expected Retry-After 1, got %q", retryAfter)
+			}
+			if !strings.Contains(w.Body.String(), `"code":"rate_limited"`) {
+				t.Errorf("expected the rate_limited code, got %s", w.Body.String())
+			}
+		})
+	}
+}
+
+func TestQuotas(t *testing.T) {
+	if err := database.InitDB(filepath.Join(t.TempDir(), "test.db")); err != nil {
+		t.Fatalf("failed to initialize database: %v", err)
+	}
+	meter := ratelimit.NewMeter(database.NewUsageStore(), map[string]ratelimit.Quota{
+		ratelimit.TranscriptionMinutes: {Monthly: 1},
+		ratelimit.SummaryTokens:        {Daily: 5},
+	})
+	handlers := createHandlersWithMocks(&MockTranscriber{}, &MockSummarizer{})
+	handlers.SetLimits(nil, meter)
+	router := NewRouterWithHandlers(createMockTranscribeHub(), handlers)
+
+	// 24 characters in and "mock summary" out are 9 tokens, taking the
+	// client past its quota of 5
+	summarize := SummarizeRequest{Text: "twenty-four characters!!"}
+	if w := sendJSON(router, http.MethodPost, "/api/v1/summarize", summarize, "", nil); w.Code != http.StatusOK {
+		t.Fatalf("expected the first summary to be allowed, got %d: %s", w.Code, w.Body.String())
+	}
+	w := sendJSON(router, http.MethodPost, "/api/v1/summarize", summarize, "", nil)
+	if w.Code != http.StatusTooManyRequests || !strings.Contains(w.Body.String(), "Daily quota of 5 summary tokens used up") {
+		t.Fatalf("expected the daily quota to be used up, got %d: %s", w.Code, w.Body.String())
+	}
+	if retryAfter, _ := strconv.Atoi(w.Header().Get("Retry-After")); retryAfter < 1 || retryAfter > 24*60*60 {
+		t.Errorf("expected to retry by midnight UTC, got Retry-After %q", w.Header().Get("Retry-After"))
+	}
+
+	// Transcriptions are checked before the upload is read
+	meter.Record(context.Background(), "ip:192.0.2.1", ratelimit.TranscriptionMinutes, 1)
+	if w := sendJSON(router, http.MethodPost, "/api/v1/transcribe", nil, "", nil); w.Code != http.StatusTooManyRequests {
+		t.Errorf("expected the monthly quota to be used up, got %d: %s", w.Code, w.Body.String())
+	}
+
+	w = sendJSON(router, http.MethodGet, "/api/v1/usage", nil, "", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected the usage report, got %d: %s", w.Code, w.Body.String())
+	}
+	var report struct {
+		Data struct {
+			Client string            `json:"client"`
+			Usage  []ratelimit.Usage `json:"usage"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &report); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if report.Data.Client != "ip:192.0.2.1" || len(report.Data.Usage) != 2 {
+		t.Fatalf("expected both metrics for the caller's address, got %s", w.Body.String())
+	}
+	minutes, tokens := report.Data.Usage[0], report.Data.Usage[1]
+	if minutes.Month.Used != 1 || minutes.Month.Remaining == nil || *minutes.Month.Remaining != 0 || minutes.Day.Limit != nil {
+		t.Errorf("unexpected transcription usage %+v", minutes)
+	}
+	if tokens.Day.Used != 9 || tokens.Day.Limit == nil || *tokens.Day.Limit != 5 {
+		t.Errorf("unexpected summary usage %+v", tokens)
+	}
+}
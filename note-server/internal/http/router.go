@@ -5,7 +5,12 @@ import (
 	
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
+	"github.com/your-org/note-server/internal/auth"
+	"github.com/your-org/note-server/internal/database"
+	"github.com/your-org/note-server/internal/metrics"
+	"github.com/your-org/note-server/internal/openapi"
 	"github.com/your-org/note-server/internal/ws"
+	"github.com/your-org/note-server/pkg/response"
 )
 
 // NewRouter creates a new HTTP router with all routes configured
@@ -17,45 +22,171 @@ func NewRouter(transcribeHub *ws.TranscribeHub) http.Handler {
 func NewRouterWithHandlers(transcribeHub *ws.TranscribeHub, handlers *Handlers) http.Handler {
 	r := chi.NewRouter()
 	
-	// Middleware
-	r.Use(middleware.Logger)
-	r.Use(middleware.Recoverer)
+	// Middleware; LogRequests also recovers from panics, so RecordMetrics
+	// sees their 500s
 	r.Use(middleware.RequestID)
-	r.Use(middleware.RealIP)
+	r.Use(RealIP(handlers.trustedProxies))
+	r.Use(TraceRequests)
+	r.Use(RecordMetrics)
+	r.Use(LogRequests)
 	
-	// Health check endpoint
+	// In development, requests and responses are checked against the OpenAPI
+	// document
+	if handlers.validateAPI {
+		doc, err := openapi.Load()
+		if err != nil {
+			panic(err)
+		}
+		r.Use(ValidateAPI(doc, r))
+	}
+	
+	// Unknown routes get the same JSON errors as handlers
+	r.NotFound(func(w http.ResponseWriter, r *http.Request) {
+		response.Error(w, r, http.StatusNotFound, "Not found")
+	})
+	r.MethodNotAllowed(func(w http.ResponseWriter, r *http.Request) {
+		response.Error(w, r, http.StatusMethodNotAllowed, "Method not allowed")
+	})
+	
+	// Liveness and readiness probes
 	r.Get("/healthz", handlers.HealthHandler)
+	r.Get("/readyz", handlers.ReadyHandler)
 	
-	// API routes
-	r.Post("/transcribe", handlers.TranscribeHandler)
-	r.Post("/summarize", handlers.SummarizeHandler)
-	r.Route("/api", func(r chi.Router) {
-		// Notes endpoints
-		r.Get("/notes", handlers.GetNotes)
-		r.Post("/notes", handlers.CreateNote)
-		
-		// Meetings endpoints
-		r.Get("/meetings", handlers.GetMeetings)
-		r.Get("/meetings/{id}", handlers.GetMeeting)
+	// Prometheus metrics
+	r.Method(http.MethodGet, "/metrics", metrics.Handler())
+	
+	// The current API version; a version that changes response shapes gets
+	// its own prefix and routes function, mounted beside this one
+	r.Route("/api/v1", func(r chi.Router) {
+		r.Use(WithAPIVersion(1))
+		routesV1(r, r, handlers, transcribeHub)
+	})
+	
+	// The unversioned paths v1 replaced, kept as deprecated aliases. The
+	// middleware goes on each route, not the /api mount, so unknown paths
+	// such as /api/v2/... aren't marked deprecated.
+	deprecated := chi.Chain(Deprecated, WithAPIVersion(1))
+	r.Route("/api", func(api chi.Router) {
+		routesV1(r.With(deprecated...), api.With(deprecated...), handlers, transcribeHub)
+	})
+	
+	return r
+}
+
+// routesV1 registers the v1 API. Transcription, summarization and WebSockets
+// go on root and everything else on api, which are the same router under
+// /api/v1 and differ for the deprecated unversioned aliases.
+func routesV1(root, api chi.Router, handlers *Handlers, transcribeHub *ws.TranscribeHub) {
+	// Personal API tokens are limited to the scopes they were granted
+	read := handlers.RequireScope(auth.ScopeRead)
+	write := handlers.RequireScope(auth.ScopeWrite)
+	transcribe := handlers.RequireScope(auth.ScopeTranscribe)
+	admin := handlers.RequireScope(auth.ScopeAdmin)
+	
+	// Transcription and summarization identify the caller first, rejecting
+	// anonymous requests once authentication is required, and are rate
+	// limited as they spend provider credits
+	root.Group(func(r chi.Router) {
+		r.Use(handlers.Authenticate, transcribe, handlers.RateLimit)
+		r.Post("/transcribe", handlers.TranscribeHandler)
+		r.Post("/summarize", handlers.SummarizeHandler)
+	})
+	
+	api.Group(func(r chi.Router) {
+		// The OpenAPI document describing these routes
+		r.Get("/openapi.json", handlers.OpenAPIHandler)
 		
-		// Interviews endpoints
-		r.Get("/interviews", handlers.GetInterviews)
+		// Login and registration are reachable without credentials
+		r.Post("/auth/register", handlers.Register)
+		r.Post("/auth/login", handlers.Login)
+		r.Post("/auth/logout", handlers.Logout)
 		
-		// Recordings endpoints
-		r.Get("/recordings", handlers.GetRecordings)
-		r.Get("/recordings/{id}", handlers.GetRecording)
-		r.Get("/recordings/{id}/audio", handlers.GetRecordingAudio)
-		r.Post("/upload-recording", handlers.UploadRecording)
+		// Share links carry their own credentials
+		r.Get("/shared/{token}/transcript", handlers.GetSharedTranscript)
+		r.Get("/shared/{token}/audio", handlers.GetSharedAudio)
 		
-		// Configuration endpoints
-		r.Get("/config", handlers.GetConfig)
-		r.Put("/config", handlers.SetConfig)
-		r.Get("/config/raw", handlers.GetConfigRaw)
+		r.Group(func(r chi.Router) {
+			r.Use(handlers.Authenticate)
+			
+			// Current user
+			r.Get("/auth/me", handlers.Me)
+			
+			// Notes endpoints
+			r.With(read).Get("/notes", handlers.GetNotes)
+			r.With(write).Post("/notes", handlers.CreateNote)
+			r.With(write).Patch("/notes/{id}", handlers.UpdateDocument(database.ResourceNote))
+			
+			// Meetings endpoints
+			r.With(read).Get("/meetings", handlers.GetMeetings)
+			r.With(read).Get("/meetings/{id}", handlers.GetMeeting)
+			r.With(write).Patch("/meetings/{id}", handlers.UpdateDocument(database.ResourceMeeting))
+			
+			// Interviews endpoints
+			r.With(read).Get("/interviews", handlers.GetInterviews)
+			r.With(write).Patch("/interviews/{id}", handlers.UpdateDocument(database.ResourceInterview))
+			
+			// Recordings endpoints
+			r.With(read).Get("/recordings", handlers.GetRecordings)
+			r.With(read).Get("/recordings/{id}", handlers.GetRecording)
+			r.With(read).Get("/recordings/{id}/audio", handlers.GetRecordingAudio)
+			r.With(read).Get("/recordings/{id}/transcript", handlers.GetRecordingTranscript)
+			// Uploads may be transcribed, spending provider credits
+			r.With(write, handlers.RateLimit).Post("/upload-recording", handlers.UploadRecording)
+			
+			// Share grants give other users viewer or editor access; owners
+			// manage them
+			for _, resource := range []struct {
+				path string
+				kind string
+			}{
+				{"/notes", database.ResourceNote},
+				{"/meetings", database.ResourceMeeting},
+				{"/interviews", database.ResourceInterview},
+				{"/recordings", database.ResourceRecording},
+			} {
+				r.With(read).Get(resource.path+"/{id}/shares", handlers.GetShares(resource.kind))
+				r.With(write).Post(resource.path+"/{id}/shares", handlers.CreateShare(resource.kind))
+				r.With(write).Delete(resource.path+"/{id}/shares/{shareId}", handlers.DeleteShare(resource.kind))
+			}
+			
+			// Expiring links for read-only access to a recording from outside
+			r.With(read).Get("/recordings/{id}/links", handlers.GetShareLinks)
+			r.With(write).Post("/recordings/{id}/links", handlers.CreateShareLink)
+			r.With(write).Delete("/recordings/{id}/links/{linkId}", handlers.DeleteShareLink)
+			
+			// The caller's transcription and summarization usage against its quotas
+			r.With(read).Get("/usage", handlers.GetUsage)
+			
+			// Structured checks of the server's dependencies
+			r.With(read).Get("/system/health", handlers.SystemHealthHandler)
+			
+			// Background jobs
+			r.With(read).Get("/jobs/{id}", handlers.GetJob)
+			r.With(read).Get("/jobs/{id}/events", handlers.StreamJobEvents)
+			
+			// Server-Sent Events fallback for the /ws event channel
+			r.With(read).Get("/events", handlers.StreamEvents)
+			
+			// WebSocket tickets, which carry the caller's scopes
+			r.Post("/ws-ticket", handlers.CreateWSTicket)
+			
+			// Personal API tokens
+			r.With(admin).Get("/tokens", handlers.GetAPITokens)
+			r.With(admin).Post("/tokens", handlers.CreateAPIToken)
+			r.With(admin).Delete("/tokens/{id}", handlers.DeleteAPIToken)
+			
+			// Configuration endpoints; changes, revealing secrets and the
+			// audit log are for administrators only
+			r.With(read).Get("/config", handlers.GetConfig)
+			r.With(handlers.RequireAdmin).Put("/config", handlers.SetConfig)
+			r.With(handlers.RequireAdmin).Patch("/config", handlers.PatchConfig)
+			r.With(handlers.RequireAdmin).Get("/config/raw", handlers.GetConfigRaw)
+			r.With(handlers.RequireAdmin).Get("/config/audit", handlers.GetConfigAudit)
+		})
 	})
 	
-	// WebSocket endpoints
-	r.Get("/ws", WebSocketHandler)
-	r.Get("/ws/transcribe", transcribeHub.ServeTranscribeWS)
-	
-	return r
+	// WebSocket endpoints authenticate their own handshakes
+	root.Get("/ws", transcribeHub.ServeEventsWS)
+	root.With(handlers.RateLimit).Get("/ws/transcribe", transcribeHub.ServeTranscribeWS)
+	root.Get("/ws/transcribe/{session}/watch", transcribeHub.ServeWatchWS)
 }
@@ -0,0 +1,412 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/your-org/note-server/internal/auth"
+	"github.com/your-org/note-server/internal/database"
+	"github.com/your-org/note-server/pkg/response"
+)
+
+// defaultShareLinkTTL is how long a share link lasts when no expiry is given
+const defaultShareLinkTTL = 7 * 24 * time.Hour
+
+// ShareRequest is the request body of POST /api/{resource}/{id}/shares
+type ShareRequest struct {
+	Username string        `json:"username"`
+	Role     database.Role `json:"role"`
+}
+
+// CreateShareLinkRequest is the request body of POST /api/recordings/{id}/links
+type CreateShareLinkRequest struct {
+	// Optional expiry, as an RFC 3339 time. Links last a week by default.
+	ExpiresAt string `json:"expiresAt,omitempty"`
+}
+
+// resourceAccess returns the ID of the resource named by the {id} URL parameter
+// and the caller's role on it. It writes a 404 when the caller can't see the
+// resource, so hidden resources look the same as missing ones.
+func (h *Handlers) resourceAccess(w http.ResponseWriter, r *http.Request, kind string) (int64, database.Role, bool) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		response.Error(w, r, http.StatusBadRequest, fmt.Sprintf("Invalid %s ID", kind))
+		return 0, database.RoleNone, false
+	}
+
+	principal, ok := h.principal(w, r)
+	if !ok {
+		return 0, database.RoleNone, false
+	}
+
+	role, err := database.ResourceRole(kind, id, owner(principal))
+	if err != nil {
+		response.Error(w, r, http.StatusInternalServerError, fmt.Sprintf("Failed to check access: %v", err))
+		return 0, database.RoleNone, false
+	}
+	if !role.CanView() {
+		response.Error(w, r, http.StatusNotFound, fmt.Sprintf("%s not found", capitalize(kind)))
+		return 0, database.RoleNone, false
+	}
+	return id, role, true
+}
+
+// requireOwner writes a 403 unless role owns the resource
+func requireOwner(w http.ResponseWriter, r *http.Request, role database.Role, kind string) bool {
+	if role != database.RoleOwner {
+		response.Error(w, r, http.StatusForbidden, fmt.Sprintf("Only the owner can share this %s", kind))
+		return false
+	}
+	return true
+}
+
+// GetShares returns a handler for GET /api/{resource}/{id}/shares requests,
+// listing the users a resource is shared with
+func (h *Handlers) GetShares(kind string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			response.Error(w, r, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+
+		id, _, ok := h.resourceAccess(w, r, kind)
+		if !ok {
+			return
+		}
+
+		shares, err := database.GetShares(kind, id)
+		if err != nil {
+			response.Error(w, r, http.StatusInternalServerError, fmt.Sprintf("Failed to get shares: %v", err))
+			return
+		}
+
+		data := map[string]any{
+			"shares": shares,
+		}
+
+		response.OK(w, r, data)
+	}
+}
+
+// CreateShare returns a handler for POST /api/{resource}/{id}/shares requests,
+// granting a user a role on a resource. Granting a user a second role
+// replaces the first.
+func (h *Handlers) CreateShare(kind string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			response.Error(w, r, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+
+		id, role, ok := h.resourceAccess(w, r, kind)
+		if !ok || !requireOwner(w, r, role, kind) {
+			return
+		}
+
+		var req ShareRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			response.Error(w, r, http.StatusBadRequest, "Invalid JSON format")
+			return
+		}
+		if !req.Role.Grantable() {
+			response.Invalid(w, r, `Role must be "viewer" or "editor"`, response.FieldError{Field: "role", Message: `must be "viewer" or "editor"`})
+			return
+		}
+
+		user, err := database.GetUserByUsername(strings.TrimSpace(req.Username))
+		if err != nil {
+			response.Error(w, r, http.StatusInternalServerError, fmt.Sprintf("Failed to get user: %v", err))
+			return
+		}
+		if user == nil {
+			response.Error(w, r, http.StatusNotFound, "User not found")
+			return
+		}
+
+		userRole, err := database.ResourceRole(kind, id, database.OwnedBy(user.ID))
+		if err != nil {
+			response.Error(w, r, http.StatusInternalServerError, fmt.Sprintf("Failed to check access: %v", err))
+			return
+		}
+		if userRole == database.RoleOwner {
+			response.Error(w, r, http.StatusBadRequest, fmt.Sprintf("%s already owns this %s", user.Username, kind))
+			return
+		}
+
+		share, err := database.GrantShare(kind, id, user.ID, req.Role)
+		if err != nil {
+			response.Error(w, r, http.StatusInternalServerError, fmt.Sprintf("Failed to share %s: %v", kind, err))
+			return
+		}
+
+		response.Created(w, r, map[string]any{
+			"share": share,
+		})
+	}
+}
+
+// DeleteShare returns a handler for DELETE /api/{resource}/{id}/shares/{shareId}
+// requests, revoking a user's access to a resource
+func (h *Handlers) DeleteShare(kind string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete {
+			response.Error(w, r, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+
+		shareID, err := strconv.ParseInt(chi.URLParam(r, "shareId"), 10, 64)
+		if err != nil {
+			response.Error(w, r, http.StatusBadRequest, "Invalid share ID")
+			return
+		}
+
+		id, role, ok := h.resourceAccess(w, r, kind)
+		if !ok || !requireOwner(w, r, role, kind) {
+			return
+		}
+
+		share, err := database.GetShare(shareID)
+		if err != nil {
+			response.Error(w, r, http.StatusInternalServerError, fmt.Sprintf("Failed to get share: %v", err))
+			return
+		}
+		if share == nil || share.ResourceType != kind || share.ResourceID != id {
+			response.Error(w, r, http.StatusNotFound, "Share not found")
+			return
+		}
+
+		if err := database.DeleteShare(shareID); err != nil {
+			response.Error(w, r, http.StatusInternalServerError, fmt.Sprintf("Failed to revoke share: %v", err))
+			return
+		}
+
+		data := map[string]any{
+			"message": "Share revoked",
+		}
+
+		response.OK(w, r, data)
+	}
+}
+
+// UpdateDocument returns a handler for PATCH /api/{notes,meetings,interviews}/{id}
+// requests. Owners and editors may change a document; viewers may not.
+func (h *Handlers) UpdateDocument(kind string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPatch {
+			response.Error(w, r, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+
+		id, role, ok := h.resourceAccess(w, r, kind)
+		if !ok {
+			return
+		}
+		if !role.CanEdit() {
+			response.Error(w, r, http.StatusForbidden, fmt.Sprintf("You have read-only access to this %s", kind))
+			return
+		}
+
+		var fields database.DocumentFields
+		if err := json.NewDecoder(r.Body).Decode(&fields); err != nil {
+			response.Error(w, r, http.StatusBadRequest, "Invalid JSON format")
+			return
+		}
+
+		updated, err := database.UpdateDocument(kind, id, fields)
+		if err != nil {
+			response.Error(w, r, http.StatusInternalServerError, fmt.Sprintf("Failed to update %s: %v", kind, err))
+			return
+		}
+		if !updated {
+			response.Error(w, r, http.StatusNotFound, fmt.Sprintf("%s not found", capitalize(kind)))
+			return
+		}
+
+		data := map[string]any{
+			"message": fmt.Sprintf("%s updated", capitalize(kind)),
+		}
+
+		response.OK(w, r, data)
+	}
+}
+
+// GetShareLinks handles GET /api/recordings/{id}/links requests, listing a
+// recording's unexpired share links
+func (h *Handlers) GetShareLinks(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		response.Error(w, r, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	id, role, ok := h.resourceAccess(w, r, database.ResourceRecording)
+	if !ok || !requireOwner(w, r, role, database.ResourceRecording) {
+		return
+	}
+
+	links, err := database.GetShareLinks(id)
+	if err != nil {
+		response.Error(w, r, http.StatusInternalServerError, fmt.Sprintf("Failed to get share links: %v", err))
+		return
+	}
+
+	data := map[string]any{
+		"links": links,
+	}
+
+	response.OK(w, r, data)
+}
+
+// CreateShareLink handles POST /api/recordings/{id}/links requests. The link's
+// token is returned only in this response; the server keeps just its hash.
+func (h *Handlers) CreateShareLink(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		response.Error(w, r, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	id, role, ok := h.resourceAccess(w, r, database.ResourceRecording)
+	if !ok || !requireOwner(w, r, role, database.ResourceRecording) {
+		return
+	}
+
+	var req CreateShareLinkRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			response.Error(w, r, http.StatusBadRequest, "Invalid JSON format")
+			return
+		}
+	}
+
+	expiresAt := time.Now().Add(defaultShareLinkTTL)
+	if req.ExpiresAt != "" {
+		var err error
+		expiresAt, err = time.Parse(time.RFC3339, req.ExpiresAt)
+		if err != nil {
+			response.Invalid(w, r, "expiresAt must be an RFC 3339 time", response.FieldError{Field: "expiresAt", Message: "must be an RFC 3339 time"})
+			return
+		}
+		if !expiresAt.After(time.Now()) {
+			response.Invalid(w, r, "expiresAt must be in the future", response.FieldError{Field: "expiresAt", Message: "must be in the future"})
+			return
+		}
+	}
+
+	principal, _ := auth.Lookup(r.Context())
+	token, tokenHash := auth.NewSessionToken()
+	link, err := database.CreateShareLink(id, principal.UserID, tokenHash, expiresAt)
+	if err != nil {
+		response.Error(w, r, http.StatusInternalServerError, fmt.Sprintf("Failed to create share link: %v", err))
+		return
+	}
+
+	response.Created(w, r, map[string]any{
+		"token":         token,
+		"link":          link,
+		"transcriptUrl": "/api/v1/shared/" + token + "/transcript",
+		"audioUrl":      "/api/v1/shared/" + token + "/audio",
+	})
+}
+
+// DeleteShareLink handles DELETE /api/recordings/{id}/links/{linkId} requests
+func (h *Handlers) DeleteShareLink(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		response.Error(w, r, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	linkID, err := strconv.ParseInt(chi.URLParam(r, "linkId"), 10, 64)
+	if err != nil {
+		response.Error(w, r, http.StatusBadRequest, "Invalid link ID")
+		return
+	}
+
+	id, role, ok := h.resourceAccess(w, r, database.ResourceRecording)
+	if !ok || !requireOwner(w, r, role, database.ResourceRecording) {
+		return
+	}
+
+	deleted, err := database.DeleteShareLink(linkID, id)
+	if err != nil {
+		response.Error(w, r, http.StatusInternalServerError, fmt.Sprintf("Failed to revoke share link: %v", err))
+		return
+	}
+	if !deleted {
+		response.Error(w, r, http.StatusNotFound, "Share link not found")
+		return
+	}
+
+	data := map[string]any{
+		"message": "Share link revoked",
+	}
+
+	response.OK(w, r, data)
+}
+
+// sharedRecording returns the recording the {token} URL parameter links to,
+// writing a 404 when the link is unknown or expired
+func sharedRecording(w http.ResponseWriter, r *http.Request) (map[string]any, bool) {
+	recordingID, err := database.GetShareLinkRecording(auth.HashToken(chi.URLParam(r, "token")))
+	if err != nil {
+		response.Error(w, r, http.StatusInternalServerError, fmt.Sprintf("Failed to get share link: %v", err))
+		return nil, false
+	}
+
+	var recording map[string]any
+	if recordingID != 0 {
+		recording, err = database.GetRecording(r.Context(), int(recordingID), database.AnyOwner)
+		if err != nil {
+			response.Error(w, r, http.StatusInternalServerError, fmt.Sprintf("Failed to get recording: %v", err))
+			return nil, false
+		}
+	}
+	if recording == nil {
+		response.Error(w, r, http.StatusNotFound, "Share link not found or expired")
+		return nil, false
+	}
+	return recording, true
+}
+
+// GetSharedTranscript handles GET /api/shared/{token}/transcript requests,
+// which need no credentials beyond the link's token
+func (h *Handlers) GetSharedTranscript(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		response.Error(w, r, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	recording, ok := sharedRecording(w, r)
+	if !ok {
+		return
+	}
+
+	writeTranscript(w, r, recording["id"].(int))
+}
+
+// GetSharedAudio handles GET /api/shared/{token}/audio requests, which need no
+// credentials beyond the link's token
+func (h *Handlers) GetSharedAudio(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		response.Error(w, r, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	recording, ok := sharedRecording(w, r)
+	if !ok {
+		return
+	}
+
+	serveRecordingAudio(w, r, recording)
+}
+
+// capitalize upper-cases the first letter of a resource kind for messages
+func capitalize(kind string) string {
+	if kind == "" {
+		return kind
+	}
+	return strings.ToUpper(kind[:1]) + kind[1:]
+}
@@ -0,0 +1,159 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/your-org/note-server/internal/auth"
+	"github.com/your-org/note-server/internal/database"
+	"github.com/your-org/note-server/pkg/response"
+)
+
+// CreateAPITokenRequest is the request body of POST /api/tokens
+type CreateAPITokenRequest struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+
+	// Optional expiry, as an RFC 3339 time
+	ExpiresAt string `json:"expiresAt,omitempty"`
+}
+
+// tokenOwner returns the user account whose tokens the caller manages, writing
+// an error when the caller isn't a user
+func (h *Handlers) tokenOwner(w http.ResponseWriter, r *http.Request) (auth.Principal, bool) {
+	principal, ok := h.principal(w, r)
+	if !ok {
+		return principal, false
+	}
+	if principal.UserID == 0 {
+		response.Error(w, r, http.StatusForbidden, "API tokens belong to user accounts; log in first")
+		return principal, false
+	}
+	return principal, true
+}
+
+// GetAPITokens handles GET /api/tokens requests, listing the caller's tokens
+func (h *Handlers) GetAPITokens(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		response.Error(w, r, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	principal, ok := h.tokenOwner(w, r)
+	if !ok {
+		return
+	}
+
+	tokens, err := database.GetAPITokens(principal.UserID)
+	if err != nil {
+		response.Error(w, r, http.StatusInternalServerError, fmt.Sprintf("Failed to get API tokens: %v", err))
+		return
+	}
+
+	data := map[string]any{
+		"tokens": tokens,
+	}
+
+	response.OK(w, r, data)
+}
+
+// CreateAPIToken handles POST /api/tokens requests. The token is returned only
+// in this response; the server keeps just its hash.
+func (h *Handlers) CreateAPIToken(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		response.Error(w, r, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	principal, ok := h.tokenOwner(w, r)
+	if !ok {
+		return
+	}
+
+	var req CreateAPITokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.Error(w, r, http.StatusBadRequest, "Invalid JSON format")
+		return
+	}
+
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		response.Invalid(w, r, "Token name is required", response.FieldError{Field: "name", Message: "is required"})
+		return
+	}
+
+	scopes, err := auth.ParseScopes(req.Scopes)
+	if err != nil {
+		response.Invalid(w, r, err.Error(), response.FieldError{Field: "scopes", Message: err.Error()})
+		return
+	}
+	if scopes == 0 {
+		response.Invalid(w, r, "At least one scope is required", response.FieldError{Field: "scopes", Message: "at least one is required"})
+		return
+	}
+
+	var expiresAt time.Time
+	if req.ExpiresAt != "" {
+		expiresAt, err = time.Parse(time.RFC3339, req.ExpiresAt)
+		if err != nil {
+			response.Invalid(w, r, "expiresAt must be an RFC 3339 time", response.FieldError{Field: "expiresAt", Message: "must be an RFC 3339 time"})
+			return
+		}
+		if !expiresAt.After(time.Now()) {
+			response.Invalid(w, r, "expiresAt must be in the future", response.FieldError{Field: "expiresAt", Message: "must be in the future"})
+			return
+		}
+	}
+
+	token, tokenHash := auth.NewAPIToken()
+	apiToken, err := database.CreateAPIToken(principal.UserID, req.Name, tokenHash, scopes, expiresAt)
+	if err != nil {
+		response.Error(w, r, http.StatusInternalServerError, fmt.Sprintf("Failed to create API token: %v", err))
+		return
+	}
+
+	response.Created(w, r, map[string]any{
+		"token":    token,
+		"apiToken": apiToken,
+	})
+}
+
+// DeleteAPIToken handles DELETE /api/tokens/{id} requests, revoking one of the caller's tokens
+func (h *Handlers) DeleteAPIToken(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		response.Error(w, r, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		response.Error(w, r, http.StatusBadRequest, "Invalid token ID")
+		return
+	}
+
+	principal, ok := h.tokenOwner(w, r)
+	if !ok {
+		return
+	}
+
+	deleted, err := database.DeleteAPIToken(id, principal.UserID)
+	if err != nil {
+		response.Error(w, r, http.StatusInternalServerError, fmt.Sprintf("Failed to revoke API token: %v", err))
+		return
+	}
+	if !deleted {
+		response.Error(w, r, http.StatusNotFound, "API token not found")
+		return
+	}
+
+	data := map[string]any{
+		"message": "API token revoked",
+	}
+
+	response.OK(w, r, data)
+}
@@ -0,0 +1,58 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// When the unversioned paths were deprecated in favor of /api/v1, and when
+// they'll be removed
+var (
+	legacyDeprecated = time.Date(2026, time.October, 18, 0, 0, 0, 0, time.UTC)
+	legacySunset     = time.Date(2027, time.April, 18, 0, 0, 0, 0, time.UTC)
+)
+
+// apiVersionKey is the context key of the API version a request was routed to
+type apiVersionKey struct{}
+
+// WithAPIVersion is middleware recording the API version whose routes serve
+// the request, for handlers shared between versions that shape responses
+// differently
+func WithAPIVersion(version int) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), apiVersionKey{}, version)))
+		})
+	}
+}
+
+// APIVersion returns the API version serving the request, or 0 outside the
+// versioned API, e.g. for /healthz
+func APIVersion(ctx context.Context) int {
+	version, _ := ctx.Value(apiVersionKey{}).(int)
+	return version
+}
+
+// Deprecated is middleware for the unversioned aliases of v1 routes. It marks
+// responses with Deprecation (RFC 9745) and Sunset (RFC 8594) headers and
+// links to the /api/v1 path replacing the alias.
+func Deprecated(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Deprecation", fmt.Sprintf("@%d", legacyDeprecated.Unix()))
+		w.Header().Set("Sunset", legacySunset.Format(http.TimeFormat))
+		w.Header().Add("Link", fmt.Sprintf(`<%s>; rel="successor-version"`, v1Path(r.URL.Path)))
+		next.ServeHTTP(w, r)
+	})
+}
+
+// v1Path returns the /api/v1 path replacing an unversioned one: /api/notes
+// becomes /api/v1/notes and /transcribe becomes /api/v1/transcribe
+func v1Path(path string) string {
+	if rest, ok := strings.CutPrefix(path, "/api/"); ok {
+		return "/api/v1/" + rest
+	}
+	return "/api/v1" + path
+}
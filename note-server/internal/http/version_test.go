@@ -0,0 +1,79 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/your-org/note-server/internal/database"
+)
+
+func TestAPIVersions(t *testing.T) {
+	if err := database.InitDB(filepath.Join(t.TempDir(), "test.db")); err != nil {
+		t.Fatalf("failed to initialize database: %v", err)
+	}
+	router := NewRouterWithHandlers(createMockTranscribeHub(), createHandlersWithMocks(&MockTranscriber{}, &MockSummarizer{}))
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		expectedStatus int
+		successor      string
+	}{
+		{"v1", http.MethodGet, "/api/v1/recordings", http.StatusOK, ""},
+		{"v1 transcription", http.MethodPost, "/api/v1/transcribe", http.StatusBadRequest, ""},
+		{"probe", http.MethodGet, "/healthz", http.StatusOK, ""},
+		{"unversioned API", http.MethodGet, "/api/recordings", http.StatusOK, "/api/v1/recordings"},
+		{"unversioned route parameter", http.MethodGet, "/api/recordings/999", http.StatusNotFound, "/api/v1/recordings/999"},
+		{"root transcription", http.MethodPost, "/transcribe", http.StatusBadRequest, "/api/v1/transcribe"},
+		{"root summarization", http.MethodPost, "/summarize", http.StatusBadRequest, "/api/v1/summarize"},
+		{"root WebSocket", http.MethodGet, "/ws", http.StatusBadRequest, "/api/v1/ws"},
+		{"unknown version", http.MethodGet, "/api/v2/recordings", http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := sendJSON(router, tt.method, tt.path, nil, "", nil)
+			if w.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+
+			deprecation := w.Header().Get("Deprecation")
+			if tt.successor == "" {
+				if deprecation != "" {
+					t.Errorf("expected no Deprecation header, got %q", deprecation)
+				}
+				return
+			}
+			if deprecation != "@1792281600" {
+				t.Errorf("expected Deprecation @1792281600, got %q", deprecation)
+			}
+			if sunset, err := http.ParseTime(w.Header().Get("Sunset")); err != nil || !sunset.Equal(legacySunset) {
+				t.Errorf("expected Sunset %v, got %q", legacySunset, w.Header().Get("Sunset"))
+			}
+			if link := w.Header().Get("Link"); link != "<"+tt.successor+`>; rel="successor-version"` {
+				t.Errorf("expected a link to %s, got %q", tt.successor, link)
+			}
+		})
+	}
+}
+
+func TestAPIVersion(t *testing.T) {
+	var version int
+	handler := WithAPIVersion(2)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		version = APIVersion(r.Context())
+	}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/v2/notes", nil))
+
+	if version != 2 {
+		t.Errorf("APIVersion() = %d, want 2", version)
+	}
+	if got := APIVersion(httptest.NewRequest(http.MethodGet, "/healthz", nil).Context()); got != 0 {
+		t.Errorf("APIVersion() outside the API = %d, want 0", got)
+	}
+	if !legacySunset.After(legacyDeprecated.Add(30 * 24 * time.Hour)) {
+		t.Errorf("expected the sunset at least a month after deprecation")
+	}
+}
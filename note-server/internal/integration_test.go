@@ -179,7 +179,7 @@ func TestIntegrationWebSocket(t *testing.T) {
 			t.Logf("Received message: %+v", msg)
 			
 			if msg.Type == "final" {
-				expectedText := "Integration test transcription: " + string(testAudio[:10])
+				expectedText := "Integration test stream result"
 				if msg.Text != expectedText {
 					t.Errorf("Expected final text %q, got %q", expectedText, msg.Text)
 				}
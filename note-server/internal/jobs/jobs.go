@@ -0,0 +1,238 @@
+// Package jobs runs background work such as transcribing uploaded recordings
+// and reports its progress on the event bus as job.progress events, visible
+// only to the user who submitted the job.
+package jobs
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/your-org/note-server/internal/events"
+	"github.com/your-org/note-server/internal/metrics"
+	"github.com/your-org/note-server/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Job states
+const (
+	StatusQueued    = "queued"
+	StatusRunning   = "running"
+	StatusCompleted = "completed"
+	StatusFailed    = "failed"
+)
+
+// Finished jobs are forgotten after this long
+const jobRetention = time.Hour
+
+var (
+	// jobsInFlight is the queue depth: jobs waiting or running, by status
+	jobsInFlight = metrics.NewGaugeVec(
+		"note_jobs",
+		"Background jobs queued or running, by status.",
+		"status",
+	)
+
+	// jobDuration records how long finished jobs ran, by kind and outcome
+	jobDuration = metrics.NewHistogramVec(
+		"note_job_duration_seconds",
+		"Time background jobs ran, by kind and final status.",
+		metrics.SlowBuckets,
+		"kind", "status",
+	)
+)
+
+// Job is a snapshot of a background job. It is also the payload of job.progress events.
+type Job struct {
+	ID        string    `json:"id"`
+	Kind      string    `json:"kind"`
+	Status    string    `json:"status"`
+	Progress  float64   `json:"progress"` // 0..1
+	Message   string    `json:"message,omitempty"`
+	Error     string    `json:"error,omitempty"`
+	Result    any       `json:"result,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+
+	// User who submitted the job, 0 for anonymous callers. Its progress is
+	// only published to them.
+	Owner int64 `json:"-"`
+}
+
+// Done reports whether the job has finished, successfully or not
+func (j Job) Done() bool {
+	return j.Status == StatusCompleted || j.Status == StatusFailed
+}
+
+// ProgressFunc reports how far a job has got (0..1) with a short message
+type ProgressFunc func(progress float64, message string)
+
+// Func is the work of a job. Its result is stored on the job when it succeeds.
+type Func func(ctx context.Context, progress ProgressFunc) (any, error)
+
+// Manager runs jobs in the background and keeps their latest state
+type Manager struct {
+	bus *events.Bus
+
+	mutex sync.RWMutex
+	jobs  map[string]*Job
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewManager creates a job manager that publishes progress to bus
+func NewManager(bus *events.Bus) *Manager {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Manager{
+		bus:    bus,
+		jobs:   make(map[string]*Job),
+		ctx:    ctx,
+		cancel: cancel,
+	}
+}
+
+// Submit starts fn in the background for the user owner and returns the
+// queued job. The job logs with ctx's logger, so its lines carry the IDs of
+// the request that submitted it, but it isn't cancelled with ctx: only
+// Shutdown cancels jobs.
+func (m *Manager) Submit(ctx context.Context, owner int64, kind string, fn Func) Job {
+	now := time.Now().UTC()
+	job := &Job{
+		ID:        newJobID(),
+		Kind:      kind,
+		Status:    StatusQueued,
+		CreatedAt: now,
+		UpdatedAt: now,
+		Owner:     owner,
+	}
+
+	m.mutex.Lock()
+	m.pruneLocked(now)
+	m.jobs[job.ID] = job
+	snapshot := *job
+	m.mutex.Unlock()
+
+	m.bus.PublishFor(snapshot.Owner, events.TopicJobProgress, snapshot)
+	jobsInFlight.WithLabelValues(StatusQueued).Inc()
+
+	// The job logs with the submitter's logger and continues its trace
+	logger := zerolog.Ctx(ctx).With().Str("job_id", job.ID).Str("job_kind", kind).Logger()
+	jobCtx := trace.ContextWithSpanContext(logger.WithContext(m.ctx), trace.SpanContextFromContext(ctx))
+
+	m.wg.Add(1)
+	go m.run(jobCtx, job.ID, kind, fn)
+
+	return snapshot
+}
+
+// Get returns the latest state of a job
+func (m *Manager) Get(id string) (Job, bool) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	job, ok := m.jobs[id]
+	if !ok {
+		return Job{}, false
+	}
+	return *job, true
+}
+
+// Shutdown cancels running jobs and waits for them to return
+func (m *Manager) Shutdown() {
+	m.cancel()
+	m.wg.Wait()
+}
+
+// run executes a job with ctx, recovering from panics so one bad job can't
+// take the server down
+func (m *Manager) run(ctx context.Context, id, kind string, fn Func) {
+	defer m.wg.Done()
+
+	ctx, span := tracing.Start(ctx, "job "+kind, attribute.String("job.id", id))
+	logger := zerolog.Ctx(ctx)
+	start := time.Now()
+	logger.Debug().Msg("Job started")
+	jobsInFlight.WithLabelValues(StatusQueued).Dec()
+	jobsInFlight.WithLabelValues(StatusRunning).Inc()
+	defer jobsInFlight.WithLabelValues(StatusRunning).Dec()
+
+	m.update(id, func(job *Job) {
+		job.Status = StatusRunning
+	})
+
+	var result any
+	var err error
+	func() {
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf("job panicked: %v", r)
+			}
+		}()
+		result, err = fn(ctx, func(progress float64, message string) {
+			m.update(id, func(job *Job) {
+				job.Progress = progress
+				job.Message = message
+			})
+		})
+	}()
+
+	tracing.End(span, err)
+
+	if err != nil {
+		logger.Error().Err(err).Dur("duration", time.Since(start)).Msg("Job failed")
+		jobDuration.WithLabelValues(kind, StatusFailed).ObserveSince(start)
+		m.update(id, func(job *Job) {
+			job.Status = StatusFailed
+			job.Error = err.Error()
+		})
+		return
+	}
+
+	logger.Info().Dur("duration", time.Since(start)).Msg("Job completed")
+	jobDuration.WithLabelValues(kind, StatusCompleted).ObserveSince(start)
+	m.update(id, func(job *Job) {
+		job.Status = StatusCompleted
+		job.Progress = 1
+		job.Message = ""
+		job.Result = result
+	})
+}
+
+// update applies fn to a job and publishes the new state
+func (m *Manager) update(id string, fn func(job *Job)) {
+	m.mutex.Lock()
+	job, ok := m.jobs[id]
+	if !ok {
+		m.mutex.Unlock()
+		return
+	}
+	fn(job)
+	job.UpdatedAt = time.Now().UTC()
+	snapshot := *job
+	m.mutex.Unlock()
+
+	m.bus.PublishFor(snapshot.Owner, events.TopicJobProgress, snapshot)
+}
+
+// pruneLocked forgets jobs that finished more than jobRetention ago. Callers hold m.mutex.
+func (m *Manager) pruneLocked(now time.Time) {
+	for id, job := range m.jobs {
+		if job.Done() && now.Sub(job.UpdatedAt) > jobRetention {
+			delete(m.jobs, id)
+		}
+	}
+}
+
+// newJobID returns a random job ID
+func newJobID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
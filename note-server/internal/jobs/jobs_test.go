@@ -0,0 +1,194 @@
+package jobs
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/your-org/note-server/internal/events"
+	"github.com/your-org/note-server/internal/tracing"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// collect gathers job.progress snapshots for a job until it finishes
+func collect(t *testing.T, sub *events.Subscription, id string) []Job {
+	t.Helper()
+	var updates []Job
+	for {
+		select {
+		case event := <-sub.C:
+			job := event.Data.(Job)
+			if job.ID != id {
+				continue
+			}
+			updates = append(updates, job)
+			if job.Done() {
+				return updates
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("Timed out waiting for job %s, got %+v", id, updates)
+		}
+	}
+}
+
+func TestManager_Submit(t *testing.T) {
+	bus := events.NewBus()
+	sub := bus.Subscribe(events.TopicJobProgress)
+	defer sub.Close()
+
+	manager := NewManager(bus)
+	defer manager.Shutdown()
+
+	job := manager.Submit(context.Background(), 0, "test", func(ctx context.Context, progress ProgressFunc) (any, error) {
+		progress(0.5, "Halfway")
+		return "done", nil
+	})
+	if job.Status != StatusQueued {
+		t.Errorf("Expected queued job, got %s", job.Status)
+	}
+
+	var statuses []string
+	for _, update := range collect(t, sub, job.ID) {
+		statuses = append(statuses, update.Status)
+	}
+	want := []string{StatusQueued, StatusRunning, StatusRunning, StatusCompleted}
+	if len(statuses) != len(want) {
+		t.Fatalf("Expected statuses %v, got %v", want, statuses)
+	}
+	for i := range want {
+		if statuses[i] != want[i] {
+			t.Fatalf("Expected statuses %v, got %v", want, statuses)
+		}
+	}
+
+	final, ok := manager.Get(job.ID)
+	if !ok || final.Result != "done" || final.Progress != 1 {
+		t.Errorf("Expected completed job with result, got %+v", final)
+	}
+}
+
+func TestManager_Failure(t *testing.T) {
+	bus := events.NewBus()
+	sub := bus.Subscribe(events.TopicJobProgress)
+	defer sub.Close()
+
+	manager := NewManager(bus)
+	defer manager.Shutdown()
+
+	tests := []struct {
+		name string
+		fn   Func
+		err  string
+	}{
+		{"error", func(ctx context.Context, progress ProgressFunc) (any, error) {
+			return nil, errors.New("boom")
+		}, "boom"},
+		{"panic", func(ctx context.Context, progress ProgressFunc) (any, error) {
+			panic("oops")
+		}, "job panicked: oops"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			job := manager.Submit(context.Background(), 0, "test", tt.fn)
+			updates := collect(t, sub, job.ID)
+			final := updates[len(updates)-1]
+			if final.Status != StatusFailed || final.Error != tt.err {
+				t.Errorf("Expected failed job with error %q, got %+v", tt.err, final)
+			}
+		})
+	}
+}
+
+func TestManager_Shutdown(t *testing.T) {
+	manager := NewManager(events.NewBus())
+
+	job := manager.Submit(context.Background(), 0, "test", func(ctx context.Context, progress ProgressFunc) (any, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+
+	manager.Shutdown()
+
+	final, _ := manager.Get(job.ID)
+	if final.Status != StatusFailed {
+		t.Errorf("Expected cancelled job to fail, got %+v", final)
+	}
+
+	if _, ok := manager.Get("missing"); ok {
+		t.Error("Expected unknown job not to be found")
+	}
+}
+
+func TestManager_Logging(t *testing.T) {
+	bus := events.NewBus()
+	sub := bus.Subscribe(events.TopicJobProgress)
+	defer sub.Close()
+
+	manager := NewManager(bus)
+	defer manager.Shutdown()
+
+	var out bytes.Buffer
+	logger := zerolog.New(&out).With().Str("request_id", "req-1").Logger()
+
+	job := manager.Submit(logger.WithContext(context.Background()), 0, "test", func(ctx context.Context, progress ProgressFunc) (any, error) {
+		// The job's context carries the job's logger for the work it calls
+		zerolog.Ctx(ctx).Info().Msg("Working")
+		return nil, errors.New("boom")
+	})
+	collect(t, sub, job.ID)
+	seen := out.String()
+
+	for _, want := range []string{`"request_id":"req-1"`, `"job_id":"` + job.ID + `"`, `"job_kind":"test"`, `"message":"Working"`, `"message":"Job failed"`, `"error":"boom"`} {
+		if !strings.Contains(seen, want) {
+			t.Errorf("Expected %s in job logs:\n%s", want, seen)
+		}
+	}
+}
+
+func TestManager_Tracing(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	shutdown := tracing.Setup(tracing.Options{SampleRatio: 1, Exporter: exporter, Sync: true})
+	defer func() {
+		shutdown(context.Background())
+		tracing.Setup(tracing.Options{})
+	}()
+
+	bus := events.NewBus()
+	sub := bus.Subscribe(events.TopicJobProgress)
+	defer sub.Close()
+
+	manager := NewManager(bus)
+	defer manager.Shutdown()
+
+	// The job outlives the request that submitted it but joins its trace
+	ctx, request := tracing.Start(context.Background(), "request")
+	job := manager.Submit(ctx, 0, "transcribe", func(ctx context.Context, progress ProgressFunc) (any, error) {
+		_, step := tracing.Start(ctx, "step")
+		step.End()
+		return nil, errors.New("boom")
+	})
+	request.End()
+	collect(t, sub, job.ID)
+
+	spans := map[string]sdktrace.ReadOnlySpan{}
+	for _, span := range exporter.GetSpans().Snapshots() {
+		spans[span.Name()] = span
+	}
+	jobSpan, step := spans["job transcribe"], spans["step"]
+	if jobSpan == nil || step == nil {
+		t.Fatalf("Expected job and step spans, got %v", spans)
+	}
+	if jobSpan.Parent().SpanID() != request.SpanContext().SpanID() || step.Parent().SpanID() != jobSpan.SpanContext().SpanID() {
+		t.Error("Expected request > job > step")
+	}
+	if jobSpan.Status().Code != codes.Error {
+		t.Errorf("Expected the job span to record the failure, got %+v", jobSpan.Status())
+	}
+}
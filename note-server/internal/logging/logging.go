@@ -0,0 +1,49 @@
+// Package logging builds the server's zerolog logger. The logger travels in
+// contexts: each request, WebSocket session and background job carries a
+// child logger with its IDs, retrieved with zerolog.Ctx. Code without a
+// context of its own logs to Default.
+package logging
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// New returns a logger writing JSON lines to w at level ("debug", "info",
+// "warn" or "error"; anything else means info). In dev mode it writes
+// human-readable console lines instead.
+func New(w io.Writer, level string, dev bool) zerolog.Logger {
+	if dev {
+		w = zerolog.ConsoleWriter{Out: w, TimeFormat: time.TimeOnly}
+	}
+	return zerolog.New(w).Level(ParseLevel(level)).With().Timestamp().Logger()
+}
+
+// ParseLevel converts a LOG_LEVEL setting to a zerolog level
+func ParseLevel(level string) zerolog.Level {
+	switch level {
+	case "debug":
+		return zerolog.DebugLevel
+	case "warn":
+		return zerolog.WarnLevel
+	case "error":
+		return zerolog.ErrorLevel
+	default:
+		return zerolog.InfoLevel
+	}
+}
+
+// SetDefault makes logger the one zerolog.Ctx returns for contexts that
+// don't carry a logger, and the one Default returns
+func SetDefault(logger zerolog.Logger) {
+	zerolog.DefaultContextLogger = &logger
+}
+
+// Default returns the logger set with SetDefault, or a disabled logger if
+// none was set, as in tests
+func Default() *zerolog.Logger {
+	return zerolog.Ctx(context.Background())
+}
@@ -0,0 +1,58 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name    string
+		level   string
+		dev     bool
+		want    []string
+		notWant []string
+	}{
+		{"JSON at info", "info", false, []string{`"level":"info"`, `"message":"shown"`, `"time":`}, []string{"hidden"}},
+		{"debug", "debug", false, []string{"hidden", "shown"}, nil},
+		{"unknown level means info", "loud", false, []string{"shown"}, []string{"hidden"}},
+		{"console in dev mode", "info", true, []string{"INF", "shown"}, []string{`"level"`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			logger := New(&out, tt.level, tt.dev)
+			logger.Debug().Msg("hidden")
+			logger.Info().Msg("shown")
+
+			for _, want := range tt.want {
+				if !strings.Contains(out.String(), want) {
+					t.Errorf("Expected %q in %q", want, out.String())
+				}
+			}
+			for _, notWant := range tt.notWant {
+				if strings.Contains(out.String(), notWant) {
+					t.Errorf("Expected no %q in %q", notWant, out.String())
+				}
+			}
+		})
+	}
+}
+
+func TestSetDefault(t *testing.T) {
+	previous := zerolog.DefaultContextLogger
+	defer func() { zerolog.DefaultContextLogger = previous }()
+
+	var out bytes.Buffer
+	SetDefault(zerolog.New(&out))
+
+	Default().Info().Msg("default")
+	zerolog.Ctx(context.Background()).Info().Msg("from context")
+	if !strings.Contains(out.String(), "default") || !strings.Contains(out.String(), "from context") {
+		t.Errorf("Expected both lines on the default logger, got %q", out.String())
+	}
+}
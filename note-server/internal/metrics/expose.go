@@ -0,0 +1,108 @@
+package metrics
+
+import (
+	"bufio"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// ContentType is the media type of the Prometheus text format
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// Handler serves the registry's metrics in the Prometheus text format
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", ContentType)
+		r.WriteText(w)
+	})
+}
+
+// Handler serves the Default registry's metrics
+func Handler() http.Handler {
+	return Default.Handler()
+}
+
+// WriteText writes every metric in the Prometheus text format, families
+// sorted by name and series by label values
+func (r *Registry) WriteText(w io.Writer) error {
+	r.mutex.Lock()
+	families := make([]*family, 0, len(r.families))
+	for _, f := range r.families {
+		families = append(families, f)
+	}
+	r.mutex.Unlock()
+	sort.Slice(families, func(i, j int) bool { return families[i].name < families[j].name })
+
+	bw := bufio.NewWriter(w)
+	for _, f := range families {
+		f.writeText(bw)
+	}
+	return bw.Flush()
+}
+
+// writeText writes the family's HELP and TYPE lines and its samples
+func (f *family) writeText(w *bufio.Writer) {
+	f.mutex.Lock()
+	all := make([]*series, 0, len(f.series))
+	for _, s := range f.series {
+		all = append(all, s)
+	}
+	f.mutex.Unlock()
+	sort.Slice(all, func(i, j int) bool {
+		return strings.Join(all[i].values, "\xff") < strings.Join(all[j].values, "\xff")
+	})
+
+	w.WriteString("# HELP " + f.name + " " + escapeHelp(f.help) + "\n")
+	w.WriteString("# TYPE " + f.name + " " + f.typ + "\n")
+
+	for _, s := range all {
+		s.mutex.Lock()
+		if f.typ != typeHistogram {
+			writeSample(w, f.name, f.labels, s.values, "", "", s.value)
+			s.mutex.Unlock()
+			continue
+		}
+		for i, bound := range f.buckets {
+			writeSample(w, f.name+"_bucket", f.labels, s.values, "le", formatFloat(bound), float64(s.counts[i]))
+		}
+		writeSample(w, f.name+"_bucket", f.labels, s.values, "le", "+Inf", float64(s.count))
+		writeSample(w, f.name+"_sum", f.labels, s.values, "", "", s.sum)
+		writeSample(w, f.name+"_count", f.labels, s.values, "", "", float64(s.count))
+		s.mutex.Unlock()
+	}
+}
+
+// writeSample writes one sample line, with an extra label such as "le" if
+// extraName isn't empty
+func writeSample(w *bufio.Writer, name string, labels, values []string, extraName, extraValue string, value float64) {
+	w.WriteString(name)
+	if len(labels) > 0 || extraName != "" {
+		w.WriteByte('{')
+		for i, label := range labels {
+			if i > 0 {
+				w.WriteByte(',')
+			}
+			w.WriteString(label + `="` + escapeLabel(values[i]) + `"`)
+		}
+		if extraName != "" {
+			if len(labels) > 0 {
+				w.WriteByte(',')
+			}
+			w.WriteString(extraName + `="` + extraValue + `"`)
+		}
+		w.WriteByte('}')
+	}
+	w.WriteString(" " + formatFloat(value) + "\n")
+}
+
+// escapeHelp escapes backslashes and newlines in HELP text
+func escapeHelp(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(s)
+}
+
+// escapeLabel escapes backslashes, quotes and newlines in label values
+func escapeLabel(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+}
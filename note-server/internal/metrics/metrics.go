@@ -0,0 +1,250 @@
+// Package metrics is a small Prometheus instrumentation library: counters,
+// gauges and histograms with labels, exposed in the Prometheus text format.
+// Packages declare their metrics as package variables on the Default
+// registry, which the router serves at /metrics.
+package metrics
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefBuckets are the default histogram buckets, in seconds, suited to
+// request latencies
+var DefBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// SlowBuckets suit operations that take seconds to minutes, such as
+// transcribing a recording
+var SlowBuckets = []float64{0.1, 0.5, 1, 2.5, 5, 10, 30, 60, 120, 300}
+
+// Metric types as written on # TYPE lines
+const (
+	typeCounter   = "counter"
+	typeGauge     = "gauge"
+	typeHistogram = "histogram"
+)
+
+// family is a named metric with one series per combination of label values
+type family struct {
+	name    string
+	help    string
+	typ     string
+	labels  []string
+	buckets []float64
+
+	mutex  sync.Mutex
+	series map[string]*series
+}
+
+// series holds the value of one combination of label values. Counters and
+// gauges use value; histograms use counts, sum and count.
+type series struct {
+	values []string
+
+	mutex  sync.Mutex
+	value  float64
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+// with returns the series for values, creating it on first use
+func (f *family) with(values []string) *series {
+	if len(values) != len(f.labels) {
+		panic(fmt.Sprintf("metrics: %s takes %d label values, got %d", f.name, len(f.labels), len(values)))
+	}
+	key := strings.Join(values, "\xff")
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	s, ok := f.series[key]
+	if !ok {
+		s = &series{values: append([]string(nil), values...)}
+		if f.typ == typeHistogram {
+			s.counts = make([]uint64, len(f.buckets))
+		}
+		f.series[key] = s
+	}
+	return s
+}
+
+// CounterVec is a counter partitioned by labels
+type CounterVec struct{ f *family }
+
+// Counter is a value that only goes up
+type Counter struct{ s *series }
+
+// WithLabelValues returns the counter for the given label values, in the
+// order the labels were declared
+func (v *CounterVec) WithLabelValues(values ...string) Counter {
+	return Counter{v.f.with(values)}
+}
+
+// Inc adds one to the counter
+func (c Counter) Inc() {
+	c.Add(1)
+}
+
+// Add adds delta, which must not be negative, to the counter
+func (c Counter) Add(delta float64) {
+	if delta < 0 {
+		panic("metrics: counters cannot decrease")
+	}
+	c.s.mutex.Lock()
+	c.s.value += delta
+	c.s.mutex.Unlock()
+}
+
+// GaugeVec is a gauge partitioned by labels
+type GaugeVec struct{ f *family }
+
+// Gauge is a value that goes up and down
+type Gauge struct{ s *series }
+
+// WithLabelValues returns the gauge for the given label values
+func (v *GaugeVec) WithLabelValues(values ...string) Gauge {
+	return Gauge{v.f.with(values)}
+}
+
+// Set sets the gauge to value
+func (g Gauge) Set(value float64) {
+	g.s.mutex.Lock()
+	g.s.value = value
+	g.s.mutex.Unlock()
+}
+
+// Inc adds one to the gauge
+func (g Gauge) Inc() {
+	g.Add(1)
+}
+
+// Dec subtracts one from the gauge
+func (g Gauge) Dec() {
+	g.Add(-1)
+}
+
+// Add adds delta to the gauge
+func (g Gauge) Add(delta float64) {
+	g.s.mutex.Lock()
+	g.s.value += delta
+	g.s.mutex.Unlock()
+}
+
+// HistogramVec is a histogram partitioned by labels
+type HistogramVec struct{ f *family }
+
+// Histogram counts observations in buckets
+type Histogram struct {
+	s       *series
+	buckets []float64
+}
+
+// WithLabelValues returns the histogram for the given label values
+func (v *HistogramVec) WithLabelValues(values ...string) Histogram {
+	return Histogram{v.f.with(values), v.f.buckets}
+}
+
+// Observe records one value
+func (h Histogram) Observe(value float64) {
+	h.s.mutex.Lock()
+	defer h.s.mutex.Unlock()
+
+	for i, bound := range h.buckets {
+		if value <= bound {
+			h.s.counts[i]++
+		}
+	}
+	h.s.sum += value
+	h.s.count++
+}
+
+// ObserveSince records the seconds elapsed since start
+func (h Histogram) ObserveSince(start time.Time) {
+	h.Observe(time.Since(start).Seconds())
+}
+
+// Registry holds metric families for exposition
+type Registry struct {
+	mutex    sync.Mutex
+	families map[string]*family
+}
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{families: make(map[string]*family)}
+}
+
+// Default is the registry package-level metrics are declared on
+var Default = NewRegistry()
+
+// register adds a family, panicking on duplicate names as they are
+// programming errors
+func (r *Registry) register(f *family) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if _, ok := r.families[f.name]; ok {
+		panic(fmt.Sprintf("metrics: %s registered twice", f.name))
+	}
+	f.series = make(map[string]*series)
+	r.families[f.name] = f
+}
+
+// NewCounterVec registers a counter with the given labels
+func (r *Registry) NewCounterVec(name, help string, labels ...string) *CounterVec {
+	f := &family{name: name, help: help, typ: typeCounter, labels: labels}
+	r.register(f)
+	return &CounterVec{f}
+}
+
+// NewGaugeVec registers a gauge with the given labels
+func (r *Registry) NewGaugeVec(name, help string, labels ...string) *GaugeVec {
+	f := &family{name: name, help: help, typ: typeGauge, labels: labels}
+	r.register(f)
+	return &GaugeVec{f}
+}
+
+// NewHistogramVec registers a histogram with the given upper bucket bounds,
+// which must be sorted, and labels
+func (r *Registry) NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	if !sort.Float64sAreSorted(buckets) {
+		panic(fmt.Sprintf("metrics: buckets of %s are not sorted", name))
+	}
+	f := &family{name: name, help: help, typ: typeHistogram, labels: labels, buckets: buckets}
+	r.register(f)
+	return &HistogramVec{f}
+}
+
+// NewCounterVec registers a counter on the Default registry
+func NewCounterVec(name, help string, labels ...string) *CounterVec {
+	return Default.NewCounterVec(name, help, labels...)
+}
+
+// NewGaugeVec registers a gauge on the Default registry
+func NewGaugeVec(name, help string, labels ...string) *GaugeVec {
+	return Default.NewGaugeVec(name, help, labels...)
+}
+
+// NewHistogramVec registers a histogram on the Default registry
+func NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	return Default.NewHistogramVec(name, help, buckets, labels...)
+}
+
+// formatFloat writes a sample value as Prometheus expects
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,97 @@
+package metrics
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRegistry_WriteText(t *testing.T) {
+	registry := NewRegistry()
+	requests := registry.NewCounterVec("test_requests_total", "Requests handled.", "route", "status")
+	connections := registry.NewGaugeVec("test_connections", "Open connections.")
+	latency := registry.NewHistogramVec("test_latency_seconds", "Latency.", []float64{0.1, 1}, "op")
+
+	requests.WithLabelValues("/b", "200").Inc()
+	requests.WithLabelValues("/a", "500").Add(2)
+	requests.WithLabelValues(`/q"uote`, "200").Inc()
+	connections.WithLabelValues().Inc()
+	connections.WithLabelValues().Inc()
+	connections.WithLabelValues().Dec()
+	latency.WithLabelValues("read").Observe(0.05)
+	latency.WithLabelValues("read").Observe(0.5)
+	latency.WithLabelValues("read").Observe(3)
+
+	var out bytes.Buffer
+	if err := registry.WriteText(&out); err != nil {
+		t.Fatalf("WriteText() error = %v", err)
+	}
+
+	want := `# HELP test_connections Open connections.
+# TYPE test_connections gauge
+test_connections 1
+# HELP test_latency_seconds Latency.
+# TYPE test_latency_seconds histogram
+test_latency_seconds_bucket{op="read",le="0.1"} 1
+test_latency_seconds_bucket{op="read",le="1"} 2
+test_latency_seconds_bucket{op="read",le="+Inf"} 3
+test_latency_seconds_sum{op="read"} 3.55
+test_latency_seconds_count{op="read"} 3
+# HELP test_requests_total Requests handled.
+# TYPE test_requests_total counter
+test_requests_total{route="/a",status="500"} 2
+test_requests_total{route="/b",status="200"} 1
+test_requests_total{route="/q\"uote",status="200"} 1
+`
+	if out.String() != want {
+		t.Errorf("WriteText() =\n%s\nwant\n%s", out.String(), want)
+	}
+}
+
+func TestRegistry_Misuse(t *testing.T) {
+	tests := []struct {
+		name string
+		fn   func(r *Registry)
+	}{
+		{"duplicate name", func(r *Registry) {
+			r.NewCounterVec("dup", "")
+			r.NewGaugeVec("dup", "")
+		}},
+		{"wrong label count", func(r *Registry) {
+			r.NewCounterVec("c", "", "a", "b").WithLabelValues("x")
+		}},
+		{"negative counter", func(r *Registry) {
+			r.NewCounterVec("c", "").WithLabelValues().Add(-1)
+		}},
+		{"unsorted buckets", func(r *Registry) {
+			r.NewHistogramVec("h", "", []float64{1, 0.5})
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Error("Expected a panic")
+				}
+			}()
+			tt.fn(NewRegistry())
+		})
+	}
+}
+
+func TestRegistry_Handler(t *testing.T) {
+	registry := NewRegistry()
+	registry.NewCounterVec("test_total", "Test.").WithLabelValues().Inc()
+
+	w := httptest.NewRecorder()
+	registry.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != ContentType {
+		t.Errorf("Expected 200 in the text format, got %d %q", w.Code, w.Header().Get("Content-Type"))
+	}
+	if !strings.Contains(w.Body.String(), "test_total 1\n") {
+		t.Errorf("Expected the counter, got:\n%s", w.Body.String())
+	}
+}
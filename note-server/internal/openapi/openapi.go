@@ -0,0 +1,185 @@
+// Package openapi holds the OpenAPI 3 description of the HTTP API, served at
+// /api/v1/openapi.json, and checks requests and responses against it. The web
+// app generates its TypeScript client from the same document.
+//
+// Only the parts of OpenAPI and JSON Schema the description uses are
+// understood: $ref, type, format, nullable, enum, required, properties,
+// additionalProperties, items, allOf, minimum and maximum.
+package openapi
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+//go:embed openapi.json
+var spec []byte
+
+// Spec returns the OpenAPI document as JSON
+func Spec() []byte {
+	return spec
+}
+
+// Document is the parsed OpenAPI document
+type Document struct {
+	OpenAPI    string              `json:"openapi"`
+	Paths      map[string]PathItem `json:"paths"`
+	Components Components          `json:"components"`
+}
+
+// PathItem maps lowercase HTTP methods to the operations of a path
+type PathItem map[string]*Operation
+
+// Components holds the schemas and responses operations refer to
+type Components struct {
+	Schemas   map[string]*Schema   `json:"schemas"`
+	Responses map[string]*Response `json:"responses"`
+}
+
+// Operation describes one method on one path
+type Operation struct {
+	OperationID string               `json:"operationId"`
+	Parameters  []Parameter          `json:"parameters,omitempty"`
+	RequestBody *RequestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*Response `json:"responses"`
+}
+
+// Parameter is a path, query or header parameter
+type Parameter struct {
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required,omitempty"`
+	Schema   *Schema `json:"schema"`
+}
+
+// RequestBody lists the media types an operation accepts
+type RequestBody struct {
+	Required bool                 `json:"required,omitempty"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+// Response describes the body of one status, or refers to a shared response
+type Response struct {
+	Ref         string               `json:"$ref,omitempty"`
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+// MediaType holds the schema of a body in one media type
+type MediaType struct {
+	Schema *Schema `json:"schema,omitempty"`
+}
+
+// Schema is the subset of a JSON Schema the document uses
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	Enum                 []any              `json:"enum,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties json.RawMessage    `json:"additionalProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AllOf                []*Schema          `json:"allOf,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+}
+
+var (
+	loadOnce sync.Once
+	document *Document
+	loadErr  error
+)
+
+// Load parses the embedded document once, returning the same Document on
+// every call
+func Load() (*Document, error) {
+	loadOnce.Do(func() {
+		var doc Document
+		if err := json.Unmarshal(spec, &doc); err != nil {
+			loadErr = fmt.Errorf("failed to parse the OpenAPI document: %w", err)
+			return
+		}
+		document = &doc
+	})
+	return document, loadErr
+}
+
+// Operation returns the operation for method on a route pattern such as
+// "/api/recordings/{id}", or nil if the document doesn't describe it. chi
+// patterns and OpenAPI paths share the {param} syntax.
+func (d *Document) Operation(method, pattern string) *Operation {
+	return d.Paths[pattern][strings.ToLower(method)]
+}
+
+// Response returns the response documented for status, resolving references
+// to shared responses, or nil if the status isn't documented
+func (d *Document) Response(op *Operation, status int) *Response {
+	resp, ok := op.Responses[fmt.Sprint(status)]
+	if !ok {
+		resp, ok = op.Responses["default"]
+	}
+	if !ok {
+		return nil
+	}
+	if name, found := strings.CutPrefix(resp.Ref, "#/components/responses/"); found {
+		return d.Components.Responses[name]
+	}
+	return resp
+}
+
+// resolve follows a schema's reference to the components
+func (d *Document) resolve(schema *Schema) *Schema {
+	for schema != nil && schema.Ref != "" {
+		name, _ := strings.CutPrefix(schema.Ref, "#/components/schemas/")
+		schema = d.Components.Schemas[name]
+	}
+	return schema
+}
+
+// findContent returns the media type of content matching contentType, which
+// may be matched by a wildcard such as "audio/*"
+func findContent(content map[string]MediaType, contentType string) (MediaType, bool) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return MediaType{}, false
+	}
+	if media, ok := content[mediaType]; ok {
+		return media, true
+	}
+	major, _, _ := strings.Cut(mediaType, "/")
+	if media, ok := content[major+"/*"]; ok {
+		return media, true
+	}
+	media, ok := content["*/*"]
+	return media, ok
+}
+
+// isJSON reports whether contentType is JSON or a JSON-based type such as
+// application/problem+json
+func isJSON(contentType string) bool {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+// mediaTypes lists the media types of content for error messages
+func mediaTypes(content map[string]MediaType) string {
+	types := make([]string, 0, len(content))
+	for mediaType := range content {
+		types = append(types, mediaType)
+	}
+	sort.Strings(types)
+	return strings.Join(types, ", ")
+}
+
+// statusText returns the status with its text, e.g. "404 Not Found"
+func statusText(status int) string {
+	return fmt.Sprintf("%d %s", status, http.StatusText(status))
+}
@@ -0,0 +1,140 @@
+package openapi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func load(t *testing.T) *Document {
+	t.Helper()
+	doc, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	return doc
+}
+
+func TestReferencesResolve(t *testing.T) {
+	doc := load(t)
+	for _, match := range regexp.MustCompile(`"\$ref": "#/components/(schemas|responses)/([^"]+)"`).FindAllStringSubmatch(string(Spec()), -1) {
+		var found bool
+		switch match[1] {
+		case "schemas":
+			found = doc.Components.Schemas[match[2]] != nil
+		case "responses":
+			found = doc.Components.Responses[match[2]] != nil
+		}
+		if !found {
+			t.Errorf("%s/%s is referenced but not defined", match[1], match[2])
+		}
+	}
+}
+
+func TestOperationsAreComplete(t *testing.T) {
+	doc := load(t)
+	for path, item := range doc.Paths {
+		for method, op := range item {
+			if op.OperationID == "" {
+				t.Errorf("%s %s has no operationId", method, path)
+			}
+			if len(op.Responses) == 0 {
+				t.Errorf("%s %s has no responses", method, path)
+			}
+			for _, param := range op.Parameters {
+				if param.In == "path" && !strings.Contains(path, "{"+param.Name+"}") {
+					t.Errorf("%s %s describes path parameter %s it doesn't have", method, path, param.Name)
+				}
+			}
+		}
+	}
+}
+
+func TestValidateRequest(t *testing.T) {
+	doc := load(t)
+	tests := []struct {
+		name        string
+		method      string
+		pattern     string
+		target      string
+		contentType string
+		body        string
+		pathParams  map[string]string
+		fields      []string
+	}{
+		{"valid body", http.MethodPost, "/api/v1/tokens", "/api/v1/tokens", "application/json", `{"name":"ci","scopes":["read","write"]}`, nil, nil},
+		{"missing required body", http.MethodPost, "/api/v1/auth/login", "/api/v1/auth/login", "", "", nil, []string{"body"}},
+		{"optional body", http.MethodPost, "/api/v1/recordings/{id}/links", "/api/v1/recordings/1/links", "", "", map[string]string{"id": "1"}, nil},
+		{"missing fields", http.MethodPost, "/api/v1/auth/login", "/api/v1/auth/login", "application/json", `{}`, nil, []string{"username", "password"}},
+		{"body without a content type", http.MethodPost, "/api/v1/summarize", "/api/v1/summarize", "", `{"text":1}`, nil, []string{"text"}},
+		{"unknown property", http.MethodPatch, "/api/v1/notes/{id}", "/api/v1/notes/1", "application/json", `{"color":"red"}`, map[string]string{"id": "1"}, []string{"color"}},
+		{"invalid date-time", http.MethodPost, "/api/v1/tokens", "/api/v1/tokens", "application/json", `{"name":"ci","scopes":["read"],"expiresAt":"tomorrow"}`, nil, []string{"expiresAt"}},
+		{"wrong content type", http.MethodPost, "/api/v1/transcribe", "/api/v1/transcribe", "application/json", `{}`, nil, []string{"Content-Type"}},
+		{"multipart upload", http.MethodPost, "/api/v1/transcribe", "/api/v1/transcribe", "multipart/form-data; boundary=x", "", nil, nil},
+		{"invalid path parameter", http.MethodGet, "/api/v1/recordings/{id}", "/api/v1/recordings/abc", "", "", map[string]string{"id": "abc"}, []string{"id"}},
+		{"invalid query parameter", http.MethodGet, "/api/v1/config/raw", "/api/v1/config/raw?reveal=maybe", "", "", nil, []string{"reveal"}},
+		{"valid query parameter", http.MethodGet, "/api/v1/config/audit", "/api/v1/config/audit?limit=5", "", "", nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			op := doc.Operation(tt.method, tt.pattern)
+			if op == nil {
+				t.Fatalf("no operation for %s %s", tt.method, tt.pattern)
+			}
+			r := httptest.NewRequest(tt.method, tt.target, nil)
+			if tt.contentType != "" {
+				r.Header.Set("Content-Type", tt.contentType)
+			}
+
+			errs := doc.ValidateRequest(op, r, tt.pathParams, []byte(tt.body))
+			var fields []string
+			for _, err := range errs {
+				fields = append(fields, err.Field)
+			}
+			if strings.Join(fields, ",") != strings.Join(tt.fields, ",") {
+				t.Errorf("ValidateRequest() fields = %v (%v), want %v", fields, errs, tt.fields)
+			}
+		})
+	}
+}
+
+func TestValidateResponse(t *testing.T) {
+	doc := load(t)
+	recording := doc.Operation(http.MethodGet, "/api/v1/recordings/{id}")
+	audio := doc.Operation(http.MethodGet, "/api/v1/recordings/{id}/audio")
+
+	tests := []struct {
+		name        string
+		op          *Operation
+		status      int
+		contentType string
+		body        string
+		wantErr     string
+	}{
+		{"valid", recording, http.StatusOK, "application/json", `{"success":true,"data":{"recording":{"id":1,"filename":"a.wav","file_path":"/a.wav","start_time":"","end_time":"","duration":0,"file_size":0,"format":"wav","sample_rate":16000,"channels":1,"created_at":""}}}`, ""},
+		{"missing property", recording, http.StatusOK, "application/json", `{"success":true,"data":{"recording":{"id":1}}}`, "data.recording.filename is required"},
+		{"missing envelope", recording, http.StatusOK, "application/json", `{"recording":{}}`, "success is required"},
+		{"shared error response", recording, http.StatusNotFound, "application/json", `{"success":false,"error":"Recording not found","code":"not_found"}`, ""},
+		{"problem details", recording, http.StatusNotFound, "application/problem+json", `{"type":"about:blank","title":"Not Found","status":404,"code":"not_found"}`, ""},
+		{"unknown error code", recording, http.StatusNotFound, "application/json", `{"success":false,"error":"Recording not found","code":"missing"}`, "code must be one of"},
+		{"undocumented status", recording, http.StatusTeapot, "application/json", `{}`, "418 I'm a teapot is not documented"},
+		{"wildcard content type", audio, http.StatusOK, "audio/webm", "", ""},
+		{"undocumented content type", audio, http.StatusOK, "text/html", "<p>", "is not one of audio/*"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := doc.ValidateResponse(tt.op, tt.status, tt.contentType, []byte(tt.body))
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("ValidateResponse() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ValidateResponse() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,291 @@
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/your-org/note-server/pkg/response"
+)
+
+// ValidationError lists the fields of a response that don't match the document
+type ValidationError struct {
+	Fields []response.FieldError
+}
+
+func (e *ValidationError) Error() string {
+	problems := make([]string, len(e.Fields))
+	for i, field := range e.Fields {
+		problems[i] = field.Field + " " + field.Message
+	}
+	return strings.Join(problems, "; ")
+}
+
+// ValidateRequest checks r against op: its path, query and header parameters,
+// its content type and, for JSON, body. pathParams holds the values the router
+// matched. It returns the fields at fault, or nil if the request is valid.
+func (d *Document) ValidateRequest(op *Operation, r *http.Request, pathParams map[string]string, body []byte) []response.FieldError {
+	var errs []response.FieldError
+	query := r.URL.Query()
+	for _, param := range op.Parameters {
+		var value string
+		var present bool
+		switch param.In {
+		case "path":
+			value, present = pathParams[param.Name]
+		case "query":
+			value, present = query.Get(param.Name), query.Has(param.Name)
+		case "header":
+			value = r.Header.Get(param.Name)
+			present = value != ""
+		}
+		if !present {
+			if param.Required {
+				errs = append(errs, response.FieldError{Field: param.Name, Message: "is required"})
+			}
+			continue
+		}
+		d.validateParameter(param, value, &errs)
+	}
+
+	if op.RequestBody != nil {
+		d.validateBody(op.RequestBody, r.Header.Get("Content-Type"), body, &errs)
+	}
+	return errs
+}
+
+// ValidateResponse checks that status is documented for op and that the body
+// matches the schema of its content type. Bodies that aren't JSON are only
+// checked for their content type.
+func (d *Document) ValidateResponse(op *Operation, status int, contentType string, body []byte) error {
+	resp := d.Response(op, status)
+	if resp == nil {
+		return fmt.Errorf("status %s is not documented", statusText(status))
+	}
+	if len(resp.Content) == 0 || (contentType == "" && len(body) == 0) {
+		return nil
+	}
+
+	media, ok := findContent(resp.Content, contentType)
+	if !ok {
+		return fmt.Errorf("content type %q of %s is not one of %s", contentType, statusText(status), mediaTypes(resp.Content))
+	}
+	if media.Schema == nil || !isJSON(contentType) {
+		return nil
+	}
+
+	var value any
+	if err := json.Unmarshal(body, &value); err != nil {
+		return fmt.Errorf("body of %s is not valid JSON: %w", statusText(status), err)
+	}
+	var errs []response.FieldError
+	d.validate(media.Schema, value, "", &errs)
+	if len(errs) > 0 {
+		return &ValidationError{Fields: errs}
+	}
+	return nil
+}
+
+// validateParameter parses a parameter's value as its schema's type and
+// checks it
+func (d *Document) validateParameter(param Parameter, value string, errs *[]response.FieldError) {
+	schema := d.resolve(param.Schema)
+	if schema == nil {
+		return
+	}
+
+	var parsed any = value
+	switch schema.Type {
+	case "integer":
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			*errs = append(*errs, response.FieldError{Field: param.Name, Message: "must be an integer"})
+			return
+		}
+		parsed = float64(n)
+	case "number":
+		n, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			*errs = append(*errs, response.FieldError{Field: param.Name, Message: "must be a number"})
+			return
+		}
+		parsed = n
+	case "boolean":
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			*errs = append(*errs, response.FieldError{Field: param.Name, Message: "must be true or false"})
+			return
+		}
+		parsed = b
+	}
+	d.validate(schema, parsed, param.Name, errs)
+}
+
+// validateBody checks a request body's content type and, for JSON, its
+// content. Bodies sent without a content type are taken to be JSON, as the
+// handlers decode them.
+func (d *Document) validateBody(requestBody *RequestBody, contentType string, body []byte, errs *[]response.FieldError) {
+	if contentType == "" && len(body) == 0 {
+		if requestBody.Required {
+			*errs = append(*errs, response.FieldError{Field: "body", Message: "is required"})
+		}
+		return
+	}
+	if contentType == "" {
+		contentType = "application/json"
+	}
+
+	media, ok := findContent(requestBody.Content, contentType)
+	if !ok {
+		*errs = append(*errs, response.FieldError{Field: "Content-Type", Message: "must be one of " + mediaTypes(requestBody.Content)})
+		return
+	}
+	if media.Schema == nil || !isJSON(contentType) {
+		return
+	}
+	if len(body) == 0 {
+		if requestBody.Required {
+			*errs = append(*errs, response.FieldError{Field: "body", Message: "is required"})
+		}
+		return
+	}
+
+	var value any
+	if err := json.Unmarshal(body, &value); err != nil {
+		*errs = append(*errs, response.FieldError{Field: "body", Message: "must be valid JSON"})
+		return
+	}
+	d.validate(media.Schema, value, "", errs)
+}
+
+// validate checks a decoded JSON value against schema, adding what's wrong
+// with it to errs. field names the value, e.g. "data.recordings[0].id"; the
+// empty name is the whole body.
+func (d *Document) validate(schema *Schema, value any, field string, errs *[]response.FieldError) {
+	schema = d.resolve(schema)
+	if schema == nil {
+		return
+	}
+	fail := func(message string) {
+		name := field
+		if name == "" {
+			name = "body"
+		}
+		*errs = append(*errs, response.FieldError{Field: name, Message: message})
+	}
+
+	for _, sub := range schema.AllOf {
+		d.validate(sub, value, field, errs)
+	}
+
+	if value == nil {
+		if schema.Type != "" && !schema.Nullable {
+			fail("must not be null")
+		}
+		return
+	}
+
+	switch schema.Type {
+	case "object":
+		object, ok := value.(map[string]any)
+		if !ok {
+			fail("must be an object")
+			return
+		}
+		for _, name := range schema.Required {
+			if _, ok := object[name]; !ok {
+				*errs = append(*errs, response.FieldError{Field: join(field, name), Message: "is required"})
+			}
+		}
+		for name, property := range object {
+			if propertySchema, ok := schema.Properties[name]; ok {
+				d.validate(propertySchema, property, join(field, name), errs)
+				continue
+			}
+			switch extra := schema.AdditionalProperties; {
+			case string(extra) == "false":
+				*errs = append(*errs, response.FieldError{Field: join(field, name), Message: "is not allowed"})
+			case len(extra) > 0 && extra[0] == '{':
+				var extraSchema Schema
+				if err := json.Unmarshal(extra, &extraSchema); err == nil {
+					d.validate(&extraSchema, property, join(field, name), errs)
+				}
+			}
+		}
+	case "array":
+		items, ok := value.([]any)
+		if !ok {
+			fail("must be an array")
+			return
+		}
+		for i, item := range items {
+			d.validate(schema.Items, item, fmt.Sprintf("%s[%d]", field, i), errs)
+		}
+	case "string":
+		s, ok := value.(string)
+		if !ok {
+			fail("must be a string")
+			return
+		}
+		if schema.Format == "date-time" {
+			if _, err := time.Parse(time.RFC3339, s); err != nil {
+				fail("must be an RFC 3339 time")
+			}
+		}
+	case "integer", "number":
+		n, ok := value.(float64)
+		if !ok || (schema.Type == "integer" && n != math.Trunc(n)) {
+			fail("must be " + article(schema.Type))
+			return
+		}
+		if schema.Minimum != nil && n < *schema.Minimum {
+			fail(fmt.Sprintf("must be at least %v", *schema.Minimum))
+		}
+		if schema.Maximum != nil && n > *schema.Maximum {
+			fail(fmt.Sprintf("must be at most %v", *schema.Maximum))
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			fail("must be true or false")
+			return
+		}
+	}
+
+	if len(schema.Enum) > 0 && !oneOf(value, schema.Enum) {
+		values := make([]string, len(schema.Enum))
+		for i, allowed := range schema.Enum {
+			values[i] = fmt.Sprintf("%q", fmt.Sprint(allowed))
+		}
+		fail("must be one of " + strings.Join(values, ", "))
+	}
+}
+
+// join appends a property name to a field path
+func join(field, name string) string {
+	if field == "" {
+		return name
+	}
+	return field + "." + name
+}
+
+// article returns a type name with its indefinite article
+func article(typeName string) string {
+	if typeName == "integer" {
+		return "an integer"
+	}
+	return "a " + typeName
+}
+
+// oneOf reports whether value equals one of the enum's values
+func oneOf(value any, enum []any) bool {
+	for _, allowed := range enum {
+		if value == allowed {
+			return true
+		}
+	}
+	return false
+}
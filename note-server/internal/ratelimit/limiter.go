@@ -0,0 +1,122 @@
+// Package ratelimit limits how fast clients may call the endpoints that spend
+// paid provider credits, and how much audio and text they may send them.
+//
+// A Limiter is a token bucket per client: each request takes a token, and
+// tokens are refilled at a steady rate up to a burst. A Meter enforces daily
+// and monthly quotas on metered work, such as minutes of transcribed audio,
+// and records what was used in a Store.
+//
+// Clients are identified by ClientKey: authenticated callers by their
+// principal, everyone else by IP address.
+package ratelimit
+
+import (
+	"math"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/your-org/note-server/internal/auth"
+)
+
+// How often idle buckets are dropped
+const sweepInterval = time.Minute
+
+// Limiter is a token bucket rate limiter keyed by client. It's safe for
+// concurrent use. A nil Limiter allows everything.
+type Limiter struct {
+	rate  float64 // tokens added per second
+	burst float64
+
+	mutex     sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+
+	// Replaced in tests
+	now func() time.Time
+}
+
+// bucket holds a client's tokens as of updated
+type bucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// New creates a limiter allowing each client rate requests per second on
+// average, and bursts of up to burst requests. rate must be positive; a burst
+// below 1 is raised to 1.
+func New(rate float64, burst int) *Limiter {
+	return &Limiter{
+		rate:    rate,
+		burst:   math.Max(float64(burst), 1),
+		buckets: make(map[string]*bucket),
+		now:     time.Now,
+	}
+}
+
+// Allow takes a token from the client's bucket. When the bucket is empty it
+// reports false and how long until a token is available.
+func (l *Limiter) Allow(client string) (bool, time.Duration) {
+	if l == nil {
+		return true, 0
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	b, ok := l.buckets[client]
+	if !ok {
+		b = &bucket{tokens: l.burst, updated: now}
+		l.buckets[client] = b
+	}
+	b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.updated).Seconds()*l.rate)
+	b.updated = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	wait := time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+	return false, wait
+}
+
+// sweep drops the buckets of clients idle long enough to have refilled, which
+// behave the same as a new bucket. The caller must hold the mutex.
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < sweepInterval {
+		return
+	}
+	l.lastSweep = now
+
+	refill := time.Duration(l.burst / l.rate * float64(time.Second))
+	for client, b := range l.buckets {
+		if now.Sub(b.updated) >= refill {
+			delete(l.buckets, client)
+		}
+	}
+}
+
+// ClientKey identifies the client limits and quotas apply to: "user:<id>" for
+// authenticated principals, whichever credential they used, and "ip:<address>"
+// for anonymous ones. remoteAddr is the request's RemoteAddr, with or without
+// a port.
+func ClientKey(principal auth.Principal, remoteAddr string) string {
+	if !principal.IsAnonymous() && principal.ID != "" {
+		return "user:" + principal.ID
+	}
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+	return "ip:" + host
+}
+
+// RetryAfter formats d as the value of a Retry-After header: whole seconds,
+// rounded up, and at least one
+func RetryAfter(d time.Duration) string {
+	return strconv.Itoa(max(int(math.Ceil(d.Seconds())), 1))
+}
@@ -0,0 +1,161 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Metered work, in the units quotas are set in
+const (
+	// Minutes of audio sent to transcription providers
+	TranscriptionMinutes = "transcription_minutes"
+
+	// Tokens of text sent to and returned by summary providers, estimated at
+	// four characters per token
+	SummaryTokens = "summary_tokens"
+)
+
+// Metrics lists the metered work in the order usage is reported
+var Metrics = []string{TranscriptionMinutes, SummaryTokens}
+
+// Quota caps a metric per UTC calendar day and month. Zero means unlimited.
+type Quota struct {
+	Daily   float64
+	Monthly float64
+}
+
+// Store keeps the usage a Meter records, by client, metric and UTC day
+type Store interface {
+	// AddUsage adds amount to the client's usage of metric on day
+	AddUsage(ctx context.Context, client, metric string, day time.Time, amount float64) error
+
+	// Usage returns the client's usage of metric on day, and in day's month
+	// up to and including day
+	Usage(ctx context.Context, client, metric string, day time.Time) (daily, monthly float64, err error)
+}
+
+// QuotaError reports that a client has used up a quota
+type QuotaError struct {
+	Metric string
+	Period string // "daily" or "monthly"
+	Limit  float64
+
+	// Time until the quota resets
+	RetryAfter time.Duration
+}
+
+func (e *QuotaError) Error() string {
+	return fmt.Sprintf("%s quota of %g %s used up", e.Period, e.Limit, strings.ReplaceAll(e.Metric, "_", " "))
+}
+
+// Meter enforces quotas and records usage. A nil Meter enforces and records
+// nothing.
+type Meter struct {
+	store  Store
+	quotas map[string]Quota
+
+	// Replaced in tests
+	now func() time.Time
+}
+
+// NewMeter creates a meter recording usage in store. Metrics without a quota
+// are recorded but not limited.
+func NewMeter(store Store, quotas map[string]Quota) *Meter {
+	return &Meter{store: store, quotas: quotas, now: time.Now}
+}
+
+// Check returns a *QuotaError if the client has used up its daily or monthly
+// quota of metric, reporting the monthly one when both are. Work already
+// admitted may take usage past a quota; the next Check catches it.
+func (m *Meter) Check(ctx context.Context, client, metric string) error {
+	if m == nil {
+		return nil
+	}
+	quota := m.quotas[metric]
+	if quota.Daily <= 0 && quota.Monthly <= 0 {
+		return nil
+	}
+
+	now := m.now().UTC()
+	daily, monthly, err := m.store.Usage(ctx, client, metric, now)
+	if err != nil {
+		return err
+	}
+	if quota.Monthly > 0 && monthly >= quota.Monthly {
+		return &QuotaError{Metric: metric, Period: "monthly", Limit: quota.Monthly, RetryAfter: nextMonth(now).Sub(now)}
+	}
+	if quota.Daily > 0 && daily >= quota.Daily {
+		return &QuotaError{Metric: metric, Period: "daily", Limit: quota.Daily, RetryAfter: nextDay(now).Sub(now)}
+	}
+	return nil
+}
+
+// Record adds amount of metric to the client's usage today
+func (m *Meter) Record(ctx context.Context, client, metric string, amount float64) error {
+	if m == nil || amount <= 0 {
+		return nil
+	}
+	return m.store.AddUsage(ctx, client, metric, m.now().UTC(), amount)
+}
+
+// Usage is a client's consumption of one metric
+type Usage struct {
+	Metric string `json:"metric"`
+	Day    Period `json:"day"`
+	Month  Period `json:"month"`
+}
+
+// Period is a client's consumption within one quota period. Limit and
+// Remaining are nil when the period is unlimited.
+type Period struct {
+	Used      float64   `json:"used"`
+	Limit     *float64  `json:"limit"`
+	Remaining *float64  `json:"remaining"`
+	ResetsAt  time.Time `json:"resetsAt"`
+}
+
+// Report returns the client's usage of every metric today and this month
+func (m *Meter) Report(ctx context.Context, client string) ([]Usage, error) {
+	if m == nil {
+		return []Usage{}, nil
+	}
+
+	now := m.now().UTC()
+	report := make([]Usage, 0, len(Metrics))
+	for _, metric := range Metrics {
+		daily, monthly, err := m.store.Usage(ctx, client, metric, now)
+		if err != nil {
+			return nil, err
+		}
+		quota := m.quotas[metric]
+		report = append(report, Usage{
+			Metric: metric,
+			Day:    period(daily, quota.Daily, nextDay(now)),
+			Month:  period(monthly, quota.Monthly, nextMonth(now)),
+		})
+	}
+	return report, nil
+}
+
+// period describes usage against limit, which is unlimited when zero
+func period(used, limit float64, resetsAt time.Time) Period {
+	p := Period{Used: used, ResetsAt: resetsAt}
+	if limit > 0 {
+		remaining := max(limit-used, 0)
+		p.Limit = &limit
+		p.Remaining = &remaining
+	}
+	return p
+}
+
+// nextDay returns the start of the UTC day after t
+func nextDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, time.UTC)
+}
+
+// nextMonth returns the start of the UTC month after t
+func nextMonth(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,174 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/your-org/note-server/internal/auth"
+	"github.com/your-org/note-server/internal/metrics"
+)
+
+// clock is a settable time source
+type clock struct{ t time.Time }
+
+func (c *clock) now() time.Time { return c.t }
+
+func TestLimiter(t *testing.T) {
+	c := &clock{t: time.Date(2026, time.October, 18, 12, 0, 0, 0, time.UTC)}
+	l := New(2, 3)
+	l.now = c.now
+
+	for i := 0; i < 3; i++ {
+		if ok, _ := l.Allow("alice"); !ok {
+			t.Fatalf("request %d of the burst was limited", i+1)
+		}
+	}
+	ok, wait := l.Allow("alice")
+	if ok || wait != 500*time.Millisecond {
+		t.Errorf("Allow() after the burst = %v, %v; want false, 500ms", ok, wait)
+	}
+	if ok, _ := l.Allow("bob"); !ok {
+		t.Error("expected clients to have separate buckets")
+	}
+
+	c.t = c.t.Add(500 * time.Millisecond)
+	if ok, _ := l.Allow("alice"); !ok {
+		t.Error("expected a token to be refilled after 500ms")
+	}
+	if ok, _ := l.Allow("alice"); ok {
+		t.Error("expected only one token to be refilled after 500ms")
+	}
+
+	// Idle clients are forgotten once their bucket would be full again
+	c.t = c.t.Add(sweepInterval)
+	l.Allow("carol")
+	if _, ok := l.buckets["alice"]; ok {
+		t.Error("expected the idle bucket to be dropped")
+	}
+
+	var unlimited *Limiter
+	if ok, _ := unlimited.Allow("alice"); !ok {
+		t.Error("expected a nil limiter to allow everything")
+	}
+}
+
+func TestClientKey(t *testing.T) {
+	tests := []struct {
+		name       string
+		principal  auth.Principal
+		remoteAddr string
+		expected   string
+	}{
+		{"session", auth.Principal{ID: "alice", Method: auth.MethodSession}, "10.0.0.1:1234", "user:alice"},
+		{"API token", auth.Principal{ID: "alice", Method: auth.MethodAPIToken}, "10.0.0.1:1234", "user:alice"},
+		{"anonymous", auth.Anonymous, "10.0.0.1:1234", "ip:10.0.0.1"},
+		{"IPv6", auth.Anonymous, "[::1]:1234", "ip:::1"},
+		{"address without port", auth.Anonymous, "10.0.0.2", "ip:10.0.0.2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ClientKey(tt.principal, tt.remoteAddr); got != tt.expected {
+				t.Errorf("ClientKey() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+// memoryStore is a Store keeping usage in a map
+type memoryStore map[string]float64
+
+func (s memoryStore) AddUsage(ctx context.Context, client, metric string, day time.Time, amount float64) error {
+	s[client+" "+metric+" "+day.Format(time.DateOnly)] += amount
+	return nil
+}
+
+func (s memoryStore) Usage(ctx context.Context, client, metric string, day time.Time) (float64, float64, error) {
+	var monthly float64
+	for d := time.Date(day.Year(), day.Month(), 1, 0, 0, 0, 0, time.UTC); !d.After(day); d = d.AddDate(0, 0, 1) {
+		monthly += s[client+" "+metric+" "+d.Format(time.DateOnly)]
+	}
+	return s[client+" "+metric+" "+day.Format(time.DateOnly)], monthly, nil
+}
+
+func TestMeter(t *testing.T) {
+	c := &clock{t: time.Date(2026, time.October, 18, 18, 0, 0, 0, time.UTC)}
+	m := NewMeter(memoryStore{}, map[string]Quota{TranscriptionMinutes: {Daily: 10, Monthly: 15}})
+	m.now = c.now
+	ctx := context.Background()
+
+	if err := m.Check(ctx, "user:alice", TranscriptionMinutes); err != nil {
+		t.Fatalf("Check() before any usage = %v", err)
+	}
+	m.Record(ctx, "user:alice", TranscriptionMinutes, 10)
+
+	var quotaErr *QuotaError
+	err := m.Check(ctx, "user:alice", TranscriptionMinutes)
+	if !errors.As(err, &quotaErr) || quotaErr.Period != "daily" || quotaErr.RetryAfter != 6*time.Hour {
+		t.Errorf("Check() at the daily quota = %v, want a daily QuotaError retrying in 6h", err)
+	}
+	if err := m.Check(ctx, "user:bob", TranscriptionMinutes); err != nil {
+		t.Errorf("Check() for another client = %v", err)
+	}
+	if err := m.Check(ctx, "user:alice", SummaryTokens); err != nil {
+		t.Errorf("Check() of an unlimited metric = %v", err)
+	}
+
+	// The next day's quota is fresh, until the month's runs out
+	c.t = c.t.Add(12 * time.Hour)
+	if err := m.Check(ctx, "user:alice", TranscriptionMinutes); err != nil {
+		t.Errorf("Check() on the next day = %v", err)
+	}
+	m.Record(ctx, "user:alice", TranscriptionMinutes, 5)
+	err = m.Check(ctx, "user:alice", TranscriptionMinutes)
+	if !errors.As(err, &quotaErr) || quotaErr.Period != "monthly" || quotaErr.RetryAfter != 12*24*time.Hour+18*time.Hour {
+		t.Errorf("Check() at the monthly quota = %v, want a monthly QuotaError retrying on November 1", err)
+	}
+
+	report, err := m.Report(ctx, "user:alice")
+	if err != nil {
+		t.Fatalf("Report() error = %v", err)
+	}
+	if len(report) != len(Metrics) || report[0].Metric != TranscriptionMinutes {
+		t.Fatalf("Report() = %+v, want every metric", report)
+	}
+	minutes, tokens := report[0], report[1]
+	if minutes.Day.Used != 5 || *minutes.Day.Remaining != 5 || minutes.Month.Used != 15 || *minutes.Month.Remaining != 0 {
+		t.Errorf("transcription minutes = %+v", minutes)
+	}
+	if !minutes.Month.ResetsAt.Equal(time.Date(2026, time.November, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("expected the month to reset on November 1, got %v", minutes.Month.ResetsAt)
+	}
+	if tokens.Day.Limit != nil || tokens.Month.Remaining != nil {
+		t.Errorf("expected summary tokens to be unlimited, got %+v", tokens)
+	}
+
+	var unmetered *Meter
+	if err := unmetered.Check(ctx, "user:alice", TranscriptionMinutes); err != nil {
+		t.Errorf("Check() on a nil meter = %v", err)
+	}
+}
+
+func TestRejectQuota(t *testing.T) {
+	w := httptest.NewRecorder()
+	RejectQuota(w, httptest.NewRequest(http.MethodPost, "/summarize", nil), &QuotaError{
+		Metric: SummaryTokens, Period: "daily", Limit: 1000, RetryAfter: 90 * time.Minute,
+	})
+
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") != "5400" {
+		t.Errorf("Expected 429 with Retry-After 5400, got %d %q", w.Code, w.Header().Get("Retry-After"))
+	}
+	if body := w.Body.String(); !strings.Contains(body, `"code":"rate_limited"`) || !strings.Contains(body, "Daily quota of 1000 summary tokens used up") {
+		t.Errorf("Expected the quota error, got %s", body)
+	}
+
+	scrape := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(scrape, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if !strings.Contains(scrape.Body.String(), `note_rate_limit_rejections_total{limit="summary_tokens"} 1`) {
+		t.Errorf("Expected the rejection counted, got:\n%s", scrape.Body.String())
+	}
+}
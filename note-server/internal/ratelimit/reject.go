@@ -0,0 +1,32 @@
+package ratelimit
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/your-org/note-server/internal/metrics"
+	"github.com/your-org/note-server/pkg/response"
+)
+
+// rejections counts requests turned away with 429, by the limit they hit:
+// "rate", or the metric whose quota was used up
+var rejections = metrics.NewCounterVec(
+	"note_rate_limit_rejections_total",
+	"Requests rejected for exceeding the rate limit or a usage quota, by limit.",
+	"limit",
+)
+
+// Reject writes a 429 telling the client when to retry, counting the
+// rejection against limit: "rate", or the metric of a used-up quota
+func Reject(w http.ResponseWriter, r *http.Request, limit string, retryAfter time.Duration, message string) {
+	rejections.WithLabelValues(limit).Inc()
+	w.Header().Set("Retry-After", RetryAfter(retryAfter))
+	response.ErrorCode(w, r, http.StatusTooManyRequests, response.CodeRateLimited, message)
+}
+
+// RejectQuota writes the 429 for a client that used up the quota err reports
+func RejectQuota(w http.ResponseWriter, r *http.Request, err *QuotaError) {
+	message := err.Error()
+	Reject(w, r, err.Metric, err.RetryAfter, strings.ToUpper(message[:1])+message[1:])
+}
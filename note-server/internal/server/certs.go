@@ -0,0 +1,116 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// fileVersion identifies a version of a certificate or key file
+type fileVersion struct {
+	modTime time.Time
+	size    int64
+}
+
+// CertReloader serves a TLS certificate from a certificate and key file,
+// reloading them when they change so renewed certificates are picked up
+// without a restart
+type CertReloader struct {
+	certFile string
+	keyFile  string
+
+	mutex    sync.RWMutex
+	cert     *tls.Certificate
+	versions [2]fileVersion
+}
+
+// NewCertReloader loads the certificate and key in certFile and keyFile
+func NewCertReloader(certFile, keyFile string) (*CertReloader, error) {
+	r := &CertReloader{certFile: certFile, keyFile: keyFile}
+
+	versions, err := r.stat()
+	if err != nil {
+		return nil, err
+	}
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load TLS certificate: %w", err)
+	}
+
+	r.cert = &cert
+	r.versions = versions
+	return r, nil
+}
+
+// GetCertificate returns the current certificate, for tls.Config.GetCertificate
+func (r *CertReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	return r.cert, nil
+}
+
+// Reload reloads the certificate if either file changed since it was last
+// loaded, reporting whether the certificate was replaced. A pair that can't
+// be loaded, e.g. a certificate renewed before its key, is rejected and the
+// current certificate kept; the same broken version isn't reported twice.
+func (r *CertReloader) Reload() (bool, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	versions, err := r.stat()
+	if err != nil {
+		return false, err
+	}
+	if versions == r.versions {
+		return false, nil
+	}
+	r.versions = versions
+
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return false, fmt.Errorf("rejected TLS certificate change, keeping the current certificate: %w", err)
+	}
+	r.cert = &cert
+	return true, nil
+}
+
+// Watch checks the files every interval and reloads them when they change,
+// until ctx is done. Rejected changes are logged to ctx's logger.
+func (r *CertReloader) Watch(ctx context.Context, interval time.Duration) {
+	logger := zerolog.Ctx(ctx)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			changed, err := r.Reload()
+			if err != nil {
+				logger.Error().Err(err).Str("path", r.certFile).Msg("TLS certificate reload failed")
+			} else if changed {
+				logger.Info().Str("path", r.certFile).Msg("Reloaded TLS certificate")
+			}
+		}
+	}
+}
+
+// stat returns the current versions of the certificate and key files
+func (r *CertReloader) stat() ([2]fileVersion, error) {
+	var versions [2]fileVersion
+	for i, path := range []string{r.certFile, r.keyFile} {
+		info, err := os.Stat(path)
+		if err != nil {
+			return versions, fmt.Errorf("failed to stat TLS file: %w", err)
+		}
+		versions[i] = fileVersion{modTime: info.ModTime(), size: info.Size()}
+	}
+	return versions, nil
+}
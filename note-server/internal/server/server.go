@@ -0,0 +1,175 @@
+// Package server runs the HTTP server on the listeners the configuration asks
+// for: TCP on Host:Port, serving HTTPS when a certificate is configured, and
+// optionally a Unix domain socket for a local reverse proxy such as the
+// Next.js frontend.
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/your-org/note-server/internal/config"
+	"github.com/your-org/note-server/internal/logging"
+)
+
+// Server serves one handler on a TCP listener and an optional Unix socket
+type Server struct {
+	cfg   *config.Config
+	http  *http.Server
+	certs *CertReloader
+
+	tcp  net.Listener
+	unix net.Listener
+
+	// Ends the certificate watch on Shutdown
+	watchCtx     context.Context
+	stopWatching context.CancelFunc
+}
+
+// New creates a server for handler, loading the TLS certificate if one is
+// configured. Connection errors and certificate reloads are logged to the
+// default logger.
+func New(cfg *config.Config, handler http.Handler) (*Server, error) {
+	logger := logging.Default().With().Str("component", "server").Logger()
+	watchCtx, stopWatching := context.WithCancel(logger.WithContext(context.Background()))
+	s := &Server{
+		cfg: cfg,
+		http: &http.Server{
+			Addr:              cfg.Address(),
+			Handler:           handler,
+			ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+			IdleTimeout:       cfg.IdleTimeout,
+			MaxHeaderBytes:    cfg.MaxHeaderBytes,
+			ErrorLog:          log.New(logger, "", 0),
+		},
+		watchCtx:     watchCtx,
+		stopWatching: stopWatching,
+	}
+
+	if cfg.TLSCertFile != "" {
+		certs, err := NewCertReloader(cfg.TLSCertFile, cfg.TLSKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		s.certs = certs
+		s.http.TLSConfig = &tls.Config{
+			MinVersion:     tls.VersionTLS12,
+			GetCertificate: certs.GetCertificate,
+		}
+	}
+
+	return s, nil
+}
+
+// Listen opens the listeners, so address problems are reported before Serve
+func (s *Server) Listen() error {
+	tcp, err := net.Listen("tcp", s.cfg.Address())
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", s.cfg.Address(), err)
+	}
+
+	if s.cfg.UnixSocket != "" {
+		unix, err := listenUnix(s.cfg.UnixSocket)
+		if err != nil {
+			tcp.Close()
+			return err
+		}
+		s.unix = unix
+	}
+
+	s.tcp = tcp
+	return nil
+}
+
+// listenUnix listens on the socket at path, replacing a stale socket left by
+// a previous run. The socket is readable and writable by the owner and group.
+func listenUnix(path string) (net.Listener, error) {
+	if info, err := os.Lstat(path); err == nil {
+		if info.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("unix socket path %s exists and is not a socket", path)
+		}
+		if conn, err := net.Dial("unix", path); err == nil {
+			conn.Close()
+			return nil, fmt.Errorf("unix socket %s is in use", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, fmt.Errorf("failed to remove stale unix socket: %w", err)
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create unix socket directory: %w", err)
+	}
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on unix socket %s: %w", path, err)
+	}
+	if err := os.Chmod(path, 0660); err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("failed to set unix socket permissions: %w", err)
+	}
+	return listener, nil
+}
+
+// Addresses describes the listeners, e.g. "https://127.0.0.1:8443" and
+// "unix:/run/note/server.sock". Listen must have been called.
+func (s *Server) Addresses() []string {
+	scheme := "http://"
+	if s.certs != nil {
+		scheme = "https://"
+	}
+	addresses := []string{scheme + s.tcp.Addr().String()}
+	if s.unix != nil {
+		addresses = append(addresses, "unix:"+s.unix.Addr().String())
+	}
+	return addresses
+}
+
+// Serve serves on the listeners opened by Listen until Shutdown, returning
+// the first error other than the server closing. The TLS certificate is
+// checked for changes every TLSReloadInterval meanwhile.
+func (s *Server) Serve() error {
+	if s.tcp == nil {
+		return errors.New("server is not listening")
+	}
+
+	if s.certs != nil && s.cfg.TLSReloadInterval > 0 {
+		go s.certs.Watch(s.watchCtx, s.cfg.TLSReloadInterval)
+	}
+
+	errs := make(chan error, 2)
+	go func() {
+		// The certificate comes from TLSConfig.GetCertificate, so no files
+		// are passed to ServeTLS
+		if s.certs != nil {
+			errs <- s.http.ServeTLS(s.tcp, "", "")
+		} else {
+			errs <- s.http.Serve(s.tcp)
+		}
+	}()
+	if s.unix != nil {
+		// The socket is local, so it is served without TLS
+		go func() {
+			errs <- s.http.Serve(s.unix)
+		}()
+	}
+
+	if err := <-errs; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// Shutdown stops accepting connections and waits for active ones to finish
+// or ctx to be done, like http.Server.Shutdown
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.stopWatching()
+	return s.http.Shutdown(ctx)
+}
@@ -0,0 +1,247 @@
+package server
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/your-org/note-server/internal/config"
+)
+
+// writeCert writes a self-signed certificate for localhost with the given
+// serial number, returning the certificate and key paths
+func writeCert(t *testing.T, dir string, serial int64) (string, string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("CreateCertificate() error = %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("MarshalECPrivateKey() error = %v", err)
+	}
+
+	certPath, keyPath := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	os.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600)
+	os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600)
+
+	// Make each version distinguishable even within the file system's
+	// timestamp resolution
+	modTime := time.Now().Add(time.Duration(serial) * time.Second)
+	os.Chtimes(certPath, modTime, modTime)
+	os.Chtimes(keyPath, modTime, modTime)
+	return certPath, keyPath
+}
+
+// serial returns the serial number of the certificate r is serving
+func serial(t *testing.T, r *CertReloader) int64 {
+	t.Helper()
+
+	cert, _ := r.GetCertificate(nil)
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatalf("ParseCertificate() error = %v", err)
+	}
+	return leaf.SerialNumber.Int64()
+}
+
+// testConfig returns settings for a server on a free local port
+func testConfig() *config.Config {
+	return &config.Config{
+		Host:              "127.0.0.1",
+		Port:              "0",
+		ReadHeaderTimeout: 5 * time.Second,
+		IdleTimeout:       time.Minute,
+		MaxHeaderBytes:    1 << 20,
+	}
+}
+
+// start listens and serves cfg until the test ends
+func start(t *testing.T, cfg *config.Config) *Server {
+	t.Helper()
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "ok")
+	})
+	srv, err := New(cfg, handler)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if err := srv.Listen(); err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+
+	served := make(chan error, 1)
+	go func() { served <- srv.Serve() }()
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		srv.Shutdown(ctx)
+		if err := <-served; err != nil {
+			t.Errorf("Serve() error = %v", err)
+		}
+	})
+	return srv
+}
+
+// get fetches url with client and returns the body
+func get(t *testing.T, client *http.Client, url string) string {
+	t.Helper()
+
+	resp, err := client.Get(url)
+	if err != nil {
+		t.Fatalf("GET %s error = %v", url, err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	return string(body)
+}
+
+func TestServer_HTTP(t *testing.T) {
+	srv := start(t, testConfig())
+
+	addresses := srv.Addresses()
+	if len(addresses) != 1 {
+		t.Fatalf("Expected one listener, got %v", addresses)
+	}
+	if body := get(t, http.DefaultClient, addresses[0]); body != "ok" {
+		t.Errorf("Expected ok, got %q", body)
+	}
+	if srv.http.ReadHeaderTimeout != 5*time.Second || srv.http.MaxHeaderBytes != 1<<20 {
+		t.Errorf("Expected the configured limits, got %+v", srv.http)
+	}
+}
+
+func TestServer_TLS(t *testing.T) {
+	cfg := testConfig()
+	cfg.TLSCertFile, cfg.TLSKeyFile = writeCert(t, t.TempDir(), 1)
+	srv := start(t, cfg)
+
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}}
+	address := srv.Addresses()[0]
+	if address[:8] != "https://" {
+		t.Fatalf("Expected an https address, got %s", address)
+	}
+	if body := get(t, client, address); body != "ok" {
+		t.Errorf("Expected ok, got %q", body)
+	}
+}
+
+func TestServer_UnixSocket(t *testing.T) {
+	dir, err := os.MkdirTemp("", "sock")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	cfg := testConfig()
+	cfg.UnixSocket = filepath.Join(dir, "run", "server.sock")
+
+	// A socket left by a previous run is replaced
+	os.MkdirAll(filepath.Dir(cfg.UnixSocket), 0755)
+	stale, err := net.Listen("unix", cfg.UnixSocket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	stale.Close()
+
+	srv := start(t, cfg)
+
+	if addresses := srv.Addresses(); len(addresses) != 2 || addresses[1] != "unix:"+cfg.UnixSocket {
+		t.Errorf("Expected the socket among the listeners, got %v", addresses)
+	}
+	info, err := os.Stat(cfg.UnixSocket)
+	if err != nil || info.Mode().Perm() != 0660 {
+		t.Errorf("Expected socket with mode 0660, got %v, %v", info, err)
+	}
+
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", cfg.UnixSocket)
+		},
+	}}
+	if body := get(t, client, "http://unix/"); body != "ok" {
+		t.Errorf("Expected ok over the socket, got %q", body)
+	}
+
+	// A socket in use by a running server isn't taken over
+	if _, err := listenUnix(cfg.UnixSocket); err == nil {
+		t.Error("Expected an error for a socket in use")
+	}
+}
+
+func TestServer_UnixSocketNotASocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notes.db")
+	os.WriteFile(path, []byte("data"), 0600)
+
+	if _, err := listenUnix(path); err == nil {
+		t.Error("Expected an error for a path that isn't a socket")
+	}
+	if data, _ := os.ReadFile(path); string(data) != "data" {
+		t.Error("Expected the file left alone")
+	}
+}
+
+func TestCertReloader(t *testing.T) {
+	dir := t.TempDir()
+	certPath, keyPath := writeCert(t, dir, 1)
+
+	reloader, err := NewCertReloader(certPath, keyPath)
+	if err != nil {
+		t.Fatalf("NewCertReloader() error = %v", err)
+	}
+	if got := serial(t, reloader); got != 1 {
+		t.Errorf("Expected serial 1, got %d", got)
+	}
+
+	if changed, err := reloader.Reload(); changed || err != nil {
+		t.Errorf("Expected no reload of unchanged files, got %v, %v", changed, err)
+	}
+
+	writeCert(t, dir, 2)
+	if changed, err := reloader.Reload(); !changed || err != nil {
+		t.Fatalf("Expected a reload, got %v, %v", changed, err)
+	}
+	if got := serial(t, reloader); got != 2 {
+		t.Errorf("Expected serial 2 after reload, got %d", got)
+	}
+
+	// A broken pair is rejected once and the current certificate kept
+	os.WriteFile(keyPath, []byte("not a key"), 0600)
+	if changed, err := reloader.Reload(); changed || err == nil {
+		t.Errorf("Expected the broken key rejected, got %v, %v", changed, err)
+	}
+	if changed, err := reloader.Reload(); changed || err != nil {
+		t.Errorf("Expected the broken version reported once, got %v, %v", changed, err)
+	}
+	if got := serial(t, reloader); got != 2 {
+		t.Errorf("Expected serial 2 kept, got %d", got)
+	}
+}
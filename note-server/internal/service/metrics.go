@@ -0,0 +1,52 @@
+package service
+
+import "github.com/your-org/note-server/internal/metrics"
+
+var (
+	// transcriptionDuration records transcriber calls: whole recordings in
+	// "batch" mode and live segments in "stream" mode
+	transcriptionDuration = metrics.NewHistogramVec(
+		"note_transcription_duration_seconds",
+		"Time taken by the transcriber, by provider, mode and outcome.",
+		metrics.SlowBuckets,
+		"provider", "mode", "outcome",
+	)
+
+	// summarizationDuration records summarizer calls
+	summarizationDuration = metrics.NewHistogramVec(
+		"note_summarization_duration_seconds",
+		"Time taken by the summarizer, by provider and outcome.",
+		metrics.SlowBuckets,
+		"provider", "outcome",
+	)
+
+	// ffmpegDuration records audio conversions, successful or not
+	ffmpegDuration = metrics.NewHistogramVec(
+		"note_ffmpeg_duration_seconds",
+		"Time taken converting audio with ffmpeg.",
+		metrics.DefBuckets,
+	)
+
+	// ffmpegFailures counts conversions ffmpeg failed
+	ffmpegFailures = metrics.NewCounterVec(
+		"note_ffmpeg_failures_total",
+		"Audio conversions that ffmpeg failed.",
+	)
+)
+
+// providerLabel names a provider for metrics; "" is the default transcriber
+// or summarizer
+func providerLabel(name string) string {
+	if name == "" {
+		return "default"
+	}
+	return name
+}
+
+// outcome labels an operation by whether err is nil
+func outcome(err error) string {
+	if err != nil {
+		return "error"
+	}
+	return "ok"
+}
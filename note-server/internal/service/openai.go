@@ -0,0 +1,128 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"time"
+
+	"github.com/your-org/note-server/internal/audio"
+	"github.com/your-org/note-server/internal/tracing"
+)
+
+const (
+	// OpenAI's API and the model used when none is configured
+	openAIBaseURL           = "https://api.openai.com/v1"
+	defaultOpenAITranscribe = "whisper-1"
+
+	// Time allowed for one transcription request
+	openAITimeout = 5 * time.Minute
+)
+
+// OpenAITranscriber transcribes audio with OpenAI's audio transcription API
+type OpenAITranscriber struct {
+	apiKey string
+	model  string
+
+	// Replaced in tests
+	baseURL string
+	client  *http.Client
+}
+
+// NewOpenAITranscriber creates a transcriber calling OpenAI with apiKey. An
+// empty model selects whisper-1.
+func NewOpenAITranscriber(apiKey, model string) *OpenAITranscriber {
+	if model == "" {
+		model = defaultOpenAITranscribe
+	}
+	return &OpenAITranscriber{
+		apiKey:  apiKey,
+		model:   model,
+		baseURL: openAIBaseURL,
+		client:  &http.Client{Transport: tracing.Transport(nil), Timeout: openAITimeout},
+	}
+}
+
+// TranscribeAudio sends audio, in any format OpenAI accepts (usually WAV from
+// convertToWav), with the language hint from ctx
+func (t *OpenAITranscriber) TranscribeAudio(ctx context.Context, audioData []byte) (string, error) {
+	if len(audioData) == 0 {
+		return "", fmt.Errorf("audio data is empty")
+	}
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	form.WriteField("model", t.model)
+	form.WriteField("response_format", "json")
+	if language := LanguageFromContext(ctx); language != "" {
+		form.WriteField("language", language)
+	}
+	file, err := form.CreateFormFile("file", "audio.wav")
+	if err != nil {
+		return "", err
+	}
+	file.Write(audioData)
+	if err := form.Close(); err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.baseURL+"/audio/transcriptions", &body)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Authorization", "Bearer "+t.apiKey)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("openai request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return "", fmt.Errorf("openai returned %s: %s", resp.Status, bytes.TrimSpace(message))
+	}
+
+	var result struct {
+		Text string `json:"text"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("invalid openai response: %w", err)
+	}
+	return result.Text, nil
+}
+
+// TranscribeStream transcribes one segment of a stream as a single final
+// result. PCM16 segments are wrapped in a WAV header using the stream's layout
+// from ctx. With VAD, StartStream also calls it with the audio so far of a
+// segment still open and forwards the result as a partial, so the API's
+// one-shot transcriptions still stream partials as speech goes on.
+func (t *OpenAITranscriber) TranscribeStream(ctx context.Context, audioChunk []byte) (<-chan TranscribeStreamResult, error) {
+	if len(audioChunk) == 0 {
+		return nil, fmt.Errorf("audio chunk is empty")
+	}
+
+	data := audioChunk
+	if opts, ok := StreamOptionsFromContext(ctx); ok && opts.Encoding == EncodingPCM16 {
+		data = audio.EncodeWAV(audioChunk, opts.SampleRate, opts.Channels)
+	}
+
+	resultChan := make(chan TranscribeStreamResult, 1)
+	go func() {
+		defer close(resultChan)
+
+		text, err := t.TranscribeAudio(ctx, data)
+		result := TranscribeStreamResult{Type: "final", Text: text}
+		if err != nil {
+			result = TranscribeStreamResult{Type: "error", Text: err.Error()}
+		}
+		resultChan <- result
+	}()
+
+	return resultChan, nil
+}
@@ -0,0 +1,63 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestOpenAITranscriber(t *testing.T) {
+	var language, model string
+	var file []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/audio/transcriptions" || r.Header.Get("Authorization") != "Bearer sk-test" {
+			http.Error(w, `{"error":{"message":"bad request"}}`, http.StatusUnauthorized)
+			return
+		}
+		upload, _, err := r.FormFile("file")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		file, _ = io.ReadAll(upload)
+		language, model = r.FormValue("language"), r.FormValue("model")
+		w.Write([]byte(`{"text":"hello there"}`))
+	}))
+	defer server.Close()
+
+	transcriber := NewOpenAITranscriber("sk-test", "")
+	transcriber.baseURL = server.URL
+
+	text, err := transcriber.TranscribeAudio(WithLanguage(context.Background(), "de"), []byte("RIFF...."))
+	if err != nil || text != "hello there" {
+		t.Fatalf("TranscribeAudio() = %q, %v", text, err)
+	}
+	if language != "de" || model != "whisper-1" {
+		t.Errorf("Expected language de and model whisper-1, got %q and %q", language, model)
+	}
+
+	// Streamed PCM16 segments are sent as WAV in the session's language
+	chunks := make(chan []byte, 1)
+	chunks <- make([]byte, 3200)
+	close(chunks)
+	service := NewTranscribeServiceWithTranscriber(transcriber)
+	var results []TranscribeStreamResult
+	for result := range service.StartStream(context.Background(), StreamOptions{Language: "fr"}, chunks) {
+		results = append(results, result)
+	}
+	if len(results) != 1 || results[0].Type != "final" || results[0].Text != "hello there" {
+		t.Fatalf("Expected one final result, got %+v", results)
+	}
+	if language != "fr" || !bytes.HasPrefix(file, []byte("RIFF")) || len(file) != 44+3200 {
+		t.Errorf("Expected a WAV segment in fr, got %d bytes in %q", len(file), language)
+	}
+
+	// Rejected requests are errors
+	transcriber.apiKey = "wrong"
+	if _, err := transcriber.TranscribeAudio(context.Background(), []byte("RIFF")); err == nil {
+		t.Error("Expected an error for a rejected request")
+	}
+}
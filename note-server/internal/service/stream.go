@@ -0,0 +1,530 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/your-org/note-server/internal/audio"
+	"github.com/your-org/note-server/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+const (
+	// Default PCM layout assumed for live audio, matching what convertToWav produces
+	defaultStreamSampleRate = 16000
+	defaultStreamChannels   = 1
+
+	// Size of the PCM16 blocks read from ffmpeg when decoding compressed
+	// streams, 100ms of 16kHz mono audio
+	decodedChunkSize = 3200
+
+	// Without VAD, decoded blocks are gathered into segments of this much
+	// audio, 5s of 16kHz mono PCM16, so the provider isn't called ten times a second
+	decodedSegmentSize = 5 * 32000
+
+	// With VAD, an open speech segment is transcribed again for a "partial"
+	// each time it has grown by this much audio
+	interimInterval = 2 * time.Second
+
+	// Number of results buffered between the transcriber and the consumer.
+	// Once full, the pipeline stops pulling audio chunks until the consumer catches up.
+	streamResultBuffer = 16
+)
+
+// Supported encodings for streamed audio
+const (
+	EncodingPCM16 = "pcm16"
+	EncodingOpus  = "opus"
+	EncodingWebM  = "webm"
+)
+
+// StreamOptions describes the audio fed into a streaming transcription session
+type StreamOptions struct {
+	SampleRate int
+	Channels   int
+	Encoding   string
+	Language   string
+	Provider   string
+
+	// Cut the stream into speech segments and drop silence. PCM16 streams
+	// must be mono; opus and webm are always decoded to mono.
+	VAD bool
+}
+
+// WithDefaults fills unset fields with the default 16kHz mono PCM16 layout
+func (o StreamOptions) WithDefaults() StreamOptions {
+	if o.SampleRate <= 0 {
+		o.SampleRate = defaultStreamSampleRate
+	}
+	if o.Channels <= 0 {
+		o.Channels = defaultStreamChannels
+	}
+	if o.Encoding == "" {
+		o.Encoding = EncodingPCM16
+	}
+	return o
+}
+
+// Validate checks that the options describe audio the pipeline can handle
+func (o StreamOptions) Validate() error {
+	switch o.Encoding {
+	case "", EncodingPCM16, EncodingOpus, EncodingWebM:
+	default:
+		return fmt.Errorf("unsupported encoding %q", o.Encoding)
+	}
+
+	if o.SampleRate != 0 && (o.SampleRate < 8000 || o.SampleRate > 48000) {
+		return fmt.Errorf("sample rate %d out of range (8000-48000)", o.SampleRate)
+	}
+
+	if o.Channels < 0 || o.Channels > 2 {
+		return fmt.Errorf("unsupported channel count %d", o.Channels)
+	}
+
+	// Checked against the audio the detector sees: compressed streams are
+	// decoded to mono PCM16 first
+	if o.VAD && (o.Encoding == "" || o.Encoding == EncodingPCM16) && o.Channels > 1 {
+		return fmt.Errorf("voice activity detection requires mono pcm16 audio")
+	}
+
+	return nil
+}
+
+// CheckDecoder returns an error if streams in encoding can't be decoded on this
+// server. Opus and WebM streams are decoded with ffmpeg.
+func CheckDecoder(encoding string) error {
+	if encoding != EncodingOpus && encoding != EncodingWebM {
+		return nil
+	}
+	if _, err := exec.LookPath("ffmpeg"); err != nil {
+		return fmt.Errorf("%s audio needs ffmpeg, which isn't installed on the server", encoding)
+	}
+	return nil
+}
+
+// chunkDuration returns the playback duration of a 16-bit PCM chunk
+func (o StreamOptions) chunkDuration(size int) time.Duration {
+	bytesPerSecond := o.SampleRate * o.Channels * 2
+	return time.Duration(size) * time.Second / time.Duration(bytesPerSecond)
+}
+
+type languageKey struct{}
+
+// WithLanguage returns a context carrying a language hint for transcribers
+func WithLanguage(ctx context.Context, language string) context.Context {
+	return context.WithValue(ctx, languageKey{}, language)
+}
+
+// LanguageFromContext returns the language hint set by WithLanguage, if any
+func LanguageFromContext(ctx context.Context) string {
+	language, _ := ctx.Value(languageKey{}).(string)
+	return language
+}
+
+type streamOptionsKey struct{}
+
+// StreamOptionsFromContext returns the layout of the audio in a stream's
+// segments, set by StartStream
+func StreamOptionsFromContext(ctx context.Context) (StreamOptions, bool) {
+	opts, ok := ctx.Value(streamOptionsKey{}).(StreamOptions)
+	return opts, ok
+}
+
+// audioSegment is a piece of audio at a known position in the stream, or a
+// voice activity event to forward in its place
+type audioSegment struct {
+	index      int
+	start, end time.Duration
+	data       []byte
+
+	// Set for "speech_start" and "speech_end", which are passed through in
+	// order with the results of the segments around them
+	event *TranscribeStreamResult
+
+	// The audio so far of a segment that hasn't closed; its results are
+	// forwarded as partials
+	interim bool
+}
+
+// StartStream feeds audio chunks, in the order they are received, into the configured
+// transcriber and returns a single channel of incremental results.
+//
+// Opus and WebM streams are decoded to 16kHz mono PCM16 by one ffmpeg process per
+// stream, as only the first chunk of such a stream carries its headers, and are
+// then handled like PCM16 streams.
+//
+// Without VAD each PCM16 chunk becomes one segment, while decoded audio is
+// gathered into segments of 5s. With VAD, chunks are cut into speech
+// segments, silence never reaches the transcriber, and "speech_start"/"speech_end"
+// results are emitted as speech is detected. While a speech segment is open it is
+// transcribed again every 2s of audio, whenever the transcriber is idle, and those
+// results are emitted as "partial"s ahead of the segment's final results. Results
+// are tagged with the segment index and the segment's offset within the stream.
+//
+// Segments are transcribed one at a time, so a slow transcriber applies backpressure to
+// the sender of chunks once a small queue fills up. The returned channel is closed once
+// chunks is closed and drained, or when ctx is cancelled.
+//
+// Callers should check opts with Validate, the provider with HasProvider and the
+// encoding with CheckDecoder first.
+func (s *TranscribeService) StartStream(ctx context.Context, opts StreamOptions, chunks <-chan []byte) <-chan TranscribeStreamResult {
+	opts = opts.WithDefaults()
+	transcriber := s.transcriberFor(opts.Provider)
+	var decodeErr func() error
+	segmentSize := 0
+	if opts.Encoding == EncodingOpus || opts.Encoding == EncodingWebM {
+		chunks, decodeErr = decodeStream(ctx, opts.Encoding, chunks)
+		opts.Encoding, opts.SampleRate, opts.Channels = EncodingPCM16, defaultStreamSampleRate, defaultStreamChannels
+		segmentSize = decodedSegmentSize
+	}
+	if opts.Language != "" {
+		ctx = WithLanguage(ctx, opts.Language)
+	}
+	ctx = context.WithValue(ctx, streamOptionsKey{}, opts)
+
+	results := make(chan TranscribeStreamResult, streamResultBuffer)
+	segments := make(chan audioSegment)
+
+	if opts.VAD {
+		go detectSpeech(ctx, opts, chunks, segments)
+	} else {
+		go splitChunks(ctx, opts, chunks, segments, segmentSize)
+	}
+
+	go func() {
+		defer close(results)
+
+		for segment := range segments {
+			if segment.event != nil {
+				select {
+				case results <- *segment.event:
+					continue
+				case <-ctx.Done():
+				}
+				for range segments {
+				}
+				return
+			}
+			if !streamSegment(ctx, transcriber, providerLabel(opts.Provider), segment, results) {
+				// Let the producer finish so it closes segments
+				for range segments {
+				}
+				return
+			}
+		}
+
+		if decodeErr == nil {
+			return
+		}
+		if err := decodeErr(); err != nil {
+			select {
+			case results <- TranscribeStreamResult{Type: "error", Text: err.Error()}:
+			case <-ctx.Done():
+			}
+		}
+	}()
+
+	return results
+}
+
+// decodeStream pipes a compressed stream through ffmpeg, returning its audio
+// as 16kHz mono PCM16. Once the returned channel is closed, wait returns the
+// error that ended decoding early, if any.
+func decodeStream(ctx context.Context, encoding string, chunks <-chan []byte) (pcm <-chan []byte, wait func() error) {
+	decoded := make(chan []byte)
+
+	ctx, span := tracing.Start(ctx, "ffmpeg decode",
+		semconv.ProcessExecutableName("ffmpeg"),
+		attribute.String("audio.encoding", encoding),
+	)
+	cmd := exec.CommandContext(ctx, "ffmpeg",
+		"-hide_banner", "-loglevel", "error",
+		"-i", "pipe:0",
+		"-f", "s16le",
+		"-ar", "16000", // 16kHz sample rate
+		"-ac", "1", // mono
+		"pipe:1",
+	)
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+
+	stdin, err := cmd.StdinPipe()
+	var stdout io.ReadCloser
+	if err == nil {
+		stdout, err = cmd.StdoutPipe()
+	}
+	if err == nil {
+		err = cmd.Start()
+	}
+	if err != nil {
+		err = fmt.Errorf("failed to start ffmpeg: %w", err)
+		tracing.End(span, err)
+		close(decoded)
+		// Drain the stream so its sender isn't blocked
+		go func() {
+			for range chunks {
+			}
+		}()
+		return decoded, func() error { return err }
+	}
+	start := time.Now()
+
+	go func() {
+		defer stdin.Close()
+
+		for {
+			select {
+			case chunk, ok := <-chunks:
+				if !ok {
+					return
+				}
+				if _, err := stdin.Write(chunk); err != nil {
+					// ffmpeg gave up; the reader reports why
+					for range chunks {
+					}
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	var decodeErr error
+	go func() {
+		defer close(decoded)
+
+		for {
+			block := make([]byte, decodedChunkSize)
+			n, readErr := io.ReadFull(stdout, block)
+			if n > 0 {
+				select {
+				case decoded <- block[:n]:
+				case <-ctx.Done():
+				}
+			}
+			if readErr != nil {
+				break
+			}
+		}
+
+		err := cmd.Wait()
+		ffmpegDuration.WithLabelValues().ObserveSince(start)
+		if err != nil && ctx.Err() == nil {
+			ffmpegFailures.WithLabelValues().Inc()
+			decodeErr = fmt.Errorf("ffmpeg decoding failed: %w, stderr: %s", err, strings.TrimSpace(stderr.String()))
+		}
+		tracing.End(span, decodeErr)
+	}()
+
+	return decoded, func() error { return decodeErr }
+}
+
+// splitChunks turns chunks into segments of at least size bytes, or every
+// chunk into its own segment when size is 0. Audio short of a full segment
+// is sent when chunks is closed.
+func splitChunks(ctx context.Context, opts StreamOptions, chunks <-chan []byte, segments chan<- audioSegment, size int) {
+	defer close(segments)
+
+	streamStart := time.Now()
+	var offset time.Duration
+	var pending []byte
+	index := 0
+
+	for {
+		var chunk []byte
+		var ok bool
+		select {
+		case chunk, ok = <-chunks:
+		case <-ctx.Done():
+			return
+		}
+
+		if ok {
+			pending = append(pending, chunk...)
+			if len(pending) == 0 || len(pending) < size {
+				continue
+			}
+		} else if len(pending) == 0 {
+			return
+		}
+		chunk, pending = pending, nil
+
+		start := offset
+		end := time.Since(streamStart)
+		if opts.Encoding == EncodingPCM16 {
+			end = start + opts.chunkDuration(len(chunk))
+		}
+		offset = end
+
+		select {
+		case segments <- audioSegment{index: index, start: start, end: end, data: chunk}:
+		case <-ctx.Done():
+			return
+		}
+		index++
+
+		if !ok {
+			return
+		}
+	}
+}
+
+// detectSpeech runs voice activity detection over the chunks, passing speech
+// boundaries and only speech segments on for transcription. Open segments are
+// passed on for interim transcription as they grow, unless the transcriber is
+// busy, so partials never hold the stream up.
+func detectSpeech(ctx context.Context, opts StreamOptions, chunks <-chan []byte, segments chan<- audioSegment) {
+	defer close(segments)
+
+	vadOptions := audio.DefaultVADOptions()
+	vadOptions.SampleRate = opts.SampleRate
+	segmenter := audio.NewSegmenter(vadOptions)
+	index := 0
+
+	// Audio of the open segment when it was last transcribed for a partial
+	interimStep := int(int64(opts.SampleRate) * 2 * int64(interimInterval) / int64(time.Second))
+	interimSize := 0
+	interim := func() {
+		start, open, ok := segmenter.Open()
+		if !ok || len(open)-interimSize < interimStep {
+			return
+		}
+		select {
+		case segments <- audioSegment{index: index, start: start, end: start + opts.chunkDuration(len(open)), data: open, interim: true}:
+			interimSize = len(open)
+		default:
+			// The transcriber is busy; a later chunk tries again
+		}
+	}
+
+	handle := func(events []audio.SpeechEvent) bool {
+		for _, event := range events {
+			result := TranscribeStreamResult{
+				Type:    event.Type,
+				Segment: index,
+				StartMs: event.Start.Milliseconds(),
+			}
+			if event.Type == audio.SpeechEnd {
+				result.EndMs = event.End.Milliseconds()
+			}
+
+			select {
+			case segments <- audioSegment{event: &result}:
+			case <-ctx.Done():
+				return false
+			}
+
+			if event.Type != audio.SpeechEnd {
+				continue
+			}
+			interimSize = 0
+
+			select {
+			case segments <- audioSegment{index: index, start: event.Start, end: event.End, data: event.Audio}:
+			case <-ctx.Done():
+				return false
+			}
+			index++
+		}
+		return true
+	}
+
+	for {
+		select {
+		case chunk, ok := <-chunks:
+			if !ok {
+				handle(segmenter.Flush())
+				return
+			}
+			if !handle(segmenter.Write(chunk)) {
+				return
+			}
+			interim()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// streamSegment transcribes one segment with the named provider and forwards its
+// results. Results of an interim segment are forwarded as partials, and its
+// failures are dropped as the segment's final transcription follows. It returns
+// false if ctx was cancelled while forwarding.
+func streamSegment(ctx context.Context, transcriber Transcriber, provider string, segment audioSegment, results chan<- TranscribeStreamResult) bool {
+	emit := func(result TranscribeStreamResult) bool {
+		result.Segment = segment.index
+		result.StartMs = segment.start.Milliseconds()
+		result.EndMs = segment.end.Milliseconds()
+		select {
+		case results <- result:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	zerolog.Ctx(ctx).Debug().
+		Int("segment", segment.index).
+		Int("bytes", len(segment.data)).
+		Int64("start_ms", segment.start.Milliseconds()).
+		Bool("interim", segment.interim).
+		Msg("Transcribing segment")
+
+	// Results are emitted with the session's ctx; only the transcriber sees
+	// the segment's span
+	spanCtx, span := tracing.Start(ctx, "transcriber transcribe",
+		attribute.String("ai.provider", provider),
+		attribute.String("transcription.mode", "stream"),
+		attribute.Int("transcription.segment", segment.index),
+		attribute.Int("audio.bytes", len(segment.data)),
+		attribute.Bool("transcription.interim", segment.interim),
+	)
+	// Providers bill interim transcriptions like any other
+	usageFromContext(ctx).addAudio(segment.end - segment.start)
+	start := time.Now()
+	segmentResults, err := transcriber.TranscribeStream(spanCtx, segment.data)
+	if err != nil {
+		transcriptionDuration.WithLabelValues(provider, "stream", outcome(err)).ObserveSince(start)
+		tracing.End(span, err)
+		if segment.interim {
+			return true
+		}
+		return emit(TranscribeStreamResult{
+			Type: "error",
+			Text: fmt.Sprintf("transcription failed: %v", err),
+		})
+	}
+
+	// The segment is timed until the transcriber's last result, including time
+	// spent waiting for the client to take results
+	var failure error
+	for result := range segmentResults {
+		if result.Type == "error" && failure == nil {
+			failure = errors.New(result.Text)
+		}
+		if segment.interim {
+			if result.Type == "error" {
+				continue
+			}
+			if result.Type == "final" {
+				result.Type = "partial"
+			}
+		}
+		if !emit(result) {
+			span.End()
+			return false
+		}
+	}
+	transcriptionDuration.WithLabelValues(provider, "stream", outcome(failure)).ObserveSince(start)
+	tracing.End(span, failure)
+
+	return ctx.Err() == nil
+}
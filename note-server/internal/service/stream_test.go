@@ -0,0 +1,454 @@
+package service
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/your-org/note-server/internal/tracing"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestTranscribeService_StartStream(t *testing.T) {
+	mockTranscriber := &MockTranscriber{
+		TranscribeStreamFunc: func(ctx context.Context, audioChunk []byte) (<-chan TranscribeStreamResult, error) {
+			resultChan := make(chan TranscribeStreamResult, 2)
+			go func() {
+				defer close(resultChan)
+				resultChan <- TranscribeStreamResult{Type: "partial", Text: "partial"}
+				resultChan <- TranscribeStreamResult{Type: "final", Text: string(audioChunk[:1])}
+			}()
+			return resultChan, nil
+		},
+	}
+
+	service := NewTranscribeServiceWithTranscriber(mockTranscriber)
+
+	// 16000 bytes is 500ms of 16kHz mono PCM16
+	chunks := make(chan []byte, 3)
+	chunks <- append([]byte("a"), make([]byte, 15999)...)
+	chunks <- []byte{}
+	chunks <- append([]byte("b"), make([]byte, 7999)...)
+	close(chunks)
+
+	var results []TranscribeStreamResult
+	for result := range service.StartStream(context.Background(), StreamOptions{}, chunks) {
+		results = append(results, result)
+	}
+
+	want := []TranscribeStreamResult{
+		{Type: "partial", Text: "partial", Segment: 0, StartMs: 0, EndMs: 500},
+		{Type: "final", Text: "a", Segment: 0, StartMs: 0, EndMs: 500},
+		{Type: "partial", Text: "partial", Segment: 1, StartMs: 500, EndMs: 750},
+		{Type: "final", Text: "b", Segment: 1, StartMs: 500, EndMs: 750},
+	}
+
+	if len(results) != len(want) {
+		t.Fatalf("Expected %d results, got %d: %+v", len(want), len(results), results)
+	}
+
+	for i := range want {
+		if results[i] != want[i] {
+			t.Errorf("Result %d: expected %+v, got %+v", i, want[i], results[i])
+		}
+	}
+}
+
+func TestTranscribeService_StartStream_Error(t *testing.T) {
+	mockTranscriber := &MockTranscriber{
+		TranscribeStreamFunc: func(ctx context.Context, audioChunk []byte) (<-chan TranscribeStreamResult, error) {
+			return nil, errors.New("provider unavailable")
+		},
+	}
+
+	service := NewTranscribeServiceWithTranscriber(mockTranscriber)
+
+	chunks := make(chan []byte, 1)
+	chunks <- []byte("chunk")
+	close(chunks)
+
+	var results []TranscribeStreamResult
+	for result := range service.StartStream(context.Background(), StreamOptions{}, chunks) {
+		results = append(results, result)
+	}
+
+	if len(results) != 1 || results[0].Type != "error" {
+		t.Fatalf("Expected a single error result, got %+v", results)
+	}
+}
+
+// fakeFFmpeg puts an ffmpeg on PATH that runs script instead of decoding
+func fakeFFmpeg(t *testing.T, script string) {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "ffmpeg"), []byte("#!/bin/sh\n"+script+"\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+func TestTranscribeService_StartStream_Decode(t *testing.T) {
+	mockTranscriber := &MockTranscriber{
+		TranscribeStreamFunc: func(ctx context.Context, audioChunk []byte) (<-chan TranscribeStreamResult, error) {
+			resultChan := make(chan TranscribeStreamResult, 1)
+			resultChan <- TranscribeStreamResult{Type: "final", Text: string(audioChunk[:1])}
+			close(resultChan)
+			return resultChan, nil
+		},
+	}
+	service := NewTranscribeServiceWithTranscriber(mockTranscriber)
+
+	t.Run("without ffmpeg", func(t *testing.T) {
+		t.Setenv("PATH", t.TempDir())
+		if err := CheckDecoder(EncodingWebM); err == nil {
+			t.Error("Expected webm to need ffmpeg")
+		}
+		if err := CheckDecoder(EncodingPCM16); err != nil {
+			t.Errorf("Expected pcm16 to need no decoder, got %v", err)
+		}
+	})
+
+	// The stand-in decoder passes the stream through, so the transcriber sees
+	// the chunks' bytes regrouped into 5s segments of the decoded layout, and
+	// the rest when the stream ends
+	t.Run("decoded into segments", func(t *testing.T) {
+		fakeFFmpeg(t, "exec cat")
+		chunks := make(chan []byte, 2)
+		chunks <- append([]byte("a"), make([]byte, 159999)...)
+		chunks <- append([]byte("b"), make([]byte, 2799)...)
+		close(chunks)
+
+		var results []TranscribeStreamResult
+		for result := range service.StartStream(context.Background(), StreamOptions{Encoding: EncodingWebM, SampleRate: 48000}, chunks) {
+			results = append(results, result)
+		}
+
+		want := []TranscribeStreamResult{
+			{Type: "final", Text: "a", Segment: 0, StartMs: 0, EndMs: 5000},
+			{Type: "final", Text: "b", Segment: 1, StartMs: 5000, EndMs: 5087},
+		}
+		if len(results) != len(want) {
+			t.Fatalf("Expected %d results, got %+v", len(want), results)
+		}
+		for i := range want {
+			if results[i] != want[i] {
+				t.Errorf("Result %d: expected %+v, got %+v", i, want[i], results[i])
+			}
+		}
+	})
+
+	// Decoded audio is mono PCM16 whatever was sent, so VAD applies to it
+	t.Run("decoded with VAD", func(t *testing.T) {
+		fakeFFmpeg(t, "exec cat")
+		pcm := make([]byte, 3*32000)
+		for i := 16000; i < 32000; i++ {
+			value := int16(10000 * math.Sin(2*math.Pi*220*float64(i)/16000))
+			binary.LittleEndian.PutUint16(pcm[i*2:], uint16(value))
+		}
+		chunks := make(chan []byte, 1)
+		chunks <- pcm
+		close(chunks)
+
+		opts := StreamOptions{Encoding: EncodingOpus, SampleRate: 48000, Channels: 2, VAD: true}
+		if err := opts.Validate(); err != nil {
+			t.Fatalf("Validate() error = %v", err)
+		}
+		var types []string
+		for result := range service.StartStream(context.Background(), opts, chunks) {
+			types = append(types, result.Type)
+		}
+		if got := strings.Join(types, ","); got != "speech_start,speech_end,final" {
+			t.Errorf("Unexpected result sequence: %s", got)
+		}
+	})
+
+	t.Run("decoding fails", func(t *testing.T) {
+		fakeFFmpeg(t, "cat >/dev/null; echo 'Invalid data found' >&2; exit 1")
+		chunks := make(chan []byte, 1)
+		chunks <- []byte("not opus")
+		close(chunks)
+
+		var results []TranscribeStreamResult
+		for result := range service.StartStream(context.Background(), StreamOptions{Encoding: EncodingOpus}, chunks) {
+			results = append(results, result)
+		}
+		if len(results) != 1 || results[0].Type != "error" || !strings.Contains(results[0].Text, "Invalid data found") {
+			t.Errorf("Expected the decoder's error, got %+v", results)
+		}
+	})
+}
+
+func TestTranscribeService_StartStream_Tracing(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	shutdown := tracing.Setup(tracing.Options{SampleRatio: 1, Exporter: exporter, Sync: true})
+	defer func() {
+		shutdown(context.Background())
+		tracing.Setup(tracing.Options{})
+	}()
+
+	mockTranscriber := &MockTranscriber{
+		TranscribeStreamFunc: func(ctx context.Context, audioChunk []byte) (<-chan TranscribeStreamResult, error) {
+			if audioChunk[0] == 'x' {
+				return nil, errors.New("provider unavailable")
+			}
+			resultChan := make(chan TranscribeStreamResult, 1)
+			resultChan <- TranscribeStreamResult{Type: "final", Text: "ok"}
+			close(resultChan)
+			return resultChan, nil
+		},
+	}
+	service := NewTranscribeServiceWithTranscriber(mockTranscriber)
+
+	chunks := make(chan []byte, 2)
+	chunks <- []byte("a")
+	chunks <- []byte("x")
+	close(chunks)
+
+	ctx, session := tracing.Start(context.Background(), "session")
+	for range service.StartStream(ctx, StreamOptions{}, chunks) {
+	}
+	session.End()
+
+	spans := exporter.GetSpans()
+	if len(spans) != 3 {
+		t.Fatalf("Expected a span per segment and the session, got %d", len(spans))
+	}
+	for i, wantCode := range []codes.Code{codes.Unset, codes.Error} {
+		span := spans[i]
+		if span.Name != "transcriber transcribe" || span.Parent.SpanID() != session.SpanContext().SpanID() {
+			t.Errorf("Segment %d: expected a transcriber span under the session, got %q", i, span.Name)
+		}
+		if span.Status.Code != wantCode {
+			t.Errorf("Segment %d: expected status %v, got %v", i, wantCode, span.Status.Code)
+		}
+	}
+}
+
+func TestTranscribeService_StartStream_Backpressure(t *testing.T) {
+	release := make(chan struct{})
+	mockTranscriber := &MockTranscriber{
+		TranscribeStreamFunc: func(ctx context.Context, audioChunk []byte) (<-chan TranscribeStreamResult, error) {
+			resultChan := make(chan TranscribeStreamResult, 1)
+			go func() {
+				defer close(resultChan)
+				<-release
+				resultChan <- TranscribeStreamResult{Type: "final", Text: "done"}
+			}()
+			return resultChan, nil
+		},
+	}
+
+	service := NewTranscribeServiceWithTranscriber(mockTranscriber)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	chunks := make(chan []byte)
+	results := service.StartStream(ctx, StreamOptions{}, chunks)
+
+	// Only a couple of chunks can queue up behind the busy transcriber before
+	// the pipeline stops accepting more
+	accepted := 0
+	for blocked := false; !blocked; {
+		select {
+		case chunks <- []byte("chunk"):
+			accepted++
+			if accepted > 3 {
+				t.Fatal("Expected pipeline to stop accepting chunks while the transcriber is busy")
+			}
+		case <-time.After(50 * time.Millisecond):
+			blocked = true
+		}
+	}
+
+	close(release)
+	<-results
+
+	select {
+	case chunks <- []byte("more"):
+	case <-time.After(1 * time.Second):
+		t.Fatal("Expected pipeline to accept chunks after the transcriber caught up")
+	}
+}
+
+func TestTranscribeService_StartStream_VAD(t *testing.T) {
+	var transcribed [][]byte
+	mockTranscriber := &MockTranscriber{
+		TranscribeStreamFunc: func(ctx context.Context, audioChunk []byte) (<-chan TranscribeStreamResult, error) {
+			transcribed = append(transcribed, audioChunk)
+			resultChan := make(chan TranscribeStreamResult, 1)
+			resultChan <- TranscribeStreamResult{Type: "final", Text: "speech"}
+			close(resultChan)
+			return resultChan, nil
+		},
+	}
+
+	service := NewTranscribeServiceWithTranscriber(mockTranscriber)
+
+	// 1s of silence, 1s of a loud tone, 1s of silence, sent as 100ms chunks
+	pcm := make([]byte, 3*32000)
+	for i := 16000; i < 32000; i++ {
+		value := int16(10000 * math.Sin(2*math.Pi*220*float64(i)/16000))
+		binary.LittleEndian.PutUint16(pcm[i*2:], uint16(value))
+	}
+
+	chunks := make(chan []byte, 30)
+	for offset := 0; offset < len(pcm); offset += 3200 {
+		chunks <- pcm[offset : offset+3200]
+	}
+	close(chunks)
+
+	var types []string
+	var final TranscribeStreamResult
+	for result := range service.StartStream(context.Background(), StreamOptions{VAD: true}, chunks) {
+		types = append(types, result.Type)
+		if result.Type == "final" {
+			final = result
+		}
+	}
+
+	if got := strings.Join(types, ","); got != "speech_start,speech_end,final" {
+		t.Fatalf("Unexpected result sequence: %s", got)
+	}
+
+	// The segment starts and ends within the padding around the tone
+	if final.StartMs < 700 || final.StartMs > 1000 || final.EndMs < 2000 || final.EndMs > 2300 {
+		t.Errorf("Expected segment around 1000-2000ms, got %d-%d", final.StartMs, final.EndMs)
+	}
+
+	if len(transcribed) != 1 || len(transcribed[0]) >= len(pcm)/2 {
+		t.Errorf("Expected a single speech segment without the surrounding silence to be transcribed")
+	}
+}
+
+func TestTranscribeService_StartStream_VADPartials(t *testing.T) {
+	// Each transcription reports how much audio it was given
+	mockTranscriber := &MockTranscriber{
+		TranscribeStreamFunc: func(ctx context.Context, audioChunk []byte) (<-chan TranscribeStreamResult, error) {
+			resultChan := make(chan TranscribeStreamResult, 1)
+			resultChan <- TranscribeStreamResult{Type: "final", Text: fmt.Sprint(len(audioChunk))}
+			close(resultChan)
+			return resultChan, nil
+		},
+	}
+
+	service := NewTranscribeServiceWithTranscriber(mockTranscriber)
+
+	// 5s of a loud tone followed by 1s of silence, sent in real-time-ish 100ms
+	// chunks so the transcriber is idle between them
+	pcm := make([]byte, 6*32000)
+	for i := 0; i < 5*16000; i++ {
+		value := int16(10000 * math.Sin(2*math.Pi*220*float64(i)/16000))
+		binary.LittleEndian.PutUint16(pcm[i*2:], uint16(value))
+	}
+	chunks := make(chan []byte)
+	go func() {
+		defer close(chunks)
+		for offset := 0; offset < len(pcm); offset += 3200 {
+			chunks <- pcm[offset : offset+3200]
+			time.Sleep(time.Millisecond)
+		}
+	}()
+
+	var types []string
+	var partials []TranscribeStreamResult
+	var final TranscribeStreamResult
+	for result := range service.StartStream(context.Background(), StreamOptions{VAD: true}, chunks) {
+		if len(types) == 0 || types[len(types)-1] != result.Type {
+			types = append(types, result.Type)
+		}
+		switch result.Type {
+		case "partial":
+			partials = append(partials, result)
+		case "final":
+			final = result
+		}
+	}
+
+	// The open segment is transcribed as partials before its final result
+	if got := strings.Join(types, ","); got != "speech_start,partial,speech_end,final" {
+		t.Fatalf("Unexpected result sequence: %s", got)
+	}
+	for i, partial := range partials {
+		if partial.Segment != 0 || partial.StartMs != final.StartMs || partial.EndMs >= final.EndMs {
+			t.Errorf("Partial %d: expected part of segment 0 (%d-%d ms), got %+v", i, final.StartMs, final.EndMs, partial)
+		}
+		if i > 0 && partial.EndMs <= partials[i-1].EndMs {
+			t.Errorf("Partial %d: expected the open segment to grow, got %+v after %+v", i, partial, partials[i-1])
+		}
+	}
+}
+
+func TestTranscribeService_StartStream_VADOrder(t *testing.T) {
+	// A slow transcriber, so the second tone is detected while the first is
+	// still being transcribed
+	mockTranscriber := &MockTranscriber{
+		TranscribeStreamFunc: func(ctx context.Context, audioChunk []byte) (<-chan TranscribeStreamResult, error) {
+			time.Sleep(50 * time.Millisecond)
+			resultChan := make(chan TranscribeStreamResult, 1)
+			resultChan <- TranscribeStreamResult{Type: "final", Text: "speech"}
+			close(resultChan)
+			return resultChan, nil
+		},
+	}
+
+	service := NewTranscribeServiceWithTranscriber(mockTranscriber)
+
+	// Two 1s tones separated and surrounded by 1s of silence
+	pcm := make([]byte, 5*32000)
+	for _, from := range []int{16000, 48000} {
+		for i := from; i < from+16000; i++ {
+			value := int16(10000 * math.Sin(2*math.Pi*220*float64(i)/16000))
+			binary.LittleEndian.PutUint16(pcm[i*2:], uint16(value))
+		}
+	}
+
+	chunks := make(chan []byte, 50)
+	for offset := 0; offset < len(pcm); offset += 3200 {
+		chunks <- pcm[offset : offset+3200]
+	}
+	close(chunks)
+
+	var got []string
+	for result := range service.StartStream(context.Background(), StreamOptions{VAD: true}, chunks) {
+		got = append(got, fmt.Sprintf("%s:%d", result.Type, result.Segment))
+	}
+
+	want := "speech_start:0,speech_end:0,final:0,speech_start:1,speech_end:1,final:1"
+	if strings.Join(got, ",") != want {
+		t.Errorf("Expected %s, got %s", want, strings.Join(got, ","))
+	}
+}
+
+func TestStreamOptions_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    StreamOptions
+		wantErr bool
+	}{
+		{name: "defaults", opts: StreamOptions{}},
+		{name: "opus", opts: StreamOptions{Encoding: EncodingOpus, SampleRate: 48000}},
+		{name: "unsupported encoding", opts: StreamOptions{Encoding: "mp3"}, wantErr: true},
+		{name: "sample rate too high", opts: StreamOptions{SampleRate: 96000}, wantErr: true},
+		{name: "too many channels", opts: StreamOptions{Channels: 6}, wantErr: true},
+		{name: "vad on pcm16", opts: StreamOptions{VAD: true}},
+		{name: "vad on opus", opts: StreamOptions{Encoding: EncodingOpus, VAD: true}},
+		{name: "vad on stereo webm", opts: StreamOptions{Encoding: EncodingWebM, Channels: 2, VAD: true}},
+		{name: "vad on stereo", opts: StreamOptions{Channels: 2, VAD: true}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.opts.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
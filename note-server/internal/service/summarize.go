@@ -4,6 +4,10 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"time"
+
+	"github.com/your-org/note-server/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // Summarizer interface allows swapping different summarization implementations
@@ -47,7 +51,18 @@ func (s *SummarizeService) SummarizeTextWithOptions(ctx context.Context, text st
 		return "", fmt.Errorf("text is empty")
 	}
 	
-	return s.summarizer.SummarizeText(ctx, text, maxWords)
+	ctx, span := tracing.Start(ctx, "summarizer summarize",
+		attribute.String("ai.provider", providerLabel("")),
+		attribute.Int("summary.max_words", maxWords),
+	)
+	start := time.Now()
+	summary, err := s.summarizer.SummarizeText(ctx, text, maxWords)
+	summarizationDuration.WithLabelValues(providerLabel(""), outcome(err)).ObserveSince(start)
+	tracing.End(span, err)
+	if err == nil {
+		usageFromContext(ctx).addSummaryTokens(estimateTokens(text) + estimateTokens(summary))
+	}
+	return summary, err
 }
 
 // SummarizeText implementation for FirstNWordsSummarizer - returns first N words
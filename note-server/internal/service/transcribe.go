@@ -7,13 +7,25 @@ import (
 	"os/exec"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/your-org/note-server/internal/audio"
+	"github.com/your-org/note-server/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
 )
 
 // TranscribeStreamResult represents a streaming transcription result
 type TranscribeStreamResult struct {
-	Type string `json:"type"` // "partial" or "final"
+	Type string `json:"type"` // "partial", "final" or "error"
 	Text string `json:"text"`
+
+	// Position of the result within a stream, set by StartStream
+	Segment int   `json:"segment"`
+	StartMs int64 `json:"start_ms"`
+	EndMs   int64 `json:"end_ms"`
 }
 
 // Transcriber interface allows swapping different transcription implementations
@@ -25,6 +37,10 @@ type Transcriber interface {
 // TranscribeService handles audio transcription
 type TranscribeService struct {
 	transcriber Transcriber
+
+	// Named transcribers that streaming sessions can select by provider
+	providers      map[string]Transcriber
+	providersMutex sync.RWMutex
 }
 
 // PlaceholderTranscriber is a dummy implementation for development
@@ -34,6 +50,7 @@ type PlaceholderTranscriber struct{}
 func NewTranscribeService() *TranscribeService {
 	return &TranscribeService{
 		transcriber: &PlaceholderTranscriber{},
+		providers:   make(map[string]Transcriber),
 	}
 }
 
@@ -41,11 +58,65 @@ func NewTranscribeService() *TranscribeService {
 func NewTranscribeServiceWithTranscriber(transcriber Transcriber) *TranscribeService {
 	return &TranscribeService{
 		transcriber: transcriber,
+		providers:   make(map[string]Transcriber),
+	}
+}
+
+// RegisterProvider makes a transcriber selectable by name for streaming sessions
+func (s *TranscribeService) RegisterProvider(name string, transcriber Transcriber) {
+	s.providersMutex.Lock()
+	defer s.providersMutex.Unlock()
+
+	s.providers[name] = transcriber
+}
+
+// SetProviders replaces the default transcriber and every named provider, as
+// when the configuration changes. Sessions already streaming keep the
+// transcriber they started with.
+func (s *TranscribeService) SetProviders(transcriber Transcriber, providers map[string]Transcriber) {
+	s.providersMutex.Lock()
+	defer s.providersMutex.Unlock()
+
+	s.transcriber = transcriber
+	s.providers = make(map[string]Transcriber, len(providers))
+	for name, provider := range providers {
+		s.providers[name] = provider
+	}
+}
+
+// HasProvider reports whether a provider can be used. The empty name always
+// refers to the default transcriber.
+func (s *TranscribeService) HasProvider(name string) bool {
+	if name == "" {
+		return true
+	}
+
+	s.providersMutex.RLock()
+	defer s.providersMutex.RUnlock()
+
+	_, ok := s.providers[name]
+	return ok
+}
+
+// transcriberFor returns the named provider, falling back to the default transcriber
+func (s *TranscribeService) transcriberFor(name string) Transcriber {
+	s.providersMutex.RLock()
+	defer s.providersMutex.RUnlock()
+
+	if transcriber, ok := s.providers[name]; ok {
+		return transcriber
 	}
+	return s.transcriber
 }
 
 // convertToWav converts audio data to WAV format using ffmpeg
-func (s *TranscribeService) convertToWav(ctx context.Context, audioData []byte) ([]byte, error) {
+func (s *TranscribeService) convertToWav(ctx context.Context, audioData []byte) (wav []byte, err error) {
+	ctx, span := tracing.Start(ctx, "ffmpeg convert",
+		semconv.ProcessExecutableName("ffmpeg"),
+		attribute.Int("audio.input_bytes", len(audioData)),
+	)
+	defer func() { tracing.End(span, err) }()
+
 	// Create temporary files for input and output
 	tempDir, err := os.MkdirTemp("", "audio_convert_*")
 	if err != nil {
@@ -75,7 +146,14 @@ func (s *TranscribeService) convertToWav(ctx context.Context, audioData []byte)
 	var stderr strings.Builder
 	cmd.Stderr = &stderr
 
-	if err := cmd.Run(); err != nil {
+	start := time.Now()
+	err = cmd.Run()
+	ffmpegDuration.WithLabelValues().ObserveSince(start)
+	if err != nil {
+		ffmpegFailures.WithLabelValues().Inc()
+		if cmd.ProcessState != nil {
+			span.SetAttributes(semconv.ProcessExitCode(cmd.ProcessState.ExitCode()))
+		}
 		return nil, fmt.Errorf("ffmpeg conversion failed: %w, stderr: %s", err, stderr.String())
 	}
 
@@ -85,25 +163,39 @@ func (s *TranscribeService) convertToWav(ctx context.Context, audioData []byte)
 		return nil, fmt.Errorf("failed to read converted file: %w", err)
 	}
 
+	span.SetAttributes(attribute.Int("audio.output_bytes", len(wavData)))
+	zerolog.Ctx(ctx).Debug().
+		Int("input_bytes", len(audioData)).
+		Int("output_bytes", len(wavData)).
+		Dur("duration", time.Since(start)).
+		Msg("Converted audio with ffmpeg")
 	return wavData, nil
 }
 
 // TranscribeAudio transcribes audio data to text
-func (s *TranscribeService) TranscribeAudio(ctx context.Context, audioData []byte) (string, error) {
+func (s *TranscribeService) TranscribeAudio(ctx context.Context, audioData []byte) (text string, err error) {
 	if len(audioData) == 0 {
 		return "", fmt.Errorf("audio data is empty")
 	}
 
-	// For PlaceholderTranscriber, skip audio conversion and pass data directly
-	if _, isPlaceholder := s.transcriber.(*PlaceholderTranscriber); isPlaceholder {
-		return s.transcriber.TranscribeAudio(ctx, audioData)
+	ctx, span := tracing.Start(ctx, "TranscribeService.TranscribeAudio", attribute.Int("audio.bytes", len(audioData)))
+	defer func() { tracing.End(span, err) }()
+
+	transcriber := s.transcriberFor("")
+
+	// For PlaceholderTranscriber, skip audio conversion and pass data directly.
+	// Unconverted audio is only metered when it's already WAV.
+	if _, isPlaceholder := transcriber.(*PlaceholderTranscriber); isPlaceholder {
+		usageFromContext(ctx).addAudio(wavDuration(audioData))
+		return transcriber.TranscribeAudio(ctx, audioData)
 	}
 
 	// For mock transcribers in tests, also skip conversion by checking if it's not a real transcriber
 	// We can identify test mocks by checking the type name
-	transciberType := fmt.Sprintf("%T", s.transcriber)
+	transciberType := fmt.Sprintf("%T", transcriber)
 	if strings.Contains(transciberType, "Mock") || strings.Contains(transciberType, "Integration") {
-		return s.transcriber.TranscribeAudio(ctx, audioData)
+		usageFromContext(ctx).addAudio(wavDuration(audioData))
+		return transcriber.TranscribeAudio(ctx, audioData)
 	}
 
 	// Convert to WAV format using ffmpeg for real transcribers
@@ -112,8 +204,42 @@ func (s *TranscribeService) TranscribeAudio(ctx context.Context, audioData []byt
 		return "", fmt.Errorf("audio conversion failed: %w", err)
 	}
 
-	// Use the configured transcriber to process the WAV data
-	return s.transcriber.TranscribeAudio(ctx, wavData)
+	// Drop silence so the transcriber only sees speech
+	wavData, hasSpeech := trimSilence(wavData)
+	if !hasSpeech {
+		zerolog.Ctx(ctx).Info().Msg("No speech in audio, skipping transcription")
+		return "", nil
+	}
+
+	// Use the configured transcriber to process the WAV data; the speech
+	// it's sent is what the provider bills
+	usageFromContext(ctx).addAudio(wavDuration(wavData))
+	providerCtx, providerSpan := tracing.Start(ctx, "transcriber transcribe",
+		attribute.String("ai.provider", providerLabel("")),
+		attribute.String("transcription.mode", "batch"),
+	)
+	start := time.Now()
+	text, err = transcriber.TranscribeAudio(providerCtx, wavData)
+	transcriptionDuration.WithLabelValues(providerLabel(""), "batch", outcome(err)).ObserveSince(start)
+	tracing.End(providerSpan, err)
+	return text, err
+}
+
+// trimSilence removes silence from 16kHz mono WAV data produced by convertToWav.
+// It reports false if the audio contains no speech. Audio that can't be decoded is
+// returned unchanged.
+func trimSilence(wavData []byte) ([]byte, bool) {
+	pcm, sampleRate, channels, err := audio.DecodeWAV(wavData)
+	if err != nil || channels != 1 {
+		return wavData, true
+	}
+
+	trimmed := audio.TrimSilence(pcm, sampleRate)
+	if len(trimmed) == 0 {
+		return nil, false
+	}
+
+	return audio.EncodeWAV(trimmed, sampleRate, channels), true
 }
 
 // TranscribeAudio implementation for PlaceholderTranscriber
@@ -185,5 +311,5 @@ type TranscribeResponse struct {
 
 // TranscribeStream processes audio chunks and returns transcription results via a channel
 func (s *TranscribeService) TranscribeStream(ctx context.Context, audioChunk []byte) (<-chan TranscribeStreamResult, error) {
-	return s.transcriber.TranscribeStream(ctx, audioChunk)
+	return s.transcriberFor("").TranscribeStream(ctx, audioChunk)
 }
@@ -2,8 +2,12 @@ package service
 
 import (
 	"context"
+	"encoding/binary"
+	"math"
 	"testing"
 	"time"
+
+	"github.com/your-org/note-server/internal/audio"
 )
 
 // Test the placeholder transcriber implementation
@@ -200,6 +204,32 @@ func TestTranscribeService_TranscribeStream_Cancellation(t *testing.T) {
 	}
 }
 
+func TestTrimSilence(t *testing.T) {
+	// 2s of silence around 500ms of a loud tone
+	pcm := make([]byte, 5*16000)
+	for i := 32000; i < 40000; i++ {
+		value := int16(10000 * math.Sin(2*math.Pi*220*float64(i)/16000))
+		binary.LittleEndian.PutUint16(pcm[i*2:], uint16(value))
+	}
+
+	trimmed, hasSpeech := trimSilence(audio.EncodeWAV(pcm, 16000, 1))
+	if !hasSpeech {
+		t.Fatal("Expected speech to be detected")
+	}
+	if len(trimmed) >= len(pcm)/2 {
+		t.Errorf("Expected silence to be trimmed, got %d of %d bytes", len(trimmed), len(pcm))
+	}
+
+	if _, hasSpeech := trimSilence(audio.EncodeWAV(make([]byte, 32000), 16000, 1)); hasSpeech {
+		t.Error("Expected silent audio to have no speech")
+	}
+
+	notWAV := []byte("not a wav file")
+	if got, hasSpeech := trimSilence(notWAV); !hasSpeech || string(got) != string(notWAV) {
+		t.Error("Expected undecodable audio to be passed through unchanged")
+	}
+}
+
 // Mock Transcriber for testing (same as in handlers_test.go)
 type MockTranscriber struct {
 	TranscribeAudioFunc  func(ctx context.Context, audioData []byte) (string, error)
@@ -0,0 +1,81 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/your-org/note-server/internal/audio"
+)
+
+// Usage tallies the work providers do for a request or session: the length
+// of the audio transcribed and the tokens of text summarized. It's safe for
+// concurrent use.
+type Usage struct {
+	mutex         sync.Mutex
+	audio         time.Duration
+	summaryTokens int
+}
+
+// Audio returns the length of the audio sent to transcribers
+func (u *Usage) Audio() time.Duration {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+	return u.audio
+}
+
+// SummaryTokens returns the estimated tokens sent to and returned by summarizers
+func (u *Usage) SummaryTokens() int {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+	return u.summaryTokens
+}
+
+func (u *Usage) addAudio(d time.Duration) {
+	if u == nil {
+		return
+	}
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+	u.audio += d
+}
+
+func (u *Usage) addSummaryTokens(n int) {
+	if u == nil {
+		return
+	}
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+	u.summaryTokens += n
+}
+
+type usageKey struct{}
+
+// WithUsage returns a context whose transcriptions and summaries are tallied
+// in u
+func WithUsage(ctx context.Context, u *Usage) context.Context {
+	return context.WithValue(ctx, usageKey{}, u)
+}
+
+// usageFromContext returns the Usage set by WithUsage, or nil
+func usageFromContext(ctx context.Context) *Usage {
+	u, _ := ctx.Value(usageKey{}).(*Usage)
+	return u
+}
+
+// wavDuration returns the length of PCM16 WAV audio, or 0 if it can't be
+// decoded
+func wavDuration(wavData []byte) time.Duration {
+	pcm, sampleRate, channels, err := audio.DecodeWAV(wavData)
+	if err != nil || sampleRate <= 0 || channels <= 0 {
+		return 0
+	}
+	return StreamOptions{SampleRate: sampleRate, Channels: channels}.chunkDuration(len(pcm))
+}
+
+// estimateTokens approximates the tokens a provider bills for text at four
+// characters each, as providers don't report counts to this service
+func estimateTokens(text string) int {
+	return (utf8.RuneCountInString(text) + 3) / 4
+}
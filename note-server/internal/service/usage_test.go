@@ -0,0 +1,51 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/your-org/note-server/internal/audio"
+)
+
+func TestUsage(t *testing.T) {
+	usage := &Usage{}
+	ctx := WithUsage(context.Background(), usage)
+
+	// 32000 bytes is one second of 16kHz mono PCM16
+	transcribeService := NewTranscribeServiceWithTranscriber(&MockTranscriber{})
+	if _, err := transcribeService.TranscribeAudio(ctx, audio.EncodeWAV(make([]byte, 32000), 16000, 1)); err != nil {
+		t.Fatalf("TranscribeAudio() error = %v", err)
+	}
+	if usage.Audio() != time.Second {
+		t.Errorf("Expected 1s of audio after TranscribeAudio, got %v", usage.Audio())
+	}
+
+	chunks := make(chan []byte, 2)
+	chunks <- make([]byte, 16000)
+	chunks <- make([]byte, 8000)
+	close(chunks)
+	for range transcribeService.StartStream(ctx, StreamOptions{}, chunks) {
+	}
+	if usage.Audio() != 1750*time.Millisecond {
+		t.Errorf("Expected 1.75s of audio after streaming, got %v", usage.Audio())
+	}
+
+	// 40 characters in, 12 out
+	summarizeService := NewSummarizeServiceWithSummarizer(&MockSummarizer{}, 50)
+	if _, err := summarizeService.SummarizeText(ctx, strings.Repeat("word ", 8)); err != nil {
+		t.Fatalf("SummarizeText() error = %v", err)
+	}
+	if usage.SummaryTokens() != 13 {
+		t.Errorf("Expected 13 summary tokens, got %d", usage.SummaryTokens())
+	}
+
+	// Work outside a metered context isn't tallied anywhere
+	if _, err := summarizeService.SummarizeText(context.Background(), "unmetered"); err != nil {
+		t.Fatalf("SummarizeText() error = %v", err)
+	}
+	if usage.SummaryTokens() != 13 {
+		t.Errorf("Expected unmetered work to be ignored, got %d tokens", usage.SummaryTokens())
+	}
+}
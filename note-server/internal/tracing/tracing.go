@@ -0,0 +1,136 @@
+// Package tracing sets up OpenTelemetry tracing. Setup installs the global
+// tracer provider and the W3C trace context propagator, so a request's trace
+// continues from its traceparent header through the services, ffmpeg, the
+// database and calls to AI providers. Spans are exported over OTLP/HTTP, or
+// to any exporter, such as tracetest's in-memory one in tests.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// instrumentationName names the tracer the server's spans come from
+const instrumentationName = "github.com/your-org/note-server"
+
+// Exporters that can be configured with TRACING_EXPORTER
+const (
+	ExporterNone = "none"
+	ExporterOTLP = "otlp"
+)
+
+// Options configure Setup
+type Options struct {
+	// ServiceName is reported as service.name on every span
+	ServiceName string
+
+	// SampleRatio is the fraction of new traces recorded. Traces continued
+	// from a traceparent header follow the caller's decision.
+	SampleRatio float64
+
+	// Exporter receives finished spans; nil records nothing
+	Exporter sdktrace.SpanExporter
+
+	// Sync exports each span as it ends instead of in batches, for tests
+	Sync bool
+}
+
+// NewExporter creates the exporter named by kind: nil for "none" or "", or
+// an OTLP/HTTP exporter sending to endpoint, e.g. http://localhost:4318. An
+// empty endpoint falls back to the OTEL_EXPORTER_OTLP_* variables.
+func NewExporter(ctx context.Context, kind, endpoint string) (sdktrace.SpanExporter, error) {
+	switch kind {
+	case "", ExporterNone:
+		return nil, nil
+	case ExporterOTLP:
+		var opts []otlptracehttp.Option
+		if endpoint != "" {
+			opts = append(opts, otlptracehttp.WithEndpointURL(endpoint))
+		}
+		exporter, err := otlptracehttp.New(ctx, opts...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create OTLP exporter: %w", err)
+		}
+		return exporter, nil
+	default:
+		return nil, fmt.Errorf("unknown tracing exporter %q", kind)
+	}
+}
+
+// Setup installs a tracer provider for opts and the trace context
+// propagator as the global ones, returning a function that flushes pending
+// spans and stops exporting. Without an exporter, incoming trace context is
+// still passed on to providers, but no spans are recorded.
+func Setup(opts Options) func(context.Context) error {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	if opts.Exporter == nil {
+		otel.SetTracerProvider(noop.NewTracerProvider())
+		return func(context.Context) error { return nil }
+	}
+
+	export := sdktrace.WithBatcher(opts.Exporter)
+	if opts.Sync {
+		export = sdktrace.WithSyncer(opts.Exporter)
+	}
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceName(opts.ServiceName),
+	))
+	if err != nil {
+		res = resource.Default()
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		export,
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(opts.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown
+}
+
+// Tracer returns the tracer for the server's spans from the global provider
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
+
+// Start starts an internal span named name as a child of any span in ctx
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return Tracer().Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// End ends span, marking it failed with err if err isn't nil
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// Transport wraps base, or http.DefaultTransport if nil, so each request is
+// a client span and carries the caller's trace context in a traceparent
+// header. Providers calling external APIs send their requests through it.
+func Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return otelhttp.NewTransport(base)
+}
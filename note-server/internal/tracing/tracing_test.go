@@ -0,0 +1,127 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// setupInMemory installs a provider recording every span to an in-memory
+// exporter until the test ends
+func setupInMemory(t *testing.T) *tracetest.InMemoryExporter {
+	t.Helper()
+	exporter := tracetest.NewInMemoryExporter()
+	shutdown := Setup(Options{ServiceName: "note-server-test", SampleRatio: 1, Exporter: exporter, Sync: true})
+	t.Cleanup(func() {
+		shutdown(context.Background())
+		Setup(Options{})
+	})
+	return exporter
+}
+
+func TestStartAndEnd(t *testing.T) {
+	exporter := setupInMemory(t)
+
+	ctx, parent := Start(context.Background(), "parent")
+	_, child := Start(ctx, "child", attribute.String("step", "convert"))
+	End(child, errors.New("ffmpeg exited"))
+	End(parent, nil)
+
+	spans := exporter.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("Expected 2 spans, got %d", len(spans))
+	}
+	childSpan, parentSpan := spans[0], spans[1]
+	if childSpan.Parent.SpanID() != parentSpan.SpanContext.SpanID() {
+		t.Errorf("Expected the child to be parented to %s, got %s", parentSpan.SpanContext.SpanID(), childSpan.Parent.SpanID())
+	}
+	if childSpan.Status.Code != codes.Error || len(childSpan.Events) != 1 {
+		t.Errorf("Expected the child to record its error, got %+v", childSpan.Status)
+	}
+	if parentSpan.Status.Code != codes.Unset {
+		t.Errorf("Expected the parent to succeed, got %+v", parentSpan.Status)
+	}
+	if service, _ := parentSpan.Resource.Set().Value("service.name"); service.AsString() != "note-server-test" {
+		t.Errorf("Expected service.name note-server-test, got %q", service.AsString())
+	}
+}
+
+func TestTransport(t *testing.T) {
+	exporter := setupInMemory(t)
+
+	var traceparent string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("traceparent")
+	}))
+	defer upstream.Close()
+
+	ctx, span := Start(context.Background(), "transcribe")
+	client := &http.Client{Transport: Transport(nil)}
+	req, _ := http.NewRequestWithContext(ctx, http.MethodPost, upstream.URL, nil)
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+	span.End()
+
+	spans := exporter.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("Expected a client span and its parent, got %d spans", len(spans))
+	}
+	clientSpan := spans[0]
+	if clientSpan.SpanKind != trace.SpanKindClient || clientSpan.Parent.SpanID() != spans[1].SpanContext.SpanID() {
+		t.Errorf("Expected a client span under the caller's span, got %+v", clientSpan)
+	}
+	want := "00-" + clientSpan.SpanContext.TraceID().String() + "-" + clientSpan.SpanContext.SpanID().String() + "-01"
+	if traceparent != want {
+		t.Errorf("Expected traceparent %q, got %q", want, traceparent)
+	}
+}
+
+func TestNewExporter(t *testing.T) {
+	tests := []struct {
+		name     string
+		kind     string
+		endpoint string
+		wantNil  bool
+		wantErr  bool
+	}{
+		{"default", "", "", true, false},
+		{"none", ExporterNone, "", true, false},
+		{"otlp", ExporterOTLP, "http://localhost:4318", false, false},
+		{"unknown", "zipkin", "", true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exporter, err := NewExporter(context.Background(), tt.kind, tt.endpoint)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewExporter() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if (exporter == nil) != tt.wantNil {
+				t.Errorf("NewExporter() = %v, want nil %v", exporter, tt.wantNil)
+			}
+			if exporter != nil {
+				exporter.Shutdown(context.Background())
+			}
+		})
+	}
+}
+
+func TestSetup_WithoutExporter(t *testing.T) {
+	shutdown := Setup(Options{})
+	defer shutdown(context.Background())
+
+	_, span := Start(context.Background(), "unrecorded")
+	defer span.End()
+	if span.IsRecording() {
+		t.Error("Expected spans not to be recorded without an exporter")
+	}
+}
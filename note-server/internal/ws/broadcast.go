@@ -0,0 +1,359 @@
+package ws
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/gorilla/websocket"
+	"github.com/rs/zerolog"
+	"github.com/your-org/note-server/internal/auth"
+	"github.com/your-org/note-server/internal/database"
+	"github.com/your-org/note-server/pkg/response"
+)
+
+const (
+	// Messages kept per session for replay to late-joining watchers
+	maxBacklog = 1000
+
+	// Messages queued per watcher on top of the backlog before it is dropped as too slow
+	watcherBuffer = 256
+)
+
+// broadcastTypes are the message types relayed to watchers. Replies that only
+// matter to the recording client, such as "pong", are not relayed, so watchers
+// may see gaps in sequence numbers.
+var broadcastTypes = map[string]bool{
+	MessageTypeStarted:     true,
+	MessageTypeStopped:     true,
+	MessageTypePaused:      true,
+	MessageTypeResumed:     true,
+	MessageTypePartial:     true,
+	MessageTypeFinal:       true,
+	MessageTypeSpeechStart: true,
+	MessageTypeSpeechEnd:   true,
+}
+
+// sessionBroadcast fans the messages of one live session out to read-only watchers
+type sessionBroadcast struct {
+	// ID of the user the session belongs to
+	owner string
+
+	// Recording the session is saved as, 0 unless it's recorded. Users it's
+	// shared with may watch.
+	recordingID int64
+
+	mutex    sync.Mutex
+	backlog  [][]byte
+	watchers map[*watcher]bool
+	closed   bool
+}
+
+// watcher is a read-only subscriber to a session with its own message queue,
+// so a slow watcher never holds up the session or other watchers
+type watcher struct {
+	log       *zerolog.Logger
+	send      chan []byte
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// newSessionBroadcast creates an empty broadcast for a session owned by owner
+// and saved as the recording recordingID
+func newSessionBroadcast(owner string, recordingID int64) *sessionBroadcast {
+	return &sessionBroadcast{
+		owner:       owner,
+		recordingID: recordingID,
+		watchers:    make(map[*watcher]bool),
+	}
+}
+
+// publish records a message in the backlog and queues it for every watcher.
+// Watchers whose queue is full are disconnected.
+func (b *sessionBroadcast) publish(data []byte) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.closed {
+		return
+	}
+
+	b.backlog = append(b.backlog, data)
+	if len(b.backlog) > maxBacklog {
+		b.backlog = b.backlog[len(b.backlog)-maxBacklog:]
+	}
+
+	for w := range b.watchers {
+		select {
+		case w.send <- data:
+		default:
+			w.log.Warn().Int("queued", len(w.send)).Msg("Dropping slow watcher")
+			delete(b.watchers, w)
+			w.close()
+		}
+	}
+}
+
+// subscribe adds a watcher, logging to logger, whose queue is pre-filled with the backlog
+func (b *sessionBroadcast) subscribe(logger *zerolog.Logger) *watcher {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	w := &watcher{
+		log:  logger,
+		send: make(chan []byte, len(b.backlog)+watcherBuffer),
+		done: make(chan struct{}),
+	}
+	for _, data := range b.backlog {
+		w.send <- data
+	}
+
+	if b.closed {
+		w.close()
+		return w
+	}
+
+	b.watchers[w] = true
+	return w
+}
+
+// unsubscribe removes a watcher, e.g. when its connection goes away
+func (b *sessionBroadcast) unsubscribe(w *watcher) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	delete(b.watchers, w)
+	w.close()
+}
+
+// close ends the broadcast; watchers drain their queues and disconnect
+func (b *sessionBroadcast) close() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.closed = true
+	for w := range b.watchers {
+		delete(b.watchers, w)
+		w.close()
+	}
+}
+
+// close signals the watcher's writer that no more messages will be queued
+func (w *watcher) close() {
+	w.closeOnce.Do(func() {
+		close(w.done)
+	})
+}
+
+// next returns the next queued message, or false once the watcher is closed and drained
+func (w *watcher) next() ([]byte, bool) {
+	select {
+	case data := <-w.send:
+		return data, true
+	default:
+	}
+
+	select {
+	case data := <-w.send:
+		return data, true
+	case <-w.done:
+		// Deliver anything queued before the close
+		select {
+		case data := <-w.send:
+			return data, true
+		default:
+			return nil, false
+		}
+	}
+}
+
+// openBroadcast registers a live session so it can be watched
+func (h *TranscribeHub) openBroadcast(sessionID string, owner string, recordingID int64) {
+	h.sessionsMutex.Lock()
+	defer h.sessionsMutex.Unlock()
+
+	h.sessions[sessionID] = newSessionBroadcast(owner, recordingID)
+}
+
+// ActiveSessions returns the number of live sessions owned by a user
+func (h *TranscribeHub) ActiveSessions(userID string) int {
+	h.sessionsMutex.RLock()
+	defer h.sessionsMutex.RUnlock()
+
+	count := 0
+	for _, broadcast := range h.sessions {
+		if broadcast.owner == userID {
+			count++
+		}
+	}
+	return count
+}
+
+// publish relays a message of a live session to its watchers
+func (h *TranscribeHub) publish(sessionID string, messageType string, data []byte) {
+	if !broadcastTypes[messageType] {
+		return
+	}
+
+	h.sessionsMutex.RLock()
+	broadcast := h.sessions[sessionID]
+	h.sessionsMutex.RUnlock()
+
+	if broadcast != nil {
+		broadcast.publish(data)
+	}
+}
+
+// closeBroadcast ends a live session's broadcast and forgets it
+func (h *TranscribeHub) closeBroadcast(sessionID string) {
+	h.sessionsMutex.Lock()
+	broadcast := h.sessions[sessionID]
+	delete(h.sessions, sessionID)
+	h.sessionsMutex.Unlock()
+
+	if broadcast != nil {
+		broadcast.close()
+	}
+}
+
+// ServeWatchWS handles /ws/transcribe/{session}/watch, subscribing a read-only
+// client to the results of a live session. Late joiners first receive the
+// session's messages so far. Once users exist, only those who may view the
+// session's recording can watch; everyone else gets 404.
+func (h *TranscribeHub) ServeWatchWS(w http.ResponseWriter, r *http.Request) {
+	principal, ok := h.authenticate(w, r, auth.ScopeRead)
+	if !ok {
+		return
+	}
+
+	sessionID := chi.URLParam(r, "session")
+
+	h.sessionsMutex.RLock()
+	broadcast := h.sessions[sessionID]
+	h.sessionsMutex.RUnlock()
+
+	if broadcast == nil || !canWatch(r.Context(), principal, broadcast) {
+		response.Error(w, r, http.StatusNotFound, "Session not found")
+		return
+	}
+
+	// Watchers count against the connection limit like recording clients
+	if !h.reserveWatcher() {
+		h.logRejected(r)
+		w.Header().Set("Retry-After", "5")
+		response.Error(w, r, http.StatusServiceUnavailable, "Too many connections")
+		return
+	}
+
+	conn, err := h.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		h.releaseWatcher()
+		zerolog.Ctx(r.Context()).Warn().Err(err).Msg("WebSocket upgrade failed")
+		return
+	}
+
+	logger := zerolog.Ctx(r.Context()).With().Str("user", principal.ID).Str("session_id", sessionID).Logger()
+	sub := broadcast.subscribe(&logger)
+	activeConnections.WithLabelValues("watch").Inc()
+	logger.Info().Msg("Watcher joined session")
+
+	go h.watchWritePump(conn, sub)
+	go h.watchReadPump(conn, broadcast, sub)
+}
+
+// canWatch reports whether principal may watch a session. Once users exist,
+// the session's owner and administrators may, and so may everyone the
+// session's recording is shared with, directly or through a document.
+func canWatch(ctx context.Context, principal auth.Principal, broadcast *sessionBroadcast) bool {
+	if principal.IsAnonymous() || principal.Admin || principal.ID == broadcast.owner {
+		return true
+	}
+	if broadcast.recordingID == 0 || principal.UserID == 0 {
+		return false
+	}
+
+	role, err := database.ResourceRole(database.ResourceRecording, broadcast.recordingID, database.OwnedBy(principal.UserID))
+	if err != nil {
+		zerolog.Ctx(ctx).Error().Err(err).Int64("recording_id", broadcast.recordingID).Msg("Failed to check access to session")
+		return false
+	}
+	return role.CanView()
+}
+
+// watchReadPump discards anything the watcher sends and detects disconnects
+func (h *TranscribeHub) watchReadPump(conn *websocket.Conn, broadcast *sessionBroadcast, sub *watcher) {
+	defer func() {
+		broadcast.unsubscribe(sub)
+		conn.Close()
+		h.releaseWatcher()
+		activeConnections.WithLabelValues("watch").Dec()
+	}()
+
+	conn.SetReadLimit(512)
+	conn.SetReadDeadline(time.Now().Add(h.options.ReadTimeout))
+	conn.SetPongHandler(func(string) error {
+		conn.SetReadDeadline(time.Now().Add(h.options.ReadTimeout))
+		return nil
+	})
+
+	for {
+		if _, _, err := conn.ReadMessage(); err != nil {
+			return
+		}
+	}
+}
+
+// watchWritePump delivers a watcher's queued messages
+func (h *TranscribeHub) watchWritePump(conn *websocket.Conn, sub *watcher) {
+	defer conn.Close()
+
+	ticker := time.NewTicker(h.options.pingPeriod())
+	defer ticker.Stop()
+
+	// Stops the reader of the queue when the connection goes away first
+	stop := make(chan struct{})
+	defer close(stop)
+
+	messages := make(chan []byte)
+	go func() {
+		defer close(messages)
+		for {
+			data, ok := sub.next()
+			if !ok {
+				return
+			}
+			select {
+			case messages <- data:
+			case <-stop:
+				return
+			case <-h.ctx.Done():
+				return
+			}
+		}
+	}()
+
+	for {
+		select {
+		case <-h.ctx.Done():
+			return
+
+		case data, ok := <-messages:
+			conn.SetWriteDeadline(time.Now().Add(h.options.WriteTimeout))
+			if !ok {
+				conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, "session ended"))
+				return
+			}
+			if err := conn.WriteMessage(websocket.TextMessage, data); err != nil {
+				return
+			}
+
+		case <-ticker.C:
+			conn.SetWriteDeadline(time.Now().Add(h.options.WriteTimeout))
+			if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
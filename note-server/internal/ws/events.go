@@ -0,0 +1,290 @@
+package ws
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/rs/zerolog"
+	"github.com/your-org/note-server/internal/auth"
+	"github.com/your-org/note-server/internal/events"
+	"github.com/your-org/note-server/pkg/response"
+)
+
+// Event channel message types
+const (
+	MessageTypeSubscribe   = "subscribe"
+	MessageTypeUnsubscribe = "unsubscribe"
+	MessageTypeSubscribed  = "subscribed"
+	MessageTypeEvent       = "event"
+)
+
+// Replies queued for an event client before its reads are paused
+const eventReplyBuffer = 16
+
+// EventControlMessage is a message sent by clients of the /ws event channel
+type EventControlMessage struct {
+	Type   string   `json:"type"`
+	Topics []string `json:"topics,omitempty"`
+}
+
+// EventMessage is a message sent to clients of the /ws event channel
+type EventMessage struct {
+	Type string `json:"type"`
+
+	// Current subscriptions, set on "subscribed"
+	Topics []string `json:"topics,omitempty"`
+
+	// Set on "event"
+	ID    int64     `json:"id,omitempty"`
+	Topic string    `json:"topic,omitempty"`
+	Time  time.Time `json:"time,omitzero"`
+	Data  any       `json:"data,omitempty"`
+
+	// Set on "error"
+	Text string `json:"text,omitempty"`
+}
+
+// ServeEventsWS handles the /ws event channel. Clients subscribe to topics and
+// receive server events as they are published:
+//
+//	-> { "type": "subscribe", "topics": ["recording.created", "job.*"] }
+//	<- { "type": "subscribed", "topics": ["job.*", "recording.created"] }
+//	<- { "type": "event", "id": 7, "topic": "job.progress", "time": "...", "data": { ... } }
+//	-> { "type": "unsubscribe", "topics": ["job.*"] }
+//	-> { "type": "ping" }
+//
+// Initial subscriptions can also be given as ?topics=a,b. Topics are listed in
+// package events; "*" subscribes to everything and "prefix.*" to a family of
+// topics. Events about a user's recordings and jobs only reach that user and
+// administrators. A client that falls behind misses events rather than holding
+// up others.
+func (h *TranscribeHub) ServeEventsWS(w http.ResponseWriter, r *http.Request) {
+	principal, ok := h.authenticate(w, r, auth.ScopeRead)
+	if !ok {
+		return
+	}
+
+	var initial []string
+	if topics := r.URL.Query().Get("topics"); topics != "" {
+		initial = strings.Split(topics, ",")
+		if err := validatePatterns(initial); err != nil {
+			response.Error(w, r, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	if !h.reserveConnection() {
+		h.logRejected(r)
+		w.Header().Set("Retry-After", "5")
+		response.Error(w, r, http.StatusServiceUnavailable, "Too many connections")
+		return
+	}
+
+	conn, err := h.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		h.releaseConnection()
+		zerolog.Ctx(r.Context()).Warn().Err(err).Msg("WebSocket upgrade failed")
+		return
+	}
+
+	client := &eventClient{
+		hub:     h,
+		conn:    conn,
+		sub:     h.options.Events.SubscribeAs(eventAudience(principal), initial...),
+		replies: make(chan []byte, eventReplyBuffer),
+	}
+	client.ctx, client.cancel = context.WithCancel(clientContext(h.ctx, r, principal))
+
+	activeConnections.WithLabelValues("events").Inc()
+	zerolog.Ctx(client.ctx).Info().Msg("Event client connected")
+
+	if len(initial) > 0 {
+		client.reply(EventMessage{Type: MessageTypeSubscribed, Topics: client.topics()})
+	}
+
+	go client.writePump()
+	go client.readPump()
+}
+
+// eventAudience returns the events a principal may receive. Administrators,
+// and the anonymous caller of a server without accounts, receive all of them.
+func eventAudience(principal auth.Principal) events.Audience {
+	if principal.Admin || principal.IsAnonymous() {
+		return events.AllUsers
+	}
+	return events.ForUser(principal.UserID)
+}
+
+// eventClient is a connection to the /ws event channel
+type eventClient struct {
+	hub     *TranscribeHub
+	conn    *websocket.Conn
+	sub     *events.Subscription
+	replies chan []byte
+
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// readPump handles subscription changes until the connection closes
+func (c *eventClient) readPump() {
+	defer c.cancel()
+
+	options := c.hub.options
+	c.conn.SetReadLimit(4096)
+	c.conn.SetReadDeadline(time.Now().Add(options.ReadTimeout))
+	c.conn.SetPongHandler(func(string) error {
+		c.conn.SetReadDeadline(time.Now().Add(options.ReadTimeout))
+		return nil
+	})
+
+	for {
+		messageType, data, err := c.conn.ReadMessage()
+		if err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
+				zerolog.Ctx(c.ctx).Warn().Err(err).Msg("WebSocket error")
+			}
+			return
+		}
+		c.conn.SetReadDeadline(time.Now().Add(options.ReadTimeout))
+
+		if messageType != websocket.TextMessage {
+			c.reply(EventMessage{Type: MessageTypeError, Text: "Binary messages are not supported"})
+			continue
+		}
+
+		var msg EventControlMessage
+		if err := json.Unmarshal(data, &msg); err != nil {
+			c.reply(EventMessage{Type: MessageTypeError, Text: "Invalid control message"})
+			continue
+		}
+
+		switch msg.Type {
+		case MessageTypeSubscribe, MessageTypeUnsubscribe:
+			if err := validatePatterns(msg.Topics); err != nil {
+				c.reply(EventMessage{Type: MessageTypeError, Text: err.Error()})
+				continue
+			}
+			if msg.Type == MessageTypeSubscribe {
+				c.sub.Add(msg.Topics...)
+			} else {
+				c.sub.Remove(msg.Topics...)
+			}
+			c.reply(EventMessage{Type: MessageTypeSubscribed, Topics: c.topics()})
+
+		case MessageTypePing:
+			c.reply(EventMessage{Type: MessageTypePong})
+
+		default:
+			c.reply(EventMessage{Type: MessageTypeError, Text: fmt.Sprintf("Unknown message type %q", msg.Type)})
+		}
+	}
+}
+
+// writePump delivers events and replies; it is the connection's only writer
+// and releases the connection when done
+func (c *eventClient) writePump() {
+	options := c.hub.options
+	ticker := time.NewTicker(options.pingPeriod())
+
+	defer func() {
+		ticker.Stop()
+		c.sub.Close()
+		c.conn.Close()
+		c.hub.releaseConnection()
+		activeConnections.WithLabelValues("events").Dec()
+	}()
+
+	for {
+		select {
+		case <-c.ctx.Done():
+			closeMessage := websocket.FormatCloseMessage(websocket.CloseNormalClosure, "")
+			if c.hub.ctx.Err() != nil {
+				closeMessage = websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutting down")
+			}
+			c.conn.WriteControl(websocket.CloseMessage, closeMessage, time.Now().Add(options.WriteTimeout))
+			return
+
+		case event := <-c.sub.C:
+			if !c.write(EventMessage{
+				Type:  MessageTypeEvent,
+				ID:    event.ID,
+				Topic: event.Topic,
+				Time:  event.Time,
+				Data:  event.Data,
+			}) {
+				return
+			}
+
+		case reply := <-c.replies:
+			c.conn.SetWriteDeadline(time.Now().Add(options.WriteTimeout))
+			if err := c.conn.WriteMessage(websocket.TextMessage, reply); err != nil {
+				c.cancel()
+				return
+			}
+
+		case <-ticker.C:
+			c.conn.SetWriteDeadline(time.Now().Add(options.WriteTimeout))
+			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				c.cancel()
+				return
+			}
+		}
+	}
+}
+
+// write sends a message, cancelling the client on failure
+func (c *eventClient) write(msg EventMessage) bool {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		zerolog.Ctx(c.ctx).Error().Err(err).Msg("Failed to marshal event message")
+		return true
+	}
+
+	c.conn.SetWriteDeadline(time.Now().Add(c.hub.options.WriteTimeout))
+	if err := c.conn.WriteMessage(websocket.TextMessage, data); err != nil {
+		c.cancel()
+		return false
+	}
+	return true
+}
+
+// reply queues a response to a control message for writePump
+func (c *eventClient) reply(msg EventMessage) {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		zerolog.Ctx(c.ctx).Error().Err(err).Msg("Failed to marshal event message")
+		return
+	}
+
+	select {
+	case c.replies <- data:
+	case <-c.ctx.Done():
+	}
+}
+
+// topics returns the client's subscriptions in a stable order
+func (c *eventClient) topics() []string {
+	topics := c.sub.Patterns()
+	sort.Strings(topics)
+	return topics
+}
+
+// validatePatterns checks that every topic pattern can match a published topic
+func validatePatterns(patterns []string) error {
+	if len(patterns) == 0 {
+		return fmt.Errorf("no topics given")
+	}
+	for _, pattern := range patterns {
+		if !events.ValidPattern(pattern) {
+			return fmt.Errorf("unknown topic %q", pattern)
+		}
+	}
+	return nil
+}
@@ -1,19 +1,82 @@
-// Package ws provides WebSocket functionality for real-time audio transcription.
+// Package ws provides WebSocket functionality for real-time audio transcription
+// and the /ws server event channel (see ServeEventsWS).
 //
 // WebSocket Transcription Endpoint: /ws/transcribe
 //
-// Usage:
-//   1. Connect to ws://localhost:8080/ws/transcribe
-//   2. Send binary audio chunks (opus/pcm format) via WebSocket binary messages
-//   3. Receive JSON messages with transcription results:
-//      - { "type": "partial", "text": "..." } - Incremental transcription
-//      - { "type": "final", "text": "..." } - Final transcription result
-//      - { "type": "error", "text": "..." } - Error message
+// Protocol:
+//  1. Connect to ws://localhost:8080/api/v1/ws/transcribe
+//  2. Send a JSON "start" text message describing the audio:
+//     { "type": "start", "sample_rate": 16000, "channels": 1, "encoding": "pcm16", "language": "en", "provider": "openai" }
+//     encoding is one of "pcm16" (little-endian, default), "opus" or "webm". All fields are optional;
+//     the defaults are 16kHz mono pcm16 with the server's default provider.
+//  3. The server acknowledges with the session ID and the effective configuration:
+//     { "type": "started", "seq": 1, "session_id": "...", "config": { ... } }
+//  4. Send audio as WebSocket binary messages. Each pcm16 message becomes one segment.
+//     opus and webm streams are decoded with ffmpeg and transcribed in segments of
+//     5s; a "start" for them is refused when the server has no ffmpeg.
+//  5. Receive sequence-numbered results:
+//     - { "type": "partial", "seq": 2, "session_id": "...", "text": "...", "segment": 0, "start_ms": 0, "end_ms": 500 }
+//     - { "type": "final", "seq": 3, "session_id": "...", "text": "...", "segment": 0, "start_ms": 0, "end_ms": 500 }
+//     - { "type": "error", "seq": 4, "session_id": "...", "text": "..." }
+//  6. Control the session with text messages:
+//     - { "type": "pause" }  - audio is discarded until resumed; replies "paused"
+//     - { "type": "resume" } - replies "resumed"
+//     - { "type": "ping" }   - replies "pong"
+//     - { "type": "stop" }   - flushes pending results, then replies "stopped"
+//     After "stopped" a new "start" begins a new session on the same connection.
+//
+// A "start" with "vad": true (opus, webm or mono pcm16) enables server-side voice
+// activity detection. Instead of fixed segments, the stream is cut into
+// speech segments, silence is never transcribed, and speech boundaries are reported:
+//   - { "type": "speech_start", "segment": 1, "start_ms": 3800 }
+//   - { "type": "speech_end", "segment": 1, "start_ms": 3800, "end_ms": 5400 }
+//
+// While a speech segment is open it is transcribed again every 2s of audio, and
+// those results arrive as "partial"s for the segment before its "final".
+//
+// When the hub has a recordings directory, a "start" with "record": true tees the
+// session's audio into media storage. On "stop" (or disconnect) it is saved as a
+// recording with its final transcript segments, and "stopped" carries the
+// "recording_id". The recording is also announced on the event channel as
+// recording.created and, when it has a transcript, transcript.ready. pcm16 audio is stored as WAV; opus and webm are stored as received.
+//
+// Sequence numbers start at 1 for every session and increase by one per message, so
+// clients can detect gaps. Segment offsets are measured in the decoded audio. Clients
+// that send audio without "start" get a session with the default configuration.
+//
+// Connections are authenticated during the handshake with an "Authorization: Bearer"
+// header, a "bearer.<token>" subprotocol offered alongside "note.v1", or a single-use
+// "?ticket=" from POST /api/ws-ticket (see package auth). When authentication is
+// required, handshakes without valid credentials get HTTP 401. Personal API tokens
+// need the "transcribe" scope here and "read" for the event channel and watching,
+// or get HTTP 403. Sessions are attributed to the authenticated user, which
+// "started" reports as "user".
+//
+// When the hub has a Meter, clients that used up their transcription quota get
+// HTTP 429 with Retry-After, or an "error" in reply to "start" on an open
+// connection. The audio a session transcribes counts against the quota while it
+// streams: once the quota is used up the session gets an "error" and is stopped.
+//
+// Other clients can follow a live session read-only at /ws/transcribe/{session}/watch.
+// Watchers first receive the session's messages so far (up to a bounded backlog),
+// then its messages as they happen, without "pong" or client-specific errors. The
+// connection closes when the session stops. A watcher that falls too far behind is
+// disconnected rather than slowing down the session or other watchers. Watchers
+// count against the connection limit. Once users exist, a session can be watched
+// by its owner, administrators, and users its recording is shared with; recorded
+// sessions report the recording's "recording_id" on "started" so it can be shared
+// while the session is live.
+//
+// Segments are transcribed in the order they were sent, and results for a segment
+// always arrive before results for the next one.
 //
 // Features:
-//   - Concurrent connection limit (100 max)
+//   - Configurable concurrent connection limit (HTTP 503 when full)
+//   - Daily and monthly transcription quotas (HTTP 429 when used up)
+//   - Origin allow-list and handshake authentication
 //   - Context-based cancellation
-//   - Goroutine-based message processing
+//   - Ordered streaming transcription with backpressure
+//   - Read-only fan-out to watchers of a live session
 //   - Automatic ping/pong for connection health
 //   - Graceful shutdown support
 package ws
@@ -21,52 +84,80 @@ package ws
 import (
 	"context"
 	"encoding/json"
-	"log"
+	"errors"
+	"fmt"
 	"net/http"
+	"net/url"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/gorilla/websocket"
+	"github.com/rs/zerolog"
+	"github.com/your-org/note-server/internal/auth"
+	"github.com/your-org/note-server/internal/events"
+	"github.com/your-org/note-server/internal/metrics"
+	"github.com/your-org/note-server/internal/ratelimit"
 	"github.com/your-org/note-server/internal/service"
+	"github.com/your-org/note-server/pkg/response"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// Defaults for HubOptions left unset
 const (
 	// Time allowed to write a message to the peer
-	writeWait = 10 * time.Second
+	defaultWriteTimeout = 10 * time.Second
 
 	// Time allowed to read the next pong message from the peer
-	pongWait = 60 * time.Second
-
-	// Send pings to peer with this period. Must be less than pongWait
-	pingPeriod = (pongWait * 9) / 10
+	defaultReadTimeout = 60 * time.Second
 
 	// Maximum message size allowed from peer
-	maxMessageSize = 1024 * 1024 // 1MB for audio chunks
+	defaultMaxMessageSize = 1024 * 1024 // 1MB for audio chunks
 
 	// Maximum number of concurrent connections
-	maxConnections = 100
+	defaultMaxConnections = 100
+
+	defaultBufferSize = 4096
 )
 
-var upgrader = websocket.Upgrader{
-	ReadBufferSize:  4096,
-	WriteBufferSize: 4096,
-	CheckOrigin: func(r *http.Request) bool {
-		return true // Allow all origins for now
-	},
-}
+const (
+	// Audio chunks queued for transcription before reads from the peer are paused
+	maxPendingChunks = 16
 
-// TranscribeMessage represents messages sent to/from the transcription WebSocket
-type TranscribeMessage struct {
-	Type string `json:"type"`
-	Text string `json:"text,omitempty"`
-}
+	// Outgoing messages queued per client before it is disconnected as too slow
+	clientSendBuffer = 256
+
+	// Time a closing client's session is given to wind down before its
+	// recording is saved without the results still in flight
+	sessionDrainTimeout = 10 * time.Second
+)
 
-// TranscribeHub manages WebSocket connections for audio transcription
+// How often a live session records the audio it transcribed and checks the
+// client's quota. Replaced in tests.
+var quotaCheckInterval = 10 * time.Second
+
+// activeConnections counts open WebSocket connections by endpoint:
+// "transcribe", "events" or "watch"
+var activeConnections = metrics.NewGaugeVec(
+	"note_ws_connections",
+	"Open WebSocket connections, by endpoint.",
+	"endpoint",
+)
+
+// TranscribeHub manages WebSocket connections for audio transcription.
+// Run owns the client set: clients are added and removed only there, and
+// connections are only ever torn down by cancelling their context.
 type TranscribeHub struct {
 	// Registered clients
 	clients map[*TranscribeClient]bool
 	mutex   sync.RWMutex
 
+	// Connection slots taken by upgrades that have not registered yet
+	reserved int
+
+	// Connection slots held by session watchers
+	watchers int
+
 	// Register requests from clients
 	register chan *TranscribeClient
 
@@ -76,6 +167,13 @@ type TranscribeHub struct {
 	// Transcription service
 	transcribeService *service.TranscribeService
 
+	options  HubOptions
+	upgrader websocket.Upgrader
+
+	// Live sessions that can be watched, by session ID
+	sessions      map[string]*sessionBroadcast
+	sessionsMutex sync.RWMutex
+
 	// Context for graceful shutdown
 	ctx    context.Context
 	cancel context.CancelFunc
@@ -88,98 +186,390 @@ type TranscribeClient struct {
 	send   chan []byte
 	ctx    context.Context
 	cancel context.CancelFunc
+
+	// Authenticated user the client's sessions are attributed to
+	principal auth.Principal
+
+	// Client the transcription quota applies to; see ratelimit.ClientKey
+	quotaKey string
+
+	// Close frame sent when the client is terminated; set once by closeWith
+	closeOnce    sync.Once
+	closeMessage []byte
+
+	// Current transcription session, only touched by readPump
+	session *transcribeSession
+
+	// Sequence numbering of outgoing messages for the current session
+	seqMutex  sync.Mutex
+	seq       int64
+	sessionID string
+}
+
+// HubOptions configures a TranscribeHub. Zero values take the defaults.
+type HubOptions struct {
+	// Directory live sessions are saved to when a client asks for recording.
+	// Recording is disabled when empty.
+	RecordingsDir string
+
+	// Maximum number of concurrent transcription and watch connections
+	MaxConnections int
+
+	// Buffer sizes for the WebSocket upgrader
+	ReadBufferSize  int
+	WriteBufferSize int
+
+	// Maximum size of a message from the peer
+	MaxMessageSize int64
+
+	// Time allowed to write a message, and to hear from the peer before the
+	// connection is considered dead. Pings are sent at 90% of ReadTimeout.
+	WriteTimeout time.Duration
+	ReadTimeout  time.Duration
+
+	// Origins allowed to connect from a browser, e.g. "http://localhost:3000".
+	// "*" allows any origin and must be set explicitly. Same-origin requests and
+	// clients that send no Origin header are always allowed.
+	AllowedOrigins []string
+
+	// Authenticates clients during the handshake. Defaults to an
+	// authenticator that lets anonymous clients in.
+	Authenticator *auth.Authenticator
+
+	// Bus served on the /ws event channel, which saved live sessions are
+	// also announced on. Defaults to a private bus.
+	Events *events.Bus
+
+	// Enforces transcription quotas on live sessions and records the audio
+	// they transcribe. Nil enforces and records nothing.
+	Meter *ratelimit.Meter
+}
+
+// withDefaults fills in unset options
+func (o HubOptions) withDefaults() HubOptions {
+	if o.MaxConnections <= 0 {
+		o.MaxConnections = defaultMaxConnections
+	}
+	if o.ReadBufferSize <= 0 {
+		o.ReadBufferSize = defaultBufferSize
+	}
+	if o.WriteBufferSize <= 0 {
+		o.WriteBufferSize = defaultBufferSize
+	}
+	if o.MaxMessageSize <= 0 {
+		o.MaxMessageSize = defaultMaxMessageSize
+	}
+	if o.WriteTimeout <= 0 {
+		o.WriteTimeout = defaultWriteTimeout
+	}
+	if o.ReadTimeout <= 0 {
+		o.ReadTimeout = defaultReadTimeout
+	}
+	if o.Authenticator == nil {
+		o.Authenticator = auth.New(auth.Options{})
+	}
+	if o.Events == nil {
+		o.Events = events.NewBus()
+	}
+	return o
+}
+
+// pingPeriod returns how often peers are pinged. Must be less than ReadTimeout.
+func (o HubOptions) pingPeriod() time.Duration {
+	return (o.ReadTimeout * 9) / 10
 }
 
 // NewTranscribeHub creates a new transcription WebSocket hub
 func NewTranscribeHub(transcribeService *service.TranscribeService) *TranscribeHub {
+	return NewTranscribeHubWithOptions(transcribeService, HubOptions{})
+}
+
+// NewTranscribeHubWithOptions creates a new transcription WebSocket hub with optional features enabled
+func NewTranscribeHubWithOptions(transcribeService *service.TranscribeService, options HubOptions) *TranscribeHub {
 	ctx, cancel := context.WithCancel(context.Background())
-	return &TranscribeHub{
+	options = options.withDefaults()
+
+	h := &TranscribeHub{
 		clients:           make(map[*TranscribeClient]bool),
 		register:          make(chan *TranscribeClient),
 		unregister:        make(chan *TranscribeClient),
 		transcribeService: transcribeService,
+		options:           options,
+		sessions:          make(map[string]*sessionBroadcast),
 		ctx:               ctx,
 		cancel:            cancel,
 	}
+	h.upgrader = websocket.Upgrader{
+		ReadBufferSize:  options.ReadBufferSize,
+		WriteBufferSize: options.WriteBufferSize,
+		CheckOrigin:     h.checkOrigin,
+		Subprotocols:    []string{auth.Subprotocol},
+		Error:           handshakeError,
+	}
+	return h
+}
+
+// handshakeError writes a failed upgrade, such as a disallowed origin, in the
+// same envelope as every other API error
+func handshakeError(w http.ResponseWriter, r *http.Request, status int, reason error) {
+	w.Header().Set("Sec-Websocket-Version", "13")
+	response.Error(w, r, status, reason.Error())
+}
+
+// checkOrigin allows same-origin requests, requests without an Origin header
+// and origins on the allow-list
+func (h *TranscribeHub) checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	if strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+
+	for _, allowed := range h.options.AllowedOrigins {
+		if allowed == "*" || strings.EqualFold(strings.TrimSuffix(allowed, "/"), origin) {
+			return true
+		}
+	}
+	return false
 }
 
-// Run starts the transcription hub
+// Run starts the transcription hub. It is the only place clients are added to
+// or removed from the hub.
 func (h *TranscribeHub) Run() {
 	for {
 		select {
 		case <-h.ctx.Done():
+			// Client contexts derive from the hub's, so every client is already terminating
+			h.mutex.Lock()
+			for client := range h.clients {
+				delete(h.clients, client)
+				activeConnections.WithLabelValues("transcribe").Dec()
+			}
+			h.mutex.Unlock()
 			return
 
 		case client := <-h.register:
 			h.mutex.Lock()
-			if len(h.clients) >= maxConnections {
-				h.mutex.Unlock()
-				client.conn.Close()
-				log.Printf("Connection rejected: maximum connections (%d) reached", maxConnections)
-				continue
-			}
+			h.reserved--
 			h.clients[client] = true
+			count := len(h.clients)
 			h.mutex.Unlock()
-			log.Printf("Transcription client connected. Active connections: %d", len(h.clients))
+			activeConnections.WithLabelValues("transcribe").Inc()
+			zerolog.Ctx(client.ctx).Info().Int("connections", count).Msg("Transcription client connected")
 
 		case client := <-h.unregister:
 			h.mutex.Lock()
 			if _, ok := h.clients[client]; ok {
 				delete(h.clients, client)
-				close(client.send)
-				client.cancel()
-				log.Printf("Transcription client disconnected. Active connections: %d", len(h.clients))
+				activeConnections.WithLabelValues("transcribe").Dec()
+				zerolog.Ctx(client.ctx).Info().Int("connections", len(h.clients)).Msg("Transcription client disconnected")
 			}
 			h.mutex.Unlock()
+			client.cancel()
 		}
 	}
 }
 
-// Shutdown gracefully shuts down the hub
+// Shutdown gracefully shuts down the hub. Clients are sent a "going away"
+// close frame and disconnected.
 func (h *TranscribeHub) Shutdown() {
 	h.cancel()
+}
+
+// authenticate identifies the client during the handshake, writing a 401 and
+// returning false when its credentials are missing or invalid, or a 403 when
+// an API token hasn't been granted scope
+func (h *TranscribeHub) authenticate(w http.ResponseWriter, r *http.Request, scope auth.Scope) (auth.Principal, bool) {
+	principal, err := h.options.Authenticator.Authenticate(r)
+	if err != nil {
+		zerolog.Ctx(r.Context()).Warn().Err(err).Str("remote_ip", r.RemoteAddr).Msg("WebSocket authentication failed")
+		w.Header().Set("WWW-Authenticate", `Bearer realm="note"`)
+		response.Error(w, r, http.StatusUnauthorized, err.Error())
+		return auth.Principal{}, false
+	}
+	if !principal.Can(scope) {
+		response.Error(w, r, http.StatusForbidden, fmt.Sprintf("Token lacks the %s scope", scope))
+		return auth.Principal{}, false
+	}
+	return principal, true
+}
+
+// clientContext returns a context for a connection that ends with parent and
+// carries the handshake request's logger, with the user added, and trace, so
+// the session's spans join the trace of the upgrade request
+func clientContext(parent context.Context, r *http.Request, principal auth.Principal) context.Context {
+	logger := zerolog.Ctx(r.Context()).With().Str("user", principal.ID).Logger()
+	ctx := trace.ContextWithSpanContext(parent, trace.SpanContextFromContext(r.Context()))
+	return logger.WithContext(ctx)
+}
+
+// logRejected logs a connection turned away because the hub is full
+func (h *TranscribeHub) logRejected(r *http.Request) {
+	zerolog.Ctx(r.Context()).Warn().
+		Int("max_connections", h.options.MaxConnections).
+		Msg("Connection rejected: maximum connections reached")
+}
+
+// reserveConnection takes a connection slot, failing when the hub is full or shutting down
+func (h *TranscribeHub) reserveConnection() bool {
 	h.mutex.Lock()
 	defer h.mutex.Unlock()
-	for client := range h.clients {
-		client.conn.Close()
+
+	if h.ctx.Err() != nil || h.usedLocked() >= h.options.MaxConnections {
+		return false
 	}
+	h.reserved++
+	return true
+}
+
+// reserveWatcher takes a connection slot for a session watcher, held until
+// releaseWatcher. It returns false if none is free.
+func (h *TranscribeHub) reserveWatcher() bool {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if h.ctx.Err() != nil || h.usedLocked() >= h.options.MaxConnections {
+		return false
+	}
+	h.watchers++
+	return true
+}
+
+// releaseWatcher gives back a watcher's connection slot
+func (h *TranscribeHub) releaseWatcher() {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.watchers--
+}
+
+// usedLocked returns the number of connection slots in use. h.mutex must be held.
+func (h *TranscribeHub) usedLocked() int {
+	return len(h.clients) + h.reserved + h.watchers
+}
+
+// Capacity reports how many connection slots are in use, including those of
+// watchers and those reserved by connections still being set up, and how many
+// there are
+func (h *TranscribeHub) Capacity() (used, max int) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	return h.usedLocked(), h.options.MaxConnections
+}
+
+// releaseConnection gives back a slot reserved by a connection that never registered
+func (h *TranscribeHub) releaseConnection() {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.reserved--
 }
 
 // ServeTranscribeWS handles WebSocket connection requests for transcription
 func (h *TranscribeHub) ServeTranscribeWS(w http.ResponseWriter, r *http.Request) {
-	conn, err := upgrader.Upgrade(w, r, nil)
+	principal, ok := h.authenticate(w, r, auth.ScopeTranscribe)
+	if !ok {
+		return
+	}
+
+	// Clients that used up their quota are turned away before taking a slot
+	quotaKey := ratelimit.ClientKey(principal, r.RemoteAddr)
+	if err := h.options.Meter.Check(r.Context(), quotaKey, ratelimit.TranscriptionMinutes); err != nil {
+		var quotaErr *ratelimit.QuotaError
+		if errors.As(err, &quotaErr) {
+			ratelimit.RejectQuota(w, r, quotaErr)
+			return
+		}
+		zerolog.Ctx(r.Context()).Error().Err(err).Msg("Failed to check transcription quota")
+		response.Error(w, r, http.StatusInternalServerError, "Failed to check usage")
+		return
+	}
+
+	// Check the limit before upgrading so rejected clients get a proper HTTP response
+	if !h.reserveConnection() {
+		h.logRejected(r)
+		w.Header().Set("Retry-After", "5")
+		response.Error(w, r, http.StatusServiceUnavailable, "Too many connections")
+		return
+	}
+
+	conn, err := h.upgrader.Upgrade(w, r, nil)
 	if err != nil {
-		log.Printf("WebSocket upgrade failed: %v", err)
+		h.releaseConnection()
+		zerolog.Ctx(r.Context()).Warn().Err(err).Msg("WebSocket upgrade failed")
 		return
 	}
 
-	ctx, cancel := context.WithCancel(h.ctx)
+	// The client's context carries its logger
+	ctx, cancel := context.WithCancel(clientContext(h.ctx, r, principal))
 	client := &TranscribeClient{
-		hub:    h,
-		conn:   conn,
-		send:   make(chan []byte, 256),
-		ctx:    ctx,
-		cancel: cancel,
+		hub:       h,
+		conn:      conn,
+		send:      make(chan []byte, clientSendBuffer),
+		ctx:       ctx,
+		cancel:    cancel,
+		principal: principal,
+		quotaKey:  quotaKey,
 	}
 
-	h.register <- client
+	select {
+	case h.register <- client:
+	case <-h.ctx.Done():
+		h.releaseConnection()
+		cancel()
+		conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutting down"), time.Now().Add(h.options.WriteTimeout))
+		conn.Close()
+		return
+	}
 
 	// Start client goroutines
 	go client.writePump()
 	go client.readPump()
 }
 
-// readPump handles incoming audio data from the client
+// closeWith terminates the client, sending the given close frame. Only the
+// first call has an effect.
+func (c *TranscribeClient) closeWith(code int, text string) {
+	c.closeOnce.Do(func() {
+		c.closeMessage = websocket.FormatCloseMessage(code, text)
+		c.cancel()
+	})
+}
+
+// abort terminates the client without a close frame, for when the connection
+// can no longer be written to. Only the first call to abort or closeWith has
+// an effect.
+func (c *TranscribeClient) abort() {
+	c.closeOnce.Do(c.cancel)
+}
+
+// readPump handles incoming control messages and audio data from the client
 func (c *TranscribeClient) readPump() {
 	defer func() {
-		c.hub.unregister <- c
-		c.conn.Close()
+		// readPump is the only sender on the session's audio channel, so it owns closing it
+		if c.session != nil {
+			c.abandonSession()
+		}
+		c.closeWith(websocket.CloseNormalClosure, "")
+		select {
+		case c.hub.unregister <- c:
+		case <-c.hub.ctx.Done():
+		}
 	}()
 
-	c.conn.SetReadLimit(maxMessageSize)
-	c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	options := c.hub.options
+	c.conn.SetReadLimit(options.MaxMessageSize)
+	c.conn.SetReadDeadline(time.Now().Add(options.ReadTimeout))
 	c.conn.SetPongHandler(func(string) error {
-		c.conn.SetReadDeadline(time.Now().Add(pongWait))
+		c.conn.SetReadDeadline(time.Now().Add(options.ReadTimeout))
 		return nil
 	})
 
@@ -190,112 +580,110 @@ func (c *TranscribeClient) readPump() {
 		default:
 		}
 
-		messageType, audioData, err := c.conn.ReadMessage()
+		messageType, data, err := c.conn.ReadMessage()
 		if err != nil {
 			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
-				log.Printf("WebSocket error: %v", err)
+				zerolog.Ctx(c.ctx).Warn().Err(err).Msg("WebSocket error")
 			}
 			break
 		}
 
-		// Only process binary messages (audio data)
-		if messageType != websocket.BinaryMessage {
-			continue
+		switch messageType {
+		case websocket.TextMessage:
+			c.handleControl(data)
+
+		case websocket.BinaryMessage:
+			if len(data) == 0 {
+				continue
+			}
+			if !c.queueAudio(data) {
+				return
+			}
 		}
 
-		// Process audio chunk in a separate goroutine to avoid blocking
-		go c.processAudioChunk(audioData)
+		// Time spent waiting on the transcriber shouldn't count against the peer
+		c.conn.SetReadDeadline(time.Now().Add(options.ReadTimeout))
 	}
 }
 
-// processAudioChunk processes an audio chunk and sends transcription results
-func (c *TranscribeClient) processAudioChunk(audioData []byte) {
-	if len(audioData) == 0 {
-		return
-	}
-
-	// Create a context with timeout for the transcription
-	ctx, cancel := context.WithTimeout(c.ctx, 30*time.Second)
-	defer cancel()
-
-	// Call the transcription service (currently a stub)
-	// In a real implementation, this would stream to a transcription service
-	// and receive partial and final results
-	go func() {
-		// Simulate partial result after a short delay
-		time.Sleep(100 * time.Millisecond)
-		partialMsg := TranscribeMessage{
-			Type: "partial",
-			Text: "Processing audio...",
-		}
-		c.sendTranscribeMessage(partialMsg)
-
-		// Call the transcription service
-		text, err := c.hub.transcribeService.TranscribeAudio(ctx, audioData)
-		if err != nil {
-			log.Printf("Transcription error: %v", err)
-			errorMsg := TranscribeMessage{
-				Type: "error",
-				Text: "Transcription failed",
-			}
-			c.sendTranscribeMessage(errorMsg)
-			return
-		}
+// resetSeq restarts sequence numbering for a new session
+func (c *TranscribeClient) resetSeq(sessionID string) {
+	c.seqMutex.Lock()
+	defer c.seqMutex.Unlock()
 
-		// Send final result
-		finalMsg := TranscribeMessage{
-			Type: "final",
-			Text: text,
-		}
-		c.sendTranscribeMessage(finalMsg)
-	}()
+	c.seq = 0
+	c.sessionID = sessionID
 }
 
-// sendTranscribeMessage sends a transcription message to the client
+// sendTranscribeMessage stamps a message with the session ID and next sequence
+// number and queues it for the client
 func (c *TranscribeClient) sendTranscribeMessage(msg TranscribeMessage) {
+	// Hold the lock until the message is queued so sequence numbers match send order
+	c.seqMutex.Lock()
+	defer c.seqMutex.Unlock()
+
+	if c.sessionID != "" {
+		c.seq++
+		msg.Seq = c.seq
+		msg.SessionID = c.sessionID
+	}
+
 	data, err := json.Marshal(msg)
 	if err != nil {
-		log.Printf("Failed to marshal transcription message: %v", err)
+		zerolog.Ctx(c.ctx).Error().Err(err).Msg("Failed to marshal transcription message")
 		return
 	}
 
+	if c.sessionID != "" {
+		c.hub.publish(c.sessionID, msg.Type, data)
+	}
+
 	select {
 	case c.send <- data:
 	case <-c.ctx.Done():
 	default:
-		// Channel is full, close the connection
-		close(c.send)
-		c.hub.unregister <- c
+		// The client isn't keeping up; drop it rather than block the session
+		zerolog.Ctx(c.ctx).Warn().Int("queued", len(c.send)).Msg("Disconnecting slow transcription client")
+		c.closeWith(websocket.CloseTryAgainLater, "client too slow")
 	}
 }
 
-// writePump handles outgoing messages to the client
+// writePump handles outgoing messages to the client. The send channel is never
+// closed; the client is terminated by cancelling its context, after which
+// writePump sends the close frame and closes the connection.
 func (c *TranscribeClient) writePump() {
 	defer c.conn.Close()
 
-	ticker := time.NewTicker(pingPeriod)
+	options := c.hub.options
+	ticker := time.NewTicker(options.pingPeriod())
 	defer ticker.Stop()
 
 	for {
 		select {
 		case <-c.ctx.Done():
-			return
-
-		case message, ok := <-c.send:
-			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
-			if !ok {
-				c.conn.WriteMessage(websocket.CloseMessage, []byte{})
-				return
+			closeMessage := websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutting down")
+			if c.hub.ctx.Err() == nil {
+				// Terminated by closeWith, which set the close frame before
+				// cancelling, or by abort, which leaves none to send
+				closeMessage = c.closeMessage
 			}
+			if closeMessage != nil {
+				c.conn.WriteControl(websocket.CloseMessage, closeMessage, time.Now().Add(options.WriteTimeout))
+			}
+			return
 
+		case message := <-c.send:
+			c.conn.SetWriteDeadline(time.Now().Add(options.WriteTimeout))
 			if err := c.conn.WriteMessage(websocket.TextMessage, message); err != nil {
-				log.Printf("WriteMessage error: %v", err)
+				zerolog.Ctx(c.ctx).Warn().Err(err).Msg("WriteMessage error")
+				c.abort()
 				return
 			}
 
 		case <-ticker.C:
-			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			c.conn.SetWriteDeadline(time.Now().Add(options.WriteTimeout))
 			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				c.abort()
 				return
 			}
 		}
@@ -2,17 +2,43 @@ package ws
 
 import (
 	"context"
+	"encoding/binary"
 	"encoding/json"
+	"fmt"
+	"math"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog"
 	"nhooyr.io/websocket"
+	"github.com/your-org/note-server/internal/auth"
+	"github.com/your-org/note-server/internal/database"
+	"github.com/your-org/note-server/internal/events"
+	"github.com/your-org/note-server/internal/ratelimit"
 	"github.com/your-org/note-server/internal/service"
+	"github.com/your-org/note-server/pkg/response"
 )
 
+// expectErrorCode checks that a rejected handshake was answered in the API's
+// error envelope
+func expectErrorCode(t *testing.T, resp *http.Response, code response.Code) {
+	t.Helper()
+	var envelope response.Envelope
+	if err := json.NewDecoder(resp.Body).Decode(&envelope); err != nil {
+		t.Fatalf("Failed to decode error response: %v", err)
+	}
+	if envelope.Success || envelope.Code != code || envelope.Error == "" {
+		t.Errorf("Expected an error with code %q, got %+v", code, envelope)
+	}
+}
+
 // Mock Transcriber for WebSocket tests
 type MockTranscriber struct {
 	TranscribeAudioFunc  func(ctx context.Context, audioData []byte) (string, error)
@@ -40,6 +66,46 @@ func (m *MockTranscriber) TranscribeStream(ctx context.Context, audioChunk []byt
 	return resultChan, nil
 }
 
+// streamOf returns a TranscribeStreamFunc that emits a partial followed by a final
+// result built from text for every chunk
+func streamOf(text func(audioChunk []byte) string) func(ctx context.Context, audioChunk []byte) (<-chan service.TranscribeStreamResult, error) {
+	return func(ctx context.Context, audioChunk []byte) (<-chan service.TranscribeStreamResult, error) {
+		resultChan := make(chan service.TranscribeStreamResult, 2)
+		go func() {
+			defer close(resultChan)
+			resultChan <- service.TranscribeStreamResult{Type: "partial", Text: "partial: " + text(audioChunk)}
+			resultChan <- service.TranscribeStreamResult{Type: "final", Text: text(audioChunk)}
+		}()
+		return resultChan, nil
+	}
+}
+
+// writeControl sends a JSON control message
+func writeControl(t *testing.T, ctx context.Context, conn *websocket.Conn, msg ControlMessage) {
+	t.Helper()
+	data, err := json.Marshal(msg)
+	if err != nil {
+		t.Fatalf("Failed to marshal control message: %v", err)
+	}
+	if err := conn.Write(ctx, websocket.MessageText, data); err != nil {
+		t.Fatalf("Failed to send control message: %v", err)
+	}
+}
+
+// readMessage reads the next JSON message from the server
+func readMessage(t *testing.T, ctx context.Context, conn *websocket.Conn) TranscribeMessage {
+	t.Helper()
+	_, data, err := conn.Read(ctx)
+	if err != nil {
+		t.Fatalf("Failed to read message: %v", err)
+	}
+	var msg TranscribeMessage
+	if err := json.Unmarshal(data, &msg); err != nil {
+		t.Fatalf("Failed to unmarshal message: %v", err)
+	}
+	return msg
+}
+
 func TestTranscribeHub_NewTranscribeHub(t *testing.T) {
 	mockTranscriber := &MockTranscriber{}
 	transcribeService := service.NewTranscribeServiceWithTranscriber(mockTranscriber)
@@ -61,9 +127,9 @@ func TestTranscribeHub_NewTranscribeHub(t *testing.T) {
 
 func TestTranscribeHub_WebSocketConnection(t *testing.T) {
 	mockTranscriber := &MockTranscriber{
-		TranscribeAudioFunc: func(ctx context.Context, audioData []byte) (string, error) {
-			return "WebSocket test transcription", nil
-		},
+		TranscribeStreamFunc: streamOf(func([]byte) string {
+			return "WebSocket test transcription"
+		}),
 	}
 	transcribeService := service.NewTranscribeServiceWithTranscriber(mockTranscriber)
 	hub := NewTranscribeHub(transcribeService)
@@ -89,6 +155,12 @@ func TestTranscribeHub_WebSocketConnection(t *testing.T) {
 	}
 	defer conn.Close(websocket.StatusNormalClosure, "test completed")
 
+	// Start a session
+	writeControl(t, ctx, conn, ControlMessage{Type: MessageTypeStart, Encoding: "pcm16", SampleRate: 16000})
+	if started := readMessage(t, ctx, conn); started.Type != MessageTypeStarted || started.SessionID == "" {
+		t.Fatalf("Expected started acknowledgement with session ID, got %+v", started)
+	}
+
 	// Send binary audio data
 	audioData := []byte("fake audio data for testing")
 	err = conn.Write(ctx, websocket.MessageBinary, audioData)
@@ -189,16 +261,409 @@ func TestTranscribeHub_MultipleConnections(t *testing.T) {
 }
 
 func TestTranscribeHub_ConnectionLimit(t *testing.T) {
-	// This test would be complex to implement properly as it requires
-	// mocking the maxConnections limit. In a real scenario, you'd want
-	// to make maxConnections configurable for testing.
-	t.Skip("Connection limit test requires refactoring to make maxConnections configurable")
+	hub := NewTranscribeHubWithOptions(service.NewTranscribeServiceWithTranscriber(&MockTranscriber{}), HubOptions{
+		MaxConnections: 2,
+	})
+
+	go hub.Run()
+	defer hub.Shutdown()
+
+	server := httptest.NewServer(http.HandlerFunc(hub.ServeTranscribeWS))
+	defer server.Close()
+
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var connections []*websocket.Conn
+	for i := 0; i < 2; i++ {
+		conn, _, err := websocket.Dial(ctx, wsURL, nil)
+		if err != nil {
+			t.Fatalf("Failed to connect WebSocket %d: %v", i, err)
+		}
+		connections = append(connections, conn)
+	}
+
+	// The limit is checked before the upgrade, so the rejection is a plain HTTP response
+	_, resp, err := websocket.Dial(ctx, wsURL, nil)
+	if err == nil {
+		t.Fatal("Expected connection over the limit to be rejected")
+	}
+	if resp == nil || resp.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("Expected status %d, got %v", http.StatusServiceUnavailable, resp)
+	}
+	if resp.Header.Get("Retry-After") == "" {
+		t.Error("Expected Retry-After header on rejection")
+	}
+
+	// Closing a connection frees its slot
+	connections[0].Close(websocket.StatusNormalClosure, "test completed")
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		conn, _, err := websocket.Dial(ctx, wsURL, nil)
+		if err == nil {
+			conn.Close(websocket.StatusNormalClosure, "test completed")
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected a slot to free up after disconnect: %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	connections[1].Close(websocket.StatusNormalClosure, "test completed")
+}
+
+func TestTranscribeHub_Quota(t *testing.T) {
+	if err := database.InitDB(filepath.Join(t.TempDir(), "test.db")); err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	store := database.NewUsageStore()
+	meter := ratelimit.NewMeter(store, map[string]ratelimit.Quota{ratelimit.TranscriptionMinutes: {Daily: 1}})
+	hub := NewTranscribeHubWithOptions(service.NewTranscribeServiceWithTranscriber(&MockTranscriber{}), HubOptions{Meter: meter})
+
+	go hub.Run()
+	defer hub.Shutdown()
+
+	server := httptest.NewServer(http.HandlerFunc(hub.ServeTranscribeWS))
+	defer server.Close()
+
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	conn, _, err := websocket.Dial(ctx, wsURL, nil)
+	if err != nil {
+		t.Fatalf("Failed to connect to WebSocket: %v", err)
+	}
+	defer conn.Close(websocket.StatusNormalClosure, "test completed")
+
+	// A session's audio is recorded by the time it stops: 3s of 16kHz mono PCM16
+	writeControl(t, ctx, conn, ControlMessage{Type: MessageTypeStart})
+	readMessage(t, ctx, conn)
+	if err := conn.Write(ctx, websocket.MessageBinary, make([]byte, 96000)); err != nil {
+		t.Fatal(err)
+	}
+	writeControl(t, ctx, conn, ControlMessage{Type: MessageTypeStop})
+	for msg := readMessage(t, ctx, conn); msg.Type != MessageTypeStopped; msg = readMessage(t, ctx, conn) {
+	}
+
+	daily, _, err := store.Usage(context.Background(), "ip:127.0.0.1", ratelimit.TranscriptionMinutes, time.Now())
+	if err != nil || daily != 0.05 {
+		t.Fatalf("Expected 0.05 minutes recorded, got %v (%v)", daily, err)
+	}
+
+	// Once the quota is used up, new sessions are refused on the connection...
+	meter.Record(context.Background(), "ip:127.0.0.1", ratelimit.TranscriptionMinutes, 1)
+	writeControl(t, ctx, conn, ControlMessage{Type: MessageTypeStart})
+	if msg := readMessage(t, ctx, conn); msg.Type != MessageTypeError || !strings.Contains(msg.Text, "quota") {
+		t.Errorf("Expected a quota error, got %+v", msg)
+	}
+
+	// ...and new connections before the upgrade
+	_, resp, err := websocket.Dial(ctx, wsURL, nil)
+	if err == nil {
+		t.Fatal("Expected a client over quota to be rejected")
+	}
+	if resp == nil || resp.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("Expected status %d, got %v", http.StatusTooManyRequests, resp)
+	}
+	if resp.Header.Get("Retry-After") == "" {
+		t.Error("Expected Retry-After header on rejection")
+	}
+}
+
+func TestTranscribeHub_QuotaWhileStreaming(t *testing.T) {
+	if err := database.InitDB(filepath.Join(t.TempDir(), "test.db")); err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	store := database.NewUsageStore()
+	meter := ratelimit.NewMeter(store, map[string]ratelimit.Quota{ratelimit.TranscriptionMinutes: {Daily: 0.1}})
+	hub := NewTranscribeHubWithOptions(service.NewTranscribeServiceWithTranscriber(&MockTranscriber{}), HubOptions{Meter: meter})
+
+	// Meter on every chunk
+	interval := quotaCheckInterval
+	quotaCheckInterval = 0
+	defer func() { quotaCheckInterval = interval }()
+
+	go hub.Run()
+	defer hub.Shutdown()
+
+	server := httptest.NewServer(http.HandlerFunc(hub.ServeTranscribeWS))
+	defer server.Close()
+
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	conn, _, err := websocket.Dial(ctx, wsURL, nil)
+	if err != nil {
+		t.Fatalf("Failed to connect to WebSocket: %v", err)
+	}
+	defer conn.Close(websocket.StatusNormalClosure, "test completed")
+
+	// Two 3s chunks of 16kHz mono PCM16 use up the 6s quota while the session
+	// is still streaming
+	writeControl(t, ctx, conn, ControlMessage{Type: MessageTypeStart})
+	readMessage(t, ctx, conn)
+	for i := 0; i < 2; i++ {
+		if err := conn.Write(ctx, websocket.MessageBinary, make([]byte, 96000)); err != nil {
+			t.Fatal(err)
+		}
+		for msg := readMessage(t, ctx, conn); msg.Type != MessageTypeFinal; msg = readMessage(t, ctx, conn) {
+		}
+	}
+
+	daily, _, err := store.Usage(context.Background(), "ip:127.0.0.1", ratelimit.TranscriptionMinutes, time.Now())
+	if err != nil || daily != 0.05 {
+		t.Fatalf("Expected the first chunk recorded while streaming, got %v minutes (%v)", daily, err)
+	}
+
+	// The next chunk finds the quota used up and stops the session untranscribed
+	if err := conn.Write(ctx, websocket.MessageBinary, make([]byte, 96000)); err != nil {
+		t.Fatal(err)
+	}
+	if msg := readMessage(t, ctx, conn); msg.Type != MessageTypeError || !strings.Contains(msg.Text, "quota") {
+		t.Fatalf("Expected a quota error, got %+v", msg)
+	}
+	if msg := readMessage(t, ctx, conn); msg.Type != MessageTypeStopped {
+		t.Fatalf("Expected the session to stop, got %+v", msg)
+	}
+
+	daily, _, err = store.Usage(context.Background(), "ip:127.0.0.1", ratelimit.TranscriptionMinutes, time.Now())
+	if err != nil || daily != 0.1 {
+		t.Errorf("Expected 0.1 minutes recorded, got %v (%v)", daily, err)
+	}
+}
+
+func TestTranscribeHub_AllowedOrigins(t *testing.T) {
+	hub := NewTranscribeHubWithOptions(service.NewTranscribeServiceWithTranscriber(&MockTranscriber{}), HubOptions{
+		AllowedOrigins: []string{"http://localhost:3000"},
+	})
+
+	go hub.Run()
+	defer hub.Shutdown()
+
+	server := httptest.NewServer(http.HandlerFunc(hub.ServeTranscribeWS))
+	defer server.Close()
+
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	tests := []struct {
+		name    string
+		origin  string
+		allowed bool
+	}{
+		{"listed origin", "http://localhost:3000", true},
+		{"same origin", server.URL, true},
+		{"no origin", "", true},
+		{"other origin", "http://evil.example", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := http.Header{}
+			if tt.origin != "" {
+				header.Set("Origin", tt.origin)
+			}
+			conn, resp, err := websocket.Dial(ctx, wsURL, &websocket.DialOptions{HTTPHeader: header})
+			if tt.allowed {
+				if err != nil {
+					t.Fatalf("Expected origin %q to be allowed: %v", tt.origin, err)
+				}
+				conn.Close(websocket.StatusNormalClosure, "test completed")
+				return
+			}
+			if err == nil {
+				conn.Close(websocket.StatusNormalClosure, "test completed")
+				t.Fatalf("Expected origin %q to be rejected", tt.origin)
+			}
+			if resp == nil || resp.StatusCode != http.StatusForbidden {
+				t.Fatalf("Expected status %d, got %v", http.StatusForbidden, resp)
+			}
+			expectErrorCode(t, resp, response.CodeForbidden)
+		})
+	}
+
+	// Without an allow-list only same-origin pages may connect, unless "*" opts
+	// into any origin
+	for _, tt := range []struct {
+		allowed []string
+		want    bool
+	}{
+		{nil, false},
+		{[]string{"*"}, true},
+	} {
+		other := NewTranscribeHubWithOptions(service.NewTranscribeServiceWithTranscriber(&MockTranscriber{}), HubOptions{AllowedOrigins: tt.allowed})
+		req := httptest.NewRequest(http.MethodGet, "/ws/transcribe", nil)
+		req.Header.Set("Origin", "http://evil.example")
+		if got := other.checkOrigin(req); got != tt.want {
+			t.Errorf("checkOrigin() with allow-list %q = %v, expected %v", tt.allowed, got, tt.want)
+		}
+	}
+
+	// Rejected upgrades must not leak connection slots
+	hub.mutex.RLock()
+	reserved := hub.reserved
+	hub.mutex.RUnlock()
+	if reserved != 0 {
+		t.Errorf("Expected no reserved slots, got %d", reserved)
+	}
+}
+
+func TestTranscribeHub_SlowConsumer(t *testing.T) {
+	hub := NewTranscribeHub(service.NewTranscribeServiceWithTranscriber(&MockTranscriber{}))
+
+	ctx, cancel := context.WithCancel(hub.ctx)
+	client := &TranscribeClient{
+		hub:    hub,
+		send:   make(chan []byte, 1),
+		ctx:    ctx,
+		cancel: cancel,
+	}
+
+	// Nobody drains the queue; overflowing it must terminate the client, not panic
+	for i := 0; i < 3; i++ {
+		client.sendTranscribeMessage(TranscribeMessage{Type: MessageTypePong})
+	}
+
+	select {
+	case <-client.ctx.Done():
+	default:
+		t.Fatal("Expected slow client to be terminated")
+	}
+
+	code := binary.BigEndian.Uint16(client.closeMessage)
+	if int(code) != 1013 {
+		t.Errorf("Expected close code 1013 (try again later), got %d", code)
+	}
+
+	// Further sends and closes are harmless
+	client.sendTranscribeMessage(TranscribeMessage{Type: MessageTypePong})
+	client.closeWith(1000, "")
+}
+
+func TestTranscribeHub_Abort(t *testing.T) {
+	hub := NewTranscribeHub(service.NewTranscribeServiceWithTranscriber(&MockTranscriber{}))
+
+	ctx, cancel := context.WithCancel(hub.ctx)
+	client := &TranscribeClient{
+		hub:    hub,
+		send:   make(chan []byte, 1),
+		ctx:    ctx,
+		cancel: cancel,
+	}
+
+	// A connection that can't be written to is dropped without a close
+	// frame, as 1006 must never be sent
+	client.abort()
+	client.closeWith(1000, "")
+
+	select {
+	case <-client.ctx.Done():
+	default:
+		t.Fatal("Expected the client to be terminated")
+	}
+	if client.closeMessage != nil {
+		t.Errorf("Expected no close frame, got %v", client.closeMessage)
+	}
+}
+
+func TestTranscribeHub_ConcurrentLifecycle(t *testing.T) {
+	mockTranscriber := &MockTranscriber{
+		TranscribeStreamFunc: streamOf(func(audioChunk []byte) string { return string(audioChunk) }),
+	}
+	hub := NewTranscribeHubWithOptions(service.NewTranscribeServiceWithTranscriber(mockTranscriber), HubOptions{
+		MaxConnections: 8,
+	})
+
+	hubDone := make(chan struct{})
+	go func() {
+		hub.Run()
+		close(hubDone)
+	}()
+
+	server := httptest.NewServer(http.HandlerFunc(hub.ServeTranscribeWS))
+	defer server.Close()
+
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// More clients than slots connect, stream and drop out abruptly or cleanly
+	var wg sync.WaitGroup
+	for i := 0; i < 24; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			conn, _, err := websocket.Dial(ctx, wsURL, nil)
+			if err != nil {
+				return // rejected over the limit
+			}
+			for j := 0; j < 5; j++ {
+				if err := conn.Write(ctx, websocket.MessageBinary, []byte(fmt.Sprintf("%02d", j))); err != nil {
+					break
+				}
+			}
+			if i%2 == 0 {
+				conn.Close(websocket.StatusNormalClosure, "done")
+			} else {
+				conn.CloseNow()
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	// Every connection releases its slot
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		hub.mutex.RLock()
+		active := len(hub.clients) + hub.reserved
+		hub.mutex.RUnlock()
+		if active == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected all connections to be released, %d remain", active)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// Shutting down with live clients closes them with "going away"
+	conn, _, err := websocket.Dial(ctx, wsURL, nil)
+	if err != nil {
+		t.Fatalf("Failed to connect WebSocket: %v", err)
+	}
+	writeControl(t, ctx, conn, ControlMessage{Type: MessageTypeStart})
+	readMessage(t, ctx, conn)
+
+	hub.Shutdown()
+	select {
+	case <-hubDone:
+	case <-time.After(time.Second):
+		t.Fatal("Hub did not shut down within timeout")
+	}
+
+	if _, _, err := conn.Read(ctx); websocket.CloseStatus(err) != websocket.StatusGoingAway {
+		t.Errorf("Expected going away close after shutdown, got %v", err)
+	}
+
+	// New connections are refused once the hub is shut down
+	if _, resp, err := websocket.Dial(ctx, wsURL, nil); err == nil || resp == nil || resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("Expected status %d after shutdown, got %v", http.StatusServiceUnavailable, resp)
+	}
 }
 
 func TestTranscribeHub_ErrorHandling(t *testing.T) {
 	mockTranscriber := &MockTranscriber{
-		TranscribeAudioFunc: func(ctx context.Context, audioData []byte) (string, error) {
-			return "", context.DeadlineExceeded // Simulate timeout error
+		TranscribeStreamFunc: func(ctx context.Context, audioChunk []byte) (<-chan service.TranscribeStreamResult, error) {
+			return nil, context.DeadlineExceeded // Simulate timeout error
 		},
 	}
 	transcribeService := service.NewTranscribeServiceWithTranscriber(mockTranscriber)
@@ -365,10 +830,10 @@ func TestTranscribeMessage_JSON(t *testing.T) {
 // Integration test combining WebSocket with HTTP
 func TestWebSocketIntegration(t *testing.T) {
 	mockTranscriber := &MockTranscriber{
-		TranscribeAudioFunc: func(ctx context.Context, audioData []byte) (string, error) {
-			// Echo back the audio data size as text for testing
-			return string(audioData), nil
-		},
+		// Echo back the audio data as text for testing
+		TranscribeStreamFunc: streamOf(func(audioChunk []byte) string {
+			return string(audioChunk)
+		}),
 	}
 	transcribeService := service.NewTranscribeServiceWithTranscriber(mockTranscriber)
 	hub := NewTranscribeHub(transcribeService)
@@ -429,28 +894,805 @@ func TestWebSocketIntegration(t *testing.T) {
 	}
 }
 
-// Benchmark WebSocket message handling
-func BenchmarkWebSocketMessageProcessing(b *testing.B) {
+func TestTranscribeHub_OrderedSegments(t *testing.T) {
+	// Earlier chunks take longer to transcribe, so unordered processing would
+	// deliver results in reverse
 	mockTranscriber := &MockTranscriber{
-		TranscribeAudioFunc: func(ctx context.Context, audioData []byte) (string, error) {
-			return "benchmark result", nil
+		TranscribeStreamFunc: func(ctx context.Context, audioChunk []byte) (<-chan service.TranscribeStreamResult, error) {
+			resultChan := make(chan service.TranscribeStreamResult, 1)
+			go func() {
+				defer close(resultChan)
+				delay := time.Duration(5-int(audioChunk[1]-'0')) * 10 * time.Millisecond
+				time.Sleep(delay)
+				resultChan <- service.TranscribeStreamResult{Type: "final", Text: string(audioChunk)}
+			}()
+			return resultChan, nil
 		},
 	}
 	transcribeService := service.NewTranscribeServiceWithTranscriber(mockTranscriber)
 	hub := NewTranscribeHub(transcribeService)
 
-	// Create a mock client for benchmarking
-	ctx := context.Background()
-	client := &TranscribeClient{
-		hub:  hub,
-		send: make(chan []byte, 256),
-		ctx:  ctx,
+	go hub.Run()
+	defer hub.Shutdown()
+
+	server := httptest.NewServer(http.HandlerFunc(hub.ServeTranscribeWS))
+	defer server.Close()
+
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	conn, _, err := websocket.Dial(ctx, wsURL, nil)
+	if err != nil {
+		t.Fatalf("Failed to connect to WebSocket: %v", err)
 	}
+	defer conn.Close(websocket.StatusNormalClosure, "test completed")
 
-	audioData := []byte("benchmark audio data")
+	// 2-byte chunks are 1 sample (1/16ms) each at 16kHz mono PCM16
+	chunks := []string{"a1", "b2", "c3", "d4"}
+	for _, chunk := range chunks {
+		if err := conn.Write(ctx, websocket.MessageBinary, []byte(chunk)); err != nil {
+			t.Fatalf("Failed to send audio data: %v", err)
+		}
+	}
 
-	b.ResetTimer()
-	for i := 0; i < b.N; i++ {
-		client.processAudioChunk(audioData)
+	// Audio without "start" gets a default session
+	if started := readMessage(t, ctx, conn); started.Type != MessageTypeStarted {
+		t.Fatalf("Expected implicit started acknowledgement, got %+v", started)
+	}
+
+	for i, want := range chunks {
+		msg := readMessage(t, ctx, conn)
+		if msg.Type != "final" || msg.Text != want {
+			t.Errorf("Message %d: expected final %q, got %s %q", i, want, msg.Type, msg.Text)
+		}
+		if msg.Segment == nil || *msg.Segment != i {
+			t.Errorf("Message %d: expected segment %d, got %v", i, i, msg.Segment)
+		}
+	}
+}
+
+func TestTranscribeHub_ControlProtocol(t *testing.T) {
+	transcribeService := service.NewTranscribeServiceWithTranscriber(&MockTranscriber{})
+	transcribeService.RegisterProvider("mock", &MockTranscriber{})
+	hub := NewTranscribeHub(transcribeService)
+
+	go hub.Run()
+	defer hub.Shutdown()
+
+	server := httptest.NewServer(http.HandlerFunc(hub.ServeTranscribeWS))
+	defer server.Close()
+
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	conn, _, err := websocket.Dial(ctx, wsURL, nil)
+	if err != nil {
+		t.Fatalf("Failed to connect to WebSocket: %v", err)
+	}
+	defer conn.Close(websocket.StatusNormalClosure, "test completed")
+
+	t.Run("rejects invalid configuration", func(t *testing.T) {
+		writeControl(t, ctx, conn, ControlMessage{Type: MessageTypeStart, Encoding: "mp3"})
+		if msg := readMessage(t, ctx, conn); msg.Type != MessageTypeError {
+			t.Errorf("Expected error for unsupported encoding, got %+v", msg)
+		}
+
+		writeControl(t, ctx, conn, ControlMessage{Type: MessageTypeStart, Provider: "nope"})
+		if msg := readMessage(t, ctx, conn); msg.Type != MessageTypeError {
+			t.Errorf("Expected error for unknown provider, got %+v", msg)
+		}
+
+		// Compressed audio can't be decoded without ffmpeg
+		t.Setenv("PATH", t.TempDir())
+		writeControl(t, ctx, conn, ControlMessage{Type: MessageTypeStart, Encoding: "webm"})
+		if msg := readMessage(t, ctx, conn); msg.Type != MessageTypeError || !strings.Contains(msg.Text, "ffmpeg") {
+			t.Errorf("Expected error for webm without ffmpeg, got %+v", msg)
+		}
+
+		writeControl(t, ctx, conn, ControlMessage{Type: MessageTypeStop})
+		if msg := readMessage(t, ctx, conn); msg.Type != MessageTypeError {
+			t.Errorf("Expected error for stop without session, got %+v", msg)
+		}
+	})
+
+	t.Run("session lifecycle", func(t *testing.T) {
+		// A stand-in decoder passes the opus stream through unchanged
+		ffmpeg := t.TempDir()
+		if err := os.WriteFile(filepath.Join(ffmpeg, "ffmpeg"), []byte("#!/bin/sh\nexec cat\n"), 0755); err != nil {
+			t.Fatal(err)
+		}
+		t.Setenv("PATH", ffmpeg+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+		writeControl(t, ctx, conn, ControlMessage{Type: MessageTypeStart, Encoding: "opus", SampleRate: 48000, Language: "de", Provider: "mock"})
+		started := readMessage(t, ctx, conn)
+		if started.Type != MessageTypeStarted || started.Seq != 1 || started.SessionID == "" {
+			t.Fatalf("Expected started with seq 1 and a session ID, got %+v", started)
+		}
+		want := SessionConfig{SampleRate: 48000, Channels: 1, Encoding: "opus", Language: "de", Provider: "mock"}
+		if started.Config == nil || *started.Config != want {
+			t.Errorf("Expected config %+v, got %+v", want, started.Config)
+		}
+
+		// Audio sent while paused is discarded
+		writeControl(t, ctx, conn, ControlMessage{Type: MessageTypePause})
+		if err := conn.Write(ctx, websocket.MessageBinary, []byte("dropped")); err != nil {
+			t.Fatal(err)
+		}
+		writeControl(t, ctx, conn, ControlMessage{Type: MessageTypePing})
+		writeControl(t, ctx, conn, ControlMessage{Type: MessageTypeResume})
+		if err := conn.Write(ctx, websocket.MessageBinary, []byte("kept")); err != nil {
+			t.Fatal(err)
+		}
+		writeControl(t, ctx, conn, ControlMessage{Type: MessageTypeStop})
+
+		var types []string
+		for {
+			msg := readMessage(t, ctx, conn)
+			if msg.SessionID != started.SessionID {
+				t.Errorf("Expected session ID %s, got %s", started.SessionID, msg.SessionID)
+			}
+			if msg.Seq != started.Seq+int64(len(types))+1 {
+				t.Errorf("Expected seq %d, got %d", started.Seq+int64(len(types))+1, msg.Seq)
+			}
+			types = append(types, msg.Type)
+			if msg.Type == MessageTypeStopped {
+				break
+			}
+		}
+
+		got := strings.Join(types, ",")
+		if got != "paused,pong,resumed,partial,final,stopped" {
+			t.Errorf("Unexpected message sequence: %s", got)
+		}
+	})
+
+	t.Run("new session after stop", func(t *testing.T) {
+		writeControl(t, ctx, conn, ControlMessage{Type: MessageTypeStart})
+		started := readMessage(t, ctx, conn)
+		if started.Type != MessageTypeStarted || started.Seq != 1 {
+			t.Errorf("Expected a fresh session starting at seq 1, got %+v", started)
+		}
+	})
+}
+
+func TestTranscribeHub_RecordSession(t *testing.T) {
+	if err := database.InitDB(filepath.Join(t.TempDir(), "test.db")); err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+
+	mockTranscriber := &MockTranscriber{
+		TranscribeStreamFunc: streamOf(func(audioChunk []byte) string {
+			return fmt.Sprintf("%d bytes", len(audioChunk))
+		}),
+	}
+	transcribeService := service.NewTranscribeServiceWithTranscriber(mockTranscriber)
+	recordingsDir := t.TempDir()
+	bus := events.NewBus()
+	announcements := bus.Subscribe(events.TopicRecordingCreated, events.TopicTranscriptReady)
+	defer announcements.Close()
+	hub := NewTranscribeHubWithOptions(transcribeService, HubOptions{RecordingsDir: recordingsDir, Events: bus})
+
+	go hub.Run()
+	defer hub.Shutdown()
+
+	server := httptest.NewServer(http.HandlerFunc(hub.ServeTranscribeWS))
+	defer server.Close()
+
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	conn, _, err := websocket.Dial(ctx, wsURL, nil)
+	if err != nil {
+		t.Fatalf("Failed to connect to WebSocket: %v", err)
+	}
+	defer conn.Close(websocket.StatusNormalClosure, "test completed")
+
+	writeControl(t, ctx, conn, ControlMessage{Type: MessageTypeStart, Encoding: "pcm16", SampleRate: 16000, Record: true})
+	if started := readMessage(t, ctx, conn); started.Type != MessageTypeStarted || started.Config == nil || !started.Config.Record {
+		t.Fatalf("Expected recorded session to start, got %+v", started)
+	}
+
+	// Two 500ms chunks of 16kHz mono PCM16
+	for i := 0; i < 2; i++ {
+		if err := conn.Write(ctx, websocket.MessageBinary, make([]byte, 16000)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeControl(t, ctx, conn, ControlMessage{Type: MessageTypeStop})
+
+	var stopped TranscribeMessage
+	for stopped.Type != MessageTypeStopped {
+		stopped = readMessage(t, ctx, conn)
+	}
+	if stopped.RecordingID == 0 {
+		t.Fatal("Expected stopped message to carry a recording ID")
+	}
+
+	recording, err := database.GetRecording(context.Background(), int(stopped.RecordingID), database.AnyOwner)
+	if err != nil || recording == nil {
+		t.Fatalf("Expected recording %d to exist, got %v (err %v)", stopped.RecordingID, recording, err)
+	}
+	if recording["format"] != "wav" || recording["duration"] != 1 || recording["sample_rate"] != 16000 {
+		t.Errorf("Unexpected recording metadata: %+v", recording)
+	}
+
+	info, err := os.Stat(recording["file_path"].(string))
+	if err != nil {
+		t.Fatalf("Expected recording file to exist: %v", err)
+	}
+	if info.Size() != 44+32000 {
+		t.Errorf("Expected WAV file of %d bytes, got %d", 44+32000, info.Size())
+	}
+
+	segments, err := database.GetTranscriptSegments(context.Background(), int(stopped.RecordingID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(segments) != 2 || segments[1].Text != "16000 bytes" || segments[1].StartMs != 500 || segments[1].EndMs != 1000 {
+		t.Errorf("Unexpected transcript segments: %+v", segments)
+	}
+
+	// The saved recording is announced on the event bus
+	created := <-announcements.C
+	if data, ok := created.Data.(events.RecordingCreated); !ok || data.RecordingID != stopped.RecordingID || data.Source != "live" {
+		t.Errorf("Expected recording.created for live recording %d, got %+v", stopped.RecordingID, created)
+	}
+	ready := <-announcements.C
+	if data, ok := ready.Data.(events.TranscriptReady); !ok || data.RecordingID != stopped.RecordingID || data.Segments != 2 {
+		t.Errorf("Expected transcript.ready with 2 segments, got %+v", ready)
+	}
+}
+
+func TestTranscribeHub_VADSession(t *testing.T) {
+	hub := NewTranscribeHub(service.NewTranscribeServiceWithTranscriber(&MockTranscriber{}))
+
+	go hub.Run()
+	defer hub.Shutdown()
+
+	server := httptest.NewServer(http.HandlerFunc(hub.ServeTranscribeWS))
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	conn, _, err := websocket.Dial(ctx, "ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("Failed to connect to WebSocket: %v", err)
+	}
+	defer conn.Close(websocket.StatusNormalClosure, "test completed")
+
+	writeControl(t, ctx, conn, ControlMessage{Type: MessageTypeStart, VAD: true})
+	if started := readMessage(t, ctx, conn); started.Type != MessageTypeStarted || !started.Config.VAD {
+		t.Fatalf("Expected VAD session to start, got %+v", started)
+	}
+
+	// 1s of silence, 1s of a loud tone, 1s of silence at 16kHz mono PCM16
+	pcm := make([]byte, 3*32000)
+	for i := 16000; i < 32000; i++ {
+		value := int16(10000 * math.Sin(2*math.Pi*220*float64(i)/16000))
+		binary.LittleEndian.PutUint16(pcm[i*2:], uint16(value))
+	}
+	for offset := 0; offset < len(pcm); offset += 3200 {
+		if err := conn.Write(ctx, websocket.MessageBinary, pcm[offset:offset+3200]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeControl(t, ctx, conn, ControlMessage{Type: MessageTypeStop})
+
+	var types []string
+	for {
+		msg := readMessage(t, ctx, conn)
+		types = append(types, msg.Type)
+		if msg.Type == MessageTypeSpeechEnd && (msg.StartMs == nil || msg.EndMs == nil || *msg.StartMs == 0 || *msg.EndMs <= *msg.StartMs) {
+			t.Errorf("Expected speech_end with offsets, got %+v", msg)
+		}
+		if msg.Type == MessageTypeStopped {
+			break
+		}
+	}
+
+	if got := strings.Join(types, ","); got != "speech_start,speech_end,partial,final,stopped" {
+		t.Errorf("Unexpected message sequence: %s", got)
+	}
+}
+
+func TestTranscribeHub_RecordDisabled(t *testing.T) {
+	hub := NewTranscribeHub(service.NewTranscribeServiceWithTranscriber(&MockTranscriber{}))
+
+	go hub.Run()
+	defer hub.Shutdown()
+
+	server := httptest.NewServer(http.HandlerFunc(hub.ServeTranscribeWS))
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	conn, _, err := websocket.Dial(ctx, "ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("Failed to connect to WebSocket: %v", err)
+	}
+	defer conn.Close(websocket.StatusNormalClosure, "test completed")
+
+	writeControl(t, ctx, conn, ControlMessage{Type: MessageTypeStart, Record: true})
+	if msg := readMessage(t, ctx, conn); msg.Type != MessageTypeError {
+		t.Errorf("Expected error when recording is disabled, got %+v", msg)
+	}
+}
+
+func TestTranscribeHub_WatchSession(t *testing.T) {
+	mockTranscriber := &MockTranscriber{
+		TranscribeStreamFunc: streamOf(func(audioChunk []byte) string { return string(audioChunk) }),
+	}
+	hub := NewTranscribeHub(service.NewTranscribeServiceWithTranscriber(mockTranscriber))
+
+	go hub.Run()
+	defer hub.Shutdown()
+
+	r := chi.NewRouter()
+	r.Get("/ws/transcribe", hub.ServeTranscribeWS)
+	r.Get("/ws/transcribe/{session}/watch", hub.ServeWatchWS)
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	t.Run("unknown session", func(t *testing.T) {
+		resp, err := http.Get(server.URL + "/ws/transcribe/missing/watch")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusNotFound {
+			t.Errorf("Expected status %d, got %d", http.StatusNotFound, resp.StatusCode)
+		}
+		expectErrorCode(t, resp, response.CodeNotFound)
+	})
+
+	conn, _, err := websocket.Dial(ctx, wsURL+"/ws/transcribe", nil)
+	if err != nil {
+		t.Fatalf("Failed to connect to WebSocket: %v", err)
+	}
+	defer conn.Close(websocket.StatusNormalClosure, "test completed")
+
+	writeControl(t, ctx, conn, ControlMessage{Type: MessageTypeStart})
+	started := readMessage(t, ctx, conn)
+	if started.Type != MessageTypeStarted {
+		t.Fatalf("Expected started, got %+v", started)
+	}
+
+	if err := conn.Write(ctx, websocket.MessageBinary, []byte("aa")); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"partial", "final"} {
+		if msg := readMessage(t, ctx, conn); msg.Type != want {
+			t.Fatalf("Expected %s, got %+v", want, msg)
+		}
+	}
+
+	// A ping reply is only meant for the recording client
+	writeControl(t, ctx, conn, ControlMessage{Type: MessageTypePing})
+	if msg := readMessage(t, ctx, conn); msg.Type != MessageTypePong {
+		t.Fatalf("Expected pong, got %+v", msg)
+	}
+
+	// Two watchers join late; one never reads and must not hold up the other
+	watchURL := wsURL + "/ws/transcribe/" + started.SessionID + "/watch"
+	watcher, _, err := websocket.Dial(ctx, watchURL, nil)
+	if err != nil {
+		t.Fatalf("Failed to connect watcher: %v", err)
+	}
+	defer watcher.Close(websocket.StatusNormalClosure, "test completed")
+
+	idle, _, err := websocket.Dial(ctx, watchURL, nil)
+	if err != nil {
+		t.Fatalf("Failed to connect idle watcher: %v", err)
+	}
+	defer idle.Close(websocket.StatusNormalClosure, "test completed")
+
+	if err := conn.Write(ctx, websocket.MessageBinary, []byte("bb")); err != nil {
+		t.Fatal(err)
+	}
+	writeControl(t, ctx, conn, ControlMessage{Type: MessageTypeStop})
+
+	var seen []string
+	for {
+		msg := readMessage(t, ctx, watcher)
+		if msg.SessionID != started.SessionID {
+			t.Errorf("Expected session ID %s, got %s", started.SessionID, msg.SessionID)
+		}
+		seen = append(seen, msg.Type+":"+msg.Text)
+		if msg.Type == MessageTypeStopped {
+			break
+		}
+	}
+
+	got := strings.Join(seen, ",")
+	want := "started:,partial:partial: aa,final:aa,partial:partial: bb,final:bb,stopped:"
+	if got != want {
+		t.Errorf("Expected watcher to see %s, got %s", want, got)
+	}
+
+	// The watcher is disconnected once the session has stopped
+	if _, _, err := watcher.Read(ctx); websocket.CloseStatus(err) != websocket.StatusNormalClosure {
+		t.Errorf("Expected normal closure after stop, got %v", err)
+	}
+
+	// Stopped sessions can no longer be watched
+	resp, err := http.Get(server.URL + "/ws/transcribe/" + started.SessionID + "/watch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected status %d for a stopped session, got %d", http.StatusNotFound, resp.StatusCode)
+	}
+}
+
+func TestTranscribeHub_WatchSharedSession(t *testing.T) {
+	if err := database.InitDB(filepath.Join(t.TempDir(), "test.db")); err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	users := make(map[string]*database.User)
+	for _, name := range []string{"alice", "bob", "carol"} {
+		user, err := database.CreateUser(name, "hash", false, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		users[name] = user
+	}
+
+	authenticator := auth.New(auth.Options{Required: true})
+	hub := NewTranscribeHubWithOptions(service.NewTranscribeServiceWithTranscriber(&MockTranscriber{}), HubOptions{
+		Authenticator: authenticator,
+		RecordingsDir: t.TempDir(),
+	})
+
+	go hub.Run()
+	defer hub.Shutdown()
+
+	r := chi.NewRouter()
+	r.Get("/ws/transcribe", hub.ServeTranscribeWS)
+	r.Get("/ws/transcribe/{session}/watch", hub.ServeWatchWS)
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http")
+	ticketFor := func(name string) string {
+		ticket, _ := authenticator.IssueTicket(users[name].Principal(auth.MethodSession))
+		return ticket
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	conn, _, err := websocket.Dial(ctx, wsURL+"/ws/transcribe?ticket="+ticketFor("alice"), nil)
+	if err != nil {
+		t.Fatalf("Failed to connect to WebSocket: %v", err)
+	}
+	defer conn.Close(websocket.StatusNormalClosure, "test completed")
+
+	writeControl(t, ctx, conn, ControlMessage{Type: MessageTypeStart, Encoding: "pcm16", SampleRate: 16000, Record: true})
+	started := readMessage(t, ctx, conn)
+	if started.Type != MessageTypeStarted || started.RecordingID == 0 {
+		t.Fatalf("Expected recorded session to start with a recording ID, got %+v", started)
+	}
+	if _, err := database.GrantShare(database.ResourceRecording, started.RecordingID, users["bob"].ID, database.RoleViewer); err != nil {
+		t.Fatal(err)
+	}
+
+	watchURL := wsURL + "/ws/transcribe/" + started.SessionID + "/watch?ticket="
+
+	// Users the recording isn't shared with can't see the session
+	_, resp, err := websocket.Dial(ctx, watchURL+ticketFor("carol"), nil)
+	if err == nil || resp == nil || resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("Expected status %d for carol, got %v", http.StatusNotFound, resp)
+	}
+
+	watcher, _, err := websocket.Dial(ctx, watchURL+ticketFor("bob"), nil)
+	if err != nil {
+		t.Fatalf("Expected bob to watch the shared session: %v", err)
+	}
+	defer watcher.Close(websocket.StatusNormalClosure, "test completed")
+
+	if msg := readMessage(t, ctx, watcher); msg.Type != MessageTypeStarted || msg.RecordingID != started.RecordingID {
+		t.Errorf("Expected started for recording %d, got %+v", started.RecordingID, msg)
+	}
+
+	// A session stopped without audio discards its recording
+	writeControl(t, ctx, conn, ControlMessage{Type: MessageTypeStop})
+	if msg := readMessage(t, ctx, conn); msg.Type != MessageTypeStopped || msg.RecordingID != 0 {
+		t.Fatalf("Expected stopped without a recording, got %+v", msg)
+	}
+	if recording, _ := database.GetRecording(context.Background(), int(started.RecordingID), database.AnyOwner); recording != nil {
+		t.Errorf("Expected empty recording to be deleted, got %+v", recording)
+	}
+}
+
+func TestTranscribeHub_WatcherLimit(t *testing.T) {
+	hub := NewTranscribeHubWithOptions(service.NewTranscribeServiceWithTranscriber(&MockTranscriber{}), HubOptions{MaxConnections: 2})
+
+	go hub.Run()
+	defer hub.Shutdown()
+
+	r := chi.NewRouter()
+	r.Get("/ws/transcribe", hub.ServeTranscribeWS)
+	r.Get("/ws/transcribe/{session}/watch", hub.ServeWatchWS)
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	conn, _, err := websocket.Dial(ctx, wsURL+"/ws/transcribe", nil)
+	if err != nil {
+		t.Fatalf("Failed to connect to WebSocket: %v", err)
+	}
+	defer conn.Close(websocket.StatusNormalClosure, "test completed")
+
+	writeControl(t, ctx, conn, ControlMessage{Type: MessageTypeStart})
+	started := readMessage(t, ctx, conn)
+	watchPath := "/ws/transcribe/" + started.SessionID + "/watch"
+
+	watcher, _, err := websocket.Dial(ctx, wsURL+watchPath, nil)
+	if err != nil {
+		t.Fatalf("Failed to connect watcher: %v", err)
+	}
+
+	// The recording client and the watcher take both slots
+	resp, err := http.Get(server.URL + watchPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("Expected status %d for a watcher over the limit, got %d", http.StatusServiceUnavailable, resp.StatusCode)
+	}
+
+	// A watcher that leaves gives its slot back
+	watcher.Close(websocket.StatusNormalClosure, "leaving")
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if used, _ := hub.Capacity(); used == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			used, _ := hub.Capacity()
+			t.Fatalf("Expected the watcher's slot to be released, %d in use", used)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	watcher, _, err = websocket.Dial(ctx, wsURL+watchPath, nil)
+	if err != nil {
+		t.Fatalf("Failed to connect a watcher to the freed slot: %v", err)
+	}
+	watcher.Close(websocket.StatusNormalClosure, "test completed")
+}
+
+func TestSessionBroadcast_SlowWatcher(t *testing.T) {
+	broadcast := newSessionBroadcast("anonymous", 0)
+	broadcast.publish([]byte("backlog"))
+
+	logger := zerolog.Nop()
+	slow := broadcast.subscribe(&logger)
+	fast := broadcast.subscribe(&logger)
+
+	if data, ok := fast.next(); !ok || string(data) != "backlog" {
+		t.Fatalf("Expected backlog replay, got %q", data)
+	}
+
+	// The fast watcher keeps up; the slow one never reads
+	for i := 0; i < watcherBuffer+10; i++ {
+		want := fmt.Sprintf("message %d", i)
+		broadcast.publish([]byte(want))
+		if data, ok := fast.next(); !ok || string(data) != want {
+			t.Fatalf("Expected fast watcher to receive %q, got %q", want, data)
+		}
+	}
+
+	select {
+	case <-slow.done:
+	default:
+		t.Error("Expected slow watcher to be dropped")
+	}
+
+	broadcast.close()
+	if _, ok := fast.next(); ok {
+		t.Error("Expected fast watcher to be closed with the broadcast")
+	}
+
+	// Late joiners after the session ended still get the backlog
+	late := broadcast.subscribe(&logger)
+	if data, ok := late.next(); !ok || string(data) != "backlog" {
+		t.Errorf("Expected late watcher to replay the backlog, got %q", data)
+	}
+}
+
+func TestTranscribeHub_Authentication(t *testing.T) {
+	authenticator := auth.New(auth.Options{
+		Required: true,
+		Tokens:   map[string]string{"alice": "alice-token"},
+	})
+	hub := NewTranscribeHubWithOptions(service.NewTranscribeServiceWithTranscriber(&MockTranscriber{}), HubOptions{
+		Authenticator: authenticator,
+	})
+
+	go hub.Run()
+	defer hub.Shutdown()
+
+	server := httptest.NewServer(http.HandlerFunc(hub.ServeTranscribeWS))
+	defer server.Close()
+
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	t.Run("rejects missing credentials", func(t *testing.T) {
+		_, resp, err := websocket.Dial(ctx, wsURL, nil)
+		if err == nil || resp == nil || resp.StatusCode != http.StatusUnauthorized {
+			t.Fatalf("Expected status %d, got %v", http.StatusUnauthorized, resp)
+		}
+		expectErrorCode(t, resp, response.CodeUnauthorized)
+	})
+
+	ticket, _ := authenticator.IssueTicket(auth.Principal{ID: "alice"})
+
+	tests := []struct {
+		name string
+		url  string
+		opts *websocket.DialOptions
+	}{
+		{"bearer header", wsURL, &websocket.DialOptions{HTTPHeader: http.Header{"Authorization": {"Bearer alice-token"}}}},
+		{"bearer subprotocol", wsURL, &websocket.DialOptions{Subprotocols: []string{auth.Subprotocol, "bearer.alice-token"}}},
+		{"ticket", wsURL + "?ticket=" + ticket, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn, _, err := websocket.Dial(ctx, tt.url, tt.opts)
+			if err != nil {
+				t.Fatalf("Failed to connect: %v", err)
+			}
+			defer conn.Close(websocket.StatusNormalClosure, "test completed")
+
+			if tt.opts != nil && len(tt.opts.Subprotocols) > 0 && conn.Subprotocol() != auth.Subprotocol {
+				t.Errorf("Expected subprotocol %s, got %q", auth.Subprotocol, conn.Subprotocol())
+			}
+
+			// Sessions are attributed to the authenticated user
+			writeControl(t, ctx, conn, ControlMessage{Type: MessageTypeStart})
+			started := readMessage(t, ctx, conn)
+			if started.Type != MessageTypeStarted || started.User != "alice" {
+				t.Errorf("Expected started for alice, got %+v", started)
+			}
+			if n := hub.ActiveSessions("alice"); n != 1 {
+				t.Errorf("Expected 1 active session for alice, got %d", n)
+			}
+
+			writeControl(t, ctx, conn, ControlMessage{Type: MessageTypeStop})
+			if msg := readMessage(t, ctx, conn); msg.Type != MessageTypeStopped {
+				t.Errorf("Expected stopped, got %+v", msg)
+			}
+		})
+	}
+
+	t.Run("ticket is single use", func(t *testing.T) {
+		_, resp, err := websocket.Dial(ctx, wsURL+"?ticket="+ticket, nil)
+		if err == nil || resp == nil || resp.StatusCode != http.StatusUnauthorized {
+			t.Fatalf("Expected status %d for a reused ticket, got %v", http.StatusUnauthorized, resp)
+		}
+	})
+}
+
+func TestTranscribeHub_EventChannel(t *testing.T) {
+	bus := events.NewBus()
+	hub := NewTranscribeHubWithOptions(service.NewTranscribeServiceWithTranscriber(&MockTranscriber{}), HubOptions{
+		Events: bus,
+	})
+
+	go hub.Run()
+	defer hub.Shutdown()
+
+	server := httptest.NewServer(http.HandlerFunc(hub.ServeEventsWS))
+	defer server.Close()
+
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	readEvent := func(conn *websocket.Conn) EventMessage {
+		t.Helper()
+		_, data, err := conn.Read(ctx)
+		if err != nil {
+			t.Fatalf("Failed to read message: %v", err)
+		}
+		var msg EventMessage
+		if err := json.Unmarshal(data, &msg); err != nil {
+			t.Fatalf("Failed to unmarshal message: %v", err)
+		}
+		return msg
+	}
+
+	t.Run("rejects unknown topics", func(t *testing.T) {
+		_, resp, err := websocket.Dial(ctx, wsURL+"?topics=bogus", nil)
+		if err == nil || resp == nil || resp.StatusCode != http.StatusBadRequest {
+			t.Fatalf("Expected status %d, got %v", http.StatusBadRequest, resp)
+		}
+	})
+
+	conn, _, err := websocket.Dial(ctx, wsURL+"?topics="+events.TopicRecordingCreated, nil)
+	if err != nil {
+		t.Fatalf("Failed to connect to WebSocket: %v", err)
+	}
+	defer conn.Close(websocket.StatusNormalClosure, "test completed")
+
+	if msg := readEvent(conn); msg.Type != MessageTypeSubscribed || strings.Join(msg.Topics, ",") != events.TopicRecordingCreated {
+		t.Fatalf("Expected subscribed to %s, got %+v", events.TopicRecordingCreated, msg)
+	}
+
+	// Only subscribed topics are delivered
+	bus.Publish(events.TopicJobProgress, nil)
+	published := bus.Publish(events.TopicRecordingCreated, events.RecordingCreated{RecordingID: 42, Source: "upload"})
+
+	msg := readEvent(conn)
+	if msg.Type != MessageTypeEvent || msg.ID != published.ID || msg.Topic != events.TopicRecordingCreated {
+		t.Fatalf("Expected recording.created event %d, got %+v", published.ID, msg)
+	}
+	if data, _ := msg.Data.(map[string]any); data["recordingId"] != float64(42) {
+		t.Errorf("Expected recordingId 42 in event data, got %v", msg.Data)
+	}
+
+	// Subscriptions can change over the connection
+	data, _ := json.Marshal(EventControlMessage{Type: MessageTypeSubscribe, Topics: []string{"job.*"}})
+	if err := conn.Write(ctx, websocket.MessageText, data); err != nil {
+		t.Fatal(err)
+	}
+	if msg := readEvent(conn); msg.Type != MessageTypeSubscribed || strings.Join(msg.Topics, ",") != "job.*,recording.created" {
+		t.Fatalf("Expected subscribed to job.* and recording.created, got %+v", msg)
+	}
+
+	bus.Publish(events.TopicJobProgress, nil)
+	if msg := readEvent(conn); msg.Topic != events.TopicJobProgress {
+		t.Errorf("Expected job.progress event, got %+v", msg)
+	}
+
+	data, _ = json.Marshal(EventControlMessage{Type: MessageTypeSubscribe, Topics: []string{"bogus"}})
+	if err := conn.Write(ctx, websocket.MessageText, data); err != nil {
+		t.Fatal(err)
+	}
+	if msg := readEvent(conn); msg.Type != MessageTypeError {
+		t.Errorf("Expected error for unknown topic, got %+v", msg)
+	}
+}
+
+// Benchmark WebSocket message handling
+func BenchmarkWebSocketMessageProcessing(b *testing.B) {
+	mockTranscriber := &MockTranscriber{}
+	transcribeService := service.NewTranscribeServiceWithTranscriber(mockTranscriber)
+
+	audioData := []byte("benchmark audio data")
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		chunks := make(chan []byte, 1)
+		chunks <- audioData
+		close(chunks)
+
+		for range transcribeService.StartStream(context.Background(), service.StreamOptions{}, chunks) {
+		}
 	}
 }
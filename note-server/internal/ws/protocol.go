@@ -0,0 +1,79 @@
+package ws
+
+// Message types sent by the client as JSON text frames
+const (
+	MessageTypeStart  = "start"
+	MessageTypeStop   = "stop"
+	MessageTypePause  = "pause"
+	MessageTypeResume = "resume"
+	MessageTypePing   = "ping"
+)
+
+// Message types sent by the server
+const (
+	MessageTypeStarted = "started"
+	MessageTypeStopped = "stopped"
+	MessageTypePaused  = "paused"
+	MessageTypeResumed = "resumed"
+	MessageTypePong    = "pong"
+	MessageTypePartial = "partial"
+	MessageTypeFinal   = "final"
+	MessageTypeError   = "error"
+
+	// Voice activity events, only sent when the session has "vad" enabled
+	MessageTypeSpeechStart = "speech_start"
+	MessageTypeSpeechEnd   = "speech_end"
+)
+
+// ControlMessage is a JSON control message sent by the client
+type ControlMessage struct {
+	Type string `json:"type"`
+
+	// Session configuration, only used by "start"
+	SampleRate int    `json:"sample_rate,omitempty"`
+	Channels   int    `json:"channels,omitempty"`
+	Encoding   string `json:"encoding,omitempty"`
+	Language   string `json:"language,omitempty"`
+	Provider   string `json:"provider,omitempty"`
+
+	// Detect speech server-side and only transcribe speech segments (pcm16 mono only)
+	VAD bool `json:"vad,omitempty"`
+
+	// Save the session's audio and transcript as a recording when it stops
+	Record bool `json:"record,omitempty"`
+}
+
+// SessionConfig is the effective configuration of a transcription session,
+// echoed back to the client in the "started" acknowledgement
+type SessionConfig struct {
+	SampleRate int    `json:"sample_rate"`
+	Channels   int    `json:"channels"`
+	Encoding   string `json:"encoding"`
+	Language   string `json:"language,omitempty"`
+	Provider   string `json:"provider,omitempty"`
+	VAD        bool   `json:"vad"`
+	Record     bool   `json:"record"`
+}
+
+// TranscribeMessage represents messages sent to the client by the transcription WebSocket
+type TranscribeMessage struct {
+	Type      string `json:"type"`
+	Seq       int64  `json:"seq,omitempty"`
+	SessionID string `json:"session_id,omitempty"`
+	Text      string `json:"text,omitempty"`
+
+	// Segment index and offsets into the session's audio, only set on results
+	// and voice activity events. Pointers so the first segment, at 0, is sent.
+	Segment *int   `json:"segment,omitempty"`
+	StartMs *int64 `json:"start_ms,omitempty"`
+	EndMs   *int64 `json:"end_ms,omitempty"`
+
+	// Effective session configuration and the user the session is attributed to,
+	// only set on "started"
+	Config *SessionConfig `json:"config,omitempty"`
+	User   string         `json:"user,omitempty"`
+
+	// ID of the recording the session is saved as, only set on "started" and
+	// "stopped" when the session is recorded
+	RecordingID int64 `json:"recording_id,omitempty"`
+}
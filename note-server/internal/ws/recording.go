@@ -0,0 +1,184 @@
+package ws
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/your-org/note-server/internal/audio"
+	"github.com/your-org/note-server/internal/database"
+	"github.com/your-org/note-server/internal/service"
+)
+
+// sessionRecording tees the audio of a session into media storage. Its
+// recording is added when the session starts, so it can be shared with
+// watchers, and completed when the session ends.
+type sessionRecording struct {
+	id       int64
+	file     *os.File
+	wav      *audio.WAVWriter // nil for compressed encodings, which are stored as received
+	filename string
+	format   string
+
+	// Wall-clock time the first audio chunk arrived
+	firstAudio time.Time
+
+	// First write error; once set the session is no longer teed and nothing is saved
+	err error
+
+	// Final transcript segments, appended by forwardResults
+	segmentsMutex sync.Mutex
+	segments      []database.TranscriptSegment
+
+	// Number of segments stored by save
+	savedSegments int
+}
+
+// newSessionRecording creates the media file for a session in dir, and its
+// recording owned by the user ownerID
+func newSessionRecording(ctx context.Context, dir string, sessionID string, ownerID int64, config SessionConfig) (*sessionRecording, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create recordings directory: %w", err)
+	}
+
+	format := "wav"
+	switch config.Encoding {
+	case service.EncodingOpus:
+		format = "opus"
+	case service.EncodingWebM:
+		format = "webm"
+	}
+
+	timestamp := time.Now().Format("2006-01-02_15-04-05")
+	filename := fmt.Sprintf("live_%s_%s.%s", timestamp, sessionID[:8], format)
+
+	file, err := os.Create(filepath.Join(dir, filename))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create recording file: %w", err)
+	}
+
+	rec := &sessionRecording{
+		file:     file,
+		filename: filename,
+		format:   format,
+	}
+
+	if config.Encoding == service.EncodingPCM16 {
+		rec.wav, err = audio.NewWAVWriter(file, config.SampleRate, config.Channels)
+		if err != nil {
+			file.Close()
+			os.Remove(file.Name())
+			return nil, err
+		}
+	}
+
+	now := time.Now()
+	rec.id, err = database.AddRecording(ctx, ownerID, filename, file.Name(), now, now, 0, 0, format, config.SampleRate, config.Channels)
+	if err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return nil, err
+	}
+
+	return rec, nil
+}
+
+// write appends an audio chunk to the recording
+func (r *sessionRecording) write(chunk []byte) error {
+	if r.firstAudio.IsZero() {
+		r.firstAudio = time.Now()
+	}
+
+	var w io.Writer = r.file
+	if r.wav != nil {
+		w = r.wav
+	}
+
+	_, err := w.Write(chunk)
+	return err
+}
+
+// addSegment records a final transcript segment
+func (r *sessionRecording) addSegment(result service.TranscribeStreamResult) {
+	r.segmentsMutex.Lock()
+	defer r.segmentsMutex.Unlock()
+
+	r.segments = append(r.segments, database.TranscriptSegment{
+		Segment: result.Segment,
+		StartMs: result.StartMs,
+		EndMs:   result.EndMs,
+		Text:    result.Text,
+	})
+}
+
+// discard closes and deletes the media file and the recording
+func (r *sessionRecording) discard(ctx context.Context) {
+	r.file.Close()
+	os.Remove(r.file.Name())
+	if err := database.DeleteRecording(ctx, r.id); err != nil {
+		zerolog.Ctx(ctx).Error().Err(err).Int64("recording_id", r.id).Msg("Failed to delete discarded recording")
+	}
+}
+
+// save closes the media file and completes the recording with its duration,
+// size and transcript, logging and tracing with ctx. Sessions that never
+// received audio, or whose audio could not be written, are discarded and
+// return an ID of 0.
+func (r *sessionRecording) save(ctx context.Context, config SessionConfig, endTime time.Time) (int64, error) {
+	if r.firstAudio.IsZero() || r.err != nil {
+		r.discard(ctx)
+		return 0, nil
+	}
+
+	if r.wav != nil {
+		if err := r.wav.Close(); err != nil {
+			r.discard(ctx)
+			return 0, fmt.Errorf("failed to finalize WAV file: %w", err)
+		}
+	}
+
+	info, err := r.file.Stat()
+	if err != nil {
+		r.discard(ctx)
+		return 0, fmt.Errorf("failed to stat recording file: %w", err)
+	}
+	if err := r.file.Close(); err != nil {
+		r.discard(ctx)
+		return 0, fmt.Errorf("failed to close recording file: %w", err)
+	}
+
+	// PCM gives an exact duration; compressed audio falls back to wall-clock time
+	startTime := r.firstAudio
+	duration := endTime.Sub(startTime)
+	if r.wav != nil {
+		bytesPerSecond := int64(config.SampleRate * config.Channels * 2)
+		duration = time.Duration(r.wav.DataSize()) * time.Second / time.Duration(bytesPerSecond)
+		endTime = startTime.Add(duration)
+	}
+
+	recordingID := r.id
+	if err := database.FinishRecording(ctx, recordingID, startTime, endTime, int(duration.Seconds()), int(info.Size())); err != nil {
+		r.discard(ctx)
+		return 0, err
+	}
+
+	r.segmentsMutex.Lock()
+	segments := r.segments
+	r.segmentsMutex.Unlock()
+
+	if len(segments) > 0 {
+		if err := database.AddTranscriptSegments(ctx, recordingID, segments); err != nil {
+			// The audio is already saved, so keep the recording and report the failure
+			zerolog.Ctx(ctx).Error().Err(err).Int64("recording_id", recordingID).Msg("Failed to save transcript")
+		} else {
+			r.savedSegments = len(segments)
+		}
+	}
+
+	return recordingID, nil
+}
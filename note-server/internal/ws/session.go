@@ -0,0 +1,384 @@
+package ws
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/your-org/note-server/internal/events"
+	"github.com/your-org/note-server/internal/ratelimit"
+	"github.com/your-org/note-server/internal/service"
+)
+
+// transcribeSession is one start/stop cycle on a transcription connection.
+// It is owned by the client's readPump goroutine.
+type transcribeSession struct {
+	id     string
+	config SessionConfig
+	paused bool
+
+	// The client's context, with the session ID added to its logger
+	ctx context.Context
+
+	// Audio chunks waiting to be fed into the streaming pipeline, in arrival order
+	audio chan []byte
+
+	// Closed once every result of the session has been forwarded to the client
+	done chan struct{}
+
+	// Media file the session is teed into, nil unless recording was requested
+	recording *sessionRecording
+
+	// Audio transcribed so far. It is recorded against the client's quota as
+	// the session streams, metered being the part already recorded.
+	usage      *service.Usage
+	meterMutex sync.Mutex
+	metered    time.Duration
+	meteredAt  time.Time
+}
+
+// newSessionID returns a random identifier for a transcription session
+func newSessionID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(fmt.Sprintf("failed to generate session id: %v", err))
+	}
+	return hex.EncodeToString(b)
+}
+
+// handleControl dispatches a JSON control message received from the client
+func (c *TranscribeClient) handleControl(data []byte) {
+	var msg ControlMessage
+	if err := json.Unmarshal(data, &msg); err != nil {
+		c.sendError("Invalid control message")
+		return
+	}
+
+	switch msg.Type {
+	case MessageTypeStart:
+		opts := service.StreamOptions{
+			SampleRate: msg.SampleRate,
+			Channels:   msg.Channels,
+			Encoding:   msg.Encoding,
+			Language:   msg.Language,
+			Provider:   msg.Provider,
+			VAD:        msg.VAD,
+		}
+		if err := c.startSession(opts, msg.Record); err != nil {
+			c.sendError(err.Error())
+		}
+
+	case MessageTypeStop:
+		if c.session == nil {
+			c.sendError("No active session")
+			return
+		}
+		c.stopSession()
+
+	case MessageTypePause, MessageTypeResume:
+		if c.session == nil {
+			c.sendError("No active session")
+			return
+		}
+		c.session.paused = msg.Type == MessageTypePause
+		reply := MessageTypeResumed
+		if c.session.paused {
+			reply = MessageTypePaused
+		}
+		c.sendTranscribeMessage(TranscribeMessage{Type: reply})
+
+	case MessageTypePing:
+		c.sendTranscribeMessage(TranscribeMessage{Type: MessageTypePong})
+
+	default:
+		c.sendError(fmt.Sprintf("Unknown message type %q", msg.Type))
+	}
+}
+
+// startSession validates opts, starts a streaming pipeline and acknowledges
+// the new session to the client
+func (c *TranscribeClient) startSession(opts service.StreamOptions, record bool) error {
+	if c.session != nil {
+		return fmt.Errorf("session %s already started", c.session.id)
+	}
+	if record && c.hub.options.RecordingsDir == "" {
+		return fmt.Errorf("recording is not enabled on this server")
+	}
+
+	if err := opts.Validate(); err != nil {
+		return err
+	}
+	opts = opts.WithDefaults()
+	if !c.hub.transcribeService.HasProvider(opts.Provider) {
+		return fmt.Errorf("unknown provider %q", opts.Provider)
+	}
+	if err := service.CheckDecoder(opts.Encoding); err != nil {
+		return err
+	}
+	if err := c.checkQuota(); err != nil {
+		return err
+	}
+
+	id := newSessionID()
+	logger := zerolog.Ctx(c.ctx).With().Str("session_id", id).Logger()
+	usage := &service.Usage{}
+	session := &transcribeSession{
+		id:  id,
+		ctx: service.WithUsage(logger.WithContext(c.ctx), usage),
+		config: SessionConfig{
+			SampleRate: opts.SampleRate,
+			Channels:   opts.Channels,
+			Encoding:   opts.Encoding,
+			Language:   opts.Language,
+			Provider:   opts.Provider,
+			VAD:        opts.VAD,
+			Record:     record,
+		},
+		audio:     make(chan []byte, maxPendingChunks),
+		done:      make(chan struct{}),
+		usage:     usage,
+		meteredAt: time.Now(),
+	}
+
+	if record {
+		recording, err := newSessionRecording(session.ctx, c.hub.options.RecordingsDir, session.id, c.principal.UserID, session.config)
+		if err != nil {
+			zerolog.Ctx(session.ctx).Error().Err(err).Msg("Failed to start recording")
+			return fmt.Errorf("failed to start recording")
+		}
+		session.recording = recording
+	}
+
+	c.session = session
+
+	var recordingID int64
+	if session.recording != nil {
+		recordingID = session.recording.id
+	}
+	c.hub.openBroadcast(session.id, c.principal.ID, recordingID)
+	c.resetSeq(session.id)
+	c.sendTranscribeMessage(TranscribeMessage{
+		Type:        MessageTypeStarted,
+		Config:      &session.config,
+		User:        c.principal.ID,
+		RecordingID: recordingID,
+	})
+
+	results := c.hub.transcribeService.StartStream(session.ctx, opts, session.audio)
+	go c.forwardResults(session, results)
+
+	zerolog.Ctx(session.ctx).Info().
+		Str("encoding", opts.Encoding).
+		Int("sample_rate", opts.SampleRate).
+		Str("provider", opts.Provider).
+		Bool("record", record).
+		Msg("Transcription session started")
+	return nil
+}
+
+// stopSession ends the audio stream, waits for the remaining results to be
+// delivered and acknowledges the stop
+func (c *TranscribeClient) stopSession() {
+	session := c.session
+	c.session = nil
+
+	close(session.audio)
+	select {
+	case <-session.done:
+	case <-c.ctx.Done():
+		// The pipeline stops with the client, but segments it already
+		// transcribed still belong in the recording
+		c.hub.closeBroadcast(session.id)
+		if session.recording != nil {
+			waitForSession(session)
+			c.saveRecording(session)
+		}
+		return
+	}
+
+	recordingID := c.saveRecording(session)
+
+	c.sendTranscribeMessage(TranscribeMessage{Type: MessageTypeStopped, RecordingID: recordingID})
+	c.hub.closeBroadcast(session.id)
+	zerolog.Ctx(session.ctx).Info().Msg("Transcription session stopped")
+}
+
+// abandonSession ends the session of a disconnecting client. Results still in
+// flight are not delivered, but a recording is saved once the pipeline winds down.
+func (c *TranscribeClient) abandonSession() {
+	session := c.session
+	c.session = nil
+
+	close(session.audio)
+	c.hub.closeBroadcast(session.id)
+	if session.recording != nil {
+		go func() {
+			waitForSession(session)
+			c.saveRecording(session)
+		}()
+	}
+}
+
+// waitForSession waits, for at most sessionDrainTimeout, until every result of
+// an ending session has been forwarded
+func waitForSession(session *transcribeSession) {
+	select {
+	case <-session.done:
+	case <-time.After(sessionDrainTimeout):
+		zerolog.Ctx(session.ctx).Warn().Msg("Session didn't wind down in time, saving the recording without its last results")
+	}
+}
+
+// saveRecording stores the session's recording, if any, and returns its ID
+func (c *TranscribeClient) saveRecording(session *transcribeSession) int64 {
+	if session.recording == nil {
+		return 0
+	}
+
+	// The session may have ended with its context, but its recording is still saved
+	recordingID, err := session.recording.save(context.WithoutCancel(session.ctx), session.config, time.Now())
+	if err != nil {
+		zerolog.Ctx(session.ctx).Error().Err(err).Msg("Failed to save recording")
+		c.sendError("Failed to save recording")
+		return 0
+	}
+
+	if recordingID != 0 {
+		zerolog.Ctx(session.ctx).Info().Int64("recording_id", recordingID).Msg("Session saved as recording")
+
+		bus := c.hub.options.Events
+		bus.PublishFor(c.principal.UserID, events.TopicRecordingCreated, events.RecordingCreated{
+			RecordingID: recordingID,
+			Filename:    session.recording.filename,
+			Source:      "live",
+		})
+		if segments := session.recording.savedSegments; segments > 0 {
+			bus.PublishFor(c.principal.UserID, events.TopicTranscriptReady, events.TranscriptReady{
+				RecordingID: recordingID,
+				Segments:    segments,
+			})
+		}
+	}
+	return recordingID
+}
+
+// queueAudio hands an audio chunk to the active session, starting a session with
+// default settings for clients that stream audio without sending "start" first.
+// It blocks while the transcriber is behind and returns false if the client is closing.
+func (c *TranscribeClient) queueAudio(chunk []byte) bool {
+	if c.session == nil {
+		if err := c.startSession(service.StreamOptions{}, false); err != nil {
+			c.sendError(err.Error())
+			return true
+		}
+	}
+
+	if c.session.paused {
+		return true
+	}
+
+	// Sessions stop once the client's quota is used up, even mid-stream
+	if time.Since(c.session.meteredAt) >= quotaCheckInterval {
+		if err := c.meterSession(c.session); err != nil {
+			c.sendError(err.Error())
+			c.stopSession()
+			return true
+		}
+	}
+
+	if recording := c.session.recording; recording != nil && recording.err == nil {
+		if err := recording.write(chunk); err != nil {
+			zerolog.Ctx(c.session.ctx).Error().Err(err).Msg("Failed to write recording")
+			recording.err = err
+			c.sendError("Recording failed, continuing without saving audio")
+		}
+	}
+
+	select {
+	case c.session.audio <- chunk:
+		return true
+	case <-c.ctx.Done():
+		return false
+	}
+}
+
+// forwardResults relays the results of a session's pipeline to the client
+func (c *TranscribeClient) forwardResults(session *transcribeSession, results <-chan service.TranscribeStreamResult) {
+	defer close(session.done)
+	defer c.recordUsage(session)
+
+	for result := range results {
+		if result.Type == MessageTypeError {
+			zerolog.Ctx(session.ctx).Error().Str("error", result.Text).Msg("Transcription error")
+			result.Text = "Transcription failed"
+		}
+
+		if result.Type == MessageTypeFinal && session.recording != nil {
+			session.recording.addSegment(result)
+		}
+
+		msg := TranscribeMessage{Type: result.Type, Text: result.Text}
+		if result.Type != MessageTypeError {
+			msg.Segment = &result.Segment
+			msg.StartMs = &result.StartMs
+			// Speech hasn't ended when it starts
+			if result.Type != MessageTypeSpeechStart {
+				msg.EndMs = &result.EndMs
+			}
+		}
+		c.sendTranscribeMessage(msg)
+	}
+}
+
+// checkQuota returns an error for the client if it has used up its
+// transcription quota
+func (c *TranscribeClient) checkQuota() error {
+	err := c.hub.options.Meter.Check(c.ctx, c.quotaKey, ratelimit.TranscriptionMinutes)
+	var quotaErr *ratelimit.QuotaError
+	if err == nil || errors.As(err, &quotaErr) {
+		return err
+	}
+	zerolog.Ctx(c.ctx).Error().Err(err).Msg("Failed to check transcription quota")
+	return fmt.Errorf("failed to check usage")
+}
+
+// meterSession records the audio the session transcribed since it was last
+// metered and returns a *ratelimit.QuotaError if the client's quota is now used
+// up. Failures to check the quota are logged and let the session continue.
+func (c *TranscribeClient) meterSession(session *transcribeSession) error {
+	session.meteredAt = time.Now()
+	c.recordUsage(session)
+
+	err := c.checkQuota()
+	var quotaErr *ratelimit.QuotaError
+	if errors.As(err, &quotaErr) {
+		return err
+	}
+	return nil
+}
+
+// recordUsage records the audio the session transcribed that isn't yet
+// counted against the client's quota. It is called while the session streams
+// and once more when its pipeline finishes.
+func (c *TranscribeClient) recordUsage(session *transcribeSession) {
+	session.meterMutex.Lock()
+	defer session.meterMutex.Unlock()
+
+	audio := session.usage.Audio()
+	minutes := (audio - session.metered).Minutes()
+	session.metered = audio
+	if err := c.hub.options.Meter.Record(context.WithoutCancel(session.ctx), c.quotaKey, ratelimit.TranscriptionMinutes, minutes); err != nil {
+		zerolog.Ctx(session.ctx).Error().Err(err).Msg("Failed to record transcription usage")
+	}
+}
+
+// sendError sends an error message to the client
+func (c *TranscribeClient) sendError(text string) {
+	c.sendTranscribeMessage(TranscribeMessage{Type: MessageTypeError, Text: text})
+}
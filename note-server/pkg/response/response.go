@@ -1,37 +1,172 @@
+// Package response writes the JSON envelope every API response shares:
+//
+//	{"success": true, "data": {...}, "requestId": "..."}
+//	{"success": false, "error": "Recording not found", "code": "not_found", "requestId": "..."}
+//
+// Errors carry a message for people and a Code for programs, and validation
+// failures list the fields at fault. Clients that prefer RFC 7807 problem
+// details ask for them with "Accept: application/problem+json".
 package response
 
 import (
 	"encoding/json"
 	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5/middleware"
 )
 
-// JSONResponse represents a standard JSON response
-type JSONResponse struct {
-	Success bool   `json:"success"`
-	Message string `json:"message,omitempty"`
-	Data    any    `json:"data,omitempty"`
-	Error   string `json:"error,omitempty"`
+// ContentTypeProblem is the media type of RFC 7807 problem details
+const ContentTypeProblem = "application/problem+json"
+
+// Code identifies the kind of error independently of its message
+type Code string
+
+// Error codes
+const (
+	CodeBadRequest       Code = "bad_request"
+	CodeValidation       Code = "validation_failed"
+	CodeUnauthorized     Code = "unauthorized"
+	CodeForbidden        Code = "forbidden"
+	CodeNotFound         Code = "not_found"
+	CodeMethodNotAllowed Code = "method_not_allowed"
+	CodeConflict         Code = "conflict"
+	CodeRateLimited      Code = "rate_limited"
+	CodeInternal         Code = "internal_error"
+	CodeUnavailable      Code = "unavailable"
+)
+
+// codeForStatus returns the code of errors written with only a status
+func codeForStatus(status int) Code {
+	switch status {
+	case http.StatusBadRequest:
+		return CodeBadRequest
+	case http.StatusUnauthorized:
+		return CodeUnauthorized
+	case http.StatusForbidden:
+		return CodeForbidden
+	case http.StatusNotFound:
+		return CodeNotFound
+	case http.StatusMethodNotAllowed:
+		return CodeMethodNotAllowed
+	case http.StatusConflict:
+		return CodeConflict
+	case http.StatusTooManyRequests:
+		return CodeRateLimited
+	case http.StatusServiceUnavailable:
+		return CodeUnavailable
+	}
+	if status >= http.StatusInternalServerError {
+		return CodeInternal
+	}
+	return CodeBadRequest
 }
 
-// WriteJSONResponse writes a JSON response to the HTTP response writer
-func WriteJSONResponse(w http.ResponseWriter, statusCode int, response JSONResponse) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(statusCode)
-	json.NewEncoder(w).Encode(response)
+// FieldError describes why one field of a request was rejected
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
 }
 
-// WriteJSONError writes a JSON error response
-func WriteJSONError(w http.ResponseWriter, statusCode int, message string) {
-	WriteJSONResponse(w, statusCode, JSONResponse{
-		Success: false,
-		Error:   message,
-	})
+// Envelope is the body of every JSON response. Successful responses carry
+// Data; failed ones carry Error, Code and, for validation failures, Fields.
+type Envelope struct {
+	Success   bool         `json:"success"`
+	Data      any          `json:"data,omitempty"`
+	Message   string       `json:"message,omitempty"`
+	Error     string       `json:"error,omitempty"`
+	Code      Code         `json:"code,omitempty"`
+	Fields    []FieldError `json:"fields,omitempty"`
+	RequestID string       `json:"requestId,omitempty"`
 }
 
-// WriteJSONSuccess writes a JSON success response
-func WriteJSONSuccess(w http.ResponseWriter, data any) {
-	WriteJSONResponse(w, http.StatusOK, JSONResponse{
-		Success: true,
-		Data:    data,
+// Problem is an error as RFC 7807 problem details, with the envelope's code,
+// fields and request ID as extension members
+type Problem struct {
+	Type      string       `json:"type"`
+	Title     string       `json:"title"`
+	Status    int          `json:"status"`
+	Detail    string       `json:"detail,omitempty"`
+	Instance  string       `json:"instance,omitempty"`
+	Code      Code         `json:"code"`
+	Fields    []FieldError `json:"fields,omitempty"`
+	RequestID string       `json:"requestId,omitempty"`
+}
+
+// Write writes envelope with status, adding the request's ID
+func Write(w http.ResponseWriter, r *http.Request, status int, envelope Envelope) {
+	envelope.RequestID = middleware.GetReqID(r.Context())
+	writeJSON(w, status, "application/json", envelope)
+}
+
+// OK writes data in a successful response
+func OK(w http.ResponseWriter, r *http.Request, data any) {
+	Write(w, r, http.StatusOK, Envelope{Success: true, Data: data})
+}
+
+// Created writes data, the resource a request created, with a 201
+func Created(w http.ResponseWriter, r *http.Request, data any) {
+	Write(w, r, http.StatusCreated, Envelope{Success: true, Data: data})
+}
+
+// Error writes an error response with the code matching status
+func Error(w http.ResponseWriter, r *http.Request, status int, message string) {
+	ErrorCode(w, r, status, codeForStatus(status), message)
+}
+
+// ErrorCode writes an error response with a specific code
+func ErrorCode(w http.ResponseWriter, r *http.Request, status int, code Code, message string) {
+	writeError(w, r, status, code, message, nil)
+}
+
+// Invalid writes a 400 for a request that failed validation, listing the
+// fields at fault
+func Invalid(w http.ResponseWriter, r *http.Request, message string, fields ...FieldError) {
+	writeError(w, r, http.StatusBadRequest, CodeValidation, message, fields)
+}
+
+// writeError writes an error as an envelope, or as problem details if the
+// client asked for them
+func writeError(w http.ResponseWriter, r *http.Request, status int, code Code, message string, fields []FieldError) {
+	requestID := middleware.GetReqID(r.Context())
+	if !wantsProblem(r) {
+		writeJSON(w, status, "application/json", Envelope{
+			Error:     message,
+			Code:      code,
+			Fields:    fields,
+			RequestID: requestID,
+		})
+		return
+	}
+
+	writeJSON(w, status, ContentTypeProblem, Problem{
+		Type:      "about:blank",
+		Title:     http.StatusText(status),
+		Status:    status,
+		Detail:    message,
+		Instance:  r.URL.Path,
+		Code:      code,
+		Fields:    fields,
+		RequestID: requestID,
 	})
 }
+
+// wantsProblem reports whether the request's Accept header lists problem details
+func wantsProblem(r *http.Request) bool {
+	for _, accept := range r.Header.Values("Accept") {
+		for _, mediaType := range strings.Split(accept, ",") {
+			mediaType, _, _ = strings.Cut(mediaType, ";")
+			if strings.EqualFold(strings.TrimSpace(mediaType), ContentTypeProblem) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// writeJSON writes body as JSON with status and contentType
+func writeJSON(w http.ResponseWriter, status int, contentType string, body any) {
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
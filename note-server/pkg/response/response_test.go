@@ -1,127 +1,147 @@
 package response
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+
+	"github.com/go-chi/chi/v5/middleware"
 )
 
-func TestWriteJSONResponse(t *testing.T) {
-	tests := []struct {
-		name       string
-		statusCode int
-		response   JSONResponse
-		wantStatus int
-		wantBody   string
-	}{
-		{
-			name:       "success response",
-			statusCode: http.StatusOK,
-			response: JSONResponse{
-				Success: true,
-				Data:    "test data",
-			},
-			wantStatus: http.StatusOK,
-			wantBody:   `{"success":true,"data":"test data"}`,
-		},
-		{
-			name:       "error response",
-			statusCode: http.StatusBadRequest,
-			response: JSONResponse{
-				Success: false,
-				Error:   "test error",
-			},
-			wantStatus: http.StatusBadRequest,
-			wantBody:   `{"success":false,"error":"test error"}`,
-		},
+// newRequest returns a request carrying a request ID, as middleware.RequestID sets
+func newRequest(accept string) *http.Request {
+	r := httptest.NewRequest(http.MethodGet, "/api/recordings/7", nil)
+	if accept != "" {
+		r.Header.Set("Accept", accept)
 	}
+	return r.WithContext(context.WithValue(r.Context(), middleware.RequestIDKey, "req-1"))
+}
 
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			w := httptest.NewRecorder()
-			WriteJSONResponse(w, tt.statusCode, tt.response)
-
-			if w.Code != tt.wantStatus {
-				t.Errorf("WriteJSONResponse() status = %v, want %v", w.Code, tt.wantStatus)
-			}
-
-			contentType := w.Header().Get("Content-Type")
-			if contentType != "application/json" {
-				t.Errorf("WriteJSONResponse() Content-Type = %v, want %v", contentType, "application/json")
-			}
-
-			body := w.Body.String()
-			// Parse JSON to ensure it's valid and compare
-			var got, want map[string]any
-			if err := json.Unmarshal([]byte(body), &got); err != nil {
-				t.Fatalf("WriteJSONResponse() returned invalid JSON: %v", err)
-			}
-			if err := json.Unmarshal([]byte(tt.wantBody), &want); err != nil {
-				t.Fatalf("Test case has invalid JSON: %v", err)
-			}
+// decode parses the response body, failing the test if it isn't JSON
+func decode(t *testing.T, w *httptest.ResponseRecorder) map[string]any {
+	t.Helper()
+	var body map[string]any
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("invalid JSON %q: %v", w.Body.String(), err)
+	}
+	return body
+}
 
-			if len(got) != len(want) {
-				t.Errorf("WriteJSONResponse() body = %v, want %v", body, tt.wantBody)
-				return
-			}
+func TestOK(t *testing.T) {
+	w := httptest.NewRecorder()
+	OK(w, newRequest(""), map[string]any{"recordings": []int{1}})
 
-			for key, wantVal := range want {
-				if gotVal, ok := got[key]; !ok || gotVal != wantVal {
-					t.Errorf("WriteJSONResponse() body[%s] = %v, want %v", key, gotVal, wantVal)
-				}
-			}
-		})
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "application/json" {
+		t.Errorf("OK() = %d %q, want 200 application/json", w.Code, w.Header().Get("Content-Type"))
+	}
+	body := decode(t, w)
+	if body["success"] != true || body["requestId"] != "req-1" || body["error"] != nil {
+		t.Errorf("OK() body = %v", body)
+	}
+	if data, _ := body["data"].(map[string]any); data["recordings"] == nil {
+		t.Errorf("OK() data = %v, want the recordings", body["data"])
 	}
 }
 
-func TestWriteJSONError(t *testing.T) {
+func TestCreated(t *testing.T) {
 	w := httptest.NewRecorder()
-	WriteJSONError(w, http.StatusNotFound, "not found")
+	Created(w, newRequest(""), map[string]any{"id": 1})
 
-	if w.Code != http.StatusNotFound {
-		t.Errorf("WriteJSONError() status = %v, want %v", w.Code, http.StatusNotFound)
+	if w.Code != http.StatusCreated {
+		t.Errorf("Created() status = %d, want 201", w.Code)
 	}
-
-	var response JSONResponse
-	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
-		t.Fatalf("WriteJSONError() returned invalid JSON: %v", err)
+	if body := decode(t, w); body["success"] != true {
+		t.Errorf("Created() body = %v", body)
 	}
+}
 
-	if response.Success != false {
-		t.Errorf("WriteJSONError() success = %v, want %v", response.Success, false)
+func TestError(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		code   Code
+	}{
+		{"bad request", http.StatusBadRequest, CodeBadRequest},
+		{"unauthorized", http.StatusUnauthorized, CodeUnauthorized},
+		{"forbidden", http.StatusForbidden, CodeForbidden},
+		{"not found", http.StatusNotFound, CodeNotFound},
+		{"method not allowed", http.StatusMethodNotAllowed, CodeMethodNotAllowed},
+		{"conflict", http.StatusConflict, CodeConflict},
+		{"too many requests", http.StatusTooManyRequests, CodeRateLimited},
+		{"internal", http.StatusInternalServerError, CodeInternal},
+		{"unavailable", http.StatusServiceUnavailable, CodeUnavailable},
+		{"other server error", http.StatusBadGateway, CodeInternal},
 	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			Error(w, newRequest(""), tt.status, "Something went wrong")
 
-	if response.Error != "not found" {
-		t.Errorf("WriteJSONError() error = %v, want %v", response.Error, "not found")
+			if w.Code != tt.status {
+				t.Errorf("Error() status = %d, want %d", w.Code, tt.status)
+			}
+			body := decode(t, w)
+			if body["success"] != false || body["error"] != "Something went wrong" || body["code"] != string(tt.code) {
+				t.Errorf("Error() body = %v, want code %s", body, tt.code)
+			}
+			if body["requestId"] != "req-1" {
+				t.Errorf("Error() requestId = %v, want req-1", body["requestId"])
+			}
+		})
 	}
 }
 
-func TestWriteJSONSuccess(t *testing.T) {
+func TestInvalid(t *testing.T) {
 	w := httptest.NewRecorder()
-	testData := map[string]string{"key": "value"}
-	WriteJSONSuccess(w, testData)
+	Invalid(w, newRequest(""), "Token name is required", FieldError{Field: "name", Message: "is required"})
 
-	if w.Code != http.StatusOK {
-		t.Errorf("WriteJSONSuccess() status = %v, want %v", w.Code, http.StatusOK)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Invalid() status = %d, want 400", w.Code)
 	}
-
-	var response JSONResponse
-	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
-		t.Fatalf("WriteJSONSuccess() returned invalid JSON: %v", err)
+	body := decode(t, w)
+	fields, _ := body["fields"].([]any)
+	if body["code"] != string(CodeValidation) || len(fields) != 1 {
+		t.Fatalf("Invalid() body = %v", body)
 	}
-
-	if response.Success != true {
-		t.Errorf("WriteJSONSuccess() success = %v, want %v", response.Success, true)
+	if field := fields[0].(map[string]any); field["field"] != "name" || field["message"] != "is required" {
+		t.Errorf("Invalid() field = %v", field)
 	}
+}
 
-	// Convert data back to map for comparison
-	dataMap, ok := response.Data.(map[string]any)
-	if !ok {
-		t.Errorf("WriteJSONSuccess() data is not a map")
+func TestProblemDetails(t *testing.T) {
+	tests := []struct {
+		name    string
+		accept  string
+		problem bool
+	}{
+		{"no Accept header", "", false},
+		{"JSON", "application/json", false},
+		{"problem details", "application/problem+json", true},
+		{"problem details among others", "application/json;q=0.9, application/problem+json", true},
 	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			Invalid(w, newRequest(tt.accept), "Invalid request", FieldError{Field: "role", Message: "is required"})
 
-	if dataMap["key"] != "value" {
-		t.Errorf("WriteJSONSuccess() data = %v, want %v", dataMap, testData)
+			if got := w.Header().Get("Content-Type") == ContentTypeProblem; got != tt.problem {
+				t.Fatalf("Content-Type = %q, want problem details %v", w.Header().Get("Content-Type"), tt.problem)
+			}
+			if !tt.problem {
+				return
+			}
+			body := decode(t, w)
+			if body["type"] != "about:blank" || body["title"] != "Bad Request" || body["status"] != float64(http.StatusBadRequest) {
+				t.Errorf("problem = %v", body)
+			}
+			if body["detail"] != "Invalid request" || body["instance"] != "/api/recordings/7" || body["code"] != string(CodeValidation) {
+				t.Errorf("problem = %v", body)
+			}
+			if body["requestId"] != "req-1" || body["fields"] == nil {
+				t.Errorf("problem = %v, want the request ID and fields", body)
+			}
+		})
 	}
 }
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"log"
 	"os"
 	"path/filepath"
@@ -28,10 +29,10 @@ func main() {
 
 	log.Printf("Database initialized at: %s", dbPath)
 // Add sample recordings
-	if _, err := database.AddRecording("test1.wav", "/path/to/test1.wav", time.Now(), time.Now().Add(1*time.Hour), 3600, 1024, "wav", 44100, 2); err != nil {
+	if _, err := database.AddRecording(context.Background(), 0, "test1.wav", "/path/to/test1.wav", time.Now(), time.Now().Add(1*time.Hour), 3600, 1024, "wav", 44100, 2); err != nil {
 		log.Fatalf("Failed to add recording: %v", err)
 	}
-	if _, err := database.AddRecording("test2.wav", "/path/to/test2.wav", time.Now(), time.Now().Add(1*time.Hour), 3600, 2048, "wav", 44100, 2); err != nil {
+	if _, err := database.AddRecording(context.Background(), 0, "test2.wav", "/path/to/test2.wav", time.Now(), time.Now().Add(1*time.Hour), 3600, 2048, "wav", 44100, 2); err != nil {
 		log.Fatalf("Failed to add recording: %v", err)
 	}
 	log.Println("Sample recordings added!")
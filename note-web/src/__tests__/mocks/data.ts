@@ -1,4 +1,4 @@
-import { Note, Meeting, Interview, Recording } from '@/lib/database'
+import type { Note, Meeting, Interview, Recording } from '@/lib/types'
 import { AppConfig } from '@/lib/config'
 
 export const mockNote: Note = {
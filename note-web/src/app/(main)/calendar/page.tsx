@@ -1,11 +1,11 @@
-import { getAllRecordings } from '@/lib/database';
+import { getRecordings } from '@/lib/noteServer';
 import WeekCalendar from '@/components/WeekCalendar';
 import { Card, CardContent } from '@/components/ui/card';
 import { Calendar } from 'lucide-react';
 
-export default function CalendarPage() {
-  // Get all recordings and convert to calendar events
-  const recordings = getAllRecordings();
+export default async function CalendarPage() {
+  // Get the caller's recordings and convert to calendar events
+  const recordings = await getRecordings();
   
   const events = recordings.map(recording => {
     const start = new Date(recording.start_time);
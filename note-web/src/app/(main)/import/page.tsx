@@ -4,7 +4,7 @@ import { useState, useEffect } from 'react';
 import Link from 'next/link';
 // Removed server action import - now using client-side API calls
 import { formatTime, formatDuration } from '@/lib/dateUtils';
-import type { Recording } from '@/lib/database';
+import type { Recording } from '@/lib/types';
 import { Card, CardContent } from '@/components/ui/card';
 import { Badge } from '@/components/ui/badge';
 import {
@@ -27,7 +27,7 @@ export default function ImportPage() {
       const response = await fetch('/api/recordings');
       if (response.ok) {
         const result = await response.json();
-        setRecordings(result.success ? result.recordings || [] : []);
+        setRecordings(result.success ? result.data.recordings || [] : []);
       } else {
         throw new Error(`Failed to fetch recordings: ${response.status}`);
       }
@@ -43,9 +43,9 @@ export default function ImportPage() {
       const response = await fetch(`/api/recordings/${recordingId}`);
       if (response.ok) {
         const result = await response.json();
-        if (result.success && result.recording) {
+        if (result.success && result.data?.recording) {
           // Add the new recording to the top of the list
-          setRecordings(prev => [result.recording, ...prev]);
+          setRecordings(prev => [result.data.recording, ...prev]);
         }
       }
     } catch (error) {
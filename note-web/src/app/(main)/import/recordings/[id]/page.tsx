@@ -10,7 +10,7 @@ import { Button } from '@/components/ui/button';
 import { Skeleton } from '@/components/ui/skeleton';
 import AudioPlayer from '@/components/AudioPlayer';
 import CopyLinkButton from '@/components/CopyLinkButton';
-import type { Recording } from '@/lib/database';
+import type { Recording } from '@/lib/types';
 
 export default function RecordingDetailsPage() {
   const params = useParams();
@@ -32,8 +32,8 @@ export default function RecordingDetailsPage() {
           throw new Error('Recording not found');
         }
         const result = await response.json();
-        if (result.success && result.recording) {
-          setRecording(result.recording);
+        if (result.success && result.data?.recording) {
+          setRecording(result.data.recording);
         } else {
           throw new Error('Invalid response format');
         }
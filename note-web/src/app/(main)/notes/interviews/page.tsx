@@ -1,10 +1,10 @@
-import { getAllInterviews } from '@/lib/database';
+import { getInterviews } from '@/lib/noteServer';
 import InterviewCard from '@/components/InterviewCard';
 import { Card, CardContent } from '@/components/ui/card';
 import { Users } from 'lucide-react';
 
-export default function InterviewsPage() {
-  const interviews = getAllInterviews();
+export default async function InterviewsPage() {
+  const interviews = await getInterviews();
 
   return (
     <div>
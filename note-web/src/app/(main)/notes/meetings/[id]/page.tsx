@@ -1,4 +1,4 @@
-import { getMeetingById } from '@/lib/database';
+import { getMeeting } from '@/lib/noteServer';
 import Link from 'next/link';
 import { notFound } from 'next/navigation';
 import { MarkdownRenderer } from '@/components/MarkdownRenderer';
@@ -18,7 +18,7 @@ export default async function MeetingPage({ params }: MeetingPageProps) {
     notFound();
   }
 
-  const meeting = getMeetingById(meetingId);
+  const meeting = await getMeeting(meetingId);
 
   if (!meeting) {
     notFound();
@@ -205,7 +205,7 @@ export async function generateMetadata({ params }: MeetingPageProps) {
     };
   }
 
-  const meeting = getMeetingById(meetingId);
+  const meeting = await getMeeting(meetingId);
 
   if (!meeting) {
     return {
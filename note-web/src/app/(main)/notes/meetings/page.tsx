@@ -1,10 +1,10 @@
-import { getAllMeetings } from '@/lib/database';
+import { getMeetings } from '@/lib/noteServer';
 import MeetingCard from '@/components/MeetingCard';
 import { Users } from 'lucide-react';
 import { Card, CardContent } from '@/components/ui/card';
 
-export default function MeetingsPage() {
-  const meetings = getAllMeetings();
+export default async function MeetingsPage() {
+  const meetings = await getMeetings();
 
   return (
     <div>
@@ -1,4 +1,4 @@
-import { getAllNotes, getAllMeetings, getAllInterviews } from '@/lib/database';
+import { getNotes, getMeetings, getInterviews } from '@/lib/noteServer';
 import NoteCard from '@/components/NoteCard';
 import MeetingCard from '@/components/MeetingCard';
 import InterviewCard from '@/components/InterviewCard';
@@ -6,10 +6,8 @@ import { Card, CardContent } from '@/components/ui/card';
 import { FileText } from 'lucide-react';
 import Link from 'next/link';
 
-export default function NotesPage() {
-  const notes = getAllNotes();
-  const meetings = getAllMeetings();
-  const interviews = getAllInterviews();
+export default async function NotesPage() {
+  const [notes, meetings, interviews] = await Promise.all([getNotes(), getMeetings(), getInterviews()]);
   const totalItems = notes.length + meetings.length + interviews.length;
 
   return (
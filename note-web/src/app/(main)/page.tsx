@@ -1,17 +1,31 @@
 import Link from 'next/link';
-import { getDashboardStats, getRecentNotes, getRecentMeetings, getRecentInterviews, getRecentRecordings } from '@/lib/database';
+import { getNotes, getMeetings, getInterviews, getRecordings } from '@/lib/noteServer';
 import NoteCard from '@/components/NoteCard';
 import MeetingCard from '@/components/MeetingCard';
 import { Users, Mic, Briefcase, FileText } from 'lucide-react';
 import { Card, CardContent, CardHeader, CardTitle } from '@/components/ui/card';
 import { Badge } from '@/components/ui/badge';
 
-export default function Home() {
-  const stats = getDashboardStats();
-  const recentNotes = getRecentNotes(3);
-  const recentMeetings = getRecentMeetings(3);
-  const recentInterviews = getRecentInterviews(3);
-  const recentRecordings = getRecentRecordings(3);
+export default async function Home() {
+  const [notes, meetings, interviews, recordings] = await Promise.all([
+    getNotes(),
+    getMeetings(),
+    getInterviews(),
+    getRecordings(),
+  ]);
+
+  const totalDuration = recordings.reduce((total, recording) => total + recording.duration, 0);
+  const stats = {
+    notes: notes.length,
+    meetings: meetings.length,
+    interviews: interviews.length,
+    recordings: recordings.length,
+    totalDurationMinutes: Math.round((totalDuration / (1000 * 1000 * 1000)) / 60),
+  };
+  const recentNotes = notes.slice(0, 3);
+  const recentMeetings = meetings.slice(0, 3);
+  const recentInterviews = interviews.slice(0, 3);
+  const recentRecordings = recordings.slice(0, 3);
 
   const StatCard = ({ title, value, description, icon: Icon, href }: {
     title: string;
@@ -46,8 +46,8 @@ export default function AISettingsPage() {
       const response = await fetch('/api/config');
       const data = await response.json();
       if (data.success) {
-        setConfig(data.config);
-        setFormData(data.config);
+        setConfig(data.data.config);
+        setFormData(data.data.config);
       } else {
         setConfig(null);
       }
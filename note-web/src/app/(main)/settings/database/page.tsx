@@ -37,7 +37,7 @@ export default function DatabaseSettingsPage() {
       const response = await fetch('/api/config');
       const data = await response.json();
       if (data.success) {
-        setConfig(data.config);
+        setConfig(data.data.config);
       } else {
         setConfig(null);
       }
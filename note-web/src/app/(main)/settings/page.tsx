@@ -30,8 +30,8 @@ export default function GeneralSettingsPage() {
       const data = await response.json();
       
       if (data.success) {
-        setConfig(data.config);
-        setFormData(data.config);
+        setConfig(data.data.config);
+        setFormData(data.data.config);
         setConfigExists(true);
       } else if (response.status === 404 && data.error === 'Configuration file not found') {
         // Config file doesn't exist - this is a setup scenario, not an error
@@ -460,8 +460,9 @@ export default function GeneralSettingsPage() {
                                 check.status === 'missing' ? 'bg-red-100 dark:bg-red-900 text-red-800 dark:text-red-200' :
                                 'bg-yellow-100 dark:bg-yellow-900 text-yellow-800 dark:text-yellow-200'
                               }`}>
-                                {check.status === 'ok' ? 'Installed' :
+                                {check.status === 'ok' ? (check.version ? 'Installed' : 'OK') :
                                  check.status === 'missing' ? 'Missing' :
+                                 check.status === 'warn' ? 'Warning' :
                                  'Error'}
                               </span>
                               {check.status === 'missing' && canInstallDependency(check.name) && (
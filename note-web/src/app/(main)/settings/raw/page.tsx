@@ -18,7 +18,7 @@ export default function RawConfigPage() {
       if (response.ok) {
         const data = await response.json();
         if (data.success) {
-          setConfig(JSON.stringify(data.config, null, 2));
+          setConfig(JSON.stringify(data.data.config, null, 2));
           setError(null);
         } else {
           setError(data.error || 'Failed to load configuration');
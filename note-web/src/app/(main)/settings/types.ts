@@ -18,7 +18,7 @@ export type Config = {
 
 export interface HealthCheck {
   name: string;
-  status: 'ok' | 'missing' | 'error';
+  status: 'ok' | 'warn' | 'missing' | 'error';
   version?: string;
   error?: string;
 }
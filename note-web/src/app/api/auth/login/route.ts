@@ -0,0 +1,31 @@
+import { NextRequest, NextResponse } from 'next/server';
+
+// Log in to the Go server. Its note_session cookie is passed on to the
+// browser, so later calls to the other API routes carry it back to the server.
+export async function POST(request: NextRequest) {
+  try {
+    const credentials = await request.json();
+
+    const response = await fetch(`${process.env.NOTE_SERVER_URL || 'http://localhost:8080'}/api/v1/auth/login`, {
+      method: 'POST',
+      headers: {
+        'Content-Type': 'application/json',
+      },
+      body: JSON.stringify(credentials),
+    });
+
+    const result = await response.json();
+    const res = NextResponse.json(result, { status: response.status });
+    const cookie = response.headers.get('set-cookie');
+    if (cookie) {
+      res.headers.set('Set-Cookie', cookie);
+    }
+    return res;
+  } catch (error) {
+    console.error('Error logging in:', error);
+    return NextResponse.json(
+      { success: false, error: 'Failed to log in' },
+      { status: 500 }
+    );
+  }
+}
@@ -0,0 +1,26 @@
+import { NextRequest, NextResponse } from 'next/server';
+import { serverHeaders } from '@/lib/noteServer';
+
+// Log out of the Go server, which ends the session and clears its cookie
+export async function POST(request: NextRequest) {
+  try {
+    const response = await fetch(`${process.env.NOTE_SERVER_URL || 'http://localhost:8080'}/api/v1/auth/logout`, {
+      method: 'POST',
+      headers: serverHeaders(request),
+    });
+
+    const result = await response.json();
+    const res = NextResponse.json(result, { status: response.status });
+    const cookie = response.headers.get('set-cookie');
+    if (cookie) {
+      res.headers.set('Set-Cookie', cookie);
+    }
+    return res;
+  } catch (error) {
+    console.error('Error logging out:', error);
+    return NextResponse.json(
+      { success: false, error: 'Failed to log out' },
+      { status: 500 }
+    );
+  }
+}
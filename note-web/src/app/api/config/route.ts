@@ -1,4 +1,5 @@
 import { NextRequest, NextResponse } from 'next/server';
+import { serverHeaders } from '@/lib/noteServer';
 
 // Get the server URL from environment or default to localhost
 function getServerUrl(): string {
@@ -11,11 +12,11 @@ export async function PUT(request: NextRequest) {
     const updatedConfig = await request.json();
     
     // Proxy the request to the server
-    const response = await fetch(`${serverUrl}/api/config`, {
+    const response = await fetch(`${serverUrl}/api/v1/config`, {
       method: 'PUT',
-      headers: {
+      headers: serverHeaders(request, {
         'Content-Type': 'application/json',
-      },
+      }),
       body: JSON.stringify(updatedConfig),
     });
     
@@ -38,16 +39,16 @@ export async function PUT(request: NextRequest) {
   }
 }
 
-export async function GET() {
+export async function GET(request: NextRequest) {
   try {
     const serverUrl = getServerUrl();
     
     // Proxy the request to the server
-    const response = await fetch(`${serverUrl}/api/config`, {
+    const response = await fetch(`${serverUrl}/api/v1/config`, {
       method: 'GET',
-      headers: {
+      headers: serverHeaders(request, {
         'Content-Type': 'application/json',
-      },
+      }),
     });
     
     if (!response.ok) {
@@ -1,4 +1,5 @@
 import { NextRequest, NextResponse } from 'next/server';
+import { serverHeaders } from '@/lib/noteServer';
 
 export async function GET(
   request: NextRequest,
@@ -8,11 +9,11 @@ export async function GET(
     const { id } = await params;
     
     // Forward request to the Go server
-    const response = await fetch(`${process.env.NOTE_SERVER_URL || 'http://localhost:8080'}/api/meetings/${id}`, {
+    const response = await fetch(`${process.env.NOTE_SERVER_URL || 'http://localhost:8080'}/api/v1/meetings/${id}`, {
       method: 'GET',
-      headers: {
+      headers: serverHeaders(request, {
         'Content-Type': 'application/json',
-      },
+      }),
     });
 
     if (!response.ok) {
@@ -1,13 +1,14 @@
-import { NextResponse } from 'next/server';
+import { NextRequest, NextResponse } from 'next/server';
+import { serverHeaders } from '@/lib/noteServer';
 
-export async function GET() {
+export async function GET(request: NextRequest) {
   try {
     // Forward request to the Go server
-    const response = await fetch(`${process.env.NOTE_SERVER_URL || 'http://localhost:8080'}/api/meetings`, {
+    const response = await fetch(`${process.env.NOTE_SERVER_URL || 'http://localhost:8080'}/api/v1/meetings`, {
       method: 'GET',
-      headers: {
+      headers: serverHeaders(request, {
         'Content-Type': 'application/json',
-      },
+      }),
     });
 
     if (!response.ok) {
@@ -1,4 +1,5 @@
 import { NextRequest, NextResponse } from 'next/server';
+import { serverHeaders } from '@/lib/noteServer';
 
 export async function GET(
   request: NextRequest,
@@ -8,8 +9,9 @@ export async function GET(
     const { id } = await params;
     
     // Forward audio streaming request to the Go server
-    const response = await fetch(`${process.env.NOTE_SERVER_URL || 'http://localhost:8080'}/api/recordings/${id}/audio`, {
+    const response = await fetch(`${process.env.NOTE_SERVER_URL || 'http://localhost:8080'}/api/v1/recordings/${id}/audio`, {
       method: 'GET',
+      headers: serverHeaders(request),
     });
 
     if (!response.ok) {
@@ -30,7 +32,8 @@ export async function GET(
     headers.set('Content-Type', response.headers.get('Content-Type') || 'audio/mpeg');
     headers.set('Content-Length', response.headers.get('Content-Length') || audioBuffer.byteLength.toString());
     headers.set('Accept-Ranges', 'bytes');
-    headers.set('Cache-Control', 'public, max-age=3600');
+    // The audio is private to the caller; never let a cache keep it
+    headers.set('Cache-Control', response.headers.get('Cache-Control') || 'private, no-store');
     
     if (response.headers.get('Content-Disposition')) {
       headers.set('Content-Disposition', response.headers.get('Content-Disposition')!);
@@ -1,25 +1,72 @@
-import { NextResponse } from 'next/server';
+import { NextRequest, NextResponse } from 'next/server';
 import { exec } from 'child_process';
 import { promisify } from 'util';
+import { serverHeaders } from '@/lib/noteServer';
 
 const execAsync = promisify(exec);
 
 interface HealthCheck {
   name: string;
-  status: 'ok' | 'missing' | 'error';
+  status: 'ok' | 'warn' | 'missing' | 'error';
   version?: string;
   error?: string;
 }
 
+// Display names for the server's checks; FFmpeg and FFprobe keep the names
+// the install buttons look for
+const serverCheckNames: Record<string, string> = {
+  database: 'Database',
+  media_dir: 'Media directory',
+  ffmpeg: 'FFmpeg',
+  ffprobe: 'FFprobe',
+  providers: 'AI providers',
+  transcribe_hub: 'Live transcription',
+};
+
+// The Go server checks its own dependencies; this reports them, or a single
+// failed check when the server can't be reached
+async function checkServer(request: NextRequest): Promise<HealthCheck[]> {
+  try {
+    const response = await fetch(`${process.env.NOTE_SERVER_URL || 'http://localhost:8080'}/api/v1/system/health`, {
+      method: 'GET',
+      headers: serverHeaders(request, {
+        'Content-Type': 'application/json',
+      }),
+      cache: 'no-store',
+    });
+
+    if (!response.ok) {
+      throw new Error(`Server responded with ${response.status}`);
+    }
+
+    const data = await response.json();
+    return (data.data.checks as HealthCheck[]).map(check => ({
+      name: serverCheckNames[check.name] || check.name,
+      status: check.status,
+      version: check.version,
+      error: check.error,
+    }));
+  } catch (error: unknown) {
+    const err = error as { message?: string };
+    return [{
+      name: 'Note server',
+      status: 'error',
+      error: err.message || 'Unknown error'
+    }];
+  }
+}
+
+// Homebrew and the Google Cloud CLI are only used on this machine, to install
+// dependencies and to sign in to Google
 async function checkCommand(commandName: string, versionCommand: string, name: string): Promise<HealthCheck> {
   try {
     // First check if the command exists using 'which'
     await execAsync(`which ${commandName}`);
-    
+
     // If it exists, get the version
     const { stdout, stderr } = await execAsync(versionCommand);
     const output = stdout.trim() || stderr.trim();
-    
+
     return {
       name,
       status: 'ok',
@@ -27,17 +74,17 @@ async function checkCommand(commandName: string, versionCommand: string, name: s
     };
   } catch (error: unknown) {
     const err = error as { code?: number; message?: string };
-    
+
     // Check if it's a "command not found" error from 'which' or the version command
-    if (err.code === 127 || err.code === 1 || 
-        err.message?.includes('command not found') || 
+    if (err.code === 127 || err.code === 1 ||
+        err.message?.includes('command not found') ||
         err.message?.includes('not found')) {
       return {
         name,
         status: 'missing'
       };
     }
-    
+
     return {
       name,
       status: 'error',
@@ -46,18 +93,17 @@ async function checkCommand(commandName: string, versionCommand: string, name: s
   }
 }
 
-export async function GET() {
+export async function GET(request: NextRequest) {
   try {
-    const checks = await Promise.all([
+    const [brew, server, gcloud] = await Promise.all([
       checkCommand('brew', 'brew --version', 'Homebrew'),
-      checkCommand('ffmpeg', 'ffmpeg -version 2>/dev/null | head -1', 'FFmpeg'),
-      checkCommand('ffprobe', 'ffprobe -version 2>/dev/null | head -1', 'FFprobe'),
+      checkServer(request),
       checkCommand('gcloud', 'gcloud version 2>/dev/null | head -1', 'Google Cloud CLI')
     ]);
 
     return NextResponse.json({
       success: true,
-      checks
+      checks: [brew, ...server, gcloud]
     });
   } catch (error) {
     console.error('System health check failed:', error);
@@ -1,4 +1,5 @@
 import { NextRequest, NextResponse } from 'next/server';
+import { serverHeaders } from '@/lib/noteServer';
 
 export async function POST(request: NextRequest) {
   try {
@@ -18,8 +19,9 @@ export async function POST(request: NextRequest) {
     if (endTimeStr) serverFormData.append('endTime', endTimeStr);
     
     // Forward the upload request to the Go server
-    const response = await fetch(`${process.env.NOTE_SERVER_URL || 'http://localhost:8080'}/api/upload-recording`, {
+    const response = await fetch(`${process.env.NOTE_SERVER_URL || 'http://localhost:8080'}/api/v1/upload-recording`, {
       method: 'POST',
+      headers: serverHeaders(request),
       body: serverFormData,
     });
 
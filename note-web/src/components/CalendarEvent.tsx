@@ -1,4 +1,4 @@
-import { CalendarEvent } from '@/lib/database';
+import type { CalendarEvent } from '@/lib/types';
 import { formatTime, formatDuration } from '@/lib/dateUtils';
 
 interface CalendarEventProps {
@@ -1,4 +1,4 @@
-import { Interview } from '@/lib/database';
+import type { Interview } from '@/lib/types';
 import { Music, Briefcase } from 'lucide-react';
 
 interface InterviewCardProps {
@@ -1,4 +1,4 @@
-import { Meeting } from '@/lib/database';
+import type { Meeting } from '@/lib/types';
 import Link from 'next/link';
 import { Music, Calendar } from 'lucide-react';
 import { Card, CardContent, CardHeader } from '@/components/ui/card';
@@ -85,10 +85,10 @@ export default function NavbarRecorder({ onRecordingComplete }: NavbarRecorderPr
             // Emit a custom event for real-time updates
             const event = new CustomEvent('recordingCompleted', {
               detail: {
-                recordingId: result.recordingId,
-                filename: result.filename,
-                size: result.size,
-                duration: result.duration
+                recordingId: result.data.recordingId,
+                filename: result.data.filename,
+                size: result.data.size,
+                duration: result.data.duration
               }
             });
             window.dispatchEvent(event);
@@ -1,4 +1,4 @@
-import { Note } from '@/lib/database';
+import type { Note } from '@/lib/types';
 import { Music, FileText } from 'lucide-react';
 import { Card, CardContent, CardHeader } from '@/components/ui/card';
 import { Badge } from '@/components/ui/badge';
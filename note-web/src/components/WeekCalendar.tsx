@@ -1,7 +1,7 @@
 'use client';
 
 import { useState } from 'react';
-import { CalendarEvent } from '@/lib/database';
+import type { CalendarEvent } from '@/lib/types';
 import CalendarEventComponent from './CalendarEvent';
 import { ChevronLeft, ChevronRight } from 'lucide-react';
 import {
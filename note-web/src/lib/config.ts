@@ -23,7 +23,7 @@ export async function loadConfig(): Promise<AppConfig | null> {
     const data = await response.json();
     
     if (data.success) {
-      return data.config || data.data?.config || null;
+      return data.data?.config || null;
     }
     return null;
   } catch (error) {
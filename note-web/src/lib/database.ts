@@ -2,73 +2,11 @@ import Database from 'better-sqlite3';
 import path from 'path';
 import os from 'os';
 import fs from 'fs';
+import type { Note, Meeting, Interview, Recording, CalendarEvent } from './types';
 
-export interface Note {
-  id: number;
-  title: string;
-  content: string;
-  summary: string;
-  tags: string;
-  recording_id?: number;
-  created_at: string;
-  updated_at: string;
-}
-
-export interface Meeting {
-  id: number;
-  title: string;
-  content: string;
-  summary: string;
-  attendees: string;
-  location: string;
-  tags: string;
-  recording_id?: number;
-  meeting_date?: string;
-  created_at: string;
-  updated_at: string;
-}
-
-export interface Interview {
-  id: number;
-  title: string;
-  content: string;
-  summary: string;
-  interviewee: string;
-  interviewer: string;
-  company: string;
-  position: string;
-  tags: string;
-  recording_id?: number;
-  interview_date?: string;
-  created_at: string;
-  updated_at: string;
-}
-
-export interface Recording {
-  id: number;
-  filename: string;
-  file_path: string;
-  start_time: string;
-  end_time: string;
-  duration: number;
-  file_size: number;
-  format: string;
-  sample_rate: number;
-  channels: number;
-  created_at: string;
-}
-
-export interface CalendarEvent {
-  id: number;
-  title: string;
-  start: Date;
-  end: Date;
-  type: 'recording' | 'note';
-  duration: number; // in minutes
-  content?: string;
-  tags?: string;
-  filename?: string;
-}
+// The row types live in types.ts so components can use them without pulling
+// in the database driver
+export type { Note, Meeting, Interview, Recording, CalendarEvent } from './types';
 
 // Get the database path (same as CLI: ~/.noteai/notes.db)
 function getDatabasePath(): string {
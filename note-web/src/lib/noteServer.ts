@@ -0,0 +1,89 @@
+import type { NextRequest } from 'next/server';
+import { cookies, headers } from 'next/headers';
+import type { Note, Meeting, Interview, Recording } from './types';
+
+// Cookie the Go server sets when a user logs in
+const SESSION_COOKIE = 'note_session';
+
+// Headers for a request proxied to the Go server, carrying the caller's
+// credentials. Once the server has user accounts it rejects requests without
+// them, so every proxy route must forward the Authorization header and the
+// session cookie it was called with. The caller's address goes along in
+// X-Forwarded-For so the server rate limits each client rather than this
+// proxy.
+export function serverHeaders(request: NextRequest, headers: Record<string, string> = {}): Record<string, string> {
+  return withCredentials(headers, request.headers, request.cookies.get(SESSION_COOKIE)?.value);
+}
+
+// Fetches data from the Go server while rendering a server component, with
+// the credentials of the request being rendered, so pages only show what the
+// server lets the caller see. Resolves to the response's data, or null when
+// the server responds 404.
+export async function fetchServerData<T>(path: string): Promise<T | null> {
+  const session = (await cookies()).get(SESSION_COOKIE)?.value;
+  const response = await fetch(`${process.env.NOTE_SERVER_URL || 'http://localhost:8080'}/api/v1${path}`, {
+    method: 'GET',
+    headers: withCredentials({ 'Content-Type': 'application/json' }, await headers(), session),
+    cache: 'no-store',
+  });
+
+  if (response.status === 404) {
+    return null;
+  }
+  if (!response.ok) {
+    throw new Error(`Server responded with ${response.status}`);
+  }
+
+  const result = await response.json();
+  return result.data as T;
+}
+
+// The notes, meetings, interviews and recordings the caller can see, newest
+// first
+export async function getNotes(): Promise<Note[]> {
+  return (await fetchServerData<{ notes: Note[] | null }>('/notes'))?.notes ?? [];
+}
+
+export async function getMeetings(): Promise<Meeting[]> {
+  return (await fetchServerData<{ meetings: Meeting[] | null }>('/meetings'))?.meetings ?? [];
+}
+
+export async function getInterviews(): Promise<Interview[]> {
+  return (await fetchServerData<{ interviews: Interview[] | null }>('/interviews'))?.interviews ?? [];
+}
+
+export async function getRecordings(): Promise<Recording[]> {
+  const recordings = (await fetchServerData<{ recordings: Recording[] | null }>('/recordings'))?.recordings ?? [];
+  return recordings.sort((a, b) => b.created_at.localeCompare(a.created_at));
+}
+
+// A meeting by ID, or null if it doesn't exist or the caller can't see it
+export async function getMeeting(id: number): Promise<Meeting | null> {
+  return (await fetchServerData<{ meeting: Meeting }>(`/meetings/${id}`))?.meeting ?? null;
+}
+
+// Adds the Authorization header, session cookie and client address of the
+// incoming request to headers
+function withCredentials(
+  headers: Record<string, string>,
+  incoming: Headers,
+  session: string | undefined
+): Record<string, string> {
+  const forwarded = { ...headers };
+
+  const authorization = incoming.get('authorization');
+  if (authorization) {
+    forwarded['Authorization'] = authorization;
+  }
+
+  if (session) {
+    forwarded['Cookie'] = `${SESSION_COOKIE}=${session}`;
+  }
+
+  const client = incoming.get('x-forwarded-for');
+  if (client) {
+    forwarded['X-Forwarded-For'] = client;
+  }
+
+  return forwarded;
+}